@@ -0,0 +1,198 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accounts tracks per-account positions and profit and loss from
+// the fills of an orderbook.OrderBook.
+package accounts
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// Position is an account's net holding. Quantity is positive when long
+// and negative when short; AvgPrice is the average entry price of the open
+// quantity.
+type Position struct {
+	Account  string  `json:"account"`
+	Quantity float64 `json:"quantity"`
+	AvgPrice float64 `json:"avgPrice"`
+	Realized float64 `json:"realized"`
+}
+
+func (p Position) Unrealized(mark float64) float64 {
+	return (mark - p.AvgPrice) * p.Quantity
+}
+
+func (p *Position) fill(side orderbook.Side, price, qty float64) {
+	q := qty
+	if side == orderbook.Sell {
+		q = -qty
+	}
+	if p.Quantity == 0 || (p.Quantity > 0) == (q > 0) {
+		open := math.Abs(p.Quantity)
+		p.AvgPrice = (p.AvgPrice*open + price*qty) / (open + qty)
+		p.Quantity += q
+		return
+	}
+	closed := math.Min(qty, math.Abs(p.Quantity))
+	if p.Quantity > 0 {
+		p.Realized += closed * (price - p.AvgPrice)
+	} else {
+		p.Realized += closed * (p.AvgPrice - price)
+	}
+	before := p.Quantity
+	p.Quantity += q
+	switch {
+	case p.Quantity == 0:
+		p.AvgPrice = 0
+	case (before > 0) != (p.Quantity > 0):
+		p.AvgPrice = price
+	}
+}
+
+// MarkFunc returns the price open positions are valued at.
+type MarkFunc func() (float64, bool)
+
+// Midpoint marks positions at the book's midpoint.
+func Midpoint(ob *orderbook.OrderBook) MarkFunc {
+	return ob.Midpoint
+}
+
+// LastTrade is an orderbook.EventSink that remembers the price of the
+// most recent trade, for use as a MarkFunc via Mark.
+type LastTrade struct {
+	lock  sync.Mutex
+	price float64
+	ok    bool
+}
+
+func (l *LastTrade) Mark() (float64, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.price, l.ok
+}
+
+func (l *LastTrade) Quote(*orderbook.Quote)    {}
+func (l *LastTrade) Diff(*orderbook.DepthDiff) {}
+func (l *LastTrade) Trade(e *orderbook.TradeEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.price, l.ok = e.Price, true
+}
+
+type Ledger struct {
+	Mark MarkFunc
+
+	lock      sync.Mutex
+	positions map[string]*Position
+}
+
+func NewLedger(mark MarkFunc) *Ledger {
+	return &Ledger{Mark: mark, positions: make(map[string]*Position)}
+}
+
+func (l *Ledger) position(account string) *Position {
+	p, ok := l.positions[account]
+	if !ok {
+		p = &Position{Account: account}
+		l.positions[account] = p
+	}
+	return p
+}
+
+// Apply books the trades of an ExecutionReport to the submitting account,
+// charging their taker fees against realized P&L.
+func (l *Ledger) Apply(account string, r orderbook.ExecutionReport) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	p := l.position(account)
+	for _, t := range r.Trades {
+		p.fill(r.Side, t.Price, t.Quantity)
+		p.Realized -= t.TakerFee
+	}
+}
+
+// Fill books a single fill, such as the passive side of a trade.
+func (l *Ledger) Fill(account string, side orderbook.Side, price, qty float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.position(account).fill(side, price, qty)
+}
+
+// Correct reverses a busted or corrected trade between the taker and maker
+// accounts at its original price, refunding the taker fee of a bust, and
+// books a correction at its new price and quantity.
+func (l *Ledger) Correct(c *orderbook.TradeCorrection, taker, maker string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	o := c.Original
+	l.position(taker).fill(o.Side.Opposite(), o.Price, o.Quantity)
+	l.position(maker).fill(o.Side, o.Price, o.Quantity)
+	if c.Busted {
+		l.position(taker).Realized += o.TakerFee
+		return
+	}
+	l.position(taker).fill(o.Side, c.Corrected.Price, c.Corrected.Quantity)
+	l.position(maker).fill(o.Side.Opposite(), c.Corrected.Price, c.Corrected.Quantity)
+}
+
+// Charge books a fee, or a rebate when negative, against realized P&L.
+func (l *Ledger) Charge(account string, fee float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.position(account).Realized -= fee
+}
+
+func (l *Ledger) Position(account string) Position {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if p, ok := l.positions[account]; ok {
+		return *p
+	}
+	return Position{Account: account}
+}
+
+// Positions returns every account's position ordered by account.
+func (l *Ledger) Positions() []Position {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	ps := make([]Position, 0, len(l.positions))
+	for _, p := range l.positions {
+		ps = append(ps, *p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Account < ps[j].Account })
+	return ps
+}
+
+// PnL returns the account's realized and unrealized profit. Unrealized is
+// zero when there is no mark price.
+func (l *Ledger) PnL(account string) (realized, unrealized float64) {
+	p := l.Position(account)
+	if l.Mark != nil {
+		if mark, ok := l.Mark(); ok {
+			unrealized = p.Unrealized(mark)
+		}
+	}
+	return p.Realized, unrealized
+}
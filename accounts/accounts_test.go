@@ -0,0 +1,127 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package accounts
+
+import (
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestFill(t *testing.T) {
+	type fill struct {
+		Side  orderbook.Side
+		Price float64
+		Qty   float64
+	}
+	tests := []struct {
+		Name     string
+		Fills    []fill
+		Expected Position
+	}{
+		{"open long", []fill{{orderbook.Buy, 10, 2}, {orderbook.Buy, 13, 1}},
+			Position{Quantity: 3, AvgPrice: 11}},
+		{"partial close", []fill{{orderbook.Buy, 10, 4}, {orderbook.Sell, 12, 1}},
+			Position{Quantity: 3, AvgPrice: 10, Realized: 2}},
+		{"flat", []fill{{orderbook.Sell, 10, 2}, {orderbook.Buy, 8, 2}},
+			Position{Quantity: 0, AvgPrice: 0, Realized: 4}},
+		{"reverse", []fill{{orderbook.Buy, 10, 1}, {orderbook.Sell, 9, 3}},
+			Position{Quantity: -2, AvgPrice: 9, Realized: -1}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			l := NewLedger(nil)
+			for _, f := range test.Fills {
+				l.Fill("acct", f.Side, f.Price, f.Qty)
+			}
+			test.Expected.Account = "acct"
+			if p := l.Position("acct"); p != test.Expected {
+				t.Errorf("Expected %+v, got %+v", test.Expected, p)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	last := &LastTrade{}
+	ob.AddSink(last)
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(100, 1, "a1"))
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(104, 1, "a2"))
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(96, 1, "b1"))
+
+	l := NewLedger(Midpoint(ob))
+	l.Apply("taker", ob.Submit(orderbook.NewOrder(100, 1, "t1"), orderbook.Buy))
+	if p := l.Position("taker"); p.Quantity != 1 || p.AvgPrice != 100 {
+		t.Errorf("Expected long 1 at 100, got %+v", p)
+	}
+	if _, u := l.PnL("taker"); u != 0 {
+		t.Errorf("Expected unrealized 0 at mid 100, got %g", u)
+	}
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(102, 1, "b2"))
+	if _, u := l.PnL("taker"); u != 3 {
+		t.Errorf("Expected unrealized 3 at mid 103, got %g", u)
+	}
+
+	l.Mark = last.Mark
+	if _, u := l.PnL("taker"); u != 0 {
+		t.Errorf("Expected unrealized 0 at last trade 100, got %g", u)
+	}
+	fees := orderbook.NewOrderBook(orderbook.WithFees(orderbook.Fees{TakerFixed: 0.25}))
+	fees.PushOrder(orderbook.Sell, orderbook.NewOrder(100, 1, "a1"))
+	l.Apply("fees", fees.Submit(orderbook.NewOrder(100, 1, "t2"), orderbook.Buy))
+	if r, _ := l.PnL("fees"); r != -0.25 {
+		t.Errorf("Expected the taker fee charged to realized, got %g", r)
+	}
+
+	if ps := l.Positions(); len(ps) != 2 || ps[1].Account != "taker" {
+		t.Errorf("Expected two positions, got %+v", ps)
+	}
+}
+
+func TestCharge(t *testing.T) {
+	l := NewLedger(nil)
+	l.Fill("a", orderbook.Buy, 10, 1)
+	l.Charge("a", 0.25)
+	l.Charge("a", -1)
+	if p := l.Position("a"); p.Realized != 0.75 {
+		t.Errorf("Expected 0.75 realized after a fee and a rebate, got %v", p.Realized)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	ob := orderbook.NewOrderBook(orderbook.WithTradeTape(8))
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(100, 2, "a1"))
+	r := ob.Submit(orderbook.NewOrder(100, 2, "b1"), orderbook.Buy)
+
+	l := NewLedger(nil)
+	l.Apply("taker", r)
+	l.Fill("maker", orderbook.Sell, 100, 2)
+	c := &orderbook.TradeCorrection{Original: r.Trades[0], Corrected: r.Trades[0]}
+	c.Corrected.Price, c.Corrected.Quantity = 99, 1
+	l.Correct(c, "taker", "maker")
+	if p := l.Position("taker"); p.Quantity != 1 || p.AvgPrice != 99 || p.Realized != 0 {
+		t.Errorf("Expected the taker long 1 at 99, got %+v", p)
+	}
+	if p := l.Position("maker"); p.Quantity != -1 || p.AvgPrice != 99 || p.Realized != 0 {
+		t.Errorf("Expected the maker short 1 at 99, got %+v", p)
+	}
+
+	l.Correct(&orderbook.TradeCorrection{Original: c.Corrected, Busted: true}, "taker", "maker")
+	for _, p := range l.Positions() {
+		if p.Quantity != 0 || p.Realized != 0 {
+			t.Errorf("Expected a bust to leave %s flat, got %+v", p.Account, p)
+		}
+	}
+}
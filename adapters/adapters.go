@@ -0,0 +1,83 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adapters maintains a local orderbook.OrderBook from public
+// exchange depth streams.
+//
+// The package does not dial WebSockets itself; any connection exposing
+// ReadMessage in the shape of github.com/gorilla/websocket.Conn can be
+// passed in.
+package adapters
+
+import (
+	"errors"
+	"strconv"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+var ErrOutOfSync = errors.New("adapters: depth stream out of sync with snapshot")
+
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+}
+
+// levelBook is a side of the book whose levels can be resized in place.
+type levelBook interface {
+	orderbook.Book
+	UpdateQuantity(key string, qty float64) bool
+}
+
+// applyLevel sets the aggregate quantity at price, removing the level when
+// quantity is zero. Levels are keyed by their normalized price.
+func applyLevel(book levelBook, price, quantity string) error {
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return err
+	}
+	q, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatFloat(p, 'f', -1, 64)
+	if q == 0 {
+		book.Remove(key)
+		return nil
+	}
+	if book.UpdateQuantity(key, q) {
+		return nil
+	}
+	o := orderbook.NewOrder(p, q, key)
+	n := orderbook.NewNode(key, &o, 1)
+	book.Push(&n)
+	return nil
+}
+
+func applyLevels(book levelBook, levels [][2]string) error {
+	for _, l := range levels {
+		if err := applyLevel(book, l[0], l[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reset(ob *orderbook.OrderBook) {
+	for ob.AskBook.Len() > 0 {
+		ob.AskBook.Pop()
+	}
+	for ob.BidBook.Len() > 0 {
+		ob.BidBook.Pop()
+	}
+}
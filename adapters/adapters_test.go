@@ -0,0 +1,207 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package adapters
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+type fakeConn struct {
+	messages []string
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	if len(c.messages) == 0 {
+		return 0, nil, io.EOF
+	}
+	msg := c.messages[0]
+	c.messages = c.messages[1:]
+	return 1, []byte(msg), nil
+}
+
+func TestBinance(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"e":"depthUpdate","s":"BNBBTC","U":98,"u":99,"b":[["0.0020","5"]],"a":[]}`,
+		`{"e":"depthUpdate","s":"BNBBTC","U":100,"u":102,"b":[["0.0024","10"]],"a":[["0.0026","0"]]}`,
+		`{"e":"depthUpdate","s":"BNBBTC","U":103,"u":104,"b":[],"a":[["0.0027","3"]]}`,
+	}}
+	ob := orderbook.NewOrderBook()
+	b := NewBinance("bnbbtc", conn, ob)
+	b.Snapshot = func(symbol string) (*BinanceSnapshot, error) {
+		return &BinanceSnapshot{
+			LastUpdateId: 100,
+			Bids:         [][2]string{{"0.0023", "1"}, {"0.0022", "2"}},
+			Asks:         [][2]string{{"0.0026", "4"}, {"0.0028", "1"}},
+		}, nil
+	}
+	if err := b.Run(); err != io.EOF {
+		t.Fatalf("Expected stream to end with EOF, got %v", err)
+	}
+	if b.LastUpdateId() != 104 {
+		t.Errorf("Expected last update id 104, got %d", b.LastUpdateId())
+	}
+	if ob.BidBook.Len() != 3 || ob.BidBook.Peek().Price != 0.0024 {
+		t.Errorf("Expected 3 bid levels with best 0.0024, got %d", ob.BidBook.Len())
+	}
+	if _, ok := ob.BidBook.Get("0.002"); ok {
+		t.Errorf("Expected update preceding the snapshot to be dropped")
+	}
+	if ob.AskBook.Len() != 2 || ob.AskBook.Peek().Price != 0.0027 {
+		t.Errorf("Expected 2 ask levels with best 0.0027, got %d", ob.AskBook.Len())
+	}
+}
+
+func TestBinanceOutOfSync(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"e":"depthUpdate","s":"BNBBTC","U":100,"u":102,"b":[],"a":[]}`,
+		`{"e":"depthUpdate","s":"BNBBTC","U":108,"u":109,"b":[],"a":[]}`,
+	}}
+	b := NewBinance("bnbbtc", conn, orderbook.NewOrderBook())
+	b.Snapshot = func(symbol string) (*BinanceSnapshot, error) {
+		return &BinanceSnapshot{LastUpdateId: 105}, nil
+	}
+	if err := b.Sync(); err != ErrOutOfSync {
+		t.Errorf("Expected ErrOutOfSync, got %v", err)
+	}
+}
+
+func TestBinanceStaleSnapshot(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"e":"depthUpdate","s":"BNBBTC","U":100,"u":102,"b":[],"a":[]}`,
+	}}
+	b := NewBinance("bnbbtc", conn, orderbook.NewOrderBook())
+	var fetches int
+	b.Snapshot = func(symbol string) (*BinanceSnapshot, error) {
+		fetches++
+		return &BinanceSnapshot{LastUpdateId: 90}, nil
+	}
+	var waits []time.Duration
+	b.Sleep = func(d time.Duration) { waits = append(waits, d) }
+	if err := b.Sync(); err != ErrStaleSnapshot {
+		t.Errorf("Expected ErrStaleSnapshot, got %v", err)
+	}
+	if fetches != BinanceSnapshotAttempts {
+		t.Errorf("Expected %d fetches, got %d", BinanceSnapshotAttempts, fetches)
+	}
+	expected := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second}
+	if !reflect.DeepEqual(waits, expected) {
+		t.Errorf("Expected waits %v, got %v", expected, waits)
+	}
+}
+
+func TestCoinbase(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"type":"subscriptions","channels":[]}`,
+		`{"type":"snapshot","product_id":"BTC-USD","bids":[["100.00","1.5"],["99.50","2"]],"asks":[["101.00","1"]]}`,
+		`{"type":"l2update","product_id":"BTC-USD","changes":[["buy","100.25","0.5"],["sell","101.00","0"],["sell","102.00","3"]]}`,
+		`{"type":"l2update","product_id":"ETH-USD","changes":[["buy","1.00","1"]]}`,
+	}}
+	ob := orderbook.NewOrderBook()
+	c := NewCoinbase("BTC-USD", conn, ob)
+	if err := c.Run(); err != io.EOF {
+		t.Fatalf("Expected stream to end with EOF, got %v", err)
+	}
+	if ob.BidBook.Len() != 3 || ob.BidBook.Peek().Price != 100.25 {
+		t.Errorf("Expected 3 bid levels with best 100.25, got %d", ob.BidBook.Len())
+	}
+	if ob.AskBook.Len() != 1 || ob.AskBook.Peek().Price != 102 {
+		t.Errorf("Expected 1 ask level at 102, got %d", ob.AskBook.Len())
+	}
+}
+
+type diffSink struct {
+	diffs []orderbook.DepthDiff
+}
+
+func (s *diffSink) Quote(*orderbook.Quote)      {}
+func (s *diffSink) Trade(*orderbook.TradeEvent) {}
+func (s *diffSink) Diff(d *orderbook.DepthDiff) { s.diffs = append(s.diffs, *d) }
+
+func TestCoinbaseLevelChange(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"type":"snapshot","product_id":"BTC-USD","bids":[["100.00","1.5"],["99.50","1"]],"asks":[]}`,
+		`{"type":"l2update","product_id":"BTC-USD","changes":[["buy","100.00","7"]]}`,
+	}}
+	ob := orderbook.NewOrderBook()
+	sink := &diffSink{}
+	ob.AddSink(sink)
+	c := NewCoinbase("BTC-USD", conn, ob)
+	if err := c.Run(); err != io.EOF {
+		t.Fatalf("Expected stream to end with EOF, got %v", err)
+	}
+	if v := ob.Volume(); v != 8 {
+		t.Errorf("Expected volume 8, got %v", v)
+	}
+	if len(sink.diffs) == 0 {
+		t.Fatalf("Expected the level change to be published")
+	}
+	last := sink.diffs[len(sink.diffs)-1]
+	if len(last.Bids) != 1 || last.Bids[0].Price != 100 || last.Bids[0].Quantity != 7 {
+		t.Errorf("Expected a diff setting 100 to 7, got %+v", last.Bids)
+	}
+}
+
+func TestCoinbaseUpdateBeforeSnapshot(t *testing.T) {
+	conn := &fakeConn{[]string{
+		`{"type":"l2update","product_id":"BTC-USD","changes":[["buy","100.25","0.5"]]}`,
+	}}
+	c := NewCoinbase("BTC-USD", conn, orderbook.NewOrderBook())
+	if err := c.Next(); err != ErrOutOfSync {
+		t.Errorf("Expected ErrOutOfSync, got %v", err)
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	tests := []struct {
+		name, body string
+		seq        uint64
+	}{
+		{"binance", `{"lastUpdateId":42,"bids":[["99.5","2"],["99","1"]],"asks":[["100","3"]]}`, 42},
+		{"coinbase", `{"sequence":"7","bids":[["99.5","2",1],["99","1",3]],"asks":[["100","3",2]]}`, 7},
+		{"kraken", `{"error":[],"result":{"XXBTZUSD":{"bids":[["99.5","2",1560000000],["99","1",1560000000]],"asks":[["100","3",1560000000]]}}}`, 0},
+		{"okx", `{"code":"0","data":[{"bids":[["99.5","2","0","1"],["99","1","0","1"]],"asks":[["100","3","0","1"]],"ts":"1"}]}`, 0},
+		{"objects", `{"bids":[{"price":"99.5","amount":"2"},{"price":99,"size":1}],"asks":[{"price":"100","quantity":3}]}`, 0},
+	}
+	for _, tt := range tests {
+		snap, err := ParseDepth([]byte(tt.body))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(snap.Bids) != 2 || snap.Bids[0] != (orderbook.Level{Price: 99.5, Quantity: 2}) ||
+			len(snap.Asks) != 1 || snap.Asks[0] != (orderbook.Level{Price: 100, Quantity: 3}) || snap.Sequence != tt.seq {
+			t.Errorf("%s: Expected two bids, one ask and sequence %d, got %+v", tt.name, tt.seq, snap)
+		}
+	}
+	for _, body := range []string{`{"foo":1,"bar":2}`, `{"bids":[["x","1"]]}`, `{"bids":[["1"]]}`} {
+		if _, err := ParseDepth([]byte(body)); err == nil {
+			t.Errorf("Expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestWarmStart(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	if err := WarmStart(ob, []byte(`{"lastUpdateId":5,"bids":[["99","1"],["98","2"]],"asks":[["101","4"]]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if p, s, ok := ob.BestBid(); !ok || p != 99 || s != 1 || !ob.Synced() {
+		t.Errorf("Expected a synced book bid 1 at 99, got %v %v %v", p, s, ok)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+const (
+	BinanceStreamURL   = "wss://stream.binance.com:9443/ws/%s@depth@100ms"
+	BinanceSnapshotURL = "https://api.binance.com/api/v3/depth?symbol=%s&limit=1000"
+	// BinanceSnapshotAttempts bounds the snapshots Sync fetches waiting
+	// for one that reaches the first buffered event. The wait between
+	// fetches starts at BinanceSnapshotBackoff and doubles.
+	BinanceSnapshotAttempts = 5
+	BinanceSnapshotBackoff  = 250 * time.Millisecond
+)
+
+var ErrStaleSnapshot = errors.New("adapters: binance snapshot never reached the depth stream")
+
+type BinanceSnapshot struct {
+	LastUpdateId uint64      `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+type BinanceDepthUpdate struct {
+	Event         string      `json:"e"`
+	Symbol        string      `json:"s"`
+	FirstUpdateId uint64      `json:"U"`
+	FinalUpdateId uint64      `json:"u"`
+	Bids          [][2]string `json:"b"`
+	Asks          [][2]string `json:"a"`
+}
+
+// Binance keeps Book in sync with a <symbol>@depth diff stream, following
+// the snapshot procedure Binance documents for local order books.
+type Binance struct {
+	Symbol string
+	Conn   Conn
+	Book   *orderbook.OrderBook
+	// Snapshot fetches the REST depth snapshot; defaults to an HTTP GET
+	// of BinanceSnapshotURL.
+	Snapshot func(symbol string) (*BinanceSnapshot, error)
+	// Sleep waits between snapshot fetches; it defaults to time.Sleep.
+	Sleep func(time.Duration)
+
+	lastUpdateId uint64
+}
+
+func NewBinance(symbol string, conn Conn, book *orderbook.OrderBook) *Binance {
+	return &Binance{
+		Symbol:   strings.ToUpper(symbol),
+		Conn:     conn,
+		Book:     book,
+		Snapshot: fetchBinanceSnapshot,
+		Sleep:    time.Sleep,
+	}
+}
+
+func fetchBinanceSnapshot(symbol string) (*BinanceSnapshot, error) {
+	resp, err := http.Get(fmt.Sprintf(BinanceSnapshotURL, symbol))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("adapters: binance snapshot: %s", resp.Status)
+	}
+	var snap BinanceSnapshot
+	err = json.NewDecoder(resp.Body).Decode(&snap)
+	return &snap, err
+}
+
+// LastUpdateId returns the final update id applied to Book.
+func (b *Binance) LastUpdateId() uint64 {
+	return b.lastUpdateId
+}
+
+func (b *Binance) read() (*BinanceDepthUpdate, error) {
+	_, msg, err := b.Conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	var u BinanceDepthUpdate
+	if err := json.Unmarshal(msg, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Sync buffers the stream, loads a snapshot no older than the first
+// buffered event and replays the buffer on top of it. It gives up with
+// ErrStaleSnapshot after BinanceSnapshotAttempts stale snapshots.
+func (b *Binance) Sync() error {
+	first, err := b.read()
+	if err != nil {
+		return err
+	}
+	buffered := []*BinanceDepthUpdate{first}
+	sleep := b.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	var snap *BinanceSnapshot
+	wait := BinanceSnapshotBackoff
+	for attempt := 0; snap == nil || snap.LastUpdateId < first.FirstUpdateId; attempt++ {
+		if attempt == BinanceSnapshotAttempts {
+			return ErrStaleSnapshot
+		}
+		if attempt > 0 {
+			sleep(wait)
+			wait *= 2
+		}
+		if snap, err = b.Snapshot(b.Symbol); err != nil {
+			return err
+		}
+	}
+	reset(b.Book)
+	if err := applyLevels(&b.Book.BidBook, snap.Bids); err != nil {
+		return err
+	}
+	if err := applyLevels(&b.Book.AskBook, snap.Asks); err != nil {
+		return err
+	}
+	b.lastUpdateId = snap.LastUpdateId
+
+	for len(buffered) > 0 && buffered[len(buffered)-1].FinalUpdateId <= b.lastUpdateId {
+		u, err := b.read()
+		if err != nil {
+			return err
+		}
+		buffered = append(buffered, u)
+	}
+	synced := false
+	for _, u := range buffered {
+		if u.FinalUpdateId <= b.lastUpdateId {
+			continue
+		}
+		if !synced && (u.FirstUpdateId > b.lastUpdateId+1) {
+			return ErrOutOfSync
+		}
+		synced = true
+		if err := b.apply(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Binance) apply(u *BinanceDepthUpdate) error {
+	if err := applyLevels(&b.Book.BidBook, u.Bids); err != nil {
+		return err
+	}
+	if err := applyLevels(&b.Book.AskBook, u.Asks); err != nil {
+		return err
+	}
+	b.lastUpdateId = u.FinalUpdateId
+	return nil
+}
+
+// Run synchronizes the book and then applies updates until the connection
+// fails. A gap in update ids triggers a fresh snapshot.
+func (b *Binance) Run() error {
+	if err := b.Sync(); err != nil {
+		return err
+	}
+	for {
+		u, err := b.read()
+		if err != nil {
+			return err
+		}
+		if u.FinalUpdateId <= b.lastUpdateId {
+			continue
+		}
+		if u.FirstUpdateId != b.lastUpdateId+1 {
+			if err := b.Sync(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.apply(u); err != nil {
+			return err
+		}
+	}
+}
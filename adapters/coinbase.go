@@ -0,0 +1,117 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+const CoinbaseStreamURL = "wss://ws-feed.exchange.coinbase.com"
+
+type coinbaseMessage struct {
+	Type      string      `json:"type"`
+	ProductId string      `json:"product_id"`
+	Bids      [][2]string `json:"bids"`
+	Asks      [][2]string `json:"asks"`
+	Changes   [][3]string `json:"changes"`
+	Message   string      `json:"message"`
+	Reason    string      `json:"reason"`
+}
+
+// Coinbase keeps Book in sync with the Coinbase Exchange level2 channel,
+// which delivers its snapshot in-band ahead of l2update messages. The
+// connection must already be subscribed; see CoinbaseSubscribe.
+type Coinbase struct {
+	ProductId string
+	Conn      Conn
+	Book      *orderbook.OrderBook
+
+	synced bool
+}
+
+func NewCoinbase(productId string, conn Conn, book *orderbook.OrderBook) *Coinbase {
+	return &Coinbase{
+		ProductId: productId,
+		Conn:      conn,
+		Book:      book,
+	}
+}
+
+// CoinbaseSubscribe returns the subscribe message for the level2 channel.
+func CoinbaseSubscribe(productIds ...string) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":        "subscribe",
+		"product_ids": productIds,
+		"channels":    []string{"level2"},
+	})
+	return msg
+}
+
+func (c *Coinbase) Synced() bool {
+	return c.synced
+}
+
+// Next reads and applies a single message from the stream.
+func (c *Coinbase) Next() error {
+	_, raw, err := c.Conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	var msg coinbaseMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+	if msg.ProductId != "" && msg.ProductId != c.ProductId {
+		return nil
+	}
+	switch msg.Type {
+	case "error":
+		return fmt.Errorf("adapters: coinbase: %s %s", msg.Message, msg.Reason)
+	case "snapshot":
+		reset(c.Book)
+		if err := applyLevels(&c.Book.BidBook, msg.Bids); err != nil {
+			return err
+		}
+		if err := applyLevels(&c.Book.AskBook, msg.Asks); err != nil {
+			return err
+		}
+		c.synced = true
+	case "l2update":
+		if !c.synced {
+			return ErrOutOfSync
+		}
+		for _, change := range msg.Changes {
+			var book levelBook = &c.Book.BidBook
+			if change[0] == "sell" {
+				book = &c.Book.AskBook
+			}
+			if err := applyLevel(book, change[1], change[2]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run applies messages until the connection fails.
+func (c *Coinbase) Run() error {
+	for {
+		if err := c.Next(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+var ErrNoDepth = errors.New("adapters: no bids or asks in depth response")
+
+// ParseDepth reads a REST depth response into a snapshot. It accepts the
+// common shapes:
+//
+//	{"bids":[["100.5","2"],...],"asks":[...]}              Binance, Bitstamp, Coinbase
+//	{"result":{"XXBTZUSD":{"bids":[[...]],"asks":[[...]]}}} Kraken
+//	{"data":[{"bids":[[...]],"asks":[[...]]}]}               OKX
+//	{"bids":[{"price":"100.5","amount":"2"}],...}           Gemini and other object levels
+//
+// Prices and quantities may be strings or numbers, and array levels may
+// carry extra fields after them, such as order counts or timestamps. A
+// lastUpdateId or sequence field becomes the snapshot's Sequence.
+func ParseDepth(data []byte) (orderbook.DepthSnapshot, error) {
+	var snap orderbook.DepthSnapshot
+	book, err := findDepth(data, 3)
+	if err != nil {
+		return snap, err
+	}
+	if snap.Bids, err = parseLevels(book["bids"]); err != nil {
+		return snap, fmt.Errorf("adapters: bids: %v", err)
+	}
+	if snap.Asks, err = parseLevels(book["asks"]); err != nil {
+		return snap, fmt.Errorf("adapters: asks: %v", err)
+	}
+	for _, field := range []string{"lastUpdateId", "sequence"} {
+		if raw, ok := book[field]; ok {
+			if snap.Sequence, err = strconv.ParseUint(string(bytes.Trim(raw, `"`)), 10, 64); err != nil {
+				return snap, fmt.Errorf("adapters: %s: %v", field, err)
+			}
+			break
+		}
+	}
+	return snap, nil
+}
+
+// findDepth returns the object holding bids and asks, looking through
+// result and data wrappers, single-element arrays and objects keyed by a
+// single symbol, at most depth levels down.
+func findDepth(data []byte, depth int) (map[string]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return nil, err
+		}
+		if len(elems) != 1 || depth == 0 {
+			return nil, ErrNoDepth
+		}
+		return findDepth(elems[0], depth-1)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	_, bids := obj["bids"]
+	_, asks := obj["asks"]
+	if bids || asks {
+		return obj, nil
+	}
+	if depth == 0 {
+		return nil, ErrNoDepth
+	}
+	for _, wrapper := range []string{"result", "data"} {
+		if inner, ok := obj[wrapper]; ok {
+			return findDepth(inner, depth-1)
+		}
+	}
+	if len(obj) == 1 {
+		for _, inner := range obj {
+			return findDepth(inner, depth-1)
+		}
+	}
+	return nil, ErrNoDepth
+}
+
+var quantityFields = []string{"quantity", "size", "amount", "qty", "volume"}
+
+func parseLevels(data json.RawMessage) ([]orderbook.Level, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	lvls := make([]orderbook.Level, 0, len(raw))
+	for i, r := range raw {
+		var price, qty json.RawMessage
+		r = bytes.TrimSpace(r)
+		if len(r) > 0 && r[0] == '{' {
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(r, &obj); err != nil {
+				return nil, err
+			}
+			price = obj["price"]
+			for _, f := range quantityFields {
+				if q, ok := obj[f]; ok {
+					qty = q
+					break
+				}
+			}
+		} else {
+			var fields []json.RawMessage
+			if err := json.Unmarshal(r, &fields); err != nil {
+				return nil, err
+			}
+			if len(fields) >= 2 {
+				price, qty = fields[0], fields[1]
+			}
+		}
+		if price == nil || qty == nil {
+			return nil, fmt.Errorf("level %d has no price or quantity", i)
+		}
+		l, err := parseLevel(price, qty)
+		if err != nil {
+			return nil, fmt.Errorf("level %d: %v", i, err)
+		}
+		lvls = append(lvls, l)
+	}
+	return lvls, nil
+}
+
+func parseLevel(price, qty json.RawMessage) (orderbook.Level, error) {
+	p, err := strconv.ParseFloat(string(bytes.Trim(price, `"`)), 64)
+	if err != nil {
+		return orderbook.Level{}, err
+	}
+	q, err := strconv.ParseFloat(string(bytes.Trim(qty, `"`)), 64)
+	if err != nil {
+		return orderbook.Level{}, err
+	}
+	return orderbook.Level{Price: p, Quantity: q}, nil
+}
+
+// WarmStart loads a REST depth response into ob with ApplySnapshot, which
+// rests each side's levels in one bulk load.
+func WarmStart(ob *orderbook.OrderBook, data []byte) error {
+	snap, err := ParseDepth(data)
+	if err != nil {
+		return err
+	}
+	return ob.ApplySnapshot(snap)
+}
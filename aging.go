@@ -0,0 +1,205 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ageHeap indexes a side's resting nodes by entry time, then arrival, so
+// the oldest is found without scanning the side and any node is removed
+// in O(log n).
+type ageHeap []*Node
+
+func (h ageHeap) Len() int { return len(h) }
+
+func (h ageHeap) Less(i, j int) bool {
+	if !h[i].Time.Equal(h[j].Time) {
+		return h[i].Time.Before(h[j].Time)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h ageHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].ageIndex = i
+	h[j].ageIndex = j
+}
+
+func (h *ageHeap) Push(x interface{}) {
+	*h = append(*h, x.(*Node))
+	(*h)[len(*h)-1].ageIndex = len(*h) - 1
+}
+
+func (h *ageHeap) Pop() interface{} {
+	x := (*h)[len(*h)-1]
+	(*h)[len(*h)-1] = nil
+	*h = (*h)[:len(*h)-1]
+	return x
+}
+
+// oldest returns the earliest entered node on the side, or at the given
+// effective price when atPrice is set; a level is scanned in time order
+// rather than the whole side. The caller holds the lock.
+func (sb *SideBook) oldest(price float64, atPrice bool) *Node {
+	if !atPrice {
+		if len(sb.byAge) == 0 {
+			return nil
+		}
+		return sb.byAge[0]
+	}
+	l, ok := sb.levels.level(price)
+	if !ok {
+		return nil
+	}
+	var oldest *Node
+	for _, n := range l.orders {
+		if oldest == nil || n.Time.Before(oldest.Time) {
+			oldest = n
+		}
+	}
+	return oldest
+}
+
+// Oldest returns the order on side that has rested longest, found from
+// the side's age index without a scan.
+func (ob *OrderBook) Oldest(side Side) (Entry, bool) {
+	return ob.oldestEntry(side, 0, false)
+}
+
+// OldestAt is Oldest among the orders at an effective price.
+func (ob *OrderBook) OldestAt(side Side, price float64) (Entry, bool) {
+	return ob.oldestEntry(side, price, true)
+}
+
+func (ob *OrderBook) oldestEntry(side Side, price float64, atPrice bool) (Entry, bool) {
+	b := ob.Side(side)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if n := b.oldest(price, atPrice); n != nil && n.Peek() != nil {
+		return Entry{side, n.Key, *n.Peek(), n.Weight, n.Time}, true
+	}
+	return Entry{}, false
+}
+
+// CancelOldest cancels the order Oldest would return and returns a copy
+// of it.
+func (ob *OrderBook) CancelOldest(side Side) (Order, bool) {
+	return ob.cancelOldest(side, 0, false)
+}
+
+// CancelOldestAt cancels the order OldestAt would return.
+func (ob *OrderBook) CancelOldestAt(side Side, price float64) (Order, bool) {
+	return ob.cancelOldest(side, price, true)
+}
+
+func (ob *OrderBook) cancelOldest(side Side, price float64, atPrice bool) (Order, bool) {
+	b := ob.Side(side)
+	defer b.flush()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	n := b.oldest(price, atPrice)
+	if n == nil || n.Peek() == nil {
+		return Order{}, false
+	}
+	o := *n.Peek()
+	b.cancel(n.Key)
+	return o, true
+}
+
+// ageAlert remembers which orders have already been reported as aged, by
+// their entry time so a re-entered order is reported again.
+type ageAlert struct {
+	lock      sync.Mutex
+	threshold time.Duration
+	fn        func(Entry)
+	alerted   map[orderRef]time.Time
+}
+
+// WithAgeAlert has CheckAges call fn once for every resting order that has
+// been on the book longer than threshold.
+func WithAgeAlert(threshold time.Duration, fn func(Entry)) Option {
+	return func(ob *OrderBook) {
+		ob.ages = &ageAlert{threshold: threshold, fn: fn, alerted: make(map[orderRef]time.Time)}
+	}
+}
+
+func aged(entries []Entry, cutoff time.Time) []Entry {
+	var old []Entry
+	for _, e := range entries {
+		if e.Time.Before(cutoff) {
+			old = append(old, e)
+		}
+	}
+	return old
+}
+
+// AgedOrders returns the resting orders entered more than olderThan ago,
+// bids then asks in priority order.
+func (ob *OrderBook) AgedOrders(olderThan time.Duration) []Entry {
+	cutoff := ob.BidBook.clock().Add(-olderThan)
+	return append(aged(ob.Entries(Buy), cutoff), aged(ob.Entries(Sell), cutoff)...)
+}
+
+// CheckAges calls the WithAgeAlert callback for each order that has
+// crossed the threshold as of now since the last check, and returns them.
+func (ob *OrderBook) CheckAges(now time.Time) []Entry {
+	a := ob.ages
+	if a == nil {
+		return nil
+	}
+	cutoff := now.Add(-a.threshold)
+	all := append(ob.Entries(Buy), ob.Entries(Sell)...)
+
+	a.lock.Lock()
+	resting := make(map[orderRef]bool, len(all))
+	var fresh []Entry
+	for _, e := range all {
+		ref := orderRef{e.Side, e.Key}
+		resting[ref] = true
+		if t, ok := a.alerted[ref]; (!ok || !t.Equal(e.Time)) && e.Time.Before(cutoff) {
+			a.alerted[ref] = e.Time
+			fresh = append(fresh, e)
+		}
+	}
+	for ref := range a.alerted {
+		if !resting[ref] {
+			delete(a.alerted, ref)
+		}
+	}
+	a.lock.Unlock()
+
+	for _, e := range fresh {
+		a.fn(e)
+	}
+	return fresh
+}
+
+// WatchAges calls CheckAges every interval until ctx is done.
+func (ob *OrderBook) WatchAges(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			ob.CheckAges(ob.BidBook.clock())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgedOrders(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	var alerts []string
+	ob := NewOrderBook(
+		WithClock(func() time.Time { return now }),
+		WithAgeAlert(time.Minute, func(e Entry) { alerts = append(alerts, e.Key) }),
+	)
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	now = now.Add(30 * time.Second)
+	ob.Submit(NewOrder(98, 1, "b2"), Buy)
+	now = now.Add(45 * time.Second)
+
+	old := ob.AgedOrders(time.Minute)
+	if len(old) != 2 || old[0].Key != "b1" || old[1].Key != "a1" {
+		t.Errorf("Expected b1 and a1 aged, got %+v", old)
+	}
+	if n := len(ob.AgedOrders(time.Hour)); n != 0 {
+		t.Errorf("Expected nothing older than an hour, got %d", n)
+	}
+
+	ob.CheckAges(now)
+	ob.CheckAges(now)
+	if len(alerts) != 2 {
+		t.Fatalf("Expected one alert each for b1 and a1, got %v", alerts)
+	}
+
+	ob.Cancel("a1")
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	now = now.Add(2 * time.Minute)
+	ob.CheckAges(now)
+	expected := []string{"b1", "a1", "b2", "a1"}
+	if len(alerts) != len(expected) {
+		t.Fatalf("Expected alerts %v, got %v", expected, alerts)
+	}
+	for i := range expected {
+		if alerts[i] != expected[i] {
+			t.Errorf("Expected alert %d for %s, got %s", i, expected[i], alerts[i])
+		}
+	}
+}
+
+func TestOldest(t *testing.T) {
+	start := time.Unix(1000, 0)
+	ob := NewOrderBook()
+	push := func(key string, price float64, at time.Time) {
+		n := NewNode(key, &Order{Price: price, Quantity: 1, OrderId: key}, 1)
+		n.Time = at
+		ob.BidBook.Push(&n)
+	}
+	push("b1", 100, start.Add(3*time.Second))
+	push("b2", 99, start.Add(time.Second))
+	push("b3", 100, start.Add(2*time.Second))
+	push("b4", 100, start.Add(4*time.Second))
+
+	if e, ok := ob.Oldest(Buy); !ok || e.Key != "b2" {
+		t.Errorf("Expected b2 to be oldest, got %+v", e)
+	}
+	if e, ok := ob.OldestAt(Buy, 100); !ok || e.Key != "b3" {
+		t.Errorf("Expected b3 to be oldest at 100, got %+v", e)
+	}
+	if _, ok := ob.Oldest(Sell); ok {
+		t.Errorf("Expected no oldest order on an empty side")
+	}
+
+	if o, ok := ob.CancelOldest(Buy); !ok || o.OrderId != "b2" {
+		t.Errorf("Expected b2 cancelled, got %+v", o)
+	}
+	if o, ok := ob.CancelOldestAt(Buy, 100); !ok || o.OrderId != "b3" {
+		t.Errorf("Expected b3 cancelled, got %+v", o)
+	}
+	ob.BidBook.Pop()
+	if e, ok := ob.Oldest(Buy); !ok || e.Key != "b4" || ob.OrderCount(Buy) != 1 {
+		t.Errorf("Expected only b4 left, got %+v", e)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Condition inspects the book and returns the value it measured and
+// whether that value should raise an alert.
+type Condition func(ob *OrderBook) (float64, bool)
+
+// Alert fires Fire when Condition starts to hold. It fires once per
+// stretch of updates the condition holds for, and no sooner than Debounce
+// after its last firing; a stretch that starts inside the debounce fires
+// on the first update after it, if it still holds.
+type Alert struct {
+	Name      string
+	Condition Condition
+	Debounce  time.Duration
+	Fire      func(AlertEvent)
+}
+
+// AlertEvent is what an Alert fires with: the condition's value and the
+// book's time.
+type AlertEvent struct {
+	Name  string    `json:"name"`
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+type alertState struct {
+	Alert
+	fired bool
+	last  time.Time
+}
+
+type alertBook struct {
+	lock   sync.Mutex
+	alerts []*alertState
+}
+
+// WithAlert registers a at construction.
+func WithAlert(a Alert) Option {
+	return func(ob *OrderBook) {
+		ob.AddAlert(a)
+	}
+}
+
+// AddAlert registers a to be checked after every change to the book.
+func (ob *OrderBook) AddAlert(a Alert) {
+	ob.alerts.lock.Lock()
+	defer ob.alerts.lock.Unlock()
+	ob.alerts.alerts = append(ob.alerts.alerts, &alertState{Alert: a})
+}
+
+// checkAlerts evaluates every alert against the book as it stands and
+// fires those due, after releasing the alert lock so callbacks may call
+// back into the book.
+func (ob *OrderBook) checkAlerts() {
+	ob.alerts.lock.Lock()
+	if len(ob.alerts.alerts) == 0 {
+		ob.alerts.lock.Unlock()
+		return
+	}
+	now := ob.BidBook.clock()
+	var due []AlertEvent
+	var fns []func(AlertEvent)
+	for _, a := range ob.alerts.alerts {
+		v, ok := a.Condition(ob)
+		if !ok {
+			a.fired = false
+			continue
+		}
+		if a.fired || !a.last.IsZero() && now.Before(a.last.Add(a.Debounce)) {
+			continue
+		}
+		a.fired, a.last = true, now
+		due = append(due, AlertEvent{a.Name, v, now})
+		fns = append(fns, a.Fire)
+	}
+	ob.alerts.lock.Unlock()
+
+	for i, e := range due {
+		if fns[i] != nil {
+			fns[i](e)
+		}
+	}
+}
+
+// Imbalance is (bid - ask) / (bid + ask) over the quantity of the best n
+// levels per side, or every level when n is non-positive. An empty book
+// has no imbalance.
+func (ob *OrderBook) Imbalance(n int) float64 {
+	bids, asks := ob.Depth(n)
+	b, a := total(bids), total(asks)
+	if a+b == 0 {
+		return 0
+	}
+	return (b - a) / (b + a)
+}
+
+func total(lvls []Level) float64 {
+	var q float64
+	for _, l := range lvls {
+		q += l.Quantity
+	}
+	return q
+}
+
+// SpreadAbove holds while both sides are quoted and the spread exceeds x.
+func SpreadAbove(x float64) Condition {
+	return func(ob *OrderBook) (float64, bool) {
+		s, ok := ob.Spread()
+		return s, ok && s > x
+	}
+}
+
+// ImbalanceAbove holds while the imbalance over the best n levels exceeds
+// y either way.
+func ImbalanceAbove(n int, y float64) Condition {
+	return func(ob *OrderBook) (float64, bool) {
+		i := ob.Imbalance(n)
+		return i, math.Abs(i) > y
+	}
+}
+
+func sideDepth(ob *OrderBook, side Side, n int) float64 {
+	bids, asks := ob.Depth(n)
+	if side == Buy {
+		return total(bids)
+	}
+	return total(asks)
+}
+
+// DepthBelow holds while the quantity of the best n levels on side is
+// under z.
+func DepthBelow(side Side, n int, z float64) Condition {
+	return func(ob *OrderBook) (float64, bool) {
+		d := sideDepth(ob, side, n)
+		return d, d < z
+	}
+}
+
+// DepthPercentileBelow holds while the quantity of the best n levels on
+// side is under the pth percentile, 0 to 100, of its last window
+// observations. It never holds before window observations have been seen.
+// The returned Condition keeps that history and belongs to a single Alert.
+func DepthPercentileBelow(side Side, n int, p float64, window int) Condition {
+	if window < 1 {
+		window = 1
+	}
+	history := make([]float64, 0, window)
+	next := 0
+	return func(ob *OrderBook) (float64, bool) {
+		d := sideDepth(ob, side, n)
+		full := len(history) == window
+		var threshold float64
+		if full {
+			sorted := append([]float64(nil), history...)
+			sort.Float64s(sorted)
+			i := int(math.Ceil(p/100*float64(window))) - 1
+			if i < 0 {
+				i = 0
+			}
+			threshold = sorted[i]
+		}
+		if len(history) < window {
+			history = append(history, d)
+		} else {
+			history[next] = d
+			next = (next + 1) % window
+		}
+		return d, full && d < threshold
+	}
+}
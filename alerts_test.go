@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlerts(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var fired []AlertEvent
+	record := func(e AlertEvent) { fired = append(fired, e) }
+	ob := NewOrderBook(WithClock(func() time.Time { return now }),
+		WithAlert(Alert{Name: "wide", Condition: SpreadAbove(2), Debounce: time.Minute, Fire: record}))
+	ob.AddAlert(Alert{Name: "thin", Condition: DepthBelow(Sell, 0, 2), Fire: record})
+
+	ob.Submit(NewOrder(100, 1, "b1"), Buy)  // thin
+	ob.Submit(NewOrder(105, 1, "a1"), Sell) // wide, still thin
+	ob.Submit(NewOrder(99, 1, "b2"), Buy)   // both still hold, no refire
+	if len(fired) != 2 || fired[0].Name != "thin" || fired[1].Name != "wide" || fired[1].Value != 5 {
+		t.Fatalf("Expected thin then wide to fire once, got %+v", fired)
+	}
+
+	ob.Submit(NewOrder(101, 2, "a2"), Sell) // narrow and deep
+	now = now.Add(30 * time.Second)
+	ob.Cancel("a2") // wide and thin again, wide inside its debounce
+	if len(fired) != 3 || fired[2].Name != "thin" {
+		t.Errorf("Expected only thin to fire again, got %+v", fired[2:])
+	}
+	now = now.Add(31 * time.Second)
+	ob.Submit(NewOrder(98, 1, "b3"), Buy)
+	if len(fired) != 4 || fired[3].Name != "wide" || !fired[3].Time.Equal(now) {
+		t.Errorf("Expected wide once the debounce passed, got %+v", fired)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(100, 3, "b1"), Buy)
+	ob.Submit(NewOrder(99, 5, "b2"), Buy)
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+
+	cases := []struct {
+		name      string
+		condition Condition
+		value     float64
+		holds     bool
+	}{
+		{"spread", SpreadAbove(1), 1, false},
+		{"imbalance top", ImbalanceAbove(1, 0.4), 0.5, true},
+		{"imbalance all", ImbalanceAbove(0, 0.8), 7.0 / 9, false},
+		{"bid depth", DepthBelow(Buy, 1, 3), 3, false},
+		{"ask depth", DepthBelow(Sell, 0, 3), 1, true},
+	}
+	for _, c := range cases {
+		if v, ok := c.condition(ob); v != c.value || ok != c.holds {
+			t.Errorf("Expected %s to give %v %v, got %v %v", c.name, c.value, c.holds, v, ok)
+		}
+	}
+	if i := NewOrderBook().Imbalance(0); i != 0 {
+		t.Errorf("Expected an empty book to have no imbalance, got %v", i)
+	}
+}
+
+func TestDepthPercentileBelow(t *testing.T) {
+	ob := NewOrderBook()
+	condition := DepthPercentileBelow(Buy, 0, 50, 4)
+	var holds []bool
+	for i, q := range []float64{4, 5, 6, 7, 4, 8, 5} {
+		ob.Submit(NewOrder(100, q, "b"), Buy)
+		_, ok := condition(ob)
+		holds = append(holds, ok)
+		if i == 3 && ok {
+			t.Errorf("Expected no alert before the window fills")
+		}
+	}
+	// medians of 4 5 6 7, then 5 6 7 4, then 6 7 4 8
+	expected := []bool{false, false, false, false, true, false, true}
+	for i := range expected {
+		if holds[i] != expected[i] {
+			t.Errorf("Expected observation %d to give %v, got %v", i, expected[i], holds[i])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sort"
+)
+
+// Allocator splits qty, which never exceeds their total, among the orders
+// resting at one price level. resting holds their quantities in time
+// priority and the result each order's share, in the same order.
+type Allocator interface {
+	Allocate(qty float64, resting []float64) []float64
+}
+
+// WithAllocator makes Match split each price level's fills with a instead
+// of strictly in time priority. Shares are rounded down to the
+// instrument's lot, or else to the quantity precision, and what rounding
+// leaves over goes to the orders in time priority.
+func WithAllocator(a Allocator) Option {
+	return func(ob *OrderBook) {
+		ob.allocator = a
+	}
+}
+
+type fifo struct{}
+
+// FIFO fills orders in time priority, which is also what a book without
+// an Allocator does.
+var FIFO Allocator = fifo{}
+
+func (fifo) Allocate(qty float64, resting []float64) []float64 {
+	shares := make([]float64, len(resting))
+	for i, q := range resting {
+		shares[i] = math.Min(qty, q)
+		if qty -= shares[i]; qty <= 0 {
+			break
+		}
+	}
+	return shares
+}
+
+type proRata struct{}
+
+// ProRata gives each order a share in proportion to its size.
+var ProRata Allocator = proRata{}
+
+func (proRata) Allocate(qty float64, resting []float64) []float64 {
+	var total float64
+	for _, q := range resting {
+		total += q
+	}
+	shares := make([]float64, len(resting))
+	if total <= 0 {
+		return shares
+	}
+	for i, q := range resting {
+		shares[i] = qty * q / total
+	}
+	return shares
+}
+
+type sizePriority struct{}
+
+// SizePriority fills the largest orders first, earlier orders first among
+// equal sizes.
+var SizePriority Allocator = sizePriority{}
+
+func (sizePriority) Allocate(qty float64, resting []float64) []float64 {
+	order := make([]int, len(resting))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return resting[order[a]] > resting[order[b]] })
+	shares := make([]float64, len(resting))
+	for _, i := range order {
+		shares[i] = math.Min(qty, resting[i])
+		if qty -= shares[i]; qty <= 0 {
+			break
+		}
+	}
+	return shares
+}
+
+type proRataWithTop struct {
+	max float64
+}
+
+// ProRataWithTop fills the first order in time priority up to max, or in
+// full when max is zero, then splits the rest pro rata over what remains
+// of every order at the level.
+func ProRataWithTop(max float64) Allocator {
+	return proRataWithTop{max}
+}
+
+func (a proRataWithTop) Allocate(qty float64, resting []float64) []float64 {
+	if len(resting) == 0 {
+		return nil
+	}
+	top := resting[0]
+	if a.max > 0 && a.max < top {
+		top = a.max
+	}
+	top = math.Min(top, qty)
+	remaining := append([]float64(nil), resting...)
+	remaining[0] -= top
+	shares := ProRata.Allocate(qty-top, remaining)
+	shares[0] += top
+	return shares
+}
+
+// allocationStep is the quantity shares are rounded down to, or zero.
+func (ob *OrderBook) allocationStep() float64 {
+	if ob.instrument.Lot > 0 {
+		return ob.instrument.Lot
+	}
+	if ob.units.scale > 0 {
+		return 1 / ob.units.scale
+	}
+	return 0
+}
+
+// allocate asks the book's Allocator for the shares of qty among the
+// orders at a level, with their quantities in resting, clamped to each
+// order's size and rounded down to the allocation step, with the leftover
+// given out in priority order.
+func (ob *OrderBook) allocate(taker *Order, qty float64, orders []*Order, resting []float64) []float64 {
+	shares := allocateWith(ob.allocator, taker, qty, orders, resting)
+	if len(shares) != len(resting) {
+		fixed := make([]float64, len(resting))
+		copy(fixed, shares)
+		shares = fixed
+	}
+	step := ob.allocationStep()
+	left := qty
+	for i, s := range shares {
+		if !(s > 0) {
+			s = 0
+		}
+		s = math.Min(s, math.Min(resting[i], left))
+		if step > 0 {
+			s = math.Floor(s/step+1e-9) * step
+		}
+		shares[i] = s
+		left = ob.units.sub(left, s)
+	}
+	for i := 0; i < len(shares) && left > 1e-12; i++ {
+		extra := math.Min(ob.units.sub(resting[i], shares[i]), left)
+		shares[i] = ob.units.add(shares[i], extra)
+		left = ob.units.sub(left, extra)
+	}
+	return shares
+}
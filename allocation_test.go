@@ -0,0 +1,82 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestAllocators(t *testing.T) {
+	tests := []struct {
+		name      string
+		allocator Allocator
+		fills     map[string]float64
+	}{
+		{"none", nil, map[string]float64{"a": 1, "b": 3, "c": 1}},
+		{"fifo", FIFO, map[string]float64{"a": 1, "b": 3, "c": 1}},
+		{"pro-rata", ProRata, map[string]float64{"a": 1, "b": 1, "c": 3}},
+		{"pro-rata-with-top", ProRataWithTop(0), map[string]float64{"a": 1, "b": 2, "c": 2}},
+		{"size", SizePriority, map[string]float64{"c": 5}},
+	}
+	for _, tt := range tests {
+		opts := []Option{WithQuantityPrecision(0)}
+		if tt.allocator != nil {
+			opts = append(opts, WithAllocator(tt.allocator))
+		}
+		ob := NewOrderBook(opts...)
+		ob.Submit(NewOrder(100, 1, "a"), Sell)
+		ob.Submit(NewOrder(100, 3, "b"), Sell)
+		ob.Submit(NewOrder(100, 6, "c"), Sell)
+		ob.Submit(NewOrder(101, 5, "d"), Sell)
+
+		report := ob.Submit(NewOrder(101, 5, "x"), Buy)
+		fills := map[string]float64{}
+		for _, trade := range report.Trades {
+			if trade.Price != 100 {
+				t.Errorf("%s: expected every fill at 100, got %f", tt.name, trade.Price)
+			}
+			fills[trade.MakerId] += trade.Quantity
+		}
+		if len(fills) != len(tt.fills) {
+			t.Errorf("%s: expected fills %v, got %v", tt.name, tt.fills, fills)
+		}
+		for key, qty := range tt.fills {
+			if fills[key] != qty {
+				t.Errorf("%s: expected %s to fill %f, got %f", tt.name, key, qty, fills[key])
+			}
+			if o, _, ok := ob.Lookup(key); ok && o.Filled != qty {
+				t.Errorf("%s: expected %s to record %f filled, got %f", tt.name, key, qty, o.Filled)
+			}
+		}
+		if report.Filled != 5 || ob.Volume() != 10 {
+			t.Errorf("%s: expected x to fill 5 and leave 10 resting, got %f and %f", tt.name, report.Filled, ob.Volume())
+		}
+	}
+}
+
+func TestAllocatorSweepsLevels(t *testing.T) {
+	ob := NewOrderBook(WithAllocator(ProRata))
+	ob.Submit(NewOrder(100, 1, "a"), Sell)
+	ob.Submit(NewOrder(100, 1, "b"), Sell)
+	ob.Submit(NewOrder(101, 2, "c"), Sell)
+
+	report := ob.Submit(NewOrder(101, 3, "x"), Buy)
+	if report.Filled != 3 || len(report.Trades) != 3 {
+		t.Fatalf("Expected x to fill 3 in 3 trades, got %+v", report)
+	}
+	if status, _ := ob.OrderStatus("b"); status != StatusFilled {
+		t.Errorf("Expected b to be filled, got %s", status)
+	}
+	if o, _, _ := ob.Lookup("c"); o.Quantity != 1 {
+		t.Errorf("Expected c to keep 1, got %f", o.Quantity)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"math"
+)
+
+type CommandOp int
+
+const (
+	OpInsert CommandOp = iota
+	OpCancel
+	OpAmend
+)
+
+func (op CommandOp) String() string {
+	return [...]string{"insert", "cancel", "amend"}[op]
+}
+
+// Command is one operation of a Batch, on Side. Inserts rest Order keyed
+// by its OrderId, without matching; cancels remove the order with
+// Order.OrderId; amends set its price and quantity to Order's, leaving
+// either unchanged when zero.
+type Command struct {
+	Op    CommandOp `json:"op"`
+	Side  Side      `json:"side"`
+	Order Order     `json:"order"`
+}
+
+func (ob *OrderBook) validAmend(o *Order) error {
+	if o.OrderId == "" {
+		return ErrMissingOrderId
+	}
+	if o.Price != 0 && !ob.validPrice(o.Price) {
+		return ErrInvalidPrice
+	}
+	if o.Quantity < 0 || math.IsNaN(o.Quantity) || math.IsInf(o.Quantity, 0) {
+		return ErrInvalidQuantity
+	}
+	return nil
+}
+
+// Batch applies cmds in order while holding both sides' locks, and
+// publishes the result as a single diff and at most one quote. Every
+// command is validated first, inserts also by the book's validators and,
+// under RejectDuplicates, against the keys already resting, and the whole
+// batch against the book's Capacity; if any fails nothing is applied. The
+// returned slice reports, per command, whether its order was found, which
+// is always true for inserts. Inserts without an OrderId are given one
+// from the book's IDGenerator once the batch has passed; cmds is left as
+// it is.
+func (ob *OrderBook) Batch(cmds []Command) ([]bool, error) {
+	cmds = append([]Command(nil), cmds...)
+	for i := range cmds {
+		c := &cmds[i]
+		var err error
+		switch c.Op {
+		case OpInsert:
+			if c.Order.OrderId == "" && ob.ids.gen != nil {
+				err = ob.validOrder(&c.Order)
+			} else {
+				err = ob.validate(&c.Order)
+			}
+			for j := 0; err == nil && j < len(ob.validators); j++ {
+				err = ob.validators[j].Validate(ob, c.Side, &c.Order)
+			}
+		case OpCancel:
+			if c.Order.OrderId == "" {
+				err = ErrMissingOrderId
+			}
+		case OpAmend:
+			if err = ob.validAmend(&c.Order); err == nil {
+				err = ob.units.check(c.Order.Quantity)
+			}
+		default:
+			err = fmt.Errorf("orderbook: unknown command %d", c.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("orderbook: command %d: %w", i, err)
+		}
+	}
+
+	applied := make([]bool, len(cmds))
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	err := ob.batchDuplicate(cmds)
+	if err == nil {
+		err = ob.batchCapacity(cmds)
+	}
+	if err != nil {
+		ob.AskBook.lock.Unlock()
+		ob.BidBook.lock.Unlock()
+		return nil, err
+	}
+	for i := range cmds {
+		if cmds[i].Op == OpInsert {
+			ob.assignId(&cmds[i].Order)
+		}
+	}
+	for i, c := range cmds {
+		b := ob.Side(c.Side)
+		switch c.Op {
+		case OpInsert:
+			o := c.Order
+			n := NewNode(o.OrderId, &o, ob.weight(&o))
+			b.push(&n)
+			applied[i] = true
+		case OpCancel:
+			applied[i] = b.cancel(c.Order.OrderId)
+		case OpAmend:
+			applied[i] = b.amend(c.Order.OrderId, c.Order.Price, c.Order.Quantity)
+		}
+	}
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	ob.publishPending(true, true, false)
+	return applied, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(99, 1, "b1"))
+	ob.PushOrder(Buy, NewOrder(99, 2, "b2"))
+	ob.PushOrder(Sell, NewOrder(101, 1, "a1"))
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+
+	applied, err := ob.Batch([]Command{
+		{OpCancel, Sell, Order{OrderId: "a1"}},
+		{OpInsert, Sell, NewOrder(100.5, 3, "a2")},
+		{OpInsert, Sell, NewOrder(102, 1, "a3")},
+		{OpAmend, Buy, Order{OrderId: "b1", Quantity: 4}},
+		{OpCancel, Buy, Order{OrderId: "missing"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{true, true, true, true, false}; !reflect.DeepEqual(applied, expected) {
+		t.Errorf("Expected applied %v, got %v", expected, applied)
+	}
+	if len(sink.diffs) != 1 || len(sink.quotes) != 1 {
+		t.Fatalf("Expected one diff and one quote, got %d and %d", len(sink.diffs), len(sink.quotes))
+	}
+	d := sink.diffs[0]
+	if !reflect.DeepEqual(d.Bids, []Level{{99, 6}}) {
+		t.Errorf("Expected bid level 99 at 6, got %v", d.Bids)
+	}
+	if !reflect.DeepEqual(d.Asks, []Level{{101, 0}, {100.5, 3}, {102, 1}}) {
+		t.Errorf("Expected ask levels removed and added, got %v", d.Asks)
+	}
+	if ob.BidBook.Peek().OrderId != "b2" {
+		t.Errorf("Expected amending b1 up to lose priority to b2")
+	}
+
+	_, err = ob.Batch([]Command{
+		{OpCancel, Buy, Order{OrderId: "b2"}},
+		{OpInsert, Buy, NewOrder(-1, 1, "bad")},
+	})
+	if !errors.Is(err, ErrInvalidPrice) {
+		t.Errorf("Expected invalid price, got %v", err)
+	}
+	if _, ok := ob.BidBook.Get("b2"); !ok || len(sink.diffs) != 1 {
+		t.Errorf("Expected a rejected batch to change nothing")
+	}
+}
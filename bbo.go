@@ -0,0 +1,99 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"time"
+)
+
+// BBO is the top of book at one point in time. Missing sides, and the
+// midpoint when either is missing, are NaN.
+type BBO struct {
+	Time time.Time `json:"time"`
+	Bid  float64   `json:"bid"`
+	Ask  float64   `json:"ask"`
+	Mid  float64   `json:"mid"`
+}
+
+// bboRing keeps the most recent samples, overwriting the oldest.
+type bboRing struct {
+	buf  []BBO
+	next int
+	full bool
+}
+
+func (r *bboRing) add(b BBO) {
+	r.buf[r.next] = b
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// since returns the samples taken at or after t, oldest first.
+func (r *bboRing) since(t time.Time) []BBO {
+	var out []BBO
+	n := r.next
+	start := 0
+	if r.full {
+		n, start = len(r.buf), r.next
+	}
+	for i := 0; i < n; i++ {
+		b := r.buf[(start+i)%len(r.buf)]
+		if !b.Time.Before(t) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// WithBBOHistory keeps the last capacity top-of-book changes in memory for
+// BBOHistory.
+func WithBBOHistory(capacity int) Option {
+	return func(ob *OrderBook) {
+		if capacity > 0 {
+			ob.bbo = &bboRing{buf: make([]BBO, capacity)}
+		}
+	}
+}
+
+func (ob *OrderBook) recordBBO(ask, bid *Order) {
+	if ob.bbo == nil {
+		return
+	}
+	b := BBO{Time: ob.BidBook.clock(), Bid: math.NaN(), Ask: math.NaN(), Mid: math.NaN()}
+	if bid != nil {
+		b.Bid = bid.Price
+	}
+	if ask != nil {
+		b.Ask = ask.Price
+	}
+	if bid != nil && ask != nil {
+		b.Mid = (b.Bid + b.Ask) / 2
+	}
+	ob.bbo.add(b)
+}
+
+// BBOHistory returns the recorded top-of-book changes from the last d,
+// oldest first. It is empty unless the book was built WithBBOHistory.
+func (ob *OrderBook) BBOHistory(d time.Duration) []BBO {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	if ob.bbo == nil {
+		return nil
+	}
+	return ob.bbo.since(ob.BidBook.clock().Add(-d))
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBBOHistory(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook(WithBBOHistory(3))
+	ob.BidBook.now = func() time.Time { return now }
+
+	step := func(side Side, price float64, id string) {
+		now = now.Add(time.Second)
+		ob.PushOrder(side, NewOrder(price, 1, id))
+	}
+	step(Buy, 99, "b1")
+	step(Sell, 101, "a1")
+	step(Sell, 105, "a2") // not a top change
+	step(Buy, 100, "b2")
+	step(Sell, 100.5, "a3")
+
+	all := ob.BBOHistory(time.Hour)
+	if len(all) != 3 {
+		t.Fatalf("Expected the ring to keep 3 samples, got %d", len(all))
+	}
+	expected := []BBO{
+		{now.Add(-3 * time.Second), 99, 101, 100},
+		{now.Add(-time.Second), 100, 101, 100.5},
+		{now, 100, 100.5, 100.25},
+	}
+	for i, b := range all {
+		if b != expected[i] {
+			t.Errorf("Expected sample %d %+v, got %+v", i, expected[i], b)
+		}
+	}
+	if recent := ob.BBOHistory(time.Second); len(recent) != 2 {
+		t.Errorf("Expected 2 samples in the last second, got %d", len(recent))
+	}
+
+	first := NewOrderBook(WithBBOHistory(2))
+	first.PushOrder(Buy, NewOrder(99, 1, "b"))
+	if b := first.BBOHistory(time.Hour); len(b) != 1 || !math.IsNaN(b[0].Ask) || !math.IsNaN(b[0].Mid) {
+		t.Errorf("Expected a one-sided sample with NaN ask and mid, got %+v", b)
+	}
+	if NewOrderBook().BBOHistory(time.Hour) != nil {
+		t.Errorf("Expected no history without the option")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"testing"
+)
+
+// filledBook returns a book with depth asks resting over ten price levels,
+// and a node for cycling one more order through the top of it.
+func filledBook(depth int) (*OrderBook, *Node) {
+	ob := NewOrderBook(WithPresize(depth + 1))
+	orders := make([]Order, depth)
+	for i := range orders {
+		orders[i] = NewOrder(float64(100+i%10), 1, fmt.Sprint(i))
+		ob.AskBook.Push(AcquireNode(orders[i].OrderId, &orders[i], 1))
+	}
+	top := NewOrder(99, 1, "top")
+	return ob, AcquireNode("top", &top, 1)
+}
+
+func TestPushPopAllocs(t *testing.T) {
+	ob, node := filledBook(1000)
+	allocs := testing.AllocsPerRun(1000, func() {
+		ob.AskBook.Push(node)
+		ob.AskBook.Pop()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected Push and Pop not to allocate, got %g allocations per run", allocs)
+	}
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	ob, node := filledBook(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.AskBook.Push(node)
+		ob.AskBook.Pop()
+	}
+}
+
+func BenchmarkPushRemove(b *testing.B) {
+	ob, node := filledBook(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.AskBook.Push(node)
+		ob.AskBook.Remove("top")
+	}
+}
+
+func BenchmarkPushPopWithSink(b *testing.B) {
+	ob, node := filledBook(1000)
+	ob.AddSink(&recordingSink{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.AskBook.Push(node)
+		ob.AskBook.Pop()
+	}
+}
+
+// BenchmarkMemoryPerOrder reports MemoryStats' estimate of the bytes held
+// per resting order, with and without interned strings.
+func BenchmarkMemoryPerOrder(b *testing.B) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{{"plain", nil}, {"interned", []Option{WithInternedStrings()}}} {
+		b.Run(tt.name, func(b *testing.B) {
+			var m MemoryStats
+			for i := 0; i < b.N; i++ {
+				ob := NewOrderBook(tt.opts...)
+				for j := 0; j < 1000; j++ {
+					o := NewOrder(float64(100+j%10), 1, fmt.Sprint(j))
+					o.Account, o.Venue = fmt.Sprint("account", j%10), fmt.Sprint("US")
+					ob.Submit(o, Sell)
+				}
+				m = ob.MemoryStats()
+			}
+			b.ReportMetric(m.PerOrder, "B/order")
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// Granularity selects how much of the book a BookView carries.
+type Granularity uint8
+
+const (
+	// L2 aggregates each price level into its quantity and order count.
+	L2 Granularity = 2
+	// L3 also lists the orders at each level in priority order.
+	L3 Granularity = 3
+)
+
+func (g Granularity) String() string {
+	if g == L3 {
+		return "L3"
+	}
+	return "L2"
+}
+
+// ViewLevel is a price level of a BookView. Orders is only filled at L3.
+type ViewLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+	Count    int     `json:"count"`
+	Orders   []Order `json:"orders,omitempty"`
+}
+
+// BookView is both sides of the book at one granularity, best first.
+type BookView struct {
+	Granularity Granularity `json:"granularity"`
+	Bids        []ViewLevel `json:"bids"`
+	Asks        []ViewLevel `json:"asks"`
+	Sequence    uint64      `json:"sequence"`
+}
+
+// BookView returns up to depth levels per side at granularity g, or every
+// level for a non-positive depth. Both sides are read at once, from the
+// published snapshot under WithSnapshotReads.
+func (ob *OrderBook) BookView(g Granularity, depth int) *BookView {
+	var bids, asks []Entry
+	var seq uint64
+	if ob.view != nil {
+		s := ob.View()
+		for _, e := range s.Orders {
+			if e.Side == Buy {
+				bids = append(bids, e)
+			} else {
+				asks = append(asks, e)
+			}
+		}
+		seq = s.Sequence
+	} else {
+		ob.BidBook.lock.Lock()
+		ob.AskBook.lock.Lock()
+		bids, asks = entries(Buy, ob.BidBook.sortedNodes()), entries(Sell, ob.AskBook.sortedNodes())
+		seq = ob.Sequence()
+		ob.AskBook.lock.Unlock()
+		ob.BidBook.lock.Unlock()
+	}
+	return &BookView{Granularity: g, Bids: viewLevels(bids, g, depth),
+		Asks: viewLevels(asks, g, depth), Sequence: seq}
+}
+
+// viewLevels groups entries, in priority order, by effective price.
+func viewLevels(entries []Entry, g Granularity, depth int) []ViewLevel {
+	var lvls []ViewLevel
+	for _, e := range entries {
+		price := e.Order.Price * e.Weight
+		if len(lvls) == 0 || lvls[len(lvls)-1].Price != price {
+			if depth > 0 && len(lvls) == depth {
+				break
+			}
+			lvls = append(lvls, ViewLevel{Price: price})
+		}
+		l := &lvls[len(lvls)-1]
+		l.Quantity += e.Order.Quantity
+		l.Count++
+		if g == L3 {
+			l.Orders = append(l.Orders, e.Order)
+		}
+	}
+	return lvls
+}
+
+// OrdersAt returns the number of orders resting on side at an effective
+// price, kept current by the level index.
+func (ob *OrderBook) OrdersAt(side Side, price float64) int {
+	return ob.Side(side).levelLen(price)
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestBookView(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithSnapshotReads()}} {
+		ob := NewOrderBook(opts...)
+		ob.Submit(NewOrder(99, 2, "b1"), Buy)
+		ob.Submit(NewOrder(99, 1, "b2"), Buy)
+		ob.Submit(NewOrder(98, 3, "b3"), Buy)
+		ob.Submit(NewOrder(101, 4, "a1"), Sell)
+
+		l2 := ob.BookView(L2, 0)
+		if len(l2.Bids) != 2 || len(l2.Asks) != 1 {
+			t.Fatalf("Expected 2 bid levels and 1 ask level, got %+v", l2)
+		}
+		if opts == nil && l2.Sequence != ob.Sequence() {
+			t.Errorf("Expected the book's sequence %d, got %d", ob.Sequence(), l2.Sequence)
+		}
+		if b := l2.Bids[0]; b.Price != 99 || b.Quantity != 3 || b.Count != 2 || b.Orders != nil {
+			t.Errorf("Expected 3 in 2 orders at 99 and no orders at L2, got %+v", b)
+		}
+
+		l3 := ob.BookView(L3, 1)
+		if len(l3.Bids) != 1 || len(l3.Asks) != 1 {
+			t.Fatalf("Expected one level per side, got %+v", l3)
+		}
+		if o := l3.Bids[0].Orders; len(o) != 2 || o[0].OrderId != "b1" || o[1].OrderId != "b2" {
+			t.Errorf("Expected b1 then b2 at 99, got %+v", o)
+		}
+		if ob.OrdersAt(Buy, 99) != 2 || ob.OrdersAt(Buy, 97) != 0 {
+			t.Errorf("Expected 2 orders at 99 and none at 97, got %d and %d", ob.OrdersAt(Buy, 99), ob.OrdersAt(Buy, 97))
+		}
+	}
+}
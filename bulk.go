@@ -0,0 +1,72 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "container/heap"
+
+// NewOrderBookFromOrders builds a book resting bids and asks, each in
+// arrival order and keyed by OrderId, without validating or matching
+// them. Each side's heaps are filled and heapified once, in O(n), rather
+// than pushed one at a time. A repeated id on a side keeps the last order
+// given, as ReplaceDuplicates would.
+func NewOrderBookFromOrders(bids, asks []Order, opts ...Option) *OrderBook {
+	ob := NewOrderBook(opts...)
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	for side, orders := range [][]Order{bids, asks} {
+		index := make(map[string]int, len(orders))
+		nodes := make([]*Node, 0, len(orders))
+		for i := range orders {
+			o := orders[i]
+			n := NewNode(o.OrderId, &o, ob.weight(&o))
+			if j, ok := index[o.OrderId]; ok {
+				nodes[j] = nil
+			}
+			index[o.OrderId] = len(nodes)
+			nodes = append(nodes, &n)
+		}
+		ob.Side(Side(side)).loadNodes(nodes)
+	}
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	ob.publishPending(true, true, false)
+	return ob
+}
+
+// loadNodes rests nodes, skipping nils, in arrival order on a side none
+// of their keys rest on, then heapifies once. The caller holds the lock.
+func (sb *SideBook) loadNodes(nodes []*Node) {
+	now := sb.clock()
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		sb.arrivals++
+		n.seq = sb.arrivals
+		if n.Time.IsZero() {
+			n.Time = now
+		}
+		n.index, n.ageIndex = len(sb.Orders.BaseHeap), len(sb.byAge)
+		sb.Orders.BaseHeap = append(sb.Orders.BaseHeap, n)
+		sb.byAge = append(sb.byAge, n)
+		sb.put(n)
+		sb.levels.add(n)
+		sb.activity.Inserts++
+		sb.countAdd(now)
+		sb.record(opPush, n, n.price)
+	}
+	heap.Init(&sb.Orders)
+	heap.Init(&sb.byAge)
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestNewOrderBookFromOrders(t *testing.T) {
+	var bids, asks []Order
+	for i := 0; i < 200; i++ {
+		id := strconv.Itoa(i)
+		bids = append(bids, NewOrder(float64(50+(i*13)%40), 1, "b"+id))
+		asks = append(asks, NewOrder(float64(100+(i*7)%40), 1, "a"+id))
+	}
+	bids = append(bids, NewOrder(60, 5, "b3"))
+
+	pushed := NewOrderBook()
+	for _, o := range bids {
+		pushed.PushOrder(Buy, o)
+	}
+	for _, o := range asks {
+		pushed.PushOrder(Sell, o)
+	}
+	bulk := NewOrderBookFromOrders(bids, asks)
+
+	for _, side := range []Side{Buy, Sell} {
+		want, got := pushed.Entries(side), bulk.Entries(side)
+		if len(want) != len(got) {
+			t.Fatalf("Expected %d %s orders, got %d", len(want), side, len(got))
+		}
+		for i := range want {
+			if want[i].Key != got[i].Key || want[i].Order != got[i].Order {
+				t.Fatalf("Expected %s order %d to be %+v, got %+v", side, i, want[i], got[i])
+			}
+		}
+	}
+	b, a := bulk.Depth(0)
+	pb, pa := pushed.Depth(0)
+	if !reflect.DeepEqual(b, pb) || !reflect.DeepEqual(a, pa) {
+		t.Errorf("Expected the same depth as pushing each order")
+	}
+	bulk.Submit(NewOrder(89, 3, "x"), Sell)
+	if bulk.OrderCount(Buy) != 197 {
+		t.Errorf("Expected the bulk-loaded book to match, got %d bids left", bulk.OrderCount(Buy))
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrUnknownTrade = errors.New("orderbook: trade is not on the tape")
+
+// TradeCorrection reports a trade busted or corrected after the fact.
+// Corrected holds the trade as it now stands and is the zero value for a
+// bust.
+type TradeCorrection struct {
+	Original  TradeEvent `json:"original"`
+	Corrected TradeEvent `json:"corrected"`
+	Busted    bool       `json:"busted"`
+	Sequence  uint64     `json:"sequence"`
+}
+
+// CorrectionSink is implemented by event sinks that also want to know
+// about trades busted or corrected with AdminBust and AdminCorrect.
+type CorrectionSink interface {
+	Corrected(*TradeCorrection)
+}
+
+type tapeEntry struct {
+	trade  TradeEvent
+	busted bool
+}
+
+// tape keeps the most recent trades, overwriting the oldest. Trade ids are
+// consecutive, so a trade's slot follows from its id.
+type tape struct {
+	buf []tapeEntry
+}
+
+func (t *tape) add(e TradeEvent) {
+	t.buf[(e.TradeId-1)%uint64(len(t.buf))] = tapeEntry{trade: e}
+}
+
+func (t *tape) lookup(id uint64) *tapeEntry {
+	if id == 0 {
+		return nil
+	}
+	if e := &t.buf[(id-1)%uint64(len(t.buf))]; e.trade.TradeId == id && !e.busted {
+		return e
+	}
+	return nil
+}
+
+// WithTradeTape keeps the last capacity trades in memory for Tape, and so
+// that they can be busted or corrected.
+func WithTradeTape(capacity int) Option {
+	return func(ob *OrderBook) {
+		if capacity > 0 {
+			ob.tape = &tape{buf: make([]tapeEntry, capacity)}
+		}
+	}
+}
+
+// Tape returns up to the last n trades still standing, oldest first, with
+// corrections applied. It is empty unless the book was built
+// WithTradeTape.
+func (ob *OrderBook) Tape(n int) []TradeEvent {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	if ob.tape == nil {
+		return nil
+	}
+	var out []TradeEvent
+	for id := ob.tradeIds; id > 0 && len(out) < n; id-- {
+		e := &ob.tape.buf[(id-1)%uint64(len(ob.tape.buf))]
+		if e.trade.TradeId != id {
+			break
+		}
+		if !e.busted {
+			out = append(out, e.trade)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// AdminBust cancels a trade on the tape, publishing a TradeCorrection to
+// the sinks. Resting orders are not restored.
+func (ob *OrderBook) AdminBust(tradeId uint64) error {
+	return ob.correct(tradeId, 0, 0, true)
+}
+
+// AdminCorrect changes the price and quantity of a trade on the tape,
+// publishing a TradeCorrection to the sinks.
+func (ob *OrderBook) AdminCorrect(tradeId uint64, price, qty float64) error {
+	if !ob.validPrice(price) {
+		return ErrInvalidPrice
+	}
+	if !(qty > 0) || math.IsInf(qty, 0) {
+		return ErrInvalidQuantity
+	}
+	return ob.correct(tradeId, price, qty, false)
+}
+
+func (ob *OrderBook) correct(tradeId uint64, price, qty float64, bust bool) error {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	if ob.tape == nil {
+		return ErrUnknownTrade
+	}
+	e := ob.tape.lookup(tradeId)
+	if e == nil {
+		return ErrUnknownTrade
+	}
+	c := &TradeCorrection{Original: e.trade, Busted: bust, Sequence: ob.nextSequence()}
+	if bust {
+		e.busted = true
+	} else {
+		e.trade.Price, e.trade.Quantity = price, qty
+		c.Corrected = e.trade
+	}
+	ob.resetLastTrade()
+	ob.toSinks(func(s EventSink) {
+		if cs, ok := s.(CorrectionSink); ok {
+			cs.Corrected(c)
+		}
+	})
+	return nil
+}
+
+// resetLastTrade resets the last trade price to the latest trade still standing.
+func (ob *OrderBook) resetLastTrade() {
+	ob.lastTrade, ob.traded = 0, false
+	for id := ob.tradeIds; id > 0; id-- {
+		e := &ob.tape.buf[(id-1)%uint64(len(ob.tape.buf))]
+		if e.trade.TradeId != id {
+			return
+		}
+		if !e.busted {
+			ob.lastTrade, ob.traded = e.trade.Price, true
+			return
+		}
+	}
+}
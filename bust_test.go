@@ -0,0 +1,100 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+type correctionSink struct {
+	recordingSink
+	corrections []TradeCorrection
+}
+
+func (s *correctionSink) Corrected(c *TradeCorrection) { s.corrections = append(s.corrections, *c) }
+
+func TestAdminBust(t *testing.T) {
+	ob := NewOrderBook(WithTradeTape(2))
+	sink := &correctionSink{}
+	ob.AddSink(sink)
+	conflated := &correctionSink{}
+	ob.AddSink(Conflate(conflated, time.Hour))
+	ob.Submit(NewOrder(100, 1, "a1"), Sell)
+	ob.Submit(NewOrder(101, 1, "a2"), Sell)
+	ob.Submit(NewOrder(102, 1, "a3"), Sell)
+	ob.Submit(NewOrder(102, 3, "b1"), Buy)
+
+	if err := ob.AdminBust(1); err != ErrUnknownTrade {
+		t.Errorf("Expected a trade off the tape to be unknown, got %v", err)
+	}
+	if err := ob.AdminBust(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := ob.AdminBust(3); err != ErrUnknownTrade {
+		t.Errorf("Expected a busted trade to be unknown, got %v", err)
+	}
+	if tape := ob.Tape(10); len(tape) != 1 || tape[0].TradeId != 2 {
+		t.Errorf("Expected only trade 2 left on the tape, got %+v", tape)
+	}
+	if s := ob.Stats(); s.LastTrade != 101 || !s.Traded {
+		t.Errorf("Expected the last trade to fall back to 101, got %v %v", s.LastTrade, s.Traded)
+	}
+	if len(sink.corrections) != 1 {
+		t.Fatalf("Expected 1 correction, got %d", len(sink.corrections))
+	}
+	if c := sink.corrections[0]; !c.Busted || c.Original.TradeId != 3 || c.Original.Price != 102 || c.Sequence != ob.Sequence() {
+		t.Errorf("Expected a bust of trade 3 at 102, got %+v", c)
+	}
+	if len(conflated.corrections) != 1 || conflated.corrections[0] != sink.corrections[0] {
+		t.Errorf("Expected the conflated sink to get the bust, got %+v", conflated.corrections)
+	}
+}
+
+func TestAdminCorrect(t *testing.T) {
+	ob := NewOrderBook(WithTradeTape(4))
+	sink := &correctionSink{}
+	ob.AddSink(sink)
+	ob.Submit(NewOrder(100, 2, "a1"), Sell)
+	ob.Submit(NewOrder(100, 2, "b1"), Buy)
+
+	tests := []struct {
+		id         uint64
+		price, qty float64
+		err        error
+	}{
+		{1, 0, 1, ErrInvalidPrice},
+		{1, 99, -1, ErrInvalidQuantity},
+		{2, 99, 1, ErrUnknownTrade},
+		{1, 99.5, 1.5, nil},
+	}
+	for _, tt := range tests {
+		if err := ob.AdminCorrect(tt.id, tt.price, tt.qty); err != tt.err {
+			t.Errorf("Expected %v correcting trade %d to %v x %v, got %v", tt.err, tt.id, tt.price, tt.qty, err)
+		}
+	}
+	if tape := ob.Tape(1); len(tape) != 1 || tape[0].Price != 99.5 || tape[0].Quantity != 1.5 {
+		t.Errorf("Expected the corrected trade on the tape, got %+v", tape)
+	}
+	if s := ob.Stats(); s.LastTrade != 99.5 {
+		t.Errorf("Expected the last trade to be corrected to 99.5, got %v", s.LastTrade)
+	}
+	if len(sink.corrections) != 1 {
+		t.Fatalf("Expected 1 correction, got %d", len(sink.corrections))
+	}
+	c := sink.corrections[0]
+	if c.Busted || c.Original.Price != 100 || c.Original.Quantity != 2 || c.Corrected.Price != 99.5 || c.Corrected.TradeId != 1 {
+		t.Errorf("Expected trade 1 corrected from 2 at 100 to 1.5 at 99.5, got %+v", c)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// CancelWhere cancels every resting order on either side that match
+// accepts, holding both sides' locks throughout, and returns copies of
+// the cancelled orders, bids before asks and each side in arrival order.
+// match must not call back into the book. A diff is published per
+// cancelled order once the locks are released.
+func (ob *OrderBook) CancelWhere(match func(*Order) bool) []Order {
+	return ob.cancelWhere(match, Buy, Sell)
+}
+
+func (ob *OrderBook) cancelWhere(match func(*Order) bool, sides ...Side) []Order {
+	var cancelled []Order
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	for _, side := range sides {
+		cancelled = append(cancelled, ob.Side(side).cancelWhere(match)...)
+	}
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	ob.publishPending(true, true, true)
+	return cancelled
+}
+
+// CancelSide cancels every order resting on side.
+func (ob *OrderBook) CancelSide(side Side) []Order {
+	return ob.cancelWhere(func(*Order) bool { return true }, side)
+}
+
+// CancelAbove cancels the orders on side priced strictly above price.
+func (ob *OrderBook) CancelAbove(side Side, price float64) []Order {
+	return ob.cancelWhere(func(o *Order) bool { return o.Price > price }, side)
+}
+
+// CancelBelow cancels the orders on side priced strictly below price.
+func (ob *OrderBook) CancelBelow(side Side, price float64) []Order {
+	return ob.cancelWhere(func(o *Order) bool { return o.Price < price }, side)
+}
+
+// CancelByAccount cancels every order resting on either side for account.
+func (ob *OrderBook) CancelByAccount(account string) []Order {
+	return ob.CancelWhere(func(o *Order) bool { return o.Account == account })
+}
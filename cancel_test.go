@@ -0,0 +1,73 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCancelWhere(t *testing.T) {
+	setup := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.PushOrder(Buy, Order{Price: 99, Quantity: 1, OrderId: "b1", Account: "x"})
+		ob.PushOrder(Buy, Order{Price: 98, Quantity: 1, OrderId: "b2", Account: "y"})
+		ob.PushOrder(Buy, Order{Price: 97, Quantity: 1, OrderId: "b3", Account: "x"})
+		ob.PushOrder(Sell, Order{Price: 101, Quantity: 1, OrderId: "a1", Account: "y"})
+		ob.PushOrder(Sell, Order{Price: 102, Quantity: 1, OrderId: "a2", Account: "x"})
+		return ob
+	}
+	tests := []struct {
+		Name      string
+		Cancel    func(ob *OrderBook) []Order
+		Cancelled []string
+	}{
+		{"where", func(ob *OrderBook) []Order {
+			return ob.CancelWhere(func(o *Order) bool { return o.Quantity == 1 && o.Price > 98 })
+		}, []string{"a1", "a2", "b1"}},
+		{"side", func(ob *OrderBook) []Order { return ob.CancelSide(Sell) }, []string{"a1", "a2"}},
+		{"above", func(ob *OrderBook) []Order { return ob.CancelAbove(Buy, 97) }, []string{"b1", "b2"}},
+		{"below", func(ob *OrderBook) []Order { return ob.CancelBelow(Sell, 102) }, []string{"a1"}},
+		{"account", func(ob *OrderBook) []Order { return ob.CancelByAccount("x") }, []string{"a2", "b1", "b3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ob := setup()
+			sink := &recordingSink{}
+			ob.AddSink(sink)
+			var ids []string
+			for _, o := range test.Cancel(ob) {
+				ids = append(ids, o.OrderId)
+			}
+			sort.Strings(ids)
+			if len(ids) != len(test.Cancelled) {
+				t.Fatalf("Expected %v cancelled, got %v", test.Cancelled, ids)
+			}
+			for i := range ids {
+				if ids[i] != test.Cancelled[i] {
+					t.Errorf("Expected %v cancelled, got %v", test.Cancelled, ids)
+				}
+				if _, _, ok := ob.Lookup(ids[i]); ok {
+					t.Errorf("Expected %s to be gone", ids[i])
+				}
+			}
+			if len(sink.diffs) != len(ids) {
+				t.Errorf("Expected a diff per cancel, got %d", len(sink.diffs))
+			}
+			if ob.OrderCount(Buy)+ob.OrderCount(Sell) != 5-len(ids) {
+				t.Errorf("Expected the rest to remain")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCapacity = errors.New("orderbook: book is at capacity")
+
+type CapacityPolicy int
+
+const (
+	// RejectNew cancels the remainder of an order that would exceed a cap.
+	RejectNew CapacityPolicy = iota
+	// EvictWorst makes room on a full side by cancelling its worst-priced
+	// orders, latest first. An order that would itself rank last is still
+	// rejected, as are orders over the per-level cap.
+	EvictWorst
+)
+
+// Capacity caps the resting orders per side and per price level; zero
+// leaves a dimension unbounded.
+type Capacity struct {
+	MaxOrders   int
+	MaxPerLevel int
+	Policy      CapacityPolicy
+}
+
+// WithCapacity enforces c on the remainders Submit and triggered stops
+// would rest and on PushOrder. Batches that would break it are refused
+// whole, never evicting, and L2 updates are refused while it is set, as
+// capping a mirrored depth would only drop levels. Nodes pushed onto a
+// SideBook directly bypass it.
+func WithCapacity(c Capacity) Option {
+	return func(ob *OrderBook) {
+		ob.capacity = &c
+	}
+}
+
+type EvictionEvent struct {
+	Side     Side   `json:"side"`
+	Order    Order  `json:"order"`
+	Sequence uint64 `json:"sequence"`
+}
+
+// EvictionSink is implemented by event sinks that also want to know about
+// orders cancelled to make room under an EvictWorst capacity.
+type EvictionSink interface {
+	Evicted(*EvictionEvent)
+}
+
+// victim returns the key and a copy of the lowest-ranked resting order,
+// or false if an order at effective price would rank below it. The caller
+// holds the lock.
+func (sb *SideBook) victim(price float64) (string, Order, bool) {
+	var worst *Node
+	sb.each(func(n *Node) bool {
+		if n.indexed && (worst == nil || sb.Orders.better(worst.price, n.price) || (worst.price == n.price && n.seq > worst.seq)) {
+			worst = n
+		}
+		return true
+	})
+	if worst == nil || !sb.Orders.better(price, worst.price) {
+		return "", Order{}, false
+	}
+	return worst.Key, *worst.Peek(), true
+}
+
+func (sb *SideBook) levelLen(price float64) int {
+	if l, ok := sb.levels.level(price); ok {
+		return len(l.orders)
+	}
+	return 0
+}
+
+// rest pushes n onto side once the capacity has made room for it. The
+// check, any evictions and the push happen under one hold of the side's
+// lock, so concurrent pushes cannot both take the last place.
+func (ob *OrderBook) rest(side Side, n *Node) error {
+	b := ob.Side(side)
+	if b.latency != nil {
+		defer b.latency.since(LatencyPush, time.Now())
+	}
+	b.lock.Lock()
+	evicted, err := ob.makeRoom(b, n.Peek().Price*n.Weight)
+	if err == nil {
+		err = b.push(n)
+	}
+	b.lock.Unlock()
+	b.flush()
+	for i := range evicted {
+		ob.publishEviction(&EvictionEvent{Side: b.side, Order: evicted[i]})
+	}
+	return err
+}
+
+// makeRoom applies the capacity to an order about to rest on b at
+// effective price, evicting orders if the policy allows, and returns
+// copies of those evicted. The caller holds the lock.
+func (ob *OrderBook) makeRoom(b *SideBook, price float64) ([]Order, error) {
+	c := ob.capacity
+	if c == nil {
+		return nil, nil
+	}
+	if c.MaxPerLevel > 0 && b.levelLen(price) >= c.MaxPerLevel {
+		return nil, ErrCapacity
+	}
+	var evicted []Order
+	for c.MaxOrders > 0 && b.Len() >= c.MaxOrders {
+		if c.Policy != EvictWorst {
+			return evicted, ErrCapacity
+		}
+		key, o, ok := b.victim(price)
+		if !ok {
+			return evicted, ErrCapacity
+		}
+		if b.cancel(key) {
+			evicted = append(evicted, o)
+		}
+	}
+	return evicted, nil
+}
+
+// batchCapacity refuses cmds with ErrCapacity if, once applied, they
+// would leave a side over MaxOrders or a level they insert into over
+// MaxPerLevel. The caller holds both locks.
+func (ob *OrderBook) batchCapacity(cmds []Command) error {
+	c := ob.capacity
+	if c == nil {
+		return nil
+	}
+	type rest struct {
+		price, weight float64
+		ok            bool
+	}
+	for _, side := range []Side{Buy, Sell} {
+		b := ob.Side(side)
+		// where each key the batch touches ends up
+		final := make(map[string]rest)
+		var inserted, fresh []float64
+		for _, cmd := range cmds {
+			if cmd.Side != side {
+				continue
+			}
+			key := cmd.Order.OrderId
+			if cmd.Op == OpInsert && key == "" {
+				// an id yet to be generated is new to the side
+				price := cmd.Order.Price * ob.weight(&cmd.Order)
+				inserted, fresh = append(inserted, price), append(fresh, price)
+				continue
+			}
+			r, touched := final[key]
+			if n, ok := b.get(key); ok && !touched {
+				r = rest{n.price, n.Weight, true}
+			}
+			switch cmd.Op {
+			case OpInsert:
+				w := ob.weight(&cmd.Order)
+				r = rest{cmd.Order.Price * w, w, true}
+				inserted = append(inserted, r.price)
+			case OpCancel:
+				r.ok = false
+			case OpAmend:
+				if r.ok && cmd.Order.Price != 0 {
+					r.price = cmd.Order.Price * r.weight
+				}
+			}
+			final[key] = r
+		}
+		count := b.size()
+		levels := make(map[float64]int)
+		for _, price := range inserted {
+			if l, ok := b.levels.level(price); ok {
+				levels[price] = len(l.orders)
+			} else {
+				levels[price] = 0
+			}
+		}
+		for key, r := range final {
+			if n, ok := b.get(key); ok {
+				count--
+				if _, counted := levels[n.price]; counted {
+					levels[n.price]--
+				}
+			}
+			if r.ok {
+				count++
+				if _, counted := levels[r.price]; counted {
+					levels[r.price]++
+				}
+			}
+		}
+		for _, price := range fresh {
+			count++
+			levels[price]++
+		}
+		if c.MaxOrders > 0 && count > c.MaxOrders {
+			return ErrCapacity
+		}
+		for _, n := range levels {
+			if c.MaxPerLevel > 0 && n > c.MaxPerLevel {
+				return ErrCapacity
+			}
+		}
+	}
+	return nil
+}
+
+func (ob *OrderBook) publishEviction(e *EvictionEvent) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	e.Sequence = ob.nextSequence()
+	ob.toSinks(func(s EventSink) {
+		if es, ok := s.(EvictionSink); ok {
+			es.Evicted(e)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type evictionSink struct {
+	recordingSink
+	evicted []*EvictionEvent
+}
+
+func (s *evictionSink) Evicted(e *EvictionEvent) { s.evicted = append(s.evicted, e) }
+
+func TestCapacity(t *testing.T) {
+	cases := []struct {
+		name     string
+		capacity Capacity
+		order    Order
+		err      error
+		evicted  string
+	}{
+		{"reject", Capacity{MaxOrders: 3}, NewOrder(100, 1, "x"), ErrCapacity, ""},
+		{"evict worst", Capacity{MaxOrders: 3, Policy: EvictWorst}, NewOrder(100, 1, "x"), nil, "a3"},
+		{"evict latest at worst price", Capacity{MaxOrders: 3, Policy: EvictWorst}, NewOrder(101, 1, "x"), nil, "a3"},
+		{"no eviction for a worse order", Capacity{MaxOrders: 3, Policy: EvictWorst}, NewOrder(103, 1, "x"), ErrCapacity, ""},
+		{"level cap", Capacity{MaxPerLevel: 2, Policy: EvictWorst}, NewOrder(102, 1, "x"), ErrCapacity, ""},
+		{"room", Capacity{MaxOrders: 4, MaxPerLevel: 2}, NewOrder(101, 1, "x"), nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ob := NewOrderBook(WithCapacity(c.capacity))
+			sink := &evictionSink{}
+			ob.AddSink(sink)
+			ob.Submit(NewOrder(101, 1, "a1"), Sell)
+			ob.Submit(NewOrder(102, 1, "a2"), Sell)
+			ob.Submit(NewOrder(102, 1, "a3"), Sell)
+
+			r := ob.Submit(c.order, Sell)
+			if !errors.Is(r.Err, c.err) || r.Resting != (c.err == nil) {
+				t.Errorf("Expected error %v, got %v resting %v", c.err, r.Err, r.Resting)
+			}
+			if c.err != nil && r.Status != StatusRejected {
+				t.Errorf("Expected rejection, got %v", r.Status)
+			}
+			if c.evicted == "" {
+				if len(sink.evicted) != 0 {
+					t.Errorf("Expected no evictions, got %+v", sink.evicted[0])
+				}
+				return
+			}
+			if len(sink.evicted) != 1 || sink.evicted[0].Order.OrderId != c.evicted {
+				t.Fatalf("Expected %s evicted, got %+v", c.evicted, sink.evicted)
+			}
+			if _, _, ok := ob.Lookup(c.evicted); ok {
+				t.Errorf("Expected %s off the book", c.evicted)
+			}
+			if s, _ := ob.OrderStatus(c.evicted); s != StatusCanceled {
+				t.Errorf("Expected evicted order canceled, got %v", s)
+			}
+		})
+	}
+}
+
+func TestCapacityPartialFill(t *testing.T) {
+	ob := NewOrderBook(WithCapacity(Capacity{MaxOrders: 1}))
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	r := ob.Submit(NewOrder(101, 3, "b2"), Buy)
+	if r.Status != StatusPartiallyFilled || r.Filled != 1 || r.Resting || !errors.Is(r.Err, ErrCapacity) {
+		t.Errorf("Expected the unfilled remainder dropped at capacity, got %+v", r)
+	}
+}
+
+func TestCapacityWeighted(t *testing.T) {
+	rates := &RateTable{}
+	rates.Set("EUR", 2)
+	ob := NewOrderBook(WithRates(rates), WithCapacity(Capacity{MaxPerLevel: 1}))
+	ob.Submit(NewOrder(100, 1, "a1"), Sell)
+	o := NewOrder(50, 1, "a2")
+	o.Venue = "EUR"
+	if r := ob.Submit(o, Sell); !errors.Is(r.Err, ErrCapacity) {
+		t.Errorf("Expected the level at an effective 100 to be full, got %v", r.Err)
+	}
+}
+
+func TestCapacityOtherPaths(t *testing.T) {
+	ob := NewOrderBook(WithCapacity(Capacity{MaxOrders: 2, MaxPerLevel: 1}), WithIDGenerator(MonotonicIDs("g")))
+	if err := ob.PushOrder(Sell, NewOrder(101, 1, "a1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ob.PushOrder(Sell, NewOrder(101, 1, "a2")); err != ErrCapacity {
+		t.Errorf("Expected PushOrder over the level cap refused, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cmds []Command
+		err  error
+	}{
+		{"over the side cap", []Command{
+			{Op: OpInsert, Side: Sell, Order: NewOrder(102, 1, "a2")},
+			{Op: OpInsert, Side: Sell, Order: NewOrder(103, 1, "a3")},
+		}, ErrCapacity},
+		{"over the level cap", []Command{
+			{Op: OpInsert, Side: Sell, Order: NewOrder(101, 1, "a2")},
+		}, ErrCapacity},
+		{"generated ids over the side cap", []Command{
+			{Op: OpInsert, Side: Sell, Order: NewOrder(104, 1, "")},
+			{Op: OpInsert, Side: Sell, Order: NewOrder(105, 1, "")},
+		}, ErrCapacity},
+		{"generated id over the level cap", []Command{
+			{Op: OpInsert, Side: Sell, Order: NewOrder(101, 1, "")},
+		}, ErrCapacity},
+		{"room made by a cancel", []Command{
+			{Op: OpCancel, Side: Sell, Order: Order{OrderId: "a1"}},
+			{Op: OpInsert, Side: Sell, Order: NewOrder(101, 1, "a2")},
+			{Op: OpInsert, Side: Sell, Order: NewOrder(102, 1, "a3")},
+		}, nil},
+	}
+	for _, tt := range tests {
+		if _, err := ob.Batch(tt.cmds); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+	if ob.AskBook.Len() != 2 {
+		t.Errorf("Expected only the last batch applied, got %d asks", ob.AskBook.Len())
+	}
+	if r := ob.Submit(NewOrder(90, 1, ""), Buy); r.OrderId != "g1" {
+		t.Errorf("Expected refused batches to use no ids, got %s next", r.OrderId)
+	}
+	if err := ob.ApplySnapshot(DepthSnapshot{Sequence: 1}); err != ErrCapacity {
+		t.Errorf("Expected L2 updates refused under a capacity, got %v", err)
+	}
+}
+
+// yieldingRates lets other goroutines in wherever the book weights an
+// order.
+type yieldingRates struct{}
+
+func (yieldingRates) Rate(string) (float64, bool) {
+	runtime.Gosched()
+	return 1, true
+}
+
+func TestCapacityConcurrentPush(t *testing.T) {
+	for _, policy := range []CapacityPolicy{RejectNew, EvictWorst} {
+		ob := NewOrderBook(WithCapacity(Capacity{MaxOrders: 5, Policy: policy}), WithRates(yieldingRates{}))
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		var rested int
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					o := NewOrder(float64(100+g*50+i), 1, strconv.Itoa(g)+"-"+strconv.Itoa(i))
+					if ob.PushOrder(Sell, o) == nil {
+						lock.Lock()
+						rested++
+						lock.Unlock()
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if n := ob.AskBook.Len(); n > 5 {
+			t.Errorf("Expected at most 5 asks under policy %d, got %d", policy, n)
+		}
+		if policy == RejectNew && rested != 5 {
+			t.Errorf("Expected exactly 5 pushes to rest, got %d", rested)
+		}
+		if err := ob.Verify(); err != nil {
+			t.Errorf("Expected a consistent book under policy %d: %v", policy, err)
+		}
+	}
+}
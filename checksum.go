@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// ChecksumFormatter renders the top levels of each side into the string
+// a venue feeds into its CRC32. Bids are ordered best (highest) first and
+// asks best (lowest) first.
+type ChecksumFormatter interface {
+	Depth() int
+	Format(bids, asks []Level) string
+}
+
+type krakenFormatter struct {
+	pricePrecision    int
+	quantityPrecision int
+}
+
+// KrakenFormatter produces the Kraken book checksum layout: the top 10
+// asks followed by the top 10 bids, each price and quantity printed at the
+// pair's precision with the decimal point and leading zeros removed.
+func KrakenFormatter(pricePrecision, quantityPrecision int) ChecksumFormatter {
+	return krakenFormatter{pricePrecision, quantityPrecision}
+}
+
+func (f krakenFormatter) Depth() int { return 10 }
+
+func (f krakenFormatter) Format(bids, asks []Level) string {
+	var b strings.Builder
+	for _, side := range [][]Level{asks, bids} {
+		for _, l := range side {
+			b.WriteString(krakenDigits(l.Price, f.pricePrecision))
+			b.WriteString(krakenDigits(l.Quantity, f.quantityPrecision))
+		}
+	}
+	return b.String()
+}
+
+func krakenDigits(v float64, precision int) string {
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	s = strings.Replace(s, ".", "", 1)
+	return strings.TrimLeft(s, "0")
+}
+
+type okxFormatter struct{}
+
+// OKXFormatter produces the OKX book checksum layout: up to 25 levels
+// interleaved as bid:size:ask:size, continuing with whichever side is
+// deeper once the other runs out.
+func OKXFormatter() ChecksumFormatter {
+	return okxFormatter{}
+}
+
+func (f okxFormatter) Depth() int { return 25 }
+
+func (f okxFormatter) Format(bids, asks []Level) string {
+	var fields []string
+	for i := 0; i < len(bids) || i < len(asks); i++ {
+		if i < len(bids) {
+			fields = append(fields, formatFloat(bids[i].Price), formatFloat(bids[i].Quantity))
+		}
+		if i < len(asks) {
+			fields = append(fields, formatFloat(asks[i].Price), formatFloat(asks[i].Quantity))
+		}
+	}
+	return strings.Join(fields, ":")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Checksum returns the CRC32 (IEEE) of the book's top levels as rendered
+// by f. Venues that publish a signed checksum (OKX) compare against
+// int32(ob.Checksum(f)).
+func (ob *OrderBook) Checksum(f ChecksumFormatter) uint32 {
+	bids := ob.BidBook.depth(f.Depth())
+	asks := ob.AskBook.depth(f.Depth())
+	return crc32.ChecksumIEEE([]byte(f.Format(bids, asks)))
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"hash/crc32"
+	"testing"
+)
+
+func checksumBook() *OrderBook {
+	ob := NewOrderBook()
+	asks := []Order{
+		NewOrder(0.05005, 1.5, "a1"),
+		NewOrder(0.05010, 0.5, "a2"),
+		NewOrder(0.05010, 0.25, "a3"),
+	}
+	bids := []Order{
+		NewOrder(0.05000, 2, "b1"),
+		NewOrder(0.04995, 0.125, "b2"),
+	}
+	for i := range asks {
+		node := NewNode(asks[i].OrderId, &asks[i], 1)
+		ob.AskBook.Push(&node)
+	}
+	for i := range bids {
+		node := NewNode(bids[i].OrderId, &bids[i], 1)
+		ob.BidBook.Push(&node)
+	}
+	return ob
+}
+
+func TestChecksum(t *testing.T) {
+	ob := checksumBook()
+	tests := []struct {
+		Name      string
+		Formatter ChecksumFormatter
+		Expected  string
+	}{
+		{"kraken", KrakenFormatter(5, 8), "50051500000005010750000005000200000000499512500000"},
+		{"okx", OKXFormatter(), "0.05:2:0.05005:1.5:0.04995:0.125:0.0501:0.75"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bids := levels(ob.BidBook.sorted(), test.Formatter.Depth())
+			asks := levels(ob.AskBook.sorted(), test.Formatter.Depth())
+			if s := test.Formatter.Format(bids, asks); s != test.Expected {
+				t.Errorf("Expected checksum input %q, got %q", test.Expected, s)
+			}
+			if sum := ob.Checksum(test.Formatter); sum != crc32.ChecksumIEEE([]byte(test.Expected)) {
+				t.Errorf("Expected checksum %d, got %d", crc32.ChecksumIEEE([]byte(test.Expected)), sum)
+			}
+		})
+	}
+	if ob.AskBook.Len() != 3 || ob.BidBook.Len() != 2 {
+		t.Errorf("Expected Checksum to leave the book intact")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"sync"
+	"time"
+)
+
+// WithClock makes the book take every timestamp it stamps (order entry
+// times, BBO history and session heartbeats) from now instead of the wall
+// clock.
+//
+// Together with a StepClock this gives bit-for-bit reproducible runs. The
+// book makes no random choices: orders at the same effective price are
+// ranked by the order in which they reached their side, each event is
+// stamped with the next sequence number when it is published, and events
+// are published in that order, every trade ahead of the book change it
+// causes. Walks over the book, such as CancelWhere or repegging, visit
+// orders in arrival order, and sessions in id order. Conflation and
+// WatchSessions still run on wall-clock timers, so leave them out of runs
+// that must be reproduced. Monotonic stamps are left zero unless
+// WithMonotonicClock is also given.
+func WithClock(now func() time.Time) Option {
+	return func(ob *OrderBook) {
+		ob.AskBook.now = now
+		ob.BidBook.now = now
+	}
+}
+
+// monoEpoch anchors the default monotonic clock, whose readings are the
+// time elapsed since the package was loaded.
+var monoEpoch = time.Now()
+
+// WithMonotonicClock makes the book take the monotonic stamps on trades,
+// and the fill latencies in execution reports, from now. Readings should
+// never decrease; only their differences are meaningful.
+func WithMonotonicClock(now func() time.Duration) Option {
+	return func(ob *OrderBook) {
+		ob.mono = now
+	}
+}
+
+// monotonic reads the book's monotonic clock. A book with a wall clock
+// from WithClock but no monotonic one reads zero, so that its events
+// stay reproducible.
+func (ob *OrderBook) monotonic() time.Duration {
+	switch {
+	case ob.mono != nil:
+		return ob.mono()
+	case ob.BidBook.now != nil:
+		return 0
+	}
+	return time.Since(monoEpoch)
+}
+
+// fillLatency returns the time from received to the last of trades.
+func fillLatency(trades []TradeEvent, received time.Duration) time.Duration {
+	if len(trades) == 0 {
+		return 0
+	}
+	return trades[len(trades)-1].Monotonic - received
+}
+
+// StepClock returns a clock that starts at start and advances by step on
+// every reading. It is safe for concurrent use.
+func StepClock(start time.Time, step time.Duration) func() time.Time {
+	var lock sync.Mutex
+	next := start
+	return func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+
+		t := next
+		next = next.Add(step)
+		return t
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func deterministicRun() ([]Entry, []Order, string) {
+	start := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook(WithClock(StepClock(start, time.Millisecond)))
+	for i := 0; i < 50; i++ {
+		ob.Submit(Order{Price: float64(100 + i%5), Quantity: 1, OrderId: fmt.Sprint("s", i), Account: fmt.Sprint(i % 3)}, Sell)
+	}
+	ob.SubmitPeg(NewOrder(0, 2, "p1"), Buy, Peg{Type: PegPrimary, Offset: -1})
+	ob.SubmitPeg(NewOrder(0, 2, "p2"), Buy, Peg{Type: PegPrimary, Offset: -2})
+	ob.Submit(NewOrder(101, 7, "t"), Buy)
+	cancelled := ob.CancelByAccount("1")
+
+	cp := NewOrderBook(WithClock(StepClock(start, time.Millisecond)))
+	Copy(ob, cp)
+	var buf bytes.Buffer
+	cp.ExportOrders(&buf, JSONL)
+	return ob.Entries(Sell), cancelled, buf.String()
+}
+
+func TestDeterministicRun(t *testing.T) {
+	entries, cancelled, copied := deterministicRun()
+	for i := 0; i < 5; i++ {
+		e, c, cp := deterministicRun()
+		if !reflect.DeepEqual(e, entries) || !reflect.DeepEqual(c, cancelled) || cp != copied {
+			t.Fatalf("Expected run %d to reproduce the first run exactly", i)
+		}
+	}
+	start := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range entries {
+		var n int
+		fmt.Sscanf(e.Key, "s%d", &n)
+		if want := start.Add(time.Duration(n) * time.Millisecond); !e.Time.Equal(want) {
+			t.Errorf("Expected %s stamped %v by the injected clock, got %v", e.Key, want, e.Time)
+		}
+	}
+	last := -1
+	for _, o := range cancelled {
+		var n int
+		fmt.Sscanf(o.OrderId, "s%d", &n)
+		if n <= last {
+			t.Errorf("Expected cancels in arrival order, got %s after s%d", o.OrderId, last)
+		}
+		last = n
+	}
+}
+
+func TestStepClock(t *testing.T) {
+	start := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := StepClock(start, time.Second)
+	for i := 0; i < 3; i++ {
+		if got := clock(); !got.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("Expected reading %d at %v, got %v", i, start.Add(time.Duration(i)*time.Second), got)
+		}
+	}
+}
+
+func TestMonotonicStamps(t *testing.T) {
+	var readings time.Duration
+	ob := NewOrderBook(WithMonotonicClock(func() time.Duration {
+		readings += 250 * time.Nanosecond
+		return readings
+	}))
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	ob.Submit(NewOrder(102, 1, "a2"), Sell)
+	r := ob.Submit(NewOrder(102, 2, "b1"), Buy)
+	// Three readings: the receipt of b1 and one per trade.
+	if len(r.Trades) != 2 || r.Trades[0].Monotonic != 1000*time.Nanosecond || r.Trades[1].Monotonic != 1250*time.Nanosecond {
+		t.Fatalf("Expected trades stamped at 1000ns and 1250ns, got %+v", r.Trades)
+	}
+	if r.FillLatency != 500*time.Nanosecond || r.Trades[0].Time.IsZero() {
+		t.Errorf("Expected a fill latency of 500ns and wall times on the trades, got %v %+v", r.FillLatency, r.Trades)
+	}
+	if r := ob.Submit(NewOrder(90, 1, "b2"), Buy); r.FillLatency != 0 {
+		t.Errorf("Expected no fill latency without a fill, got %v", r.FillLatency)
+	}
+
+	ob = NewOrderBook()
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	if r := ob.Submit(NewOrder(101, 1, "b1"), Buy); r.Trades[0].Monotonic <= 0 || r.FillLatency < 0 {
+		t.Errorf("Expected a monotonic stamp from the default clock, got %+v", r)
+	}
+	ob = NewOrderBook(WithClock(StepClock(time.Unix(0, 0), time.Second)))
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	if r := ob.Submit(NewOrder(101, 1, "b1"), Buy); r.Trades[0].Monotonic != 0 {
+		t.Errorf("Expected no monotonic stamp under a fixed wall clock, got %v", r.Trades[0].Monotonic)
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command orderbook inspects, replays and serves order books.
+//
+//	orderbook inspect -orders book.csv [-depth 10]
+//	orderbook replay -events flow.jsonl [-orders book.csv] [-speed 1]
+//	orderbook serve -orders book.csv [-events flow.jsonl] [-addr :8080]
+//	orderbook watch -events flow.jsonl [-orders book.csv] [-speed 1]
+//
+// Orders files are in the format written by OrderBook.ExportOrders and
+// event logs in the format read by the simulation package; both are
+// chosen by extension (.csv, or .jsonl otherwise).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+	"github.com/laneshetron/go-orderbook/simulation"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: orderbook inspect|replay|serve|watch [flags]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "inspect":
+		err = inspect(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	case "serve":
+		err = serve(os.Args[2:])
+	case "watch":
+		err = watch(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "orderbook:", err)
+		os.Exit(1)
+	}
+}
+
+func loadOrders(path string) (*orderbook.OrderBook, error) {
+	ob := orderbook.NewOrderBook()
+	if path == "" {
+		return ob, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	format := orderbook.JSONL
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = orderbook.CSV
+	}
+	return ob, ob.ImportOrders(f, format)
+}
+
+func loadEvents(path string) ([]simulation.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return simulation.ReadCSV(f)
+	}
+	return simulation.ReadJSONL(f)
+}
+
+// imbalance is (bid - ask) / (bid + ask) over the given levels.
+func imbalance(bids, asks []orderbook.Level) float64 {
+	var b, a float64
+	for _, l := range bids {
+		b += l.Quantity
+	}
+	for _, l := range asks {
+		a += l.Quantity
+	}
+	if a+b == 0 {
+		return 0
+	}
+	return (b - a) / (b + a)
+}
+
+func summarize(w io.Writer, ob *orderbook.OrderBook, depth int) {
+	ob.RenderDepth(w, depth)
+	bids, asks := ob.Depth(depth)
+	if spread, ok := ob.Spread(); ok {
+		mid, _ := ob.Midpoint()
+		fmt.Fprintf(w, "spread %g  mid %g", spread, mid)
+	} else {
+		fmt.Fprint(w, "spread -  mid -")
+	}
+	fmt.Fprintf(w, "  imbalance %.4f  orders %d/%d\n", imbalance(bids, asks), ob.OrderCount(orderbook.Buy), ob.OrderCount(orderbook.Sell))
+}
+
+func inspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	orders := fs.String("orders", "", "orders file to load")
+	depth := fs.Int("depth", 10, "levels to print per side")
+	fs.Parse(args)
+	ob, err := loadOrders(*orders)
+	if err != nil {
+		return err
+	}
+	summarize(os.Stdout, ob, *depth)
+	return nil
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	orders := fs.String("orders", "", "orders file to start from")
+	events := fs.String("events", "", "event log to replay")
+	speed := fs.Float64("speed", 0, "replay speed multiple, 0 for as fast as possible")
+	depth := fs.Int("depth", 10, "levels to print per side")
+	verbose := fs.Bool("v", false, "print every fill")
+	fs.Parse(args)
+	ob, err := loadOrders(*orders)
+	if err != nil {
+		return err
+	}
+	flow, err := loadEvents(*events)
+	if err != nil {
+		return err
+	}
+	// stamp the book in the log's time rather than the replay's
+	clock := simulation.NewClock(time.Time{})
+	orderbook.WithClock(clock.Now)(ob)
+	sim := simulation.New(ob, *speed)
+	sim.Clock = clock
+	res := sim.Run(flow)
+	if *verbose {
+		for _, f := range res.Fills {
+			fmt.Printf("%s %s %s %g @ %g\n", f.Time.Format(time.RFC3339Nano), f.Side, f.TakerId, f.Quantity, f.Price)
+		}
+	}
+	fmt.Printf("%+v\n", res.Stats)
+	summarize(os.Stdout, ob, *depth)
+	return nil
+}
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	orders := fs.String("orders", "", "orders file to load")
+	events := fs.String("events", "", "event log to replay while serving")
+	speed := fs.Float64("speed", 1, "replay speed multiple")
+	addr := fs.String("addr", ":8080", "listen address")
+	fs.Parse(args)
+	ob, err := loadOrders(*orders)
+	if err != nil {
+		return err
+	}
+	srv := newServer(ob)
+	if *events != "" {
+		flow, err := loadEvents(*events)
+		if err != nil {
+			return err
+		}
+		go simulation.New(ob, *speed).Run(flow)
+	}
+	fmt.Fprintf(os.Stderr, "serving on %s\n", *addr)
+	return http.ListenAndServe(*addr, srv)
+}
+
+// redraw is an EventSink that signals on every book update without
+// blocking the book.
+type redraw chan struct{}
+
+func (r redraw) signal() {
+	select {
+	case r <- struct{}{}:
+	default:
+	}
+}
+
+func (r redraw) Quote(*orderbook.Quote)      { r.signal() }
+func (r redraw) Trade(*orderbook.TradeEvent) { r.signal() }
+func (r redraw) Diff(*orderbook.DepthDiff)   { r.signal() }
+
+func watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	orders := fs.String("orders", "", "orders file to start from")
+	events := fs.String("events", "", "event log to replay")
+	speed := fs.Float64("speed", 1, "replay speed multiple")
+	depth := fs.Int("depth", 10, "levels to show per side")
+	fs.Parse(args)
+	ob, err := loadOrders(*orders)
+	if err != nil {
+		return err
+	}
+	flow, err := loadEvents(*events)
+	if err != nil {
+		return err
+	}
+	updates := make(redraw, 1)
+	ob.AddSink(updates)
+	done := make(chan struct{})
+	go func() {
+		simulation.New(ob, *speed).Run(flow)
+		close(done)
+	}()
+
+	render := func() {
+		fmt.Print("\x1b[H\x1b[2J")
+		summarize(os.Stdout, ob, *depth)
+	}
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	dirty := true
+	for {
+		select {
+		case <-updates:
+			dirty = true
+		case <-tick.C:
+			if dirty {
+				render()
+				dirty = false
+			}
+		case <-done:
+			render()
+			return nil
+		}
+	}
+}
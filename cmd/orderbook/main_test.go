@@ -0,0 +1,109 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestSummarize(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(99, 3, "a"))
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(101, 1, "b"))
+	var buf bytes.Buffer
+	summarize(&buf, ob, 5)
+	out := buf.String()
+	if !strings.Contains(out, "spread 2  mid 100  imbalance 0.5000  orders 1/1") {
+		t.Errorf("Expected spread, mid and imbalance in summary, got:\n%s", out)
+	}
+}
+
+func TestServer(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	srv := httptest.NewServer(newServer(ob))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(99, 3, "a"))
+
+	reader := bufio.NewReader(resp.Body)
+	var events []string
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimSpace(strings.TrimPrefix(line, "event: ")))
+		}
+	}
+	if events[0] != "diff" || events[1] != "quote" {
+		t.Errorf("Expected a diff then a quote, got %v", events)
+	}
+
+	rec := httptest.NewRecorder()
+	newServer(ob).ServeHTTP(rec, httptest.NewRequest("GET", "/depth?n=5", nil))
+	var d orderbook.DepthDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Bids) != 1 || d.Bids[0].Quantity != 3 {
+		t.Errorf("Expected one bid level of 3, got %+v", d)
+	}
+}
+
+func TestServerGzip(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(99, 3, "a"))
+	srv := newServer(ob)
+
+	req := httptest.NewRequest("GET", "/snapshot", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzipped snapshot, got headers %v", rec.Header())
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := orderbook.NewOrderBook()
+	if err := restored.RestoreSnapshot(data); err != nil || restored.BidBook.Len() != 1 {
+		t.Errorf("Expected the snapshot to restore one bid, got %d (%v)", restored.BidBook.Len(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/snapshot", nil))
+	if rec.Header().Get("Content-Encoding") != "" || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("Expected plain JSON without Accept-Encoding, got %v %q", rec.Header(), rec.Body.String())
+	}
+}
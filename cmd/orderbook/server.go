@@ -0,0 +1,164 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// server exposes the book over HTTP: /depth (optionally ?bucket=size),
+// /quote and /snapshot return JSON and /stream pushes quotes, trades and
+// diffs as server-sent events. Responses are gzipped for clients that
+// accept it.
+type server struct {
+	*http.ServeMux
+	ob *orderbook.OrderBook
+
+	lock    sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+func newServer(ob *orderbook.OrderBook) *server {
+	s := &server{
+		ServeMux: http.NewServeMux(),
+		ob:       ob,
+		clients:  make(map[chan []byte]struct{}),
+	}
+	s.HandleFunc("/depth", gzipped(s.depth))
+	s.HandleFunc("/quote", gzipped(s.quote))
+	s.HandleFunc("/snapshot", gzipped(s.snapshot))
+	s.HandleFunc("/stream", gzipped(s.stream))
+	ob.AddSink(s)
+	return s
+}
+
+// gzipWriter compresses a response, flushing the compressor along with
+// the response so streams are not held back.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (g gzipWriter) Write(p []byte) (int, error) { return g.gz.Write(p) }
+
+func (g gzipWriter) Flush() {
+	g.gz.Flush()
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func gzipped(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			h(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		h(gzipWriter{w, gz}, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) depth(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+	size, _ := strconv.ParseFloat(r.URL.Query().Get("bucket"), 64)
+	bids, asks := s.ob.BucketDepth(n, size)
+	writeJSON(w, orderbook.DepthDiff{Bids: bids, Asks: asks, Sequence: s.ob.Sequence()})
+}
+
+func (s *server) quote(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n < 1 {
+		n = 1
+	}
+	writeJSON(w, s.ob.QuoteN(n))
+}
+
+func (s *server) snapshot(w http.ResponseWriter, r *http.Request) {
+	data, err := s.ob.EncodeSnapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan []byte, 64)
+	s.lock.Lock()
+	s.clients[ch] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.clients, ch)
+		s.lock.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+	for {
+		select {
+		case msg := <-ch:
+			w.Write(msg)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// broadcast drops the event for clients that have fallen behind rather
+// than blocking the book.
+func (s *server) broadcast(event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	msg := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+func (s *server) Quote(q *orderbook.Quote)      { s.broadcast("quote", q) }
+func (s *server) Trade(e *orderbook.TradeEvent) { s.broadcast("trade", e) }
+func (s *server) Diff(d *orderbook.DepthDiff)   { s.broadcast("diff", d) }
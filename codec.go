@@ -0,0 +1,144 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// Codec serializes snapshots, events and entries for storage and the
+// wire. JSON, gob and the versioned JSON envelope are provided; formats
+// needing generated or third-party code, such as protobuf or msgpack, are
+// plugged in by implementing it outside this module.
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v interface{}) ([]byte, error)    { return json.Marshal(v) }
+func (jsonCodec) Decode(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type versionedCodec struct{}
+
+func (versionedCodec) Encode(v interface{}) ([]byte, error)    { return Seal(v) }
+func (versionedCodec) Decode(data []byte, v interface{}) error { return Open(data, v) }
+
+var (
+	JSONCodec Codec = jsonCodec{}
+	GobCodec  Codec = gobCodec{}
+	// VersionedCodec writes Seal envelopes and reads them with Open, so
+	// it only handles the kinds an Envelope can carry.
+	VersionedCodec Codec = versionedCodec{}
+)
+
+type gzipCodec struct {
+	codec Codec
+	level int
+}
+
+// GzipCodec compresses what c encodes with gzip at level, such as
+// gzip.BestSpeed, for large snapshots:
+// WithCodec(GzipCodec(JSONCodec, gzip.DefaultCompression)). Decode also
+// reads data c wrote uncompressed, so books can switch to it without
+// rewriting what they already stored. zstd needs code from outside the
+// standard library, so it is plugged in as a Codec of its own.
+func GzipCodec(c Codec, level int) Codec {
+	return gzipCodec{c, level}
+}
+
+func (g gzipCodec) Encode(v interface{}) ([]byte, error) {
+	data, err := g.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, g.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (g gzipCodec) Decode(data []byte, v interface{}) error {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return g.codec.Decode(data, v)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return g.codec.Decode(plain, v)
+}
+
+// WithCodec sets the codec EncodeSnapshot and RestoreSnapshot use, JSON
+// by default.
+func WithCodec(c Codec) Option {
+	return func(ob *OrderBook) {
+		ob.codec = c
+	}
+}
+
+func (ob *OrderBook) snapshotCodec() Codec {
+	if ob.codec == nil {
+		return JSONCodec
+	}
+	return ob.codec
+}
+
+// EncodeSnapshot serializes a Snapshot of the book with its codec.
+func (ob *OrderBook) EncodeSnapshot() ([]byte, error) {
+	return ob.snapshotCodec().Encode(ob.Snapshot())
+}
+
+// RestoreSnapshot decodes a snapshot written by EncodeSnapshot and rests
+// its orders in priority order.
+func (ob *OrderBook) RestoreSnapshot(data []byte) error {
+	var s BookSnapshot
+	if err := ob.snapshotCodec().Decode(data, &s); err != nil {
+		return err
+	}
+	ob.Restore(s.Orders)
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"compress/gzip"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCodecs(t *testing.T) {
+	for name, c := range map[string]Codec{"json": JSONCodec, "gob": GobCodec, "versioned": VersionedCodec,
+		"gzip": GzipCodec(JSONCodec, gzip.BestSpeed)} {
+		ob := NewOrderBook(WithCodec(c))
+		ob.Submit(NewOrder(99, 2, "b1"), Buy)
+		ob.Submit(Order{Price: 101, Quantity: 1, OrderId: "a1", Account: "acct", Category: MarketMaker}, Sell)
+		data, err := ob.EncodeSnapshot()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		restored := NewOrderBook(WithCodec(c))
+		if err := restored.RestoreSnapshot(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, side := range []Side{Buy, Sell} {
+			want, got := ob.Entries(side), restored.Entries(side)
+			for i := range want {
+				want[i].Time = want[i].Time.Round(0)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("%s: Expected %v side to round trip:\n%+v\n%+v", name, side, want, got)
+			}
+		}
+
+		trade := TradeEvent{Price: 100, Quantity: 1, Side: Sell, MakerId: "m", Time: time.Unix(5, 0).UTC()}
+		data, err = c.Encode(&trade)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got TradeEvent
+		if err := c.Decode(data, &got); err != nil || !reflect.DeepEqual(got, trade) {
+			t.Errorf("%s: Expected %+v to round trip, got %+v %v", name, trade, got, err)
+		}
+	}
+}
+
+func TestGzipCodec(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 0; i < 100; i++ {
+		ob.Submit(NewOrder(float64(100+i%5), 1, "order-"+strconv.Itoa(i)), Sell)
+	}
+	plain, _ := ob.EncodeSnapshot()
+	ob.codec = GzipCodec(JSONCodec, gzip.DefaultCompression)
+	compressed, err := ob.EncodeSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(plain)/2 {
+		t.Errorf("Expected compression to at least halve %d bytes, got %d", len(plain), len(compressed))
+	}
+	for _, data := range [][]byte{plain, compressed} {
+		restored := NewOrderBook(WithCodec(GzipCodec(JSONCodec, gzip.DefaultCompression)))
+		if err := restored.RestoreSnapshot(data); err != nil || restored.AskBook.Len() != 100 {
+			t.Errorf("Expected 100 asks restored, got %d (%v)", restored.AskBook.Len(), err)
+		}
+	}
+	if _, err := GzipCodec(JSONCodec, 42).Encode(1); err == nil {
+		t.Errorf("Expected an invalid level to fail")
+	}
+}
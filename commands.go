@@ -0,0 +1,56 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "fmt"
+
+// Apply executes c on ob as an Engine does: inserts are submitted, and so
+// match before any remainder rests, unlike in a Batch; cancels and amends
+// are applied as in a Batch. Books built with the same options and clock
+// that apply the same commands in the same order end up holding the same
+// orders and reporting the same trades, so a command log is enough to
+// keep replicas of a book in step.
+func (c Command) Apply(ob *OrderBook) EngineResult {
+	var r EngineResult
+	switch c.Op {
+	case OpInsert:
+		r.Report = ob.Submit(c.Order, c.Side)
+		r.Err = r.Report.Err
+	case OpCancel:
+		r.Found = ob.cancelOn(c.Side, c.Order.OrderId)
+	case OpAmend:
+		var found []bool
+		if found, r.Err = ob.Batch([]Command{c}); r.Err == nil {
+			r.Found = found[0]
+		}
+	default:
+		r.Err = fmt.Errorf("orderbook: unknown command %d", c.Op)
+	}
+	return r
+}
+
+// Encode seals c in an envelope for a replicated log. Its order must
+// carry an OrderId, since one assigned on apply could differ between
+// replicas.
+func (c Command) Encode() ([]byte, error) {
+	if c.Order.OrderId == "" {
+		return nil, ErrMissingOrderId
+	}
+	return Seal(c)
+}
+
+// Decode reads a command written by Encode into c.
+func (c *Command) Decode(data []byte) error {
+	return Open(data, c)
+}
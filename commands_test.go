@@ -0,0 +1,75 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommandReplicas(t *testing.T) {
+	now := func() time.Time { return time.Unix(1, 0) }
+	primary, replica := NewOrderBook(WithClock(now)), NewOrderBook(WithClock(now))
+	cmds := []Command{
+		{Op: OpInsert, Side: Sell, Order: NewOrder(101, 2, "a1")},
+		{Op: OpInsert, Side: Sell, Order: NewOrder(102, 1, "a2")},
+		{Op: OpInsert, Side: Buy, Order: NewOrder(99, 3, "b1")},
+		{Op: OpAmend, Side: Buy, Order: Order{OrderId: "b1", Price: 100}},
+		{Op: OpInsert, Side: Buy, Order: NewOrder(102, 2.5, "b2")},
+		{Op: OpCancel, Side: Sell, Order: Order{OrderId: "a2"}},
+		{Op: OpCancel, Side: Sell, Order: Order{OrderId: "missing"}},
+	}
+	for i, c := range cmds {
+		data, err := c.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Command
+		if err := decoded.Decode(data); err != nil {
+			t.Fatal(err)
+		}
+		want, got := c.Apply(primary), decoded.Apply(replica)
+		if want.Found != got.Found || want.Err != got.Err || len(want.Report.Trades) != len(got.Report.Trades) {
+			t.Errorf("Expected command %d to give %+v on the replica, got %+v", i, want, got)
+		}
+	}
+	if d := Diff(primary.Snapshot(), replica.Snapshot()); !d.Empty() {
+		t.Errorf("Expected the replicas to converge, got %+v", d)
+	}
+	if _, size, ok := replica.BestBid(); !ok || size != 3 {
+		t.Errorf("Expected 3 bid on the replica, got %v %v", size, ok)
+	}
+	if _, err := (Command{Op: OpInsert, Order: NewOrder(100, 1, "")}).Encode(); err != ErrMissingOrderId {
+		t.Errorf("Expected ErrMissingOrderId encoding an order without an id, got %v", err)
+	}
+	if r := (Command{Op: CommandOp(9)}).Apply(primary); r.Err == nil {
+		t.Errorf("Expected an unknown command to fail")
+	}
+}
+
+func TestCommandCancelSide(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(99, 1, "x"), Buy)
+	ob.Submit(NewOrder(101, 1, "x"), Sell)
+
+	if r := (Command{Op: OpCancel, Side: Sell, Order: Order{OrderId: "x"}}).Apply(ob); !r.Found {
+		t.Fatalf("Expected the sell to be found")
+	}
+	if ob.AskBook.Len() != 0 || ob.BidBook.Len() != 1 {
+		t.Errorf("Expected only the sell cancelled, got %d bids and %d asks", ob.BidBook.Len(), ob.AskBook.Len())
+	}
+	if r := (Command{Op: OpCancel, Side: Sell, Order: Order{OrderId: "x"}}).Apply(ob); r.Found {
+		t.Errorf("Expected no sell left to cancel")
+	}
+}
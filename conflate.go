@@ -0,0 +1,147 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"sync"
+	"time"
+)
+
+// WithConflation wraps every sink added to the book with Conflate.
+func WithConflation(interval time.Duration) Option {
+	return func(ob *OrderBook) {
+		ob.conflation = interval
+	}
+}
+
+// Conflate returns a sink that delivers quotes and diffs to s at most once
+// per interval. Updates arriving in between are merged, so s always ends
+// up with the latest quote and the latest quantity of every level touched;
+// merged events carry the sequence of the newest event in them. Trades,
+// group events, evictions, indicative prices and trade corrections are
+// passed straight through, and so may reach s ahead of updates with lower
+// sequence numbers.
+func Conflate(s EventSink, interval time.Duration) EventSink {
+	return &conflator{sink: s, interval: interval}
+}
+
+type conflator struct {
+	sink     EventSink
+	interval time.Duration
+
+	lock   sync.Mutex
+	quotes throttle
+	diffs  throttle
+	quote  *Quote
+	diff   *DepthDiff
+}
+
+// throttle limits one kind of event to a delivery per interval.
+type throttle struct {
+	last  time.Time
+	timer *time.Timer
+}
+
+// schedule calls deliver now if the interval has passed since the last
+// delivery, or arms a timer to call it when it has. It is called, and
+// calls deliver, with lock held.
+func (t *throttle) schedule(lock *sync.Mutex, interval time.Duration, deliver func()) {
+	if t.timer != nil {
+		return
+	}
+	wait := interval - time.Since(t.last)
+	if wait <= 0 {
+		deliver()
+		t.last = time.Now()
+		return
+	}
+	t.timer = time.AfterFunc(wait, func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		t.timer = nil
+		deliver()
+		t.last = time.Now()
+	})
+}
+
+func (c *conflator) Trade(e *TradeEvent) {
+	c.sink.Trade(e)
+}
+
+func (c *conflator) Group(e *GroupEvent) {
+	if gs, ok := c.sink.(GroupSink); ok {
+		gs.Group(e)
+	}
+}
+
+func (c *conflator) Evicted(e *EvictionEvent) {
+	if es, ok := c.sink.(EvictionSink); ok {
+		es.Evicted(e)
+	}
+}
+
+func (c *conflator) Indicative(e *IndicativeEvent) {
+	if is, ok := c.sink.(IndicativeSink); ok {
+		is.Indicative(e)
+	}
+}
+
+func (c *conflator) Corrected(e *TradeCorrection) {
+	if cs, ok := c.sink.(CorrectionSink); ok {
+		cs.Corrected(e)
+	}
+}
+
+func (c *conflator) Quote(q *Quote) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.quote = q
+	c.quotes.schedule(&c.lock, c.interval, func() {
+		c.sink.Quote(c.quote)
+	})
+}
+
+func mergeLevels(into, from []Level) []Level {
+	for _, l := range from {
+		found := false
+		for i := range into {
+			if into[i].Price == l.Price {
+				into[i].Quantity, found = l.Quantity, true
+				break
+			}
+		}
+		if !found {
+			into = append(into, l)
+		}
+	}
+	return into
+}
+
+func (c *conflator) Diff(d *DepthDiff) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.diff == nil {
+		c.diff = &DepthDiff{}
+	}
+	c.diff.Bids = mergeLevels(c.diff.Bids, d.Bids)
+	c.diff.Asks = mergeLevels(c.diff.Asks, d.Asks)
+	c.diff.Sequence = d.Sequence
+	c.diffs.schedule(&c.lock, c.interval, func() {
+		c.sink.Diff(c.diff)
+		c.diff = nil
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lockedSink struct {
+	lock sync.Mutex
+	recordingSink
+}
+
+func (s *lockedSink) Quote(q *Quote) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.recordingSink.Quote(q)
+}
+
+func (s *lockedSink) Trade(e *TradeEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.recordingSink.Trade(e)
+}
+
+func (s *lockedSink) Diff(d *DepthDiff) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.recordingSink.Diff(d)
+}
+
+func (s *lockedSink) counts() (quotes, trades, diffs int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.quotes), len(s.trades), len(s.diffs)
+}
+
+func TestConflation(t *testing.T) {
+	ob := NewOrderBook(WithConflation(50 * time.Millisecond))
+	sink := &lockedSink{}
+	ob.AddSink(sink)
+
+	ob.PushOrder(Buy, NewOrder(99, 1, "b1")) // delivered immediately
+	for i := 2; i <= 10; i++ {
+		ob.PushOrder(Buy, NewOrder(99+float64(i)/100, 1, "b"))
+	}
+	ob.PushOrder(Sell, NewOrder(101, 1, "a1"))
+	ob.Submit(NewOrder(101, 0.5, "t"), Buy)
+
+	if q, tr, d := sink.counts(); q != 1 || d != 1 || tr != 1 {
+		t.Fatalf("Expected only the first update and the trade before the interval, got %d quotes %d trades %d diffs", q, tr, d)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if q, _, d := sink.counts(); q != 2 || d != 2 {
+		t.Fatalf("Expected one conflated quote and diff, got %d and %d", q, d)
+	}
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	last := sink.quotes[1]
+	if last.Bid.Price != 99.1 || last.Ask.Quantity != 0.5 {
+		t.Errorf("Expected the latest quote, got bid %+v ask %+v", last.Bid, last.Ask)
+	}
+	d := sink.diffs[1]
+	if !reflect.DeepEqual(d.Asks, []Level{{101, 0.5}}) {
+		t.Errorf("Expected merged ask level at its latest quantity, got %v", d.Asks)
+	}
+	if d.Sequence < sink.trades[0].Sequence {
+		t.Errorf("Expected the merged diff to carry its newest sequence, got %d", d.Sequence)
+	}
+}
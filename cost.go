@@ -0,0 +1,91 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "math"
+
+// Cost estimates what taking liquidity would cost right now.
+type Cost struct {
+	// Quantity is how much the book can fill, at most the quantity asked.
+	Quantity float64 `json:"quantity"`
+	// Notional is price times quantity, scaled by the instrument's
+	// multiplier.
+	Notional float64 `json:"notional"`
+	Fees     float64 `json:"fees"`
+	// Total is Notional plus Fees when buying, what is paid, and Notional
+	// less Fees when selling, what is received.
+	Total        float64 `json:"total"`
+	AveragePrice float64 `json:"averagePrice"`
+	WorstPrice   float64 `json:"worstPrice"`
+	// Complete is false when the side ran out before the full quantity.
+	Complete bool `json:"complete"`
+}
+
+// CostToBuy walks the asks to estimate buying qty at market, with the
+// book's taker fees.
+func (ob *OrderBook) CostToBuy(qty float64) Cost {
+	return ob.Cost(Buy, qty, "", nil)
+}
+
+// CostToSell walks the bids to estimate selling qty at market, with the
+// book's taker fees.
+func (ob *OrderBook) CostToSell(qty float64) Cost {
+	return ob.Cost(Sell, qty, "", nil)
+}
+
+// Cost estimates a market order for qty on side, by account, against the
+// opposite side as it stands. Fees are charged per resting order filled,
+// as Match would, from fees or, when it is nil, the book's schedule. The
+// book is not changed.
+func (ob *OrderBook) Cost(side Side, qty float64, account string, fees FeeSchedule) Cost {
+	if fees == nil {
+		fees = ob.fees
+	}
+	b := ob.Side(side.Opposite())
+	b.lock.Lock()
+	nodes := b.sortedNodes()
+	b.lock.Unlock()
+
+	var c Cost
+	var value float64
+	left := qty
+	for _, n := range nodes {
+		if left <= 0 {
+			break
+		}
+		o := n.Peek()
+		if o == nil {
+			continue
+		}
+		fill := math.Min(left, o.Quantity)
+		notional := ob.instrument.Notional(o.Price, fill)
+		c.Notional += notional
+		if fees != nil {
+			c.Fees += fees.Fee(account, Taker, math.Abs(notional))
+		}
+		value += o.Price * fill
+		c.Quantity = ob.units.add(c.Quantity, fill)
+		c.WorstPrice = o.Price
+		left = ob.units.sub(left, fill)
+	}
+	c.Complete = left <= 0
+	if c.Quantity > 0 {
+		c.AveragePrice = value / c.Quantity
+	}
+	c.Total = c.Notional + c.Fees
+	if side == Sell {
+		c.Total = c.Notional - c.Fees
+	}
+	return c
+}
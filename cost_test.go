@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestCost(t *testing.T) {
+	ob := NewOrderBook(WithFees(Fees{TakerBps: 10, TakerFixed: 1}))
+	ob.Submit(NewOrder(100, 1, "a"), Sell)
+	ob.Submit(NewOrder(101, 2, "b"), Sell)
+	ob.Submit(NewOrder(99, 5, "c"), Buy)
+
+	tests := []struct {
+		name     string
+		cost     Cost
+		expected Cost
+	}{
+		{"buy", ob.CostToBuy(2), Cost{Quantity: 2, Notional: 201, Fees: 2.201, Total: 203.201,
+			AveragePrice: 100.5, WorstPrice: 101, Complete: true}},
+		{"short", ob.CostToBuy(5), Cost{Quantity: 3, Notional: 302, Fees: 2.302, Total: 304.302,
+			AveragePrice: 302.0 / 3, WorstPrice: 101}},
+		{"sell", ob.CostToSell(4), Cost{Quantity: 4, Notional: 396, Fees: 1.396, Total: 394.604,
+			AveragePrice: 99, WorstPrice: 99, Complete: true}},
+		{"no fees", ob.Cost(Buy, 1, "", Fees{}), Cost{Quantity: 1, Notional: 100, Total: 100,
+			AveragePrice: 100, WorstPrice: 100, Complete: true}},
+	}
+	for _, tt := range tests {
+		if !costNear(tt.cost, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, tt.cost)
+		}
+	}
+	if ob.AskBook.Len() != 2 || ob.BidBook.Len() != 1 {
+		t.Errorf("Expected costing to leave the book untouched")
+	}
+}
+
+func costNear(a, b Cost) bool {
+	near := func(x, y float64) bool { return x-y < 1e-9 && y-x < 1e-9 }
+	return near(a.Quantity, b.Quantity) && near(a.Notional, b.Notional) && near(a.Fees, b.Fees) &&
+		near(a.Total, b.Total) && near(a.AveragePrice, b.AveragePrice) &&
+		near(a.WorstPrice, b.WorstPrice) && a.Complete == b.Complete
+}
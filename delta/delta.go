@@ -0,0 +1,258 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package delta packs depth snapshots and diffs into compact binary frames
+// for feeds.
+//
+// A frame is a flags byte, the sequence as a varint offset from the last
+// frame's, then the bid and ask levels, each side a count followed by price
+// and quantity pairs. Prices are varint tick offsets from the level before,
+// or for a side's first level from the previous frame's first level, and
+// quantities varint lot offsets from what the level last held, so a
+// typical update of a few levels near the top takes a handful of bytes.
+// Encoder and Decoder each track the levels they have seen, so frames must
+// be decoded in the order they were encoded, starting from a snapshot.
+package delta
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+var (
+	ErrOffGrid = errors.New("delta: price or quantity is not a whole number of ticks or lots")
+	ErrCorrupt = errors.New("delta: malformed frame")
+	ErrNoReset = errors.New("delta: frame decoded before a snapshot")
+)
+
+const flagSnapshot = 1
+
+// side is the quantity, in lots, held at each price, in ticks.
+type side map[int64]int64
+
+type state struct {
+	tick, lot float64
+	sequence  uint64
+	sides     [2]side
+	// anchors are the first price of each side in the last frame
+	anchors [2]int64
+	started bool
+}
+
+func newState(tick, lot float64) state {
+	if tick <= 0 {
+		tick = 1
+	}
+	if lot <= 0 {
+		lot = 1
+	}
+	return state{tick: tick, lot: lot}
+}
+
+func (s *state) reset() {
+	s.sides = [2]side{{}, {}}
+	s.anchors = [2]int64{}
+	s.started = true
+}
+
+// grid returns v as a whole number of steps of size step.
+func grid(v, step float64) (int64, bool) {
+	n := math.Round(v / step)
+	if math.Abs(v/step-n) > 1e-6 || math.Abs(n) > math.MaxInt64/2 {
+		return 0, false
+	}
+	return int64(n), true
+}
+
+// Encoder turns depth updates into frames.
+type Encoder struct {
+	state
+	buf []byte
+}
+
+// NewEncoder returns an encoder for prices on a grid of tick and
+// quantities in multiples of lot. Non-positive values mean 1.
+func NewEncoder(tick, lot float64) *Encoder {
+	return &Encoder{state: newState(tick, lot)}
+}
+
+// EncodeSnapshot encodes s as a frame that replaces whatever the decoder
+// held. A feed sends one first, and again to resynchronize.
+func (e *Encoder) EncodeSnapshot(s orderbook.DepthSnapshot) ([]byte, error) {
+	e.reset()
+	e.sequence = 0
+	return e.encode(flagSnapshot, s.Sequence, s.Bids, s.Asks)
+}
+
+// Encode encodes d, whose levels hold the new quantity at each price with
+// zero removing it. The returned slice is reused by the next call.
+func (e *Encoder) Encode(d orderbook.DepthDiff) ([]byte, error) {
+	if !e.started {
+		e.reset()
+	}
+	return e.encode(0, d.Sequence, d.Bids, d.Asks)
+}
+
+func (e *Encoder) encode(flags byte, sequence uint64, bids, asks []orderbook.Level) ([]byte, error) {
+	b := append(e.buf[:0], flags)
+	b = binary.AppendUvarint(b, sequence-e.sequence)
+	for i, lvls := range [][]orderbook.Level{bids, asks} {
+		b = binary.AppendUvarint(b, uint64(len(lvls)))
+		prev := e.anchors[i]
+		for _, l := range lvls {
+			price, ok := grid(l.Price, e.tick)
+			qty, qok := grid(l.Quantity, e.lot)
+			if !ok || !qok {
+				return nil, ErrOffGrid
+			}
+			b = binary.AppendVarint(b, price-prev)
+			b = binary.AppendVarint(b, qty-e.sides[i][price])
+			prev = price
+		}
+	}
+	// only commit the new state once the whole frame is encoded
+	for i, lvls := range [][]orderbook.Level{bids, asks} {
+		for j, l := range lvls {
+			price, _ := grid(l.Price, e.tick)
+			if j == 0 {
+				e.anchors[i] = price
+			}
+			if qty, _ := grid(l.Quantity, e.lot); qty == 0 {
+				delete(e.sides[i], price)
+			} else {
+				e.sides[i][price] = qty
+			}
+		}
+	}
+	e.sequence = sequence
+	e.buf = b
+	return b, nil
+}
+
+// Decoder turns frames back into depth updates.
+type Decoder struct {
+	state
+}
+
+// NewDecoder returns a decoder for frames from an encoder with the same
+// tick and lot.
+func NewDecoder(tick, lot float64) *Decoder {
+	return &Decoder{newState(tick, lot)}
+}
+
+// Decode returns the update carried by frame. Snapshot frames come back as
+// a diff holding every level, with snapshot true.
+func (d *Decoder) Decode(frame []byte) (diff orderbook.DepthDiff, snapshot bool, err error) {
+	if len(frame) == 0 {
+		return diff, false, ErrCorrupt
+	}
+	snapshot = frame[0]&flagSnapshot != 0
+	if !snapshot && !d.started {
+		return diff, false, ErrNoReset
+	}
+	b := frame[1:]
+	next := func() (uint64, error) {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, ErrCorrupt
+		}
+		b = b[n:]
+		return v, nil
+	}
+	nextSigned := func() (int64, error) {
+		v, n := binary.Varint(b)
+		if n <= 0 {
+			return 0, ErrCorrupt
+		}
+		b = b[n:]
+		return v, nil
+	}
+
+	seq, err := next()
+	if err != nil {
+		return diff, false, err
+	}
+	sides, anchors := d.sides, d.anchors
+	base := d.sequence
+	if snapshot {
+		sides, anchors = [2]side{{}, {}}, [2]int64{}
+		base = 0
+	}
+	var out [2][]orderbook.Level
+	type update struct {
+		i            int
+		price, units int64
+	}
+	var updates []update
+	for i := range out {
+		count, err := next()
+		if err != nil {
+			return diff, false, err
+		}
+		if count > uint64(len(b)) {
+			return diff, false, ErrCorrupt
+		}
+		prev := anchors[i]
+		for j := uint64(0); j < count; j++ {
+			dp, err := nextSigned()
+			if err != nil {
+				return diff, false, err
+			}
+			dq, err := nextSigned()
+			if err != nil {
+				return diff, false, err
+			}
+			price := prev + dp
+			qty := sides[i][price] + dq
+			if qty < 0 {
+				return diff, false, ErrCorrupt
+			}
+			out[i] = append(out[i], orderbook.Level{Price: float64(price) * d.tick, Quantity: float64(qty) * d.lot})
+			updates = append(updates, update{i, price, qty})
+			if j == 0 {
+				anchors[i] = price
+			}
+			prev = price
+		}
+	}
+	if len(b) != 0 {
+		return diff, false, ErrCorrupt
+	}
+
+	d.sides, d.anchors, d.started, d.sequence = sides, anchors, true, base+seq
+	for _, u := range updates {
+		if u.units == 0 {
+			delete(d.sides[u.i], u.price)
+		} else {
+			d.sides[u.i][u.price] = u.units
+		}
+	}
+	return orderbook.DepthDiff{Bids: out[0], Asks: out[1], Sequence: d.sequence}, snapshot, nil
+}
+
+// Apply decodes frame into ob: snapshots through ApplySnapshot and diffs
+// through ApplyDiff.
+func (d *Decoder) Apply(ob *orderbook.OrderBook, frame []byte) error {
+	diff, snapshot, err := d.Decode(frame)
+	if err != nil {
+		return err
+	}
+	if snapshot {
+		return ob.ApplySnapshot(orderbook.DepthSnapshot{Bids: diff.Bids, Asks: diff.Asks, Sequence: diff.Sequence})
+	}
+	return ob.ApplyDiff(diff)
+}
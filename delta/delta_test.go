@@ -0,0 +1,184 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package delta
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestRoundTrip(t *testing.T) {
+	enc := NewEncoder(0.01, 0.001)
+	dec := NewDecoder(0.01, 0.001)
+	ob := orderbook.NewOrderBook()
+
+	snapshot := orderbook.DepthSnapshot{
+		Bids:     []orderbook.Level{{Price: 100.05, Quantity: 1.5}, {Price: 100.04, Quantity: 2}, {Price: 99.9, Quantity: 0.25}},
+		Asks:     []orderbook.Level{{Price: 100.07, Quantity: 3}, {Price: 100.1, Quantity: 0.001}},
+		Sequence: 1000,
+	}
+	diffs := []orderbook.DepthDiff{
+		{Bids: []orderbook.Level{{Price: 100.05, Quantity: 1.4}}, Sequence: 1001},
+		{Bids: []orderbook.Level{{Price: 100.06, Quantity: 1}}, Asks: []orderbook.Level{{Price: 100.07, Quantity: 0}, {Price: 100.08, Quantity: 4}}, Sequence: 1002},
+		{Asks: []orderbook.Level{{Price: 100.1, Quantity: 0}}, Sequence: 1003},
+	}
+
+	frame, err := enc.EncodeSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Apply(ob, frame); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		frame, err := enc.Encode(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, snap, err := cloneDecoder(dec).Decode(frame)
+		if err != nil || snap {
+			t.Fatalf("Expected a diff, got %v %v", snap, err)
+		}
+		if got.Sequence != d.Sequence || len(got.Bids) != len(d.Bids) || len(got.Asks) != len(d.Asks) {
+			t.Errorf("Expected %+v, got %+v", d, got)
+		}
+		if err := dec.Apply(ob, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bids, asks := ob.Depth(0)
+	expectedBids := []orderbook.Level{{Price: 100.06, Quantity: 1}, {Price: 100.05, Quantity: 1.4}, {Price: 100.04, Quantity: 2}, {Price: 99.9, Quantity: 0.25}}
+	expectedAsks := []orderbook.Level{{Price: 100.08, Quantity: 4}}
+	if !equalLevels(bids, expectedBids) || !equalLevels(asks, expectedAsks) {
+		t.Errorf("Expected %v %v, got %v %v", expectedBids, expectedAsks, bids, asks)
+	}
+	if !ob.Synced() {
+		t.Errorf("Expected the book to be synced")
+	}
+}
+
+// cloneDecoder copies d so a frame can be inspected without consuming it.
+func cloneDecoder(d *Decoder) *Decoder {
+	c := &Decoder{d.state}
+	for i, s := range d.sides {
+		c.sides[i] = side{}
+		for k, v := range s {
+			c.sides[i][k] = v
+		}
+	}
+	return c
+}
+
+func equalLevels(a, b []orderbook.Level) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if d := a[i].Price - b[i].Price; d > 1e-9 || d < -1e-9 {
+			return false
+		}
+		if d := a[i].Quantity - b[i].Quantity; d > 1e-9 || d < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestErrors(t *testing.T) {
+	enc := NewEncoder(0.5, 1)
+	if _, err := enc.Encode(orderbook.DepthDiff{Bids: []orderbook.Level{{Price: 100.2, Quantity: 1}}}); err != ErrOffGrid {
+		t.Errorf("Expected ErrOffGrid, got %v", err)
+	}
+	if _, err := enc.Encode(orderbook.DepthDiff{Bids: []orderbook.Level{{Price: 100.5, Quantity: 1.5}}}); err != ErrOffGrid {
+		t.Errorf("Expected ErrOffGrid, got %v", err)
+	}
+
+	dec := NewDecoder(0.5, 1)
+	frame, _ := enc.Encode(orderbook.DepthDiff{Bids: []orderbook.Level{{Price: 100.5, Quantity: 1}}, Sequence: 1})
+	if _, _, err := dec.Decode(frame); err != ErrNoReset {
+		t.Errorf("Expected ErrNoReset, got %v", err)
+	}
+	frame, _ = enc.EncodeSnapshot(orderbook.DepthSnapshot{Bids: []orderbook.Level{{Price: 100.5, Quantity: 1}}, Sequence: 5})
+	for _, bad := range [][]byte{nil, frame[:len(frame)-1], append(append([]byte(nil), frame...), 0)} {
+		if _, _, err := dec.Decode(bad); err != ErrCorrupt {
+			t.Errorf("Expected ErrCorrupt for %v, got %v", bad, err)
+		}
+	}
+	if _, _, err := dec.Decode(frame); err != nil {
+		t.Errorf("Expected the snapshot to decode after bad frames, got %v", err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	enc := NewEncoder(0.01, 0.0001)
+	dec := NewDecoder(0.01, 0.0001)
+	var snapshot orderbook.DepthSnapshot
+	for i := 0; i < 50; i++ {
+		snapshot.Bids = append(snapshot.Bids, orderbook.Level{Price: 25000 - float64(i)*0.01, Quantity: 1})
+		snapshot.Asks = append(snapshot.Asks, orderbook.Level{Price: 25000.01 + float64(i)*0.01, Quantity: 1})
+	}
+	frame, _ := enc.EncodeSnapshot(snapshot)
+	if _, _, err := dec.Decode(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	var binary, text int
+	for seq := uint64(1); seq <= 1000; seq++ {
+		d := orderbook.DepthDiff{Sequence: seq}
+		for j := 0; j < 3; j++ {
+			l := orderbook.Level{
+				Price:    25000 - float64(r.Intn(50))*0.01,
+				Quantity: float64(r.Intn(20000)) / 10000,
+			}
+			if !containsPrice(d.Bids, l.Price) {
+				d.Bids = append(d.Bids, l)
+			}
+		}
+		frame, err := enc.Encode(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := dec.Decode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalLevels(got.Bids, d.Bids) || got.Sequence != seq {
+			t.Fatalf("Expected %+v, got %+v", d, got)
+		}
+		js, _ := json.Marshal(d)
+		binary += len(frame)
+		text += len(js)
+	}
+	if binary*10 > text {
+		t.Errorf("Expected frames under a tenth of the JSON size, got %d bytes against %d", binary, text)
+	}
+	if !reflect.DeepEqual(enc.sides, dec.sides) {
+		t.Errorf("Expected encoder and decoder to agree on the book")
+	}
+}
+
+func containsPrice(lvls []orderbook.Level, price float64) bool {
+	for _, l := range lvls {
+		if l.Price == price {
+			return true
+		}
+	}
+	return false
+}
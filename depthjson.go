@@ -0,0 +1,48 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type depthJSON struct {
+	Bids [][2]string `json:"bids"`
+	Asks [][2]string `json:"asks"`
+}
+
+// DepthJSON returns up to n aggregated levels per side, as Depth does, in
+// the {"bids":[["price","qty"],...],"asks":[...]} form most exchange APIs
+// and depth charts use. Prices and quantities are written as strings with
+// priceDecimals and quantityDecimals digits after the point; a negative
+// count writes the shortest exact form.
+func (ob *OrderBook) DepthJSON(n, priceDecimals, quantityDecimals int) ([]byte, error) {
+	bids, asks := ob.Depth(n)
+	return json.Marshal(depthJSON{
+		Bids: depthPairs(bids, priceDecimals, quantityDecimals),
+		Asks: depthPairs(asks, priceDecimals, quantityDecimals),
+	})
+}
+
+func depthPairs(lvls []Level, priceDecimals, quantityDecimals int) [][2]string {
+	pairs := make([][2]string, len(lvls))
+	for i, l := range lvls {
+		pairs[i] = [2]string{
+			strconv.FormatFloat(l.Price, 'f', priceDecimals, 64),
+			strconv.FormatFloat(l.Quantity, 'f', quantityDecimals, 64),
+		}
+	}
+	return pairs
+}
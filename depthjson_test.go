@@ -0,0 +1,43 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestDepthJSON(t *testing.T) {
+	ob := NewOrderBook()
+	if data, err := ob.DepthJSON(0, 2, 3); err != nil || string(data) != `{"bids":[],"asks":[]}` {
+		t.Errorf("Expected empty sides for an empty book, got %s %v", data, err)
+	}
+
+	ob.Submit(NewOrder(99.5, 1, "b1"), Buy)
+	ob.Submit(NewOrder(99.5, 0.25, "b2"), Buy)
+	ob.Submit(NewOrder(98, 2, "b3"), Buy)
+	ob.Submit(NewOrder(101.126, 3, "a1"), Sell)
+
+	tests := []struct {
+		n, priceDecimals, quantityDecimals int
+		expected                           string
+	}{
+		{0, 2, 3, `{"bids":[["99.50","1.250"],["98.00","2.000"]],"asks":[["101.13","3.000"]]}`},
+		{1, -1, -1, `{"bids":[["99.5","1.25"]],"asks":[["101.126","3"]]}`},
+		{1, 0, 1, `{"bids":[["100","1.2"]],"asks":[["101","3.0"]]}`},
+	}
+	for _, tt := range tests {
+		data, err := ob.DepthJSON(tt.n, tt.priceDecimals, tt.quantityDecimals)
+		if err != nil || string(data) != tt.expected {
+			t.Errorf("Expected %s, got %s %v", tt.expected, data, err)
+		}
+	}
+}
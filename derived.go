@@ -0,0 +1,135 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sync"
+)
+
+// Derivation computes the implied levels of a derived book, best first,
+// from the full depth of its two legs.
+type Derivation func(near, far DepthSnapshot) (bids, asks []Level)
+
+// CalendarSpread prices the spread near - far. Buying the spread buys near
+// and sells far, so its implied bids pair near's bids with far's asks, and
+// its asks pair near's asks with far's bids, each level as deep as the
+// smaller of the two.
+func CalendarSpread(near, far DepthSnapshot) (bids, asks []Level) {
+	return implied(near.Bids, far.Asks), implied(near.Asks, far.Bids)
+}
+
+// implied walks two ladders best first, pairing off quantity at the
+// price a - b. The result is best first too, as a and b run in opposite
+// directions.
+func implied(a, b []Level) []Level {
+	var out []Level
+	var i, j int
+	var qa, qb float64
+	for i < len(a) && j < len(b) {
+		if qa == 0 {
+			qa = a[i].Quantity
+		}
+		if qb == 0 {
+			qb = b[j].Quantity
+		}
+		q := math.Min(qa, qb)
+		price := math.Round((a[i].Price-b[j].Price)*1e9) / 1e9
+		if n := len(out); n > 0 && out[n-1].Price == price {
+			out[n-1].Quantity += q
+		} else if q > 0 {
+			out = append(out, Level{price, q})
+		}
+		if qa -= q; qa <= 0 {
+			i, qa = i+1, 0
+		}
+		if qb -= q; qb <= 0 {
+			j, qb = j+1, 0
+		}
+	}
+	return out
+}
+
+// DerivedBook maintains Book, a book of the implied levels a Derivation
+// computes from two underlying books, one order per level. It is an
+// EventSink on both legs and refreshes Book after every diff either
+// publishes, so Book's own diffs, quotes and depth follow the legs.
+type DerivedBook struct {
+	Book      *OrderBook
+	near, far *OrderBook
+	derive    Derivation
+
+	lock sync.Mutex
+}
+
+// NewDerivedBook returns a DerivedBook over near and far whose Book is
+// built with opts, never matches and takes negative prices, and adds it to
+// both legs as a sink.
+func NewDerivedBook(near, far *OrderBook, derive Derivation, opts ...Option) *DerivedBook {
+	d := &DerivedBook{
+		Book:   NewOrderBook(append(opts, WithoutMatching(), WithNegativePrices())...),
+		near:   near,
+		far:    far,
+		derive: derive,
+	}
+	d.Refresh()
+	near.AddSink(d)
+	far.AddSink(d)
+	return d
+}
+
+// NewSpreadBook is NewDerivedBook for the CalendarSpread of near and far.
+func NewSpreadBook(near, far *OrderBook, opts ...Option) *DerivedBook {
+	return NewDerivedBook(near, far, CalendarSpread, opts...)
+}
+
+// Refresh recomputes Book from the legs as they stand, publishing only
+// the levels that changed.
+func (d *DerivedBook) Refresh() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var near, far DepthSnapshot
+	near.Bids, near.Asks = d.near.Depth(0)
+	far.Bids, far.Asks = d.far.Depth(0)
+	bids, asks := d.derive(near, far)
+	oldBids, oldAsks := d.Book.Depth(0)
+	d.Book.setLevels(levelChanges(oldBids, bids), levelChanges(oldAsks, asks), false)
+}
+
+// levelChanges returns the levels of next that differ from prev, plus a
+// zero quantity for every level of prev that next drops.
+func levelChanges(prev, next []Level) []Level {
+	held := make(map[float64]float64, len(prev))
+	for _, l := range prev {
+		held[l.Price] = l.Quantity
+	}
+	var out []Level
+	for _, l := range next {
+		if q, ok := held[l.Price]; !ok || q != l.Quantity {
+			out = append(out, l)
+		}
+		delete(held, l.Price)
+	}
+	for _, l := range prev {
+		if _, ok := held[l.Price]; ok {
+			out = append(out, Level{l.Price, 0})
+		}
+	}
+	return out
+}
+
+func (d *DerivedBook) Quote(*Quote)      {}
+func (d *DerivedBook) Trade(*TradeEvent) {}
+func (d *DerivedBook) Diff(*DepthDiff)   { d.Refresh() }
@@ -0,0 +1,82 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImplied(t *testing.T) {
+	cases := []struct {
+		a, b     []Level
+		expected []Level
+	}{
+		{nil, []Level{{100, 1}}, nil},
+		{[]Level{{105, 2}}, []Level{{100, 5}}, []Level{{5, 2}}},
+		{[]Level{{105, 2}, {104, 3}}, []Level{{100, 1}, {101, 10}}, []Level{{5, 1}, {4, 1}, {3, 3}}},
+		{[]Level{{105, 1}, {104, 1}}, []Level{{100, 1}, {101, 1}}, []Level{{5, 1}, {3, 1}}},
+		{[]Level{{105, 1}, {106, 1}}, []Level{{100, 1}, {101, 1}}, []Level{{5, 2}}},
+	}
+	for _, c := range cases {
+		if got := implied(c.a, c.b); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Expected %v from %v and %v, got %v", c.expected, c.a, c.b, got)
+		}
+	}
+}
+
+func TestSpreadBook(t *testing.T) {
+	near, far := NewOrderBook(), NewOrderBook()
+	near.Submit(NewOrder(105, 2, "nb"), Buy)
+	near.Submit(NewOrder(107, 2, "na"), Sell)
+	far.Submit(NewOrder(100, 1, "fb"), Buy)
+
+	d := NewSpreadBook(near, far)
+	diffs := &recordingSink{}
+	d.Book.AddSink(diffs)
+	bids, asks := d.Book.Depth(0)
+	if len(bids) != 0 || !reflect.DeepEqual(asks, []Level{{7, 1}}) {
+		t.Errorf("Expected only an implied ask of 1 at 7, got %v %v", bids, asks)
+	}
+
+	far.Submit(NewOrder(101, 3, "fa"), Sell)
+	far.Submit(NewOrder(102, 1, "fa2"), Sell)
+	bids, _ = d.Book.Depth(0)
+	if !reflect.DeepEqual(bids, []Level{{4, 2}}) {
+		t.Errorf("Expected an implied bid of 2 at 4, got %v", bids)
+	}
+
+	// a near ask trades away, so the implied ask goes too
+	near.Submit(NewOrder(107, 2, "x"), Buy)
+	bids, asks = d.Book.Depth(0)
+	if len(asks) != 0 || !reflect.DeepEqual(bids, []Level{{4, 2}}) {
+		t.Errorf("Expected the implied ask to be gone, got %v %v", bids, asks)
+	}
+	far.Cancel("fb")
+	if len(diffs.diffs) != 2 {
+		t.Fatalf("Expected one diff per change to the implied levels, got %d", len(diffs.diffs))
+	}
+	if last := diffs.diffs[1]; !reflect.DeepEqual(last.Asks, []Level{{7, 0}}) || len(last.Bids) != 0 {
+		t.Errorf("Expected the last diff to remove the ask, got %+v", last)
+	}
+}
+
+func TestLevelChanges(t *testing.T) {
+	prev := []Level{{5, 1}, {4, 2}, {3, 1}}
+	next := []Level{{5, 1}, {4, 3}, {2, 1}}
+	expected := []Level{{4, 3}, {2, 1}, {3, 0}}
+	if got := levelChanges(prev, next); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
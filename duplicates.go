@@ -0,0 +1,95 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrDuplicateOrder = errors.New("orderbook: an order with this key is already resting")
+
+// DuplicatePolicy decides what pushing a node whose key is already resting
+// on its side does.
+type DuplicatePolicy int
+
+const (
+	// ReplaceDuplicates removes the resting order and pushes the new one
+	// at the back of its level. It is the default.
+	ReplaceDuplicates DuplicatePolicy = iota
+	// RejectDuplicates leaves the resting order alone and refuses the new
+	// one with ErrDuplicateOrder.
+	RejectDuplicates
+	// AmendDuplicates sets the resting order's price and quantity to the
+	// new one's, as Batch amends do, keeping its weight, entry time and,
+	// unless it moves or grows, its priority.
+	AmendDuplicates
+)
+
+func (p DuplicatePolicy) String() string {
+	return [...]string{"replace", "reject", "amend"}[p]
+}
+
+// WithDuplicatePolicy sets how both sides treat a push whose key is
+// already resting.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(ob *OrderBook) {
+		ob.AskBook.duplicates = p
+		ob.BidBook.duplicates = p
+	}
+}
+
+// rejects reports whether pushing key would be refused as a duplicate.
+func (sb *SideBook) rejects(key string) bool {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	_, ok := sb.get(key)
+	return ok && sb.duplicates == RejectDuplicates
+}
+
+// batchDuplicate returns the first insert in cmds that a side rejecting
+// duplicates would refuse, counting keys inserted and cancelled by the
+// commands before it. The caller holds both locks.
+func (ob *OrderBook) batchDuplicate(cmds []Command) error {
+	var live map[orderRef]bool
+	for i, c := range cmds {
+		b := ob.Side(c.Side)
+		if b.duplicates != RejectDuplicates || c.Order.OrderId == "" {
+			// an id yet to be generated is new to the side
+			continue
+		}
+		ref := orderRef{c.Side, c.Order.OrderId}
+		switch c.Op {
+		case OpInsert:
+			resting, ok := live[ref]
+			if !ok {
+				_, resting = b.get(ref.key)
+			}
+			if resting {
+				return fmt.Errorf("orderbook: command %d: %w", i, ErrDuplicateOrder)
+			}
+			if live == nil {
+				live = make(map[orderRef]bool)
+			}
+			live[ref] = true
+		case OpCancel:
+			if live == nil {
+				live = make(map[orderRef]bool)
+			}
+			live[ref] = false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		policy   DuplicatePolicy
+		err      error
+		price    float64
+		quantity float64
+		first    string
+	}{
+		{ReplaceDuplicates, nil, 101, 3, "b"},
+		{RejectDuplicates, ErrDuplicateOrder, 100, 2, "a"},
+		{AmendDuplicates, nil, 100, 1, "a"},
+	}
+	for _, tt := range tests {
+		ob := NewOrderBook(WithDuplicatePolicy(tt.policy))
+		ob.PushOrder(Buy, NewOrder(100, 2, "a"))
+		ob.PushOrder(Buy, NewOrder(100, 2, "b"))
+
+		o := NewOrder(100, 1, "a")
+		if tt.policy == ReplaceDuplicates {
+			o = NewOrder(101, 3, "a")
+		}
+		n := NewNode("a", &o, 1)
+		if err := ob.BidBook.Insert(&n); err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.policy, tt.err, err)
+		}
+		got, _, _ := ob.Lookup("a")
+		if got.Price != tt.price || got.Quantity != tt.quantity {
+			t.Errorf("%s: expected a at %f x %f, got %f x %f", tt.policy, tt.price, tt.quantity, got.Price, got.Quantity)
+		}
+		if ob.BidBook.Len() != 2 {
+			t.Errorf("%s: expected 2 resting bids, got %d", tt.policy, ob.BidBook.Len())
+		}
+		if tt.policy != ReplaceDuplicates {
+			if first := ob.BidBook.Peek().OrderId; first != tt.first {
+				t.Errorf("%s: expected %s to keep priority, got %s", tt.policy, tt.first, first)
+			}
+		}
+	}
+}
+
+func TestRejectDuplicates(t *testing.T) {
+	ob := NewOrderBook(WithDuplicatePolicy(RejectDuplicates))
+	ob.Submit(NewOrder(100, 1, "a"), Buy)
+	ob.Submit(NewOrder(102, 1, "s"), Sell)
+
+	// the duplicate would trade against s, so it must be refused up front
+	report := ob.Submit(NewOrder(102, 1, "a"), Buy)
+	if report.Status != StatusRejected || report.Err != ErrDuplicateOrder || len(report.Trades) != 0 {
+		t.Errorf("Expected duplicate to be rejected before matching, got %+v", report)
+	}
+	if report := ob.Submit(NewOrder(101, 1, "a"), Sell); report.Err != nil {
+		t.Errorf("Expected the same key on the other side to be accepted, got %v", report.Err)
+	}
+
+	ob.Cancel("s")
+	cmds := []Command{
+		{Op: OpCancel, Side: Buy, Order: Order{OrderId: "a"}},
+		{Op: OpInsert, Side: Buy, Order: NewOrder(99, 1, "a")},
+		{Op: OpInsert, Side: Buy, Order: NewOrder(98, 1, "a")},
+	}
+	if _, err := ob.Batch(cmds); !errors.Is(err, ErrDuplicateOrder) {
+		t.Errorf("Expected the second insert of a to fail the batch, got %v", err)
+	}
+	if o, _, _ := ob.Lookup("a"); o.Price != 100 {
+		t.Errorf("Expected a failed batch to leave a at 100, got %f", o.Price)
+	}
+	if _, err := ob.Batch(cmds[:2]); err != nil {
+		t.Errorf("Expected cancel then insert of a to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+)
+
+var ErrEngineClosed = errors.New("orderbook: engine is closed")
+
+// EngineResult is the outcome of one Engine command. Inserts fill Report;
+// cancels and amends set Found.
+type EngineResult struct {
+	Report ExecutionReport
+	Found  bool
+	Err    error
+}
+
+// Future is the pending result of a command queued with Engine.Do.
+type Future struct {
+	done   chan struct{}
+	result EngineResult
+}
+
+// Done is closed once the result is ready.
+func (f *Future) Done() <-chan struct{} { return f.done }
+
+// Wait blocks until the command has run and returns its result.
+func (f *Future) Wait() EngineResult {
+	<-f.done
+	return f.result
+}
+
+type engineCommand struct {
+	cmd    Command
+	future *Future
+	done   func(EngineResult)
+}
+
+type ringSlot struct {
+	seq uint64
+	cmd engineCommand
+}
+
+// commandRing is a bounded lock-free queue for many producers and the one
+// engine goroutine consuming. Each slot's sequence number says whether it
+// is free for the producer claiming position pos (seq == pos) or holds a
+// command for the consumer (seq == pos+1).
+type commandRing struct {
+	slots []ringSlot
+	mask  uint64
+	_     [56]byte
+	head  uint64
+	_     [56]byte
+	tail  uint64
+}
+
+func newCommandRing(size int) *commandRing {
+	n := 2
+	for n < size {
+		n <<= 1
+	}
+	r := &commandRing{slots: make([]ringSlot, n), mask: uint64(n - 1)}
+	for i := range r.slots {
+		r.slots[i].seq = uint64(i)
+	}
+	return r
+}
+
+// push queues c and reports false when the ring is full.
+func (r *commandRing) push(c engineCommand) bool {
+	pos := atomic.LoadUint64(&r.head)
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch seq := atomic.LoadUint64(&slot.seq); {
+		case seq == pos:
+			if atomic.CompareAndSwapUint64(&r.head, pos, pos+1) {
+				slot.cmd = c
+				atomic.StoreUint64(&slot.seq, pos+1)
+				return true
+			}
+			pos = atomic.LoadUint64(&r.head)
+		case int64(seq-pos) < 0:
+			return false
+		default:
+			pos = atomic.LoadUint64(&r.head)
+		}
+	}
+}
+
+// pop takes the next command, reporting false when the ring is empty.
+// Only the engine goroutine calls it.
+func (r *commandRing) pop() (engineCommand, bool) {
+	slot := &r.slots[r.tail&r.mask]
+	if atomic.LoadUint64(&slot.seq) != r.tail+1 {
+		return engineCommand{}, false
+	}
+	c := slot.cmd
+	slot.cmd = engineCommand{}
+	atomic.StoreUint64(&slot.seq, r.tail+r.mask+1)
+	r.tail++
+	return c, true
+}
+
+// Engine runs every command against its book on a single goroutine, fed
+// by a lock-free ring of commands from any number of goroutines. As only
+// that goroutine touches the book, the book's locks are never contended;
+// other goroutines should read the book through a WithSnapshotReads view
+// or its sinks rather than calling into it directly.
+//
+// Inserts run as Submit, matching the order; cancels as Cancel; amends
+// as a one-command Batch.
+type Engine struct {
+	Book *OrderBook
+
+	ring      *commandRing
+	wake      chan struct{}
+	stop      chan struct{}
+	stopped   chan struct{}
+	closed    uint32
+	producers int64
+}
+
+// NewEngine starts an Engine over ob whose ring holds size commands,
+// rounded up to a power of two.
+func NewEngine(ob *OrderBook, size int) *Engine {
+	e := &Engine{
+		Book:    ob,
+		ring:    newCommandRing(size),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Do queues c and returns a Future for its result. It waits for room when
+// the ring is full.
+func (e *Engine) Do(c Command) *Future {
+	f := &Future{done: make(chan struct{})}
+	if err := e.enqueue(engineCommand{cmd: c, future: f}); err != nil {
+		f.result.Err = err
+		close(f.done)
+	}
+	return f
+}
+
+// Go queues c and calls done with its result on the engine goroutine,
+// where it must not block or wait on another command. Go waits for room
+// when the ring is full, and returns ErrEngineClosed, without calling
+// done, once the engine is closed.
+func (e *Engine) Go(c Command, done func(EngineResult)) error {
+	return e.enqueue(engineCommand{cmd: c, done: done})
+}
+
+func (e *Engine) enqueue(c engineCommand) error {
+	atomic.AddInt64(&e.producers, 1)
+	defer atomic.AddInt64(&e.producers, -1)
+	for {
+		if atomic.LoadUint32(&e.closed) != 0 {
+			return ErrEngineClosed
+		}
+		if e.ring.push(c) {
+			break
+		}
+		runtime.Gosched()
+	}
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops accepting commands, waits for those already queued to run
+// and stops the engine goroutine.
+func (e *Engine) Close() {
+	if atomic.CompareAndSwapUint32(&e.closed, 0, 1) {
+		close(e.stop)
+	}
+	<-e.stopped
+}
+
+func (e *Engine) run() {
+	defer close(e.stopped)
+	for {
+		if c, ok := e.ring.pop(); ok {
+			e.execute(c)
+			continue
+		}
+		select {
+		case <-e.wake:
+		case <-e.stop:
+			// Producers that saw the engine open may still be queueing.
+			for atomic.LoadInt64(&e.producers) > 0 {
+				runtime.Gosched()
+			}
+			for c, ok := e.ring.pop(); ok; c, ok = e.ring.pop() {
+				e.execute(c)
+			}
+			return
+		}
+	}
+}
+
+func (e *Engine) execute(c engineCommand) {
+	r := c.cmd.Apply(e.Book)
+	if c.future != nil {
+		c.future.result = r
+		close(c.future.done)
+	}
+	if c.done != nil {
+		c.done(r)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestEngine(t *testing.T) {
+	e := NewEngine(NewOrderBook(), 8)
+	defer e.Close()
+
+	e.Do(Command{Op: OpInsert, Side: Sell, Order: NewOrder(101, 2, "a1")})
+	e.Do(Command{Op: OpInsert, Side: Buy, Order: NewOrder(99, 2, "b1")})
+	r := e.Do(Command{Op: OpInsert, Side: Buy, Order: NewOrder(101, 1, "b2")}).Wait()
+	if r.Err != nil || r.Report.Filled != 1 || r.Report.Trades[0].MakerId != "a1" {
+		t.Errorf("Expected b2 to fill 1 against a1, got %+v", r)
+	}
+	if r := e.Do(Command{Op: OpAmend, Side: Buy, Order: Order{OrderId: "b1", Quantity: 5}}).Wait(); !r.Found || r.Err != nil {
+		t.Errorf("Expected b1 to be amended, got %+v", r)
+	}
+	if r := e.Do(Command{Op: OpAmend, Side: Buy, Order: Order{Quantity: 5}}).Wait(); r.Err == nil {
+		t.Errorf("Expected an amend without an id to fail")
+	}
+	if r := e.Do(Command{Op: OpCancel, Order: Order{OrderId: "nope"}}).Wait(); r.Found {
+		t.Errorf("Expected an unknown order not to be found")
+	}
+	if r := e.Do(Command{Op: CommandOp(9)}).Wait(); r.Err == nil {
+		t.Errorf("Expected an unknown command to fail")
+	}
+
+	done := make(chan EngineResult, 1)
+	if err := e.Go(Command{Op: OpCancel, Side: Sell, Order: Order{OrderId: "a1"}}, func(r EngineResult) { done <- r }); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-done; !r.Found {
+		t.Errorf("Expected the callback to report a1 cancelled, got %+v", r)
+	}
+	if price, size, ok := e.Book.BestBid(); !ok || price != 99 || size != 5 {
+		t.Errorf("Expected 5 bid at 99, got %v %v %v", price, size, ok)
+	}
+}
+
+func TestEngineConcurrent(t *testing.T) {
+	e := NewEngine(NewOrderBook(), 4)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var filled float64
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				e.Do(Command{Op: OpInsert, Side: Sell, Order: NewOrder(100, 1, "a"+id)})
+				e.Go(Command{Op: OpInsert, Side: Buy, Order: NewOrder(100, 1, "b"+id)}, func(r EngineResult) {
+					lock.Lock()
+					filled += r.Report.Filled
+					lock.Unlock()
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+	e.Close()
+	if filled != 800 || e.Book.BidBook.Len() != 0 || e.Book.AskBook.Len() != 0 {
+		t.Errorf("Expected every order to match, got %v filled, %d bids, %d asks",
+			filled, e.Book.BidBook.Len(), e.Book.AskBook.Len())
+	}
+	if r := e.Do(Command{Op: OpCancel, Order: Order{OrderId: "x"}}).Wait(); r.Err != ErrEngineClosed {
+		t.Errorf("Expected ErrEngineClosed after Close, got %v", r.Err)
+	}
+	if err := e.Go(Command{Op: OpCancel}, nil); err != ErrEngineClosed {
+		t.Errorf("Expected ErrEngineClosed after Close, got %v", err)
+	}
+}
+
+func TestCommandRing(t *testing.T) {
+	r := newCommandRing(3)
+	for i := 0; i < 4; i++ {
+		if !r.push(engineCommand{cmd: Command{Order: Order{OrderId: strconv.Itoa(i)}}}) {
+			t.Fatalf("Expected room for command %d", i)
+		}
+	}
+	if r.push(engineCommand{}) {
+		t.Errorf("Expected a ring of 4 to be full")
+	}
+	for i := 0; i < 4; i++ {
+		c, ok := r.pop()
+		if !ok || c.cmd.Order.OrderId != strconv.Itoa(i) {
+			t.Errorf("Expected command %d, got %+v %v", i, c, ok)
+		}
+	}
+	if _, ok := r.pop(); ok {
+		t.Errorf("Expected the ring to be empty")
+	}
+}
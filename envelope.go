@@ -0,0 +1,159 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// WireVersion is the envelope version Seal writes.
+//
+// Additions that older readers can ignore, such as a new optional field,
+// keep the version, since unknown JSON fields are skipped on decode. A
+// change that would make older readers misread a document bumps it, with
+// a migration from the previous version: Open upgrades anything older
+// before decoding and refuses anything newer with ErrUnsupportedVersion,
+// so old state always loads and new state never loads wrongly.
+const WireVersion = 1
+
+var (
+	ErrUnsupportedVersion = errors.New("orderbook: wire version is newer than this library")
+	ErrUnknownKind        = errors.New("orderbook: value has no wire kind")
+	ErrKindMismatch       = errors.New("orderbook: envelope holds a different kind")
+)
+
+// Kind names what an envelope carries.
+type Kind string
+
+const (
+	KindSnapshot      Kind = "snapshot"
+	KindDepthSnapshot Kind = "depthSnapshot"
+	KindDiff          Kind = "diff"
+	KindQuote         Kind = "quote"
+	KindTrade         Kind = "trade"
+	KindEntry         Kind = "entry"
+	KindCommand       Kind = "command"
+)
+
+// Envelope wraps a serialized snapshot, event or entry with its kind and
+// the wire version it was written at.
+type Envelope struct {
+	Version int             `json:"v"`
+	Kind    Kind            `json:"kind"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Migration rewrites the data of one kind from the version it is keyed
+// under to the next.
+type Migration func(kind Kind, data json.RawMessage) (json.RawMessage, error)
+
+// migrations[v] upgrades version v to v+1. Version 0 is the bare JSON
+// written before envelopes, which is already the version 1 data.
+var migrations = []Migration{
+	func(_ Kind, data json.RawMessage) (json.RawMessage, error) { return data, nil },
+}
+
+func kindOf(v interface{}) (Kind, bool) {
+	switch v.(type) {
+	case BookSnapshot, *BookSnapshot:
+		return KindSnapshot, true
+	case DepthSnapshot, *DepthSnapshot:
+		return KindDepthSnapshot, true
+	case DepthDiff, *DepthDiff:
+		return KindDiff, true
+	case Quote, *Quote:
+		return KindQuote, true
+	case TradeEvent, *TradeEvent:
+		return KindTrade, true
+	case Entry, *Entry:
+		return KindEntry, true
+	case Command, *Command:
+		return KindCommand, true
+	}
+	return "", false
+}
+
+// Seal serializes v in an envelope at WireVersion.
+func Seal(v interface{}) ([]byte, error) {
+	kind, ok := kindOf(v)
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{WireVersion, kind, data})
+}
+
+// Open decodes an envelope written by Seal into v, a pointer to the kind
+// sealed, migrating it from an older version first. Bare JSON from before
+// envelopes is read as version 0.
+func Open(data []byte, v interface{}) error {
+	kind, ok := kindOf(v)
+	if !ok {
+		return ErrUnknownKind
+	}
+	env, err := openEnvelope(data, kind)
+	if err != nil {
+		return err
+	}
+	if env.Kind != kind {
+		return ErrKindMismatch
+	}
+	return json.Unmarshal(env.Data, v)
+}
+
+// Migrate rewrites a document of the given kind, enveloped or bare, as an
+// envelope at WireVersion, for upgrading persisted state in place.
+func Migrate(data []byte, kind Kind) ([]byte, error) {
+	env, err := openEnvelope(data, kind)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// openEnvelope parses data and migrates it to WireVersion. Bare documents
+// take the given kind.
+func openEnvelope(data []byte, kind Kind) (Envelope, error) {
+	var raw struct {
+		Version *int            `json:"v"`
+		Kind    Kind            `json:"kind"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Envelope{}, err
+	}
+	env := Envelope{Kind: kind, Data: data}
+	if raw.Version != nil {
+		env = Envelope{*raw.Version, raw.Kind, raw.Data}
+	}
+	if env.Version > WireVersion {
+		return Envelope{}, ErrUnsupportedVersion
+	}
+	if env.Version < 0 {
+		return Envelope{}, fmt.Errorf("orderbook: invalid wire version %d", env.Version)
+	}
+	for env.Version < WireVersion {
+		migrated, err := migrations[env.Version](env.Kind, env.Data)
+		if err != nil {
+			return Envelope{}, fmt.Errorf("orderbook: migrating %s from version %d: %v", env.Kind, env.Version, err)
+		}
+		env.Version, env.Data = env.Version+1, migrated
+	}
+	return env, nil
+}
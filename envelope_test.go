@@ -0,0 +1,68 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	trade := TradeEvent{Price: 100, Quantity: 2, Sequence: 7, MakerId: "m", TakerId: "t"}
+	data, err := Seal(&trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Version != WireVersion || env.Kind != KindTrade {
+		t.Fatalf("Expected a version %d trade envelope, got %+v %v", WireVersion, env, err)
+	}
+	var got TradeEvent
+	if err := Open(data, &got); err != nil || got != trade {
+		t.Errorf("Expected %+v to round trip, got %+v %v", trade, got, err)
+	}
+
+	var q Quote
+	if err := Open(data, &q); err != ErrKindMismatch {
+		t.Errorf("Expected ErrKindMismatch opening a trade as a quote, got %v", err)
+	}
+	if _, err := Seal(42); err != ErrUnknownKind {
+		t.Errorf("Expected ErrUnknownKind, got %v", err)
+	}
+	future := []byte(`{"v":99,"kind":"trade","data":{}}`)
+	if err := Open(future, &got); err != ErrUnsupportedVersion {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestEnvelopeLegacy(t *testing.T) {
+	bare, _ := json.Marshal(Entry{Side: Sell, Key: "a", Order: NewOrder(101, 1, "a"), Weight: 1})
+	var e Entry
+	if err := Open(bare, &e); err != nil || e.Key != "a" || e.Order.Price != 101 {
+		t.Errorf("Expected a bare entry to open as version 0, got %+v %v", e, err)
+	}
+	migrated, err := Migrate(bare, KindEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	json.Unmarshal(migrated, &env)
+	if env.Version != WireVersion || env.Kind != KindEntry {
+		t.Errorf("Expected the entry migrated to version %d, got %+v", WireVersion, env)
+	}
+	var again Entry
+	if err := Open(migrated, &again); err != nil || again != e {
+		t.Errorf("Expected the migrated entry to open unchanged, got %+v %v", again, err)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// EventSink receives every event the book publishes, already stamped with
+// its sequence number. Sinks are called synchronously and in sequence
+// order, outside of the side book locks.
+type EventSink interface {
+	Quote(*Quote)
+	Trade(*TradeEvent)
+	Diff(*DepthDiff)
+}
+
+func (ob *OrderBook) AddSink(s EventSink) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	if ob.conflation > 0 {
+		s = Conflate(s, ob.conflation)
+	}
+	ob.sinks = append(ob.sinks, s)
+}
+
+// toSinks calls fn with each sink, marking the book as delivering
+// meanwhile so that a sink calling Snapshot does not wait for the event
+// lock. The caller holds eventLock.
+func (ob *OrderBook) toSinks(fn func(EventSink)) {
+	if len(ob.sinks) == 0 {
+		return
+	}
+	ob.delivering.Store(true)
+	defer ob.delivering.Store(false)
+	for _, s := range ob.sinks {
+		fn(s)
+	}
+}
+
+// quoted holds a copy of the order last published at the top of a side.
+type quoted struct {
+	order Order
+	ok    bool
+}
+
+func (q *quoted) same(o *Order) bool {
+	if o == nil {
+		return !q.ok
+	}
+	return q.ok && q.order == *o
+}
+
+func (q *quoted) set(o *Order) {
+	if q.ok = o != nil; q.ok {
+		q.order = *o
+	}
+}
+
+func copyOrder(o *Order) *Order {
+	if o == nil {
+		return nil
+	}
+	c := *o
+	return &c
+}
+
+// changed is the side books' change hook.
+func (ob *OrderBook) changed(side Side) {
+	ob.publishPending(side == Buy, side == Sell, false)
+}
+
+// publishPending takes the changes pending on the chosen sides and
+// publishes them, as a diff per change when each is set. Changes are
+// taken and stamped under the event lock, so they go out in the order
+// they were applied whichever goroutine applied them, each carrying the
+// level as it stood then.
+func (ob *OrderBook) publishPending(bid, ask, each bool) {
+	ob.eventLock.Lock()
+	bids, asks := ob.takePending(bid, ask)
+	ob.publishChanges(bids, asks, each)
+	ob.eventLock.Unlock()
+	ob.settleChanges(bids, asks)
+}
+
+// takePending takes the chosen sides' pending changes. The caller holds
+// eventLock.
+func (ob *OrderBook) takePending(bid, ask bool) (bids, asks []change) {
+	if bid {
+		bids = ob.BidBook.takePending()
+	}
+	if ask {
+		asks = ob.AskBook.takePending()
+	}
+	return bids, asks
+}
+
+// publishChanges publishes changes taken from both sides. The caller
+// holds eventLock.
+func (ob *OrderBook) publishChanges(bids, asks []change, each bool) {
+	if len(bids)+len(asks) == 0 {
+		return
+	}
+	ob.statuses.track(bids, asks)
+	ob.ids.track(bids, asks)
+	ob.refreshView()
+	if !each {
+		ob.bookChanged(bids, asks)
+		return
+	}
+	for _, c := range bids {
+		ob.bookChanged([]change{c}, nil)
+	}
+	for _, c := range asks {
+		ob.bookChanged(nil, []change{c})
+	}
+}
+
+// settleChanges hands the buffers of published changes back to their
+// sides and settles the book after them.
+func (ob *OrderBook) settleChanges(bids, asks []change) {
+	ob.BidBook.recycle(bids)
+	ob.AskBook.recycle(asks)
+	if len(bids)+len(asks) > 0 {
+		ob.settle()
+	}
+}
+
+// settle checks the alerts and reprices the pegged orders against the
+// book. During a match it runs once the match is over, as alert callbacks
+// may submit orders in turn.
+func (ob *OrderBook) settle() {
+	if ob.holdSettle() {
+		return
+	}
+	ob.checkAlerts()
+	ob.repeg()
+}
+
+// bookChanged logs and publishes one diff of changes and the quote and
+// uncross that follow. The caller holds eventLock.
+func (ob *OrderBook) bookChanged(bids, asks []change) {
+	if ob.logger != nil {
+		for side, changes := range [][]change{bids, asks} {
+			for _, c := range changes {
+				ob.logger.Debug("orderbook: "+c.op.String(), "side", Side(side).String(), "key", c.node.Key,
+					"price", c.price, "quantity", c.quantity, "weight", c.node.Weight)
+			}
+		}
+	}
+	ob.publishDiff(bids, asks)
+	ob.publishQuote()
+	ob.publishIndicative()
+}
+
+func touchesLevels(changes []change) bool {
+	for _, c := range changes {
+		if c.level {
+			return true
+		}
+	}
+	return false
+}
+
+// diffLevels returns the final aggregate of every level touched by
+// changes.
+func diffLevels(changes []change) []Level {
+	var lvls []Level
+	seen := make(map[float64]int)
+	for _, c := range changes {
+		if !c.level {
+			continue
+		}
+		if i, ok := seen[c.price]; ok {
+			lvls[i].Quantity = c.levelQuantity
+			continue
+		}
+		seen[c.price] = len(lvls)
+		lvls = append(lvls, Level{c.price, c.levelQuantity})
+	}
+	return lvls
+}
+
+// publishDiff publishes a single diff of the levels touched on both sides.
+func (ob *OrderBook) publishDiff(bids, asks []change) {
+	if len(ob.sinks) == 0 && len(ob.streams.depths) == 0 && len(ob.streams.watches) == 0 {
+		if touchesLevels(bids) || touchesLevels(asks) {
+			ob.nextSequence()
+		}
+		return
+	}
+	d := &DepthDiff{Bids: diffLevels(bids), Asks: diffLevels(asks)}
+	if len(d.Bids)+len(d.Asks) == 0 {
+		return
+	}
+	d.Sequence = ob.nextSequence()
+	ob.toSinks(func(s EventSink) { s.Diff(d) })
+	ob.streams.diff(ob, d)
+}
+
+// publishQuote publishes a Quote whenever the top of either side differs
+// from the last one published.
+func (ob *OrderBook) publishQuote() {
+	askTop, askOk := ob.AskBook.PeekOrder()
+	bidTop, bidOk := ob.BidBook.PeekOrder()
+	var ask, bid *Order
+	if askOk {
+		ask = &askTop
+	}
+	if bidOk {
+		bid = &bidTop
+	}
+	if ob.lastAsk.same(ask) && ob.lastBid.same(bid) {
+		return
+	}
+	ob.lastAsk.set(ask)
+	ob.lastBid.set(bid)
+	ob.recordBBO(ask, bid)
+	seq := ob.nextSequence()
+	if len(ob.sinks) == 0 && len(ob.streams.quotes) == 0 {
+		// nothing can observe the quote, so skip building it
+		return
+	}
+	q := &Quote{
+		Ask:      copyOrder(ask),
+		Bid:      copyOrder(bid),
+		Sequence: seq,
+	}
+	ob.toSinks(func(s EventSink) { s.Quote(q) })
+	ob.streams.quote(q)
+}
+
+type bookOp int
+
+const (
+	opPush bookOp = iota
+	opReplace
+	opRemove
+	opPop
+	opFix
+	opReduce
+)
+
+func (op bookOp) String() string {
+	return [...]string{"push", "replace", "remove", "pop", "fix", "reduce"}[op]
+}
+
+// change records a single mutation of a side book, with the order's
+// quantity and the aggregate at the affected level captured at the time it
+// was applied.
+type change struct {
+	op            bookOp
+	node          *Node
+	price         float64
+	quantity      float64
+	levelQuantity float64
+	level         bool
+}
+
+func newChange(op bookOp, n *Node, price, levelQuantity float64) change {
+	c := change{op: op, node: n, price: price, levelQuantity: levelQuantity}
+	if o := n.Peek(); o != nil {
+		c.quantity, c.level = o.Quantity, true
+	}
+	return c
+}
+
+// publishTrade stamps e and publishes it to the sinks and trade streams.
+// The caller holds eventLock.
+func (ob *OrderBook) publishTrade(e *TradeEvent) {
+	e.Sequence = ob.nextSequence()
+	ob.tradeIds++
+	e.TradeId = ob.tradeIds
+	ob.lastTrade, ob.traded = e.Price, true
+	if ob.logger != nil {
+		ob.logger.Debug("orderbook: trade", "side", e.Side.String(), "maker", e.MakerId, "taker", e.TakerId,
+			"price", e.Price, "quantity", e.Quantity)
+	}
+	if ob.tape != nil {
+		ob.tape.add(*e)
+	}
+	w := ob.trades.bucket(e.Time)
+	w.Trades++
+	w.Volume += e.Quantity
+	ob.toSinks(func(s EventSink) { s.Trade(e) })
+	ob.streams.trade(e)
+}
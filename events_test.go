@@ -0,0 +1,133 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type recordingSink struct {
+	quotes []*Quote
+	trades []*TradeEvent
+	diffs  []*DepthDiff
+}
+
+func (s *recordingSink) Quote(q *Quote)      { s.quotes = append(s.quotes, q) }
+func (s *recordingSink) Trade(e *TradeEvent) { s.trades = append(s.trades, e) }
+func (s *recordingSink) Diff(d *DepthDiff)   { s.diffs = append(s.diffs, d) }
+
+func TestQuoteEvents(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+
+	orders := []Order{
+		NewOrder(100, 1, "a"),
+		NewOrder(101, 1, "b"), // behind the best ask, no quote
+		NewOrder(99, 1, "c"),
+	}
+	for i := range orders {
+		node := NewNode(orders[i].OrderId, &orders[i], 1)
+		ob.AskBook.Push(&node)
+	}
+	ob.AskBook.Remove("b")
+	ob.AskBook.Pop()
+
+	expected := []float64{100, 99, 100}
+	if len(sink.quotes) != len(expected) {
+		t.Fatalf("Expected %d quotes, got %d", len(expected), len(sink.quotes))
+	}
+	var last uint64
+	for i, q := range sink.quotes {
+		if q.Ask.Price != expected[i] {
+			t.Errorf("Expected quote %d at %f, got %f", i, expected[i], q.Ask.Price)
+		}
+		if q.Sequence <= last {
+			t.Errorf("Expected quote %d sequence above %d, got %d", i, last, q.Sequence)
+		}
+		last = q.Sequence
+	}
+	sink.quotes[2].Ask.Price = 1
+	if ob.AskBook.Peek().Price != 100 {
+		t.Errorf("Expected published quote to be a copy of the live order")
+	}
+}
+
+func TestDiffOrderConcurrent(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := strconv.Itoa(g) + "-" + strconv.Itoa(i)
+				ob.PushOrder(Sell, NewOrder(float64(100+i%3), float64(1+g), id))
+				runtime.Gosched()
+				if i%2 == 0 {
+					ob.AskBook.Remove(id)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	levels := make(map[float64]float64)
+	var last uint64
+	for _, d := range sink.diffs {
+		if d.Sequence <= last {
+			t.Fatalf("Expected diffs in sequence order, got %d after %d", d.Sequence, last)
+		}
+		last = d.Sequence
+		for _, l := range d.Asks {
+			levels[l.Price] = l.Quantity
+		}
+	}
+	_, asks := ob.Depth(0)
+	for _, l := range asks {
+		if levels[l.Price] != l.Quantity {
+			t.Errorf("Expected the diffs to leave %v at %v, got %v", l.Quantity, l.Price, levels[l.Price])
+		}
+	}
+}
+
+func TestSnapshotPublishesPending(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	ob.PushOrder(Buy, NewOrder(99, 1, "b1"))
+
+	// a change applied but not yet flushed, as another goroutine might
+	// leave it between releasing the side lock and publishing
+	o := NewOrder(98, 2, "b2")
+	n := NewNode("b2", &o, 1)
+	ob.BidBook.lock.Lock()
+	ob.BidBook.push(&n)
+	ob.BidBook.lock.Unlock()
+
+	s := ob.Snapshot()
+	if len(s.Bids) != 2 {
+		t.Fatalf("Expected both bids in the snapshot, got %+v", s.Bids)
+	}
+	d := sink.diffs[len(sink.diffs)-1]
+	if len(d.Bids) != 1 || d.Bids[0].Price != 98 || d.Sequence > s.Sequence {
+		t.Errorf("Expected the diff for b2 at or before sequence %d, got %+v", s.Sequence, d)
+	}
+}
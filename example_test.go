@@ -0,0 +1,165 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook_test
+
+import (
+	"context"
+	"fmt"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func ExampleOrderBook_Submit() {
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(101, 2, "ask-1"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(102, 1, "ask-2"), orderbook.Sell)
+
+	r := ob.Submit(orderbook.NewOrder(102, 4, "bid-1"), orderbook.Buy)
+	for _, t := range r.Trades {
+		fmt.Printf("%s bought %g at %g from %s\n", t.TakerId, t.Quantity, t.Price, t.MakerId)
+	}
+	fmt.Println(r.Status, "with", r.Remaining, "resting")
+	// Output:
+	// bid-1 bought 2 at 101 from ask-1
+	// bid-1 bought 1 at 102 from ask-2
+	// partially_filled with 1 resting
+}
+
+func ExampleOrderBook_Cancel() {
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(99, 1, "bid-1"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(98, 1, "bid-2"), orderbook.Buy)
+
+	fmt.Println(ob.Cancel("bid-1"), ob.Cancel("bid-1"))
+	price, size, _ := ob.BestBid()
+	fmt.Println(price, size)
+	// Output:
+	// true false
+	// 98 1
+}
+
+func ExampleOrderBook_Trades() {
+	ob := orderbook.NewOrderBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	trades := ob.Trades(ctx)
+
+	ob.Submit(orderbook.NewOrder(100, 1, "ask-1"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(100, 1, "bid-1"), orderbook.Buy)
+	cancel()
+
+	for t := range trades {
+		fmt.Printf("trade %d: %g at %g\n", t.TradeId, t.Quantity, t.Price)
+	}
+	// Output:
+	// trade 1: 1 at 100
+}
+
+func ExampleOrderBook_WatchPrice() {
+	ob := orderbook.NewOrderBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	level := ob.WatchPrice(ctx, orderbook.Sell, 101)
+
+	ob.Submit(orderbook.NewOrder(101, 2, "ask-1"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(102, 5, "ask-2"), orderbook.Sell) // another level
+	ob.Submit(orderbook.NewOrder(101, 2, "bid-1"), orderbook.Buy)
+	cancel()
+
+	for l := range level {
+		fmt.Printf("%g at %g\n", l.Quantity, l.Price)
+	}
+	// Output:
+	// 2 at 101
+	// 0 at 101
+}
+
+func ExampleOrderBook_EncodeSnapshot() {
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(99, 1, "bid-1"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(101, 3, "ask-1"), orderbook.Sell)
+	data, err := ob.EncodeSnapshot()
+	if err != nil {
+		panic(err)
+	}
+
+	restored := orderbook.NewOrderBook()
+	if err := restored.RestoreSnapshot(data); err != nil {
+		panic(err)
+	}
+	bids, asks := restored.Depth(0)
+	fmt.Println(bids, asks)
+	// Output:
+	// [{99 1}] [{101 3}]
+}
+
+func ExampleOrderBook_AdminBust() {
+	ob := orderbook.NewOrderBook(orderbook.WithTradeTape(100))
+	ob.Submit(orderbook.NewOrder(100, 1, "ask-1"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(101, 1, "ask-2"), orderbook.Sell)
+	r := ob.Submit(orderbook.NewOrder(101, 2, "bid-1"), orderbook.Buy)
+
+	if err := ob.AdminBust(r.Trades[1].TradeId); err != nil {
+		panic(err)
+	}
+	for _, t := range ob.Tape(10) {
+		fmt.Printf("trade %d: %g at %g\n", t.TradeId, t.Quantity, t.Price)
+	}
+	fmt.Println(ob.Stats().LastTrade)
+	// Output:
+	// trade 1: 1 at 100
+	// 100
+}
+
+func ExampleCommand_Apply() {
+	primary, replica := orderbook.NewOrderBook(), orderbook.NewOrderBook()
+	log := [][]byte{}
+	for _, c := range []orderbook.Command{
+		{Op: orderbook.OpInsert, Side: orderbook.Sell, Order: orderbook.NewOrder(101, 2, "ask-1")},
+		{Op: orderbook.OpInsert, Side: orderbook.Buy, Order: orderbook.NewOrder(101, 1, "bid-1")},
+		{Op: orderbook.OpAmend, Side: orderbook.Sell, Order: orderbook.Order{OrderId: "ask-1", Price: 102}},
+	} {
+		c.Apply(primary)
+		data, err := c.Encode()
+		if err != nil {
+			panic(err)
+		}
+		log = append(log, data)
+	}
+
+	for _, data := range log {
+		var c orderbook.Command
+		if err := c.Decode(data); err != nil {
+			panic(err)
+		}
+		c.Apply(replica)
+	}
+	diff := orderbook.Diff(primary.Snapshot(), replica.Snapshot())
+	fmt.Println(diff.Empty())
+	price, size, _ := replica.BestAsk()
+	fmt.Println(price, size)
+	// Output:
+	// true
+	// 102 1
+}
+
+func ExampleEngine() {
+	ob := orderbook.NewOrderBook()
+	e := orderbook.NewEngine(ob, 64)
+	defer e.Close()
+
+	e.Do(orderbook.Command{Op: orderbook.OpInsert, Side: orderbook.Sell, Order: orderbook.NewOrder(100, 1, "ask-1")})
+	r := e.Do(orderbook.Command{Op: orderbook.OpInsert, Side: orderbook.Buy, Order: orderbook.NewOrder(100, 1, "bid-1")}).Wait()
+	fmt.Println(r.Report.Status, r.Report.Filled)
+	// Output:
+	// filled 1
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "math"
+
+// PriceRule decides the price a trade executes at.
+type PriceRule int
+
+const (
+	// MakerPrice executes at the resting order's price, giving any
+	// improvement to the taker. It is the default.
+	MakerPrice PriceRule = iota
+	// TakerPrice executes at the incoming order's limit, giving any
+	// improvement to the maker.
+	TakerPrice
+	// MidpointPrice executes halfway between the two, splitting the
+	// improvement as dark pools do.
+	MidpointPrice
+)
+
+func (r PriceRule) String() string {
+	return [...]string{"maker", "taker", "midpoint"}[r]
+}
+
+// Rounding decides which way a MidpointPrice that falls between ticks is
+// rounded onto the instrument's tick.
+type Rounding int
+
+const (
+	// RoundForMaker rounds toward the maker's price.
+	RoundForMaker Rounding = iota
+	// RoundForTaker rounds toward the taker's limit.
+	RoundForTaker
+)
+
+type executionRule struct {
+	rule     PriceRule
+	rounding Rounding
+	// reference, when set, prices every trade at its midpoint, which match
+	// records in mid as it starts.
+	reference *OrderBook
+	mid       float64
+}
+
+// WithExecutionPrice sets the price trades execute at. Market orders, and
+// rules that would trade at a limit no better than the maker's price,
+// execute at the maker's price.
+func WithExecutionPrice(rule PriceRule, rounding Rounding) Option {
+	return func(ob *OrderBook) {
+		ob.execution.rule, ob.execution.rounding = rule, rounding
+	}
+}
+
+// tradePrice returns the price o, incoming on side, trades with maker at.
+func (ob *OrderBook) tradePrice(side Side, o, maker *Order) float64 {
+	if ob.execution.reference != nil {
+		return ob.execution.mid
+	}
+	limit := o.Price
+	improves := limit > maker.Price
+	if side == Sell {
+		improves = limit < maker.Price
+	}
+	if ob.execution.rule == MakerPrice || math.IsInf(limit, 0) || !improves {
+		return maker.Price
+	}
+	if ob.execution.rule == TakerPrice {
+		return limit
+	}
+	mid := (limit + maker.Price) / 2
+	if ob.execution.rounding == RoundForTaker {
+		// The taker's own rounding is the passive one for its side.
+		return ob.instrument.passive(side, mid)
+	}
+	return ob.instrument.passive(side.Opposite(), mid)
+}
+
+// improvements returns how much better than their own prices, per unit,
+// the taker on side and the maker traded at price.
+func improvements(side Side, limit, makerPrice, price float64) (taker, maker float64) {
+	if side == Sell {
+		limit, makerPrice, price = -limit, -makerPrice, -price
+	}
+	if !math.IsInf(limit, 0) {
+		taker = limit - price
+	}
+	return taker, price - makerPrice
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestExecutionPrice(t *testing.T) {
+	tests := []struct {
+		rule     PriceRule
+		rounding Rounding
+		prices   []float64
+		taker    []float64
+		maker    []float64
+	}{
+		{MakerPrice, RoundForMaker, []float64{100, 101}, []float64{2, 1}, []float64{0, 0}},
+		{TakerPrice, RoundForMaker, []float64{102, 102}, []float64{0, 0}, []float64{2, 1}},
+		// The second midpoint, 101.5, is off the tick.
+		{MidpointPrice, RoundForMaker, []float64{101, 102}, []float64{1, 0}, []float64{1, 1}},
+		{MidpointPrice, RoundForTaker, []float64{101, 101}, []float64{1, 1}, []float64{1, 0}},
+	}
+	for _, tt := range tests {
+		ob := NewOrderBook(WithExecutionPrice(tt.rule, tt.rounding), WithInstrument(Instrument{Tick: 1}))
+		ob.Submit(NewOrder(100, 1, "a1"), Sell)
+		ob.Submit(NewOrder(101, 1, "a2"), Sell)
+		r := ob.Submit(NewOrder(102, 2, "b"), Buy)
+		if len(r.Trades) != 2 {
+			t.Fatalf("Expected %s to make 2 trades, got %+v", tt.rule, r.Trades)
+		}
+		for i, trade := range r.Trades {
+			if trade.Price != tt.prices[i] || trade.TakerImprovement != tt.taker[i] || trade.MakerImprovement != tt.maker[i] {
+				t.Errorf("Expected %s trade %d at %v improving taker %v and maker %v, got %+v",
+					tt.rule, i, tt.prices[i], tt.taker[i], tt.maker[i], trade)
+			}
+		}
+	}
+
+	ob := NewOrderBook(WithExecutionPrice(TakerPrice, RoundForMaker))
+	ob.Submit(NewOrder(90, 1, "b1"), Buy)
+	if r := ob.Submit(NewOrder(88, 1, "s"), Sell); r.Trades[0].Price != 88 || r.Trades[0].MakerImprovement != 2 {
+		t.Errorf("Expected a sell to trade at its limit of 88 improving the bid by 2, got %+v", r.Trades[0])
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package execution slices a parent order into child orders submitted to
+// a book over time, on TWAP or VWAP schedules, through an
+// orderbook.Engine.
+package execution
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+var ErrEmptySchedule = errors.New("execution: schedule has no slices")
+
+// Slice is one child order of a schedule, of Quantity, due After the
+// schedule starts.
+type Slice struct {
+	After    time.Duration
+	Quantity float64
+}
+
+// TWAP splits quantity into n equal slices spread evenly over d, the first
+// due at once. It returns nil unless n is positive.
+func TWAP(quantity float64, d time.Duration, n int) []Slice {
+	if n < 1 {
+		return nil
+	}
+	profile := make([]float64, n)
+	for i := range profile {
+		profile[i] = 1
+	}
+	return VWAP(quantity, d, profile)
+}
+
+// VWAP splits quantity over d in proportion to profile, the expected
+// volume in each of len(profile) equal intervals, with a slice due at the
+// start of each interval. It returns nil when profile has no positive
+// volume. Rounding is left in the last slice, so the slices sum to
+// quantity.
+func VWAP(quantity float64, d time.Duration, profile []float64) []Slice {
+	var total float64
+	for _, v := range profile {
+		if v > 0 {
+			total += v
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	slices := make([]Slice, len(profile))
+	step := d / time.Duration(len(profile))
+	var assigned float64
+	for i, v := range profile {
+		slices[i].After = step * time.Duration(i)
+		if v > 0 {
+			slices[i].Quantity = quantity * v / total
+		}
+		assigned += slices[i].Quantity
+	}
+	slices[len(slices)-1].Quantity += quantity - assigned
+	return slices
+}
+
+// Progress is the state of a parent order after a slice.
+type Progress struct {
+	Slices       int     // slices submitted so far
+	Filled       float64 // across every child
+	Remaining    float64
+	AveragePrice float64
+	// ArrivalPrice is the book's midpoint when Run started, and Slippage
+	// how much worse than it per unit the average price is, so a cost is
+	// positive. Both are zero when the book had no midpoint.
+	ArrivalPrice float64
+	Slippage     float64
+}
+
+// Algo works a parent order through an Engine on a schedule.
+//
+// Each child is a copy of Order, with the id Order.OrderId suffixed by
+// the slice number, submitted at Order.Price and cancelled if it does not
+// fill at once, so no child rests. What a slice leaves unfilled is carried
+// into the next; what the last leaves is reported as Remaining.
+type Algo struct {
+	Engine   *orderbook.Engine
+	Order    orderbook.Order
+	Side     orderbook.Side
+	Schedule []Slice
+	// OnProgress, when set, is called after every slice.
+	OnProgress func(Progress)
+	// Sleep waits for d or until ctx is done; it defaults to a timer.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Run works the order until the schedule ends or ctx is done, returning
+// the progress made and, if it stopped early, ctx's error.
+func (a *Algo) Run(ctx context.Context) (Progress, error) {
+	if len(a.Schedule) == 0 {
+		return Progress{}, ErrEmptySchedule
+	}
+	sleep := a.Sleep
+	if sleep == nil {
+		sleep = wait
+	}
+	var p Progress
+	for _, s := range a.Schedule {
+		p.Remaining += s.Quantity
+	}
+	if mid, ok := a.Engine.Book.Midpoint(); ok {
+		p.ArrivalPrice = mid
+	}
+
+	var value, carry float64
+	var last time.Duration
+	for i, s := range a.Schedule {
+		if err := sleep(ctx, s.After-last); err != nil {
+			return p, err
+		}
+		last = s.After
+		qty := s.Quantity + carry
+		if qty <= 0 {
+			continue
+		}
+		child := a.Order
+		child.OrderId, child.Quantity = a.Order.OrderId+"-"+strconv.Itoa(i), qty
+		r := a.Engine.Do(orderbook.Command{Op: orderbook.OpInsert, Side: a.Side, Order: child}).Wait()
+		if r.Report.Resting {
+			a.Engine.Do(orderbook.Command{Op: orderbook.OpCancel, Side: a.Side, Order: child}).Wait()
+		}
+		for _, t := range r.Report.Trades {
+			value += t.Price * t.Quantity
+		}
+		carry = qty - r.Report.Filled
+		p.Slices = i + 1
+		p.Filled += r.Report.Filled
+		p.Remaining -= r.Report.Filled
+		if p.Filled > 0 {
+			p.AveragePrice = value / p.Filled
+			p.Slippage = slippage(a.Side, p.ArrivalPrice, p.AveragePrice)
+		}
+		if a.OnProgress != nil {
+			a.OnProgress(p)
+		}
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func slippage(side orderbook.Side, arrival, average float64) float64 {
+	if arrival == 0 {
+		return 0
+	}
+	if side == orderbook.Buy {
+		return average - arrival
+	}
+	return arrival - average
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package execution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		slices   []Slice
+		expected []Slice
+	}{
+		{"twap", TWAP(6, 3*time.Minute, 3), []Slice{{0, 2}, {time.Minute, 2}, {2 * time.Minute, 2}}},
+		{"vwap", VWAP(10, time.Hour, []float64{1, 0, 3, 1}),
+			[]Slice{{0, 2}, {15 * time.Minute, 0}, {30 * time.Minute, 6}, {45 * time.Minute, 2}}},
+		{"no slices", TWAP(6, time.Minute, 0), nil},
+		{"no volume", VWAP(6, time.Minute, []float64{0, -1}), nil},
+	}
+	for _, tt := range tests {
+		if len(tt.slices) != len(tt.expected) {
+			t.Errorf("Expected %s to be %v, got %v", tt.name, tt.expected, tt.slices)
+			continue
+		}
+		for i := range tt.slices {
+			if tt.slices[i] != tt.expected[i] {
+				t.Errorf("Expected %s to be %v, got %v", tt.name, tt.expected, tt.slices)
+				break
+			}
+		}
+	}
+}
+
+func TestAlgo(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(99, 5, "bid"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(101, 2, "a1"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(102, 2, "a2"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(103, 10, "a3"), orderbook.Sell)
+	e := orderbook.NewEngine(ob, 16)
+	defer e.Close()
+
+	var slept []time.Duration
+	var progress []Progress
+	algo := Algo{
+		Engine:     e,
+		Order:      orderbook.NewOrder(102, 0, "parent"),
+		Side:       orderbook.Buy,
+		Schedule:   TWAP(6, 3*time.Minute, 3),
+		OnProgress: func(p Progress) { progress = append(progress, p) },
+		Sleep: func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		},
+	}
+	p, err := algo.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Progress{Slices: 3, Filled: 4, Remaining: 2, AveragePrice: 101.5, ArrivalPrice: 100, Slippage: 1.5}
+	if p != expected {
+		t.Errorf("Expected %+v, got %+v", expected, p)
+	}
+	if len(progress) != 3 || progress[0].Filled != 2 || progress[0].AveragePrice != 101 {
+		t.Errorf("Expected progress after every slice, got %+v", progress)
+	}
+	if len(slept) != 3 || slept[0] != 0 || slept[1] != time.Minute || slept[2] != time.Minute {
+		t.Errorf("Expected to wait a minute between slices, got %v", slept)
+	}
+	if ob.BidBook.Len() != 1 {
+		t.Errorf("Expected no child to rest, got %d bids", ob.BidBook.Len())
+	}
+	if _, err := (&Algo{Engine: e}).Run(context.Background()); err != ErrEmptySchedule {
+		t.Errorf("Expected ErrEmptySchedule, got %v", err)
+	}
+}
+
+func TestAlgoCarry(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(99, 1, "bid"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(101, 0.5, "a1"), orderbook.Sell)
+	e := orderbook.NewEngine(ob, 16)
+	defer e.Close()
+
+	var filled []float64
+	algo := Algo{
+		Engine:   e,
+		Order:    orderbook.NewOrder(101, 0, "parent"),
+		Side:     orderbook.Buy,
+		Schedule: VWAP(4, 3*time.Minute, []float64{1, 0, 3}),
+		OnProgress: func(p Progress) {
+			filled = append(filled, p.Filled)
+			if p.Slices == 1 {
+				e.Do(orderbook.Command{Op: orderbook.OpInsert, Side: orderbook.Sell, Order: orderbook.NewOrder(101, 10, "a2")}).Wait()
+			}
+		},
+		Sleep: func(context.Context, time.Duration) error { return nil },
+	}
+	if p, err := algo.Run(context.Background()); err != nil || p.Filled != 4 || p.Remaining != 0 {
+		t.Errorf("Expected the unfilled half of the first slice carried forward, got %+v %v", p, err)
+	}
+	if len(filled) != 3 || filled[0] != 0.5 || filled[1] != 1 || filled[2] != 4 {
+		t.Errorf("Expected fills of 0.5, 1 and 4, got %v", filled)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	algo.OnProgress = nil
+	algo.Sleep = func(ctx context.Context, d time.Duration) error {
+		if d > 0 {
+			cancel()
+		}
+		return ctx.Err()
+	}
+	if p, err := algo.Run(ctx); err != context.Canceled || p.Slices != 1 {
+		t.Errorf("Expected the run to stop after the first slice, got %+v %v", p, err)
+	}
+}
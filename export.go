@@ -0,0 +1,153 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Format int
+
+const (
+	CSV Format = iota
+	JSONL
+)
+
+var csvHeader = []string{"side", "key", "order_id", "price", "quantity", "venue", "weight", "time", "account"}
+
+// ExportOrders writes every resting order, bids first, each side in
+// priority order.
+func (ob *OrderBook) ExportOrders(w io.Writer, format Format) error {
+	entries := append(ob.Entries(Buy), ob.Entries(Sell)...)
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, e := range entries {
+			cw.Write([]string{
+				e.Side.String(),
+				e.Key,
+				e.Order.OrderId,
+				strconv.FormatFloat(e.Order.Price, 'f', -1, 64),
+				strconv.FormatFloat(e.Order.Quantity, 'f', -1, 64),
+				e.Order.Venue,
+				strconv.FormatFloat(e.Weight, 'f', -1, 64),
+				e.Time.Format(time.RFC3339Nano),
+				e.Order.Account,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case JSONL:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("orderbook: unknown format %d", format)
+}
+
+// ImportOrders reads orders written by ExportOrders and rests them in the
+// order read. Missing weights default to 1.
+func (ob *OrderBook) ImportOrders(r io.Reader, format Format) error {
+	var entries []Entry
+	var err error
+	switch format {
+	case CSV:
+		entries, err = readCSVEntries(r)
+	case JSONL:
+		entries, err = readJSONLEntries(r)
+	default:
+		err = fmt.Errorf("orderbook: unknown format %d", format)
+	}
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].Weight == 0 {
+			entries[i].Weight = 1
+		}
+		if entries[i].Key == "" {
+			entries[i].Key = entries[i].Order.OrderId
+		}
+	}
+	ob.Restore(entries)
+	return nil
+}
+
+func readJSONLEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("orderbook: line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func readCSVEntries(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	// Files written before the account column was added have one field
+	// fewer.
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for i, rec := range records {
+		if len(rec) < len(csvHeader)-1 || len(rec) > len(csvHeader) {
+			return nil, fmt.Errorf("orderbook: record %d: wrong number of fields", i+1)
+		}
+		if i == 0 && rec[0] == csvHeader[0] {
+			continue
+		}
+		e := Entry{Key: rec[1], Order: Order{OrderId: rec[2], Venue: rec[5]}}
+		if len(rec) == len(csvHeader) {
+			e.Order.Account = rec[8]
+		}
+		if e.Side, err = ParseSide(rec[0]); err == nil {
+			e.Order.Price, err = strconv.ParseFloat(rec[3], 64)
+		}
+		if err == nil {
+			e.Order.Quantity, err = strconv.ParseFloat(rec[4], 64)
+		}
+		if err == nil && rec[6] != "" {
+			e.Weight, err = strconv.ParseFloat(rec[6], 64)
+		}
+		if err == nil && rec[7] != "" {
+			e.Time, err = time.Parse(time.RFC3339Nano, rec[7])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("orderbook: record %d: %v", i+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
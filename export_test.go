@@ -0,0 +1,69 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(100, 1, "a"))
+	ob.PushOrder(Buy, NewOrder(100, 2, "b"))
+	ob.PushOrder(Sell, Order{Price: 90, Quantity: 1.5, OrderId: "c", Venue: "GB", Account: "acct-1"})
+	n, _ := ob.AskBook.Get("c")
+	n.Weight = 1.25
+	ob.AskBook.Fix("c")
+
+	for _, format := range []Format{CSV, JSONL} {
+		var buf bytes.Buffer
+		if err := ob.ExportOrders(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		imported := NewOrderBook()
+		if err := imported.ImportOrders(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		for _, side := range []Side{Buy, Sell} {
+			if !reflect.DeepEqual(ob.Entries(side), imported.Entries(side)) {
+				t.Errorf("Expected format %d to round trip %v:\n%+v\n%+v", format, side, ob.Entries(side), imported.Entries(side))
+			}
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	input := `side,key,order_id,price,quantity,country,weight,time
+buy,,x,10.5,3,US,,2019-06-01T09:30:00Z
+sell,,y,11,1,,,
+`
+	ob := NewOrderBook()
+	if err := ob.ImportOrders(strings.NewReader(input), CSV); err != nil {
+		t.Fatal(err)
+	}
+	bids := ob.Entries(Buy)
+	if len(bids) != 1 || bids[0].Key != "x" || bids[0].Weight != 1 || !bids[0].Time.Equal(time.Date(2019, 6, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected x keyed by order id with weight 1 and its timestamp, got %+v", bids)
+	}
+	if asks := ob.Entries(Sell); len(asks) != 1 || asks[0].Time.IsZero() {
+		t.Errorf("Expected y stamped on entry, got %+v", asks)
+	}
+	if err := ob.ImportOrders(strings.NewReader("buy,,x,abc,1,,,\n"), CSV); err == nil {
+		t.Errorf("Expected a malformed price to fail")
+	}
+}
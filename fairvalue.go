@@ -0,0 +1,128 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "math"
+
+// FairValueModel estimates a book's fair value from its aggregated depth,
+// best first on each side.
+type FairValueModel interface {
+	// Levels is how many levels per side Estimate reads; zero is all.
+	Levels() int
+	Estimate(bids, asks []Level) (float64, bool)
+}
+
+// WithFairValue sets the model FairValue uses. The default is SimpleMid.
+func WithFairValue(m FairValueModel) Option {
+	return func(ob *OrderBook) {
+		ob.fairValue = m
+	}
+}
+
+// FairValue estimates the book's value with its FairValueModel, and false
+// when the model cannot, as when a side it needs is empty.
+func (ob *OrderBook) FairValue() (float64, bool) {
+	m := ob.fairValue
+	if m == nil {
+		m = SimpleMid{}
+	}
+	bids, asks := ob.Depth(m.Levels())
+	return m.Estimate(bids, asks)
+}
+
+// SimpleMid is the mean of the best bid and ask.
+type SimpleMid struct{}
+
+func (SimpleMid) Levels() int { return 1 }
+
+func (SimpleMid) Estimate(bids, asks []Level) (float64, bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return 0, false
+	}
+	return (bids[0].Price + asks[0].Price) / 2, true
+}
+
+// SizeWeightedMid weights the best bid by the size offered and the best
+// ask by the size bid, so the estimate leans toward the side more likely
+// to trade through next.
+type SizeWeightedMid struct{}
+
+func (SizeWeightedMid) Levels() int { return 1 }
+
+func (SizeWeightedMid) Estimate(bids, asks []Level) (float64, bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return 0, false
+	}
+	b, a := bids[0], asks[0]
+	if b.Quantity+a.Quantity <= 0 {
+		return (b.Price + a.Price) / 2, true
+	}
+	return (b.Price*a.Quantity + a.Price*b.Quantity) / (b.Quantity + a.Quantity), true
+}
+
+// DepthWeighted is the mean of each side's volume-weighted price over its
+// best N levels, or all of them when N is zero.
+type DepthWeighted struct {
+	N int
+}
+
+func (d DepthWeighted) Levels() int { return d.N }
+
+func (d DepthWeighted) Estimate(bids, asks []Level) (float64, bool) {
+	bid, bok := weightedPrice(bids, func(Level) float64 { return 1 })
+	ask, aok := weightedPrice(asks, func(Level) float64 { return 1 })
+	if !bok || !aok {
+		return 0, false
+	}
+	return (bid + ask) / 2, true
+}
+
+// DecayWeighted is DepthWeighted with each level's size discounted by
+// exp(-Decay * distance), its distance in price from the midpoint, so
+// quantity far from the touch counts for less.
+type DecayWeighted struct {
+	N     int
+	Decay float64
+}
+
+func (d DecayWeighted) Levels() int { return d.N }
+
+func (d DecayWeighted) Estimate(bids, asks []Level) (float64, bool) {
+	mid, ok := SimpleMid{}.Estimate(bids, asks)
+	if !ok {
+		return 0, false
+	}
+	decay := func(l Level) float64 { return math.Exp(-d.Decay * math.Abs(l.Price-mid)) }
+	bid, bok := weightedPrice(bids, decay)
+	ask, aok := weightedPrice(asks, decay)
+	if !bok || !aok {
+		return 0, false
+	}
+	return (bid + ask) / 2, true
+}
+
+// weightedPrice is the average price of lvls weighted by quantity times
+// weight.
+func weightedPrice(lvls []Level, weight func(Level) float64) (float64, bool) {
+	var value, total float64
+	for _, l := range lvls {
+		w := l.Quantity * weight(l)
+		value += l.Price * w
+		total += w
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return value / total, true
+}
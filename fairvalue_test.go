@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFairValue(t *testing.T) {
+	submit := func(ob *OrderBook) {
+		ob.Submit(NewOrder(99, 1, "b1"), Buy)
+		ob.Submit(NewOrder(98, 3, "b2"), Buy)
+		ob.Submit(NewOrder(101, 3, "a1"), Sell)
+		ob.Submit(NewOrder(102, 1, "a2"), Sell)
+	}
+	tests := []struct {
+		name     string
+		model    FairValueModel
+		expected float64
+	}{
+		{"default", nil, 100},
+		{"size weighted", SizeWeightedMid{}, 99.5},
+		{"top level", DepthWeighted{N: 1}, 100},
+		{"two levels", DepthWeighted{N: 2}, 99.75},
+		{"decay", DecayWeighted{Decay: math.Ln2}, (98.4 + 177.0/1.75) / 2},
+	}
+	for _, tt := range tests {
+		var opts []Option
+		if tt.model != nil {
+			opts = append(opts, WithFairValue(tt.model))
+		}
+		ob := NewOrderBook(opts...)
+		if _, ok := ob.FairValue(); ok {
+			t.Errorf("Expected no %s fair value for an empty book", tt.name)
+		}
+		submit(ob)
+		if v, ok := ob.FairValue(); !ok || math.Abs(v-tt.expected) > 1e-9 {
+			t.Errorf("Expected the %s fair value to be %v, got %v %v", tt.name, tt.expected, v, ok)
+		}
+	}
+}
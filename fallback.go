@@ -0,0 +1,104 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// QuoteSource says where a ReferenceQuote's price came from.
+type QuoteSource int
+
+const (
+	// FromBook is the midpoint of a book quoted on both sides.
+	FromBook QuoteSource = iota
+	// FromLastTrade is the book's last trade price.
+	FromLastTrade
+	// FromClose is the previous close set with SetClose.
+	FromClose
+	// FromOneSide is the best price of the only side quoted.
+	FromOneSide
+)
+
+func (s QuoteSource) String() string {
+	return [...]string{"book", "last trade", "close", "one side"}[s]
+}
+
+// ReferenceQuote is a price for downstream pricing that stays defined when
+// the book is quoted on one side or none. Spread is the book's spread when
+// Source is FromBook, and zero otherwise.
+type ReferenceQuote struct {
+	Price  float64     `json:"price"`
+	Spread float64     `json:"spread"`
+	Source QuoteSource `json:"source"`
+}
+
+type fallbackQuote struct {
+	sources  []QuoteSource
+	close    float64
+	hasClose bool
+}
+
+// WithFallbackQuote sets the sources ReferenceQuote tries, in order, when
+// either side of the book is empty. Without it only FromBook is used.
+func WithFallbackQuote(sources ...QuoteSource) Option {
+	return func(ob *OrderBook) {
+		ob.fallback.sources = sources
+	}
+}
+
+// SetClose records the previous close for FromClose.
+func (ob *OrderBook) SetClose(price float64) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	ob.fallback.close, ob.fallback.hasClose = price, true
+}
+
+// ReferenceQuote returns the book's midpoint when both sides are quoted,
+// and otherwise the first fallback source that has a price. It returns
+// false when none has.
+func (ob *OrderBook) ReferenceQuote() (ReferenceQuote, bool) {
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	ask, hasAsk := topPrice(ob.AskBook.top())
+	bid, hasBid := topPrice(ob.BidBook.top())
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	if hasAsk && hasBid {
+		return ReferenceQuote{(ask + bid) / 2, ask - bid, FromBook}, true
+	}
+	ob.eventLock.Lock()
+	last, traded := ob.lastTrade, ob.traded
+	close, hasClose := ob.fallback.close, ob.fallback.hasClose
+	ob.eventLock.Unlock()
+
+	for _, s := range ob.fallback.sources {
+		switch {
+		case s == FromLastTrade && traded:
+			return ReferenceQuote{Price: last, Source: s}, true
+		case s == FromClose && hasClose:
+			return ReferenceQuote{Price: close, Source: s}, true
+		case s == FromOneSide && hasAsk:
+			return ReferenceQuote{Price: ask, Source: s}, true
+		case s == FromOneSide && hasBid:
+			return ReferenceQuote{Price: bid, Source: s}, true
+		}
+	}
+	return ReferenceQuote{}, false
+}
+
+func topPrice(n *Node) (float64, bool) {
+	if n == nil || n.Peek() == nil {
+		return 0, false
+	}
+	return n.Peek().Price, true
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestReferenceQuote(t *testing.T) {
+	ob := NewOrderBook()
+	if q, ok := ob.ReferenceQuote(); ok {
+		t.Errorf("Expected no quote for an empty book without fallbacks, got %+v", q)
+	}
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	if q, ok := ob.ReferenceQuote(); ok {
+		t.Errorf("Expected no quote for a one-sided book without fallbacks, got %+v", q)
+	}
+
+	tests := []struct {
+		name     string
+		sources  []QuoteSource
+		setup    func(ob *OrderBook)
+		expected ReferenceQuote
+		ok       bool
+	}{
+		{"both sides", nil, func(ob *OrderBook) {
+			ob.Submit(NewOrder(99, 1, "b1"), Buy)
+			ob.Submit(NewOrder(101, 1, "a1"), Sell)
+		}, ReferenceQuote{100, 2, FromBook}, true},
+		{"last trade", []QuoteSource{FromLastTrade, FromOneSide}, func(ob *OrderBook) {
+			ob.Submit(NewOrder(99, 2, "b1"), Buy)
+			ob.Submit(NewOrder(99, 1, "a1"), Sell)
+		}, ReferenceQuote{Price: 99, Source: FromLastTrade}, true},
+		{"no trade yet", []QuoteSource{FromLastTrade, FromOneSide}, func(ob *OrderBook) {
+			ob.Submit(NewOrder(101, 1, "a1"), Sell)
+		}, ReferenceQuote{Price: 101, Source: FromOneSide}, true},
+		{"close", []QuoteSource{FromClose, FromOneSide}, func(ob *OrderBook) {
+			ob.SetClose(98.5)
+			ob.Submit(NewOrder(101, 1, "a1"), Sell)
+		}, ReferenceQuote{Price: 98.5, Source: FromClose}, true},
+		{"nothing", []QuoteSource{FromLastTrade, FromClose, FromOneSide}, func(*OrderBook) {},
+			ReferenceQuote{}, false},
+	}
+	for _, tt := range tests {
+		ob := NewOrderBook(WithFallbackQuote(tt.sources...))
+		tt.setup(ob)
+		if q, ok := ob.ReferenceQuote(); q != tt.expected || ok != tt.ok {
+			t.Errorf("Expected %s to quote %+v %v, got %+v %v", tt.name, tt.expected, tt.ok, q, ok)
+		}
+	}
+}
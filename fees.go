@@ -0,0 +1,71 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+type Liquidity int
+
+const (
+	Maker Liquidity = iota
+	Taker
+)
+
+func (l Liquidity) String() string {
+	return [...]string{"maker", "taker"}[l]
+}
+
+// FeeSchedule prices each side of a trade from the magnitude of its
+// notional, which is never negative even at negative prices. Match stamps
+// the results on the TradeEvent; negative fees are rebates.
+type FeeSchedule interface {
+	Fee(account string, role Liquidity, notional float64) float64
+}
+
+func WithFees(s FeeSchedule) Option {
+	return func(ob *OrderBook) {
+		ob.fees = s
+	}
+}
+
+// Fees charges basis points of the trade notional plus a fixed amount per
+// trade, separately for makers and takers.
+type Fees struct {
+	MakerBps   float64
+	TakerBps   float64
+	MakerFixed float64
+	TakerFixed float64
+}
+
+func (f Fees) Fee(_ string, role Liquidity, notional float64) float64 {
+	if role == Maker {
+		return notional*f.MakerBps/1e4 + f.MakerFixed
+	}
+	return notional*f.TakerBps/1e4 + f.TakerFixed
+}
+
+// TieredFees looks up each account's schedule, falling back to Default
+// for accounts without one.
+type TieredFees struct {
+	Default  FeeSchedule
+	Accounts map[string]FeeSchedule
+}
+
+func (t TieredFees) Fee(account string, role Liquidity, notional float64) float64 {
+	if s, ok := t.Accounts[account]; ok {
+		return s.Fee(account, role, notional)
+	}
+	if t.Default == nil {
+		return 0
+	}
+	return t.Default.Fee(account, role, notional)
+}
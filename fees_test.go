@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestFees(t *testing.T) {
+	schedule := TieredFees{
+		Default: Fees{MakerBps: -1, TakerBps: 5},
+		Accounts: map[string]FeeSchedule{
+			"vip": Fees{TakerBps: 2, TakerFixed: 0.5},
+		},
+	}
+	ob := NewOrderBook(WithFees(schedule))
+	ob.PushOrder(Sell, Order{Price: 100, Quantity: 1, OrderId: "a1", Account: "mm"})
+	ob.PushOrder(Sell, Order{Price: 200, Quantity: 2, OrderId: "a2", Account: "vip"})
+
+	tests := []struct {
+		Name     string
+		Order    Order
+		MakerFee float64
+		TakerFee float64
+	}{
+		{"default", Order{Price: 100, Quantity: 1, OrderId: "t1", Account: "retail"}, -0.01, 0.05},
+		{"vip-taker", Order{Price: 200, Quantity: 1, OrderId: "t2", Account: "vip"}, 0, 0.54},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			report := ob.Submit(test.Order, Buy)
+			if len(report.Trades) != 1 {
+				t.Fatalf("Expected 1 trade, got %d", len(report.Trades))
+			}
+			trade := report.Trades[0]
+			if trade.MakerFee != test.MakerFee || trade.TakerFee != test.TakerFee {
+				t.Errorf("Expected fees %g/%g, got %g/%g", test.MakerFee, test.TakerFee, trade.MakerFee, trade.TakerFee)
+			}
+			if report.Fees != test.TakerFee {
+				t.Errorf("Expected report fees %g, got %g", test.TakerFee, report.Fees)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"time"
+)
+
+// OrderFilter selects resting orders. Zero fields match every order.
+type OrderFilter struct {
+	// Sides limits the search to the given sides; empty searches both.
+	Sides   []Side
+	Account string
+	Venue   string
+	// MinPrice and MaxPrice bound the effective price, inclusive, as
+	// RangeQuery does; a zero bound is open.
+	MinPrice float64
+	MaxPrice float64
+	// OlderThan and NewerThan bound how long an order has rested, by the
+	// book's clock.
+	OlderThan time.Duration
+	NewerThan time.Duration
+}
+
+// FindOrders returns copies of the resting orders f selects, bids then
+// asks, each side in priority order. A price bound narrows the search to
+// the levels in range through the level index rather than every order.
+func (ob *OrderBook) FindOrders(f OrderFilter) []Entry {
+	sides := f.Sides
+	if len(sides) == 0 {
+		sides = []Side{Buy, Sell}
+	}
+	var found []Entry
+	for _, side := range []Side{Buy, Sell} {
+		if !hasSide(sides, side) {
+			continue
+		}
+		b := ob.Side(side)
+		b.lock.Lock()
+		now := b.clock()
+		var nodes []*Node
+		if f.MinPrice != 0 || f.MaxPrice != 0 {
+			min, max := math.Inf(-1), math.Inf(1)
+			if f.MinPrice != 0 {
+				min = f.MinPrice
+			}
+			if f.MaxPrice != 0 {
+				max = f.MaxPrice
+			}
+			nodes = b.inRange(min, max)
+		} else {
+			nodes = b.sortedNodes()
+		}
+		for _, n := range nodes {
+			if o := n.Peek(); o != nil && f.matches(o, now.Sub(n.Time)) {
+				found = append(found, Entry{side, n.Key, *o, n.Weight, n.Time})
+			}
+		}
+		b.lock.Unlock()
+	}
+	return found
+}
+
+func (f *OrderFilter) matches(o *Order, age time.Duration) bool {
+	return (f.Account == "" || o.Account == f.Account) &&
+		(f.Venue == "" || o.Venue == f.Venue) &&
+		(f.OlderThan == 0 || age > f.OlderThan) &&
+		(f.NewerThan == 0 || age < f.NewerThan)
+}
+
+func hasSide(sides []Side, side Side) bool {
+	for _, s := range sides {
+		if s == side {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindOrders(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	ob := NewOrderBook(WithClock(func() time.Time { return now }))
+	submit := func(price, qty float64, id, account, venue string, side Side) {
+		o := NewOrder(price, qty, id)
+		o.Account, o.Venue = account, venue
+		ob.Submit(o, side)
+		now = now.Add(time.Minute)
+	}
+	submit(99, 1, "b1", "alice", "US", Buy)
+	submit(98, 1, "b2", "bob", "GB", Buy)
+	submit(100, 1, "b3", "alice", "GB", Buy)
+	submit(101, 1, "a1", "alice", "US", Sell)
+	submit(103, 1, "a2", "bob", "US", Sell)
+	submit(102, 1, "a3", "alice", "US", Sell)
+
+	tests := []struct {
+		name     string
+		filter   OrderFilter
+		expected []string
+	}{
+		{"all", OrderFilter{}, []string{"b3", "b1", "b2", "a1", "a3", "a2"}},
+		{"account", OrderFilter{Account: "alice"}, []string{"b3", "b1", "a1", "a3"}},
+		{"side", OrderFilter{Sides: []Side{Sell}, Account: "bob"}, []string{"a2"}},
+		{"venue", OrderFilter{Venue: "GB"}, []string{"b3", "b2"}},
+		{"above", OrderFilter{Account: "alice", MinPrice: 100}, []string{"b3", "a1", "a3"}},
+		{"range", OrderFilter{MinPrice: 99, MaxPrice: 101}, []string{"b3", "b1", "a1"}},
+		{"older", OrderFilter{OlderThan: 4 * time.Minute}, []string{"b1", "b2"}},
+		{"newer", OrderFilter{NewerThan: 2 * time.Minute, Sides: []Side{Sell}}, []string{"a3"}},
+	}
+	for _, tt := range tests {
+		found := ob.FindOrders(tt.filter)
+		var ids []string
+		for _, e := range found {
+			ids = append(ids, e.Order.OrderId)
+		}
+		if len(ids) != len(tt.expected) {
+			t.Errorf("Expected %s to find %v, got %v", tt.name, tt.expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.expected[i] {
+				t.Errorf("Expected %s to find %v, got %v", tt.name, tt.expected, ids)
+				break
+			}
+		}
+	}
+
+	for _, e := range ob.FindOrders(OrderFilter{Account: "alice", MinPrice: 101, Sides: []Side{Sell}}) {
+		ob.Cancel(e.Order.OrderId)
+	}
+	if found := ob.FindOrders(OrderFilter{Sides: []Side{Sell}}); len(found) != 1 || found[0].Order.OrderId != "a2" {
+		t.Errorf("Expected only a2 left on the sell side, got %+v", found)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"sync"
+)
+
+// Follower maintains a read replica of a primary book from the events the
+// primary publishes. It is an EventSink, GroupSink and EvictionSink, so
+// it can be added to the primary directly, fed events decoded from a
+// network transport, or driven from a channel with Run. Every event counts
+// towards the primary's sequence, so a conflated stream will not do.
+//
+// The replica holds the primary's aggregated depth, one order per level,
+// in Book. Until the first snapshot, and after any gap in the stream, the
+// follower calls Snapshot on the next event and replays what it buffered
+// meanwhile; readers of Book may briefly see stale depth but never a
+// sequence applied out of order.
+type Follower struct {
+	Book     *OrderBook
+	Snapshot func() (DepthSnapshot, error)
+
+	lock sync.Mutex
+	err  error
+}
+
+// NewFollower returns a Follower whose replica is built with opts.
+func NewFollower(snapshot func() (DepthSnapshot, error), opts ...Option) *Follower {
+	return &Follower{Book: NewOrderBook(opts...), Snapshot: snapshot}
+}
+
+// Sync fetches a snapshot and applies it to the replica.
+func (f *Follower) Sync() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.sync()
+}
+
+func (f *Follower) sync() error {
+	s, err := f.Snapshot()
+	if err == nil {
+		err = f.Book.ApplySnapshot(s)
+	}
+	f.err = err
+	return err
+}
+
+// Err returns the error from the last attempt to synchronise, if it
+// failed.
+func (f *Follower) Err() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.err
+}
+
+// observe applies d, which is empty for events other than diffs, and
+// resynchronises when the replica is not in sync afterwards.
+func (f *Follower) observe(d DepthDiff) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.Book.ApplyDiff(d)
+	if !f.Book.Synced() {
+		f.sync()
+	}
+}
+
+func (f *Follower) Quote(q *Quote) {
+	f.observe(DepthDiff{Sequence: q.Sequence})
+}
+
+func (f *Follower) Trade(e *TradeEvent) {
+	f.observe(DepthDiff{Sequence: e.Sequence})
+}
+
+func (f *Follower) Diff(d *DepthDiff) {
+	f.observe(*d)
+}
+
+func (f *Follower) Group(e *GroupEvent) {
+	f.observe(DepthDiff{Sequence: e.Sequence})
+}
+
+func (f *Follower) Evicted(e *EvictionEvent) {
+	f.observe(DepthDiff{Sequence: e.Sequence})
+}
+
+// Run applies events received on ch until it is closed or ctx is done.
+// It accepts the pointer types the primary hands its sinks and ignores
+// anything else.
+func (f *Follower) Run(ctx context.Context, ch <-chan interface{}) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			switch e := e.(type) {
+			case *Quote:
+				f.Quote(e)
+			case *TradeEvent:
+				f.Trade(e)
+			case *DepthDiff:
+				f.Diff(e)
+			case *GroupEvent:
+				f.Group(e)
+			case *EvictionEvent:
+				f.Evicted(e)
+			}
+		}
+	}
+}
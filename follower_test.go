@@ -0,0 +1,82 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// lossySink forwards events to a channel, dropping the ones at the
+// sequences in drop.
+type lossySink struct {
+	ch   chan interface{}
+	drop map[uint64]bool
+}
+
+func (s *lossySink) send(seq uint64, e interface{}) {
+	if !s.drop[seq] {
+		s.ch <- e
+	}
+}
+
+func (s *lossySink) Quote(q *Quote)      { s.send(q.Sequence, q) }
+func (s *lossySink) Trade(e *TradeEvent) { s.send(e.Sequence, e) }
+func (s *lossySink) Diff(d *DepthDiff)   { s.send(d.Sequence, d) }
+
+func TestFollower(t *testing.T) {
+	primary := NewOrderBook()
+	primary.Submit(NewOrder(99, 2, "b1"), Buy)
+	snapshots := 0
+	snapshot := func() (DepthSnapshot, error) {
+		snapshots++
+		return primary.Snapshot().DepthSnapshot, nil
+	}
+
+	direct := NewFollower(snapshot)
+	primary.AddSink(direct)
+	sink := &lossySink{ch: make(chan interface{}, 64), drop: map[uint64]bool{}}
+	primary.AddSink(sink)
+	remote := NewFollower(snapshot)
+
+	primary.Submit(NewOrder(101, 3, "a1"), Sell)
+	primary.Submit(NewOrder(98, 1, "b2"), Buy)
+	close(sink.ch)
+	if err := remote.Run(context.Background(), sink.ch); err != nil {
+		t.Fatal(err)
+	}
+
+	sink.ch = make(chan interface{}, 64)
+	sink.drop[primary.Sequence()+1] = true
+	primary.Submit(NewOrder(101, 1, "t"), Buy)
+	primary.Submit(NewOrder(102, 5, "a2"), Sell)
+	close(sink.ch)
+	remote.Run(context.Background(), sink.ch)
+
+	bids, asks := primary.Depth(0)
+	for name, f := range map[string]*Follower{"direct": direct, "remote": remote} {
+		t.Run(name, func(t *testing.T) {
+			if !f.Book.Synced() || f.Err() != nil {
+				t.Fatalf("Expected follower in sync, got error %v", f.Err())
+			}
+			if b, a := f.Book.Depth(0); !reflect.DeepEqual(b, bids) || !reflect.DeepEqual(a, asks) {
+				t.Errorf("Expected replica depth %v %v, got %v %v", bids, asks, b, a)
+			}
+		})
+	}
+	if snapshots != 3 {
+		t.Errorf("Expected a snapshot for each follower to start and one after the gap, got %d", snapshots)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidFunds = errors.New("orderbook: funds must be positive and finite")
+
+// FundsReport is the report of an order sized in quote currency. Filled is
+// the base quantity executed and Spent the notional it cost or raised.
+type FundsReport struct {
+	ExecutionReport
+	Funds float64 `json:"funds"`
+	Spent float64 `json:"spent"`
+}
+
+// AveragePrice returns the quantity-weighted price of the report's
+// trades, or zero if there were none.
+func (r *ExecutionReport) AveragePrice() float64 {
+	var qty, value float64
+	for _, t := range r.Trades {
+		qty += t.Quantity
+		value += t.Price * t.Quantity
+	}
+	if qty == 0 {
+		return 0
+	}
+	return value / qty
+}
+
+// SubmitFunds buys, or sells, as much base quantity as funds of quote
+// currency will pay for, or raise, walking the opposite side level by
+// level and converting what is left of funds at each price. Quantities
+// are rounded down to the instrument's lot and the book's precision, and
+// fees are charged on top of funds. order.Quantity is ignored; a zero
+// Price makes it a market order and any other a limit. Whatever funds
+// cannot be spent within the limit are left unspent: the order never
+// rests.
+func (ob *OrderBook) SubmitFunds(order Order, side Side, funds float64) FundsReport {
+	ob.assignId(&order)
+	order.Quantity = 0
+	report := FundsReport{ExecutionReport: ExecutionReport{OrderId: order.OrderId,
+		ClientOrderId: order.ClientOrderId, Side: side}, Funds: funds}
+	r := &report.ExecutionReport
+	if !(funds > 0) || math.IsInf(funds, 0) {
+		ob.reject(r, ErrInvalidFunds)
+		return report
+	}
+	if order.OrderId == "" {
+		ob.reject(r, ErrMissingOrderId)
+		return report
+	}
+	if order.Price == 0 {
+		order.Price = math.Inf(1)
+		if side == Sell {
+			order.Price = math.Inf(-1)
+		}
+	} else if !ob.validPrice(order.Price) {
+		ob.reject(r, ErrInvalidPrice)
+		return report
+	}
+	for _, v := range ob.validators {
+		if err := v.Validate(ob, side, &order); err != nil {
+			ob.reject(r, err)
+			return report
+		}
+	}
+	if err := ob.preMatch(side, &order); err != nil {
+		ob.reject(r, err)
+		return report
+	}
+
+	trades, spent, exhausted, err := ob.matchFunds(side, &order, funds)
+	r.Trades, report.Spent = trades, spent
+	for _, t := range r.Trades {
+		r.Fees += t.TakerFee
+		r.Filled = ob.units.add(r.Filled, t.Quantity)
+	}
+	switch {
+	case r.Filled == 0 && err != nil:
+		ob.reject(r, err)
+		return report
+	case r.Filled == 0:
+		r.Status = StatusCanceled
+	case exhausted:
+		r.Status = StatusFilled
+		ob.statuses.set(order.OrderId, StatusFilled)
+	default:
+		r.Status = StatusPartiallyFilled
+		r.Err = err
+	}
+	ob.postMatch(r)
+	r.Triggered = ob.runStops(tradePrices(r.Trades))
+	return report
+}
+
+// matchFunds fills o against the opposite side until funds are spent,
+// returning the trades, the notional they spent and whether what is left
+// of funds is too little for another fill.
+func (ob *OrderBook) matchFunds(side Side, o *Order, funds float64) ([]TradeEvent, float64, bool, error) {
+	ob.lockMatching()
+	defer ob.unlockMatching()
+
+	opposite := ob.Side(side.Opposite())
+	limit := o.Price * ob.weight(o)
+	var trades []TradeEvent
+	var spent float64
+	for {
+		n, maker, price, ok := opposite.head()
+		if !ok || !permits(side, limit, price) {
+			break
+		}
+		perUnit := math.Abs(ob.instrument.Notional(ob.tradePrice(side, o, &maker), 1))
+		if perUnit == 0 {
+			break
+		}
+		qty := ob.floorQuantity(math.Min(maker.Quantity, (funds-spent)/perUnit))
+		if qty <= 0 {
+			o.Quantity = 0
+			return trades, spent, true, nil
+		}
+		o.Quantity = qty
+		trade, err := ob.trade(side, o, n, maker, qty)
+		if err == errStale {
+			continue
+		}
+		if err != nil {
+			return trades, spent, false, err
+		}
+		trades = append(trades, trade)
+		spent += math.Abs(ob.instrument.Notional(trade.Price, trade.Quantity))
+	}
+	o.Quantity = 0
+	return trades, spent, false, nil
+}
+
+// floorQuantity rounds q down to the instrument's lot and the book's
+// quantity precision.
+func (ob *OrderBook) floorQuantity(q float64) float64 {
+	if lot := ob.instrument.Lot; lot > 0 {
+		q = math.Floor(q/lot+1e-9) * lot
+	}
+	if s := ob.units.scale; s > 0 {
+		q = math.Floor(q*s+1e-9) / s
+	}
+	return q
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubmitFunds(t *testing.T) {
+	ob := NewOrderBook(WithInstrument(Instrument{Lot: 0.01}))
+	ob.Submit(NewOrder(100, 20, "a1"), Sell)
+	ob.Submit(NewOrder(110, 100, "a2"), Sell)
+
+	r := ob.SubmitFunds(Order{OrderId: "x"}, Buy, 10000)
+	// 2000 buys all 20 at 100, and the remaining 8000 buys 72.72 at 110.
+	if r.Status != StatusFilled || math.Abs(r.Filled-92.72) > 1e-9 || len(r.Trades) != 2 {
+		t.Fatalf("Expected 92.72 filled in 2 trades, got %+v", r)
+	}
+	if math.Abs(r.Spent-9999.2) > 1e-6 || math.Abs(r.AveragePrice()-r.Spent/r.Filled) > 1e-9 {
+		t.Errorf("Expected 9999.2 spent at %v, got %v at %v", r.Spent/r.Filled, r.Spent, r.AveragePrice())
+	}
+	if ob.OrderCount(Buy) != 0 {
+		t.Errorf("Expected a funds order never to rest")
+	}
+
+	r = ob.SubmitFunds(Order{OrderId: "y", Price: 105}, Buy, 1000)
+	if r.Status != StatusCanceled || r.Filled != 0 {
+		t.Errorf("Expected nothing within a limit of 105, got %+v", r)
+	}
+	r = ob.SubmitFunds(Order{OrderId: "z"}, Sell, 500)
+	if r.Status != StatusCanceled {
+		t.Errorf("Expected a sell against no bids to fill nothing, got %+v", r)
+	}
+	if r := ob.SubmitFunds(Order{OrderId: "w"}, Buy, -1); r.Err != ErrInvalidFunds {
+		t.Errorf("Expected ErrInvalidFunds, got %v", r.Err)
+	}
+	ob.Submit(NewOrder(90, 1, "b1"), Buy)
+	if r := ob.SubmitFunds(Order{OrderId: "v"}, Sell, 500); r.Status != StatusPartiallyFilled || r.Filled != 1 {
+		t.Errorf("Expected the only bid to fill, short of the funds, got %+v", r)
+	}
+}
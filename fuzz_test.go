@@ -0,0 +1,210 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+)
+
+func checkHeap(t *testing.T, name string, h BaseHeap, less func(i, j int) bool, orders OrdersMap) {
+	t.Helper()
+	for i := range h {
+		if h[i].index != i {
+			t.Fatalf("%s: node %s at %d records index %d", name, h[i].Key, i, h[i].index)
+		}
+		if n, ok := orders[h[i].Key]; !ok || n != h[i] {
+			t.Fatalf("%s: node %s at %d missing from OrdersMap", name, h[i].Key, i)
+		}
+		if i > 0 && less(i, (i-1)/2) {
+			t.Fatalf("%s: heap property violated between %d and parent %d", name, i, (i-1)/2)
+		}
+	}
+	if len(orders) != len(h) {
+		t.Fatalf("%s: OrdersMap holds %d entries for %d heap nodes", name, len(orders), len(h))
+	}
+}
+
+func checkLevels(t *testing.T, name string, h BaseHeap, levels levelIndex) {
+	t.Helper()
+	var indexed int
+	var qty, notional float64
+	for _, l := range levels.byPrice {
+		for i, n := range l.orders {
+			if !n.indexed || n.price != l.price || n.price != n.Peek().Price*n.Weight {
+				t.Fatalf("%s: node %s misfiled under level %f", name, n.Key, l.price)
+			}
+			if i > 0 && l.orders[i-1].seq >= n.seq {
+				t.Fatalf("%s: level %f out of time priority", name, l.price)
+			}
+		}
+		for _, n := range l.orders {
+			qty += n.Peek().Quantity
+			notional += n.price * n.Peek().Quantity
+		}
+		indexed += len(l.orders)
+	}
+	if math.Abs(levels.quantity-qty) > 1e-6 || math.Abs(levels.notional-notional) > 1e-6 {
+		t.Fatalf("%s: totals %f/%f drifted from %f/%f", name, levels.quantity, levels.notional, qty, notional)
+	}
+	if indexed != len(h) {
+		t.Fatalf("%s: level index holds %d nodes for %d heap nodes", name, indexed, len(h))
+	}
+	if len(levels.prices) != len(levels.byPrice) || !sort.Float64sAreSorted(levels.prices) {
+		t.Fatalf("%s: level prices %v out of step with %d levels", name, levels.prices, len(levels.byPrice))
+	}
+	for _, p := range levels.prices {
+		if _, ok := levels.byPrice[p]; !ok {
+			t.Fatalf("%s: level price %f has no level", name, p)
+		}
+	}
+}
+
+func checkInvariants(t *testing.T, ob *OrderBook, model map[string]float64) {
+	t.Helper()
+	checkHeap(t, "asks", ob.AskBook.Orders.BaseHeap, ob.AskBook.Orders.Less, ob.AskBook.OrdersMap)
+	checkHeap(t, "bids", ob.BidBook.Orders.BaseHeap, ob.BidBook.Orders.Less, ob.BidBook.OrdersMap)
+	checkLevels(t, "asks", ob.AskBook.Orders.BaseHeap, ob.AskBook.levels)
+	checkLevels(t, "bids", ob.BidBook.Orders.BaseHeap, ob.BidBook.levels)
+	var total float64
+	for _, q := range model {
+		total += q
+	}
+	if math.Abs(ob.Volume()-total) > 1e-9 {
+		t.Fatalf("Expected volume %f, got %f", total, ob.Volume())
+	}
+	if crosses(ob, Sell, bestPrice(ob, Sell)) {
+		t.Fatalf("Expected an uncrossed book, got bid %f over ask %f", bestPrice(ob, Buy), bestPrice(ob, Sell))
+	}
+}
+
+// bestPrice is the best price on side, or NaN when it is empty.
+func bestPrice(ob *OrderBook, side Side) float64 {
+	best := ob.BestAsk
+	if side == Buy {
+		best = ob.BestBid
+	}
+	if price, _, ok := best(); ok {
+		return price
+	}
+	return math.NaN()
+}
+
+// crosses reports whether an order priced at price on side would trade
+// with the opposite side.
+func crosses(ob *OrderBook, side Side, price float64) bool {
+	best := bestPrice(ob, side.Opposite())
+	if side == Buy {
+		return price >= best
+	}
+	return price <= best
+}
+
+// checkFills asserts that trades account for everything o filled and that
+// filled and remaining quantity add up to what was ordered, taking the
+// makers' fills out of the model.
+func checkFills(t *testing.T, trades []TradeEvent, filled, remaining, qty float64, model map[string]float64) {
+	t.Helper()
+	var traded float64
+	for _, tr := range trades {
+		traded += tr.Quantity
+		if model[tr.MakerId] -= tr.Quantity; model[tr.MakerId] <= 1e-9 {
+			delete(model, tr.MakerId)
+		}
+	}
+	if math.Abs(traded-filled) > 1e-9 || math.Abs(filled+remaining-qty) > 1e-9 {
+		t.Fatalf("Expected %f traded and %f remaining to make %f, got %f filled", traded, remaining, qty, filled)
+	}
+}
+
+// FuzzBook interprets the input as a sequence of 4-byte operations
+// (op, key, price, quantity) applied to both sides of a book. Orders are
+// only rested directly when they would not cross, so the book must stay
+// uncrossed throughout.
+func FuzzBook(f *testing.F) {
+	f.Add([]byte{0, 1, 100, 5, 0, 2, 90, 3, 1, 1, 0, 0, 2, 2, 120, 7, 3, 0, 0, 0})
+	f.Add([]byte{4, 3, 50, 1, 4, 3, 51, 2, 5, 3, 0, 0, 0, 3, 50, 1})
+	f.Add([]byte{0, 1, 100, 5, 6, 2, 99, 3, 7, 4, 98, 1, 8, 1, 0, 1, 9, 6, 120, 2, 6, 3, 130, 9})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ob := NewOrderBook()
+		model := make(map[string]float64)
+		for len(data) >= 4 {
+			op, k, price, qty := data[0]%10, data[1]%16, float64(data[2])+1, float64(data[3]%32)+1
+			data = data[4:]
+			var book Book = &ob.AskBook
+			side, prefix := Sell, "a"
+			if k%2 == 1 {
+				book, side, prefix = &ob.BidBook, Buy, "b"
+			}
+			key := fmt.Sprintf("%s%d", prefix, k)
+			switch op {
+			case 0, 4: // insert or replace
+				if crosses(ob, side, price) {
+					break
+				}
+				o := NewOrder(price, qty, key)
+				n := NewNode(key, &o, 1)
+				book.Push(&n)
+				model[key] = qty
+			case 1, 5: // cancel
+				book.Remove(key)
+				delete(model, key)
+			case 2: // amend price in place
+				if n, ok := book.Get(key); ok && !crosses(ob, side, price) {
+					n.Peek().Price = price
+					n.Peek().Quantity = qty
+					book.Fix(key)
+					model[key] = qty
+				}
+			case 3: // consume the best order
+				if book.Len() > 0 {
+					delete(model, book.Pop().Key)
+				}
+			case 6: // submit
+				report := ob.Submit(NewOrder(price, qty, key), side)
+				checkFills(t, report.Trades, report.Filled, report.Remaining, qty, model)
+				if report.Resting {
+					model[key] = report.Remaining
+				}
+			case 7: // match and discard the remainder
+				o := NewOrder(price, qty, key)
+				trades := ob.Match(side, &o)
+				checkFills(t, trades, o.Filled, o.Quantity, qty, model)
+			case 8: // reduce
+				if remaining, ok := ob.Reduce(key, qty); ok {
+					if model[key] = remaining; remaining == 0 {
+						delete(model, key)
+					}
+				}
+			case 9: // batch an insert with a cancel of the side's next key
+				if crosses(ob, side, price) {
+					break
+				}
+				other := fmt.Sprintf("%s%d", prefix, (k+2)%16)
+				_, err := ob.Batch([]Command{
+					{Op: OpInsert, Side: side, Order: NewOrder(price, qty, key)},
+					{Op: OpCancel, Side: side, Order: Order{OrderId: other}},
+				})
+				if err != nil {
+					t.Fatalf("Expected the batch to apply, got %v", err)
+				}
+				model[key] = qty
+				delete(model, other)
+			}
+			checkInvariants(t, ob, model)
+		}
+	})
+}
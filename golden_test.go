@@ -0,0 +1,115 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// replayLine is one message of a recorded depth session.
+type replayLine struct {
+	Snapshot *DepthSnapshot `json:"snapshot"`
+	Diff     *DepthDiff     `json:"diff"`
+}
+
+// replayResult is what a golden file holds: the outcome of every message
+// and the book the session leaves.
+type replayResult struct {
+	Outcomes []string `json:"outcomes"`
+	Synced   bool     `json:"synced"`
+	Bids     []Level  `json:"bids"`
+	Asks     []Level  `json:"asks"`
+}
+
+// TestReplayGolden replays each depth session in testdata/replay, snapshot
+// and diff messages in the order they were received, and compares the
+// outcome with its .golden file. Run with -update to accept a change.
+func TestReplayGolden(t *testing.T) {
+	sessions, err := filepath.Glob(filepath.Join("testdata", "replay", "*.jsonl"))
+	if err != nil || len(sessions) == 0 {
+		t.Fatalf("Expected recorded sessions, got %v %v", sessions, err)
+	}
+	for _, path := range sessions {
+		name := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(replay(t, path), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := strings.TrimSuffix(path, ".jsonl") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Expected %s to replay as in %s, got:\n%s", path, golden, got)
+			}
+		})
+	}
+}
+
+func replay(t *testing.T, path string) replayResult {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ob := NewOrderBook()
+	var res replayResult
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var msg replayLine
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("%s:%d: %v", path, line, err)
+		}
+		var outcome string
+		switch {
+		case msg.Snapshot != nil:
+			err, outcome = ob.ApplySnapshot(*msg.Snapshot), fmt.Sprint("snapshot ", msg.Snapshot.Sequence)
+		case msg.Diff != nil:
+			err, outcome = ob.ApplyDiff(*msg.Diff), fmt.Sprint("diff ", msg.Diff.Sequence)
+		default:
+			t.Fatalf("%s:%d: neither a snapshot nor a diff", path, line)
+		}
+		if err != nil {
+			outcome += ": " + err.Error()
+		} else if !ob.Synced() {
+			outcome += ": buffered"
+		}
+		res.Outcomes = append(res.Outcomes, outcome)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	res.Synced = ob.Synced()
+	res.Bids, res.Asks = ob.Depth(0)
+	return res
+}
@@ -0,0 +1,276 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrDuplicateGroup = errors.New("orderbook: group id already in use")
+	ErrGroupMember    = errors.New("orderbook: order already belongs to a group")
+)
+
+type GroupStatus int
+
+const (
+	GroupActive GroupStatus = iota
+	// GroupTriggered is published when a bracket's entry has filled and its
+	// exits have been placed.
+	GroupTriggered
+	// GroupCompleted is published when a member fills or, for stops,
+	// triggers, and its siblings have been cancelled.
+	GroupCompleted
+	GroupCancelled
+)
+
+func (s GroupStatus) String() string {
+	return [...]string{"active", "triggered", "completed", "cancelled"}[s]
+}
+
+func (s GroupStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type GroupEvent struct {
+	GroupId  string      `json:"groupId"`
+	Status   GroupStatus `json:"status"`
+	OrderId  string      `json:"orderId,omitempty"` // the member that caused the change
+	Sequence uint64      `json:"sequence"`
+}
+
+// GroupSink is implemented by event sinks that also want group status
+// events.
+type GroupSink interface {
+	Group(*GroupEvent)
+}
+
+// Bracket is an entry order with exits: once the entry has completely
+// filled, a take-profit limit for the filled quantity rests on the
+// opposite side, linked one-cancels-other with a stop-loss stop-market.
+// Exit order ids are the entry's with "-tp" and "-sl" appended.
+type Bracket struct {
+	Entry      Order   `json:"entry"`
+	Side       Side    `json:"side"`
+	TakeProfit float64 `json:"takeProfit"`
+	StopLoss   float64 `json:"stopLoss"`
+}
+
+type group struct {
+	id      string
+	members []string
+	bracket *Bracket
+}
+
+type groupBook struct {
+	lock    sync.Mutex
+	groups  map[string]*group
+	byOrder map[string]*group
+}
+
+func (gb *groupBook) add(g *group) error {
+	gb.lock.Lock()
+	defer gb.lock.Unlock()
+
+	if gb.groups == nil {
+		gb.groups = make(map[string]*group)
+		gb.byOrder = make(map[string]*group)
+	}
+	if _, ok := gb.groups[g.id]; ok {
+		return ErrDuplicateGroup
+	}
+	for _, id := range g.members {
+		if _, ok := gb.byOrder[id]; ok {
+			return ErrGroupMember
+		}
+	}
+	gb.groups[g.id] = g
+	for _, id := range g.members {
+		gb.byOrder[id] = g
+	}
+	return nil
+}
+
+// list returns copies of the groups in id order.
+func (gb *groupBook) list() []*group {
+	gb.lock.Lock()
+	defer gb.lock.Unlock()
+
+	groups := make([]*group, 0, len(gb.groups))
+	for _, g := range gb.groups {
+		c := &group{id: g.id, members: append([]string(nil), g.members...)}
+		if g.bracket != nil {
+			b := *g.bracket
+			c.bracket = &b
+		}
+		groups = append(groups, c)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].id < groups[j].id })
+	return groups
+}
+
+func equalGroups(a, b []*group) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].id != b[i].id || len(a[i].members) != len(b[i].members) ||
+			(a[i].bracket == nil) != (b[i].bracket == nil) ||
+			a[i].bracket != nil && *a[i].bracket != *b[i].bracket {
+			return false
+		}
+		for j := range a[i].members {
+			if a[i].members[j] != b[i].members[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// take removes and returns the group containing orderId.
+func (gb *groupBook) take(orderId string) *group {
+	gb.lock.Lock()
+	defer gb.lock.Unlock()
+
+	g, ok := gb.byOrder[orderId]
+	if !ok {
+		return nil
+	}
+	gb.drop(g)
+	return g
+}
+
+func (gb *groupBook) drop(g *group) {
+	delete(gb.groups, g.id)
+	for _, id := range g.members {
+		delete(gb.byOrder, id)
+	}
+}
+
+// LinkOCO groups resting orders and pending stops so that the first member
+// to fill, or for a stop to trigger, cancels the rest.
+func (ob *OrderBook) LinkOCO(groupId string, orderIds ...string) error {
+	if err := ob.groups.add(&group{id: groupId, members: orderIds}); err != nil {
+		return err
+	}
+	ob.publishGroup(&GroupEvent{GroupId: groupId, Status: GroupActive})
+	return nil
+}
+
+// CancelGroup cancels every member of the group and reports whether it
+// existed.
+func (ob *OrderBook) CancelGroup(groupId string) bool {
+	ob.groups.lock.Lock()
+	g, ok := ob.groups.groups[groupId]
+	if ok {
+		ob.groups.drop(g)
+	}
+	ob.groups.lock.Unlock()
+	if !ok {
+		return false
+	}
+	for _, id := range g.members {
+		ob.cancelMember(id)
+	}
+	ob.publishGroup(&GroupEvent{GroupId: groupId, Status: GroupCancelled})
+	return true
+}
+
+// SubmitBracket submits the entry under groupId and returns its report.
+// If the entry fills completely on arrival its exits are placed at once.
+func (ob *OrderBook) SubmitBracket(groupId string, b Bracket) ExecutionReport {
+	g := &group{id: groupId, members: []string{b.Entry.OrderId}, bracket: &b}
+	if err := ob.groups.add(g); err != nil {
+		return ExecutionReport{OrderId: b.Entry.OrderId, Side: b.Side, Remaining: b.Entry.Quantity,
+			Status: StatusRejected, Err: err}
+	}
+	report := ob.Submit(b.Entry, b.Side)
+	switch report.Status {
+	case StatusRejected:
+		ob.groups.take(b.Entry.OrderId)
+	case StatusFilled:
+		ob.memberFilled(b.Entry.OrderId, true)
+	default:
+		ob.publishGroup(&GroupEvent{GroupId: groupId, Status: GroupActive, OrderId: b.Entry.OrderId})
+	}
+	return report
+}
+
+func (ob *OrderBook) cancelMember(orderId string) {
+	if !ob.Cancel(orderId) {
+		ob.CancelStop(orderId)
+	}
+}
+
+// memberFilled is called whenever a resting order fills, done when it has
+// no quantity left, and when a stop triggers.
+func (ob *OrderBook) memberFilled(orderId string, done bool) {
+	if g := ob.completeMember(orderId, done); g != nil {
+		ob.placeExits(g.id, g.bracket)
+	}
+}
+
+// completeMember is memberFilled leaving a bracket whose exits are due to
+// the caller, which places them once it may submit orders.
+func (ob *OrderBook) completeMember(orderId string, done bool) *group {
+	ob.groups.lock.Lock()
+	g, ok := ob.groups.byOrder[orderId]
+	if !ok || (g.bracket != nil && !done) {
+		ob.groups.lock.Unlock()
+		return nil
+	}
+	ob.groups.drop(g)
+	ob.groups.lock.Unlock()
+
+	if g.bracket != nil {
+		return g
+	}
+	for _, id := range g.members {
+		if id != orderId {
+			ob.cancelMember(id)
+		}
+	}
+	ob.publishGroup(&GroupEvent{GroupId: g.id, Status: GroupCompleted, OrderId: orderId})
+	return nil
+}
+
+func (ob *OrderBook) placeExits(groupId string, b *Bracket) {
+	exit := b.Side.Opposite()
+	tp := Order{Price: b.TakeProfit, Quantity: b.Entry.Quantity, OrderId: b.Entry.OrderId + "-tp",
+		Venue: b.Entry.Venue, Account: b.Entry.Account}
+	sl := tp
+	sl.Price, sl.OrderId = 0, b.Entry.OrderId+"-sl"
+
+	ob.groups.add(&group{id: groupId, members: []string{tp.OrderId, sl.OrderId}})
+	ob.publishGroup(&GroupEvent{GroupId: groupId, Status: GroupTriggered, OrderId: b.Entry.OrderId})
+	ob.SubmitStop(Stop{Order: sl, Side: exit, StopPrice: b.StopLoss})
+	if r := ob.Submit(tp, exit); r.Filled > 0 {
+		ob.memberFilled(tp.OrderId, r.Status == StatusFilled)
+	}
+}
+
+func (ob *OrderBook) publishGroup(e *GroupEvent) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	e.Sequence = ob.nextSequence()
+	ob.toSinks(func(s EventSink) {
+		if gs, ok := s.(GroupSink); ok {
+			gs.Group(e)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+type groupSink struct {
+	recordingSink
+	groups []GroupStatus
+}
+
+func (s *groupSink) Group(e *GroupEvent) { s.groups = append(s.groups, e.Status) }
+
+func TestOCO(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &groupSink{}
+	ob.AddSink(sink)
+	ob.PushOrder(Sell, NewOrder(105, 1, "tp"))
+	ob.SubmitStop(Stop{Order: NewOrder(0, 1, "sl"), Side: Sell, StopPrice: 95})
+	if err := ob.LinkOCO("g", "tp", "sl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ob.LinkOCO("g2", "tp"); err != ErrGroupMember {
+		t.Errorf("Expected grouped order to be rejected, got %v", err)
+	}
+
+	ob.Submit(NewOrder(105, 0.5, "taker"), Buy)
+	if len(ob.Stops()) != 0 {
+		t.Errorf("Expected a partial fill of tp to cancel the stop")
+	}
+	if _, _, ok := ob.Lookup("tp"); !ok {
+		t.Errorf("Expected tp to keep resting")
+	}
+	if expected := []GroupStatus{GroupActive, GroupCompleted}; !reflect.DeepEqual(sink.groups, expected) {
+		t.Errorf("Expected group events %v, got %v", expected, sink.groups)
+	}
+
+	ob.PushOrder(Buy, NewOrder(90, 1, "x"))
+	ob.PushOrder(Buy, NewOrder(91, 1, "y"))
+	ob.LinkOCO("g3", "x", "y")
+	if !ob.CancelGroup("g3") || ob.BidBook.Len() != 0 || ob.CancelGroup("g3") {
+		t.Errorf("Expected group cancel to remove both orders once")
+	}
+}
+
+func TestBracket(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Exit   float64 // price the market trades at after entry
+		Filled string  // exit expected to execute
+	}{
+		{"take-profit", 110, "e-tp"},
+		{"stop-loss", 90, "e-sl"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ob := NewOrderBook()
+			sink := &groupSink{}
+			ob.AddSink(sink)
+			ob.PushOrder(Sell, NewOrder(100, 1, "ask"))
+			ob.PushOrder(Buy, NewOrder(80, 5, "support"))
+
+			report := ob.SubmitBracket("b", Bracket{Entry: NewOrder(100, 1, "e"), Side: Buy, TakeProfit: 110, StopLoss: 95})
+			if report.Status != StatusFilled {
+				t.Fatalf("Expected entry to fill, got %+v", report)
+			}
+			if _, _, ok := ob.Lookup("e-tp"); !ok || len(ob.Stops()) != 1 {
+				t.Fatalf("Expected take-profit resting and stop-loss pending")
+			}
+
+			if test.Exit > 100 {
+				ob.Submit(NewOrder(test.Exit, 1, "lift"), Buy)
+			} else {
+				ob.PushOrder(Buy, NewOrder(test.Exit, 1, "bid"))
+				ob.Submit(NewOrder(test.Exit, 1, "hit"), Sell)
+			}
+			if _, _, ok := ob.Lookup("e-tp"); ok || len(ob.Stops()) != 0 {
+				t.Errorf("Expected both exits gone after %s", test.Filled)
+			}
+			expected := []GroupStatus{GroupTriggered, GroupCompleted}
+			if !reflect.DeepEqual(sink.groups, expected) {
+				t.Errorf("Expected group events %v, got %v", expected, sink.groups)
+			}
+			if test.Filled == "e-sl" && ob.BidBook.Peek().Quantity != 4 {
+				t.Errorf("Expected stop-loss to sell into the bid, got %+v", ob.BidBook.Peek())
+			}
+		})
+	}
+}
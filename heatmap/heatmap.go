@@ -0,0 +1,106 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package heatmap samples the top levels of an orderbook.OrderBook at a
+// fixed interval into CSV, one row per sample, for plotting liquidity over
+// time.
+//
+// Each row holds the sample time in unix nanoseconds, the book sequence,
+// then bid_px_1, bid_qty_1 ... bid_px_N, bid_qty_N followed by the same
+// for asks. Missing levels are left empty.
+package heatmap
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+type Recorder struct {
+	Book     *orderbook.OrderBook
+	Levels   int
+	Interval time.Duration
+	Now      func() time.Time
+
+	w      *csv.Writer
+	header bool
+}
+
+func NewRecorder(ob *orderbook.OrderBook, w io.Writer, levels int, interval time.Duration) *Recorder {
+	return &Recorder{Book: ob, Levels: levels, Interval: interval, Now: time.Now, w: csv.NewWriter(w)}
+}
+
+func (r *Recorder) Header() []string {
+	h := []string{"time", "sequence"}
+	for _, side := range []string{"bid", "ask"} {
+		for i := 1; i <= r.Levels; i++ {
+			n := strconv.Itoa(i)
+			h = append(h, side+"_px_"+n, side+"_qty_"+n)
+		}
+	}
+	return h
+}
+
+func (r *Recorder) row(t time.Time) []string {
+	bids, asks := r.Book.Depth(r.Levels)
+	row := []string{strconv.FormatInt(t.UnixNano(), 10), strconv.FormatUint(r.Book.Sequence(), 10)}
+	for _, lvls := range [][]orderbook.Level{bids, asks} {
+		for i := 0; i < r.Levels; i++ {
+			if i < len(lvls) {
+				row = append(row,
+					strconv.FormatFloat(lvls[i].Price, 'g', -1, 64),
+					strconv.FormatFloat(lvls[i].Quantity, 'g', -1, 64))
+			} else {
+				row = append(row, "", "")
+			}
+		}
+	}
+	return row
+}
+
+// Sample writes one row for the current state of the book, preceded by
+// the header on the first call, and flushes it.
+func (r *Recorder) Sample() error {
+	if !r.header {
+		if err := r.w.Write(r.Header()); err != nil {
+			return err
+		}
+		r.header = true
+	}
+	if err := r.w.Write(r.row(r.Now())); err != nil {
+		return err
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// Run samples every Interval until ctx is done or a write fails.
+func (r *Recorder) Run(ctx context.Context) error {
+	tick := time.NewTicker(r.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			if err := r.Sample(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package heatmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestSample(t *testing.T) {
+	ob := orderbook.NewOrderBook()
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(99, 2, "b1"))
+	ob.PushOrder(orderbook.Buy, orderbook.NewOrder(99, 1, "b2"))
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(101, 5, "a1"))
+
+	var buf bytes.Buffer
+	r := NewRecorder(ob, &buf, 2, time.Second)
+	r.Now = func() time.Time { return time.Unix(0, 42) }
+	if err := r.Sample(); err != nil {
+		t.Fatal(err)
+	}
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(102, 1, "a2"))
+	if err := r.Sample(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], r.Header()) {
+		t.Errorf("Expected header %v, got %v", r.Header(), rows[0])
+	}
+	expected := [][]string{
+		{"42", rows[1][1], "99", "3", "", "", "101", "5", "", ""},
+		{"42", rows[2][1], "99", "3", "", "", "101", "5", "102", "1"},
+	}
+	for i, e := range expected {
+		if !reflect.DeepEqual(rows[i+1], e) {
+			t.Errorf("Expected row %v, got %v", e, rows[i+1])
+		}
+	}
+	if rows[1][1] == rows[2][1] {
+		t.Errorf("Expected sequence to advance between samples, got %s", rows[1][1])
+	}
+}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(orderbook.NewOrderBook(), &buf, 1, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	rows, _ := csv.NewReader(&buf).ReadAll()
+	if len(rows) < 2 {
+		t.Errorf("Expected samples to be written, got %d rows", len(rows))
+	}
+}
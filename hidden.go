@@ -0,0 +1,57 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// HiddenBook is a dark book beside a lit reference book. Its orders rest
+// apart from the reference, so they never appear in its depth or quotes,
+// and HiddenBook itself offers no depth or quotes of them. They match one
+// another only at the reference's midpoint, and only while both orders'
+// limits permit it; while the reference has no midpoint nothing matches
+// and incoming orders rest. Within the eligible orders, the best limit
+// and then the earliest fills first.
+type HiddenBook struct {
+	book *OrderBook
+}
+
+// NewHiddenBook returns an empty HiddenBook priced off ref. Its orders rest
+// in a book built with opts, so fees, hooks and the rest apply to them as
+// usual, but an execution price set with them is ignored.
+func NewHiddenBook(ref *OrderBook, opts ...Option) *HiddenBook {
+	opts = append(opts, func(ob *OrderBook) {
+		ob.execution.reference = ref
+	})
+	return &HiddenBook{NewOrderBook(opts...)}
+}
+
+// Submit matches order against the hidden orders on the opposite side at
+// the reference's midpoint, resting any remainder, as OrderBook.Submit
+// does.
+func (h *HiddenBook) Submit(order Order, side Side) ExecutionReport {
+	return h.book.Submit(order, side)
+}
+
+// Cancel removes a hidden order and reports whether it was found.
+func (h *HiddenBook) Cancel(orderId string) bool {
+	return h.book.Cancel(orderId)
+}
+
+// Lookup returns a hidden order and its side.
+func (h *HiddenBook) Lookup(orderId string) (Order, Side, bool) {
+	return h.book.Lookup(orderId)
+}
+
+// Len returns the number of hidden orders resting on side.
+func (h *HiddenBook) Len(side Side) int {
+	return h.book.Side(side).Len()
+}
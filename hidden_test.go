@@ -0,0 +1,73 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestHiddenBook(t *testing.T) {
+	lit := NewOrderBook()
+	dark := NewHiddenBook(lit)
+
+	// Without a lit midpoint nothing matches.
+	dark.Submit(NewOrder(101, 2, "s1"), Sell)
+	if r := dark.Submit(NewOrder(102, 1, "b1"), Buy); len(r.Trades) != 0 || !r.Resting {
+		t.Errorf("Expected b1 to rest without a reference, got %+v", r)
+	}
+	dark.Cancel("b1")
+
+	lit.Submit(NewOrder(99, 5, "lb"), Buy)
+	lit.Submit(NewOrder(101, 5, "la"), Sell)
+	if bids, asks := lit.Depth(0); len(bids) != 1 || len(asks) != 1 || asks[0].Quantity != 5 {
+		t.Errorf("Expected the lit book to hold only its own orders, got %v %v", bids, asks)
+	}
+
+	dark.Submit(NewOrder(99.5, 1, "s2"), Sell)
+	tests := []struct {
+		id       string
+		side     Side
+		price    float64
+		qty      float64
+		makers   []string
+		filled   float64
+		resting  bool
+		darkBids int
+	}{
+		// b2 does not reach the midpoint of 100.
+		{"b2", Buy, 99.9, 1, nil, 0, true, 1},
+		// s2 permits 100 but s1, at 101, does not.
+		{"b3", Buy, 100, 3, []string{"s2"}, 1, true, 2},
+		{"s3", Sell, 100, 5, []string{"b3"}, 2, true, 1},
+	}
+	for _, tt := range tests {
+		r := dark.Submit(NewOrder(tt.price, tt.qty, tt.id), tt.side)
+		if len(r.Trades) != len(tt.makers) || r.Filled != tt.filled || r.Resting != tt.resting {
+			t.Errorf("Expected %s to fill %v against %v, got %+v", tt.id, tt.filled, tt.makers, r)
+			continue
+		}
+		for i, trade := range r.Trades {
+			if trade.MakerId != tt.makers[i] || trade.Price != 100 {
+				t.Errorf("Expected %s to trade with %s at 100, got %+v", tt.id, tt.makers[i], trade)
+			}
+		}
+		if n := dark.Len(Buy); n != tt.darkBids {
+			t.Errorf("Expected %d hidden bids after %s, got %d", tt.darkBids, tt.id, n)
+		}
+	}
+	if price, size, ok := lit.BestAsk(); !ok || price != 101 || size != 5 {
+		t.Errorf("Expected the lit ask untouched, got %v %v %v", price, size, ok)
+	}
+	if o, side, ok := dark.Lookup("s3"); !ok || side != Sell || o.Quantity != 3 {
+		t.Errorf("Expected 3 of s3 hidden on the sell side, got %+v %s %v", o, side, ok)
+	}
+}
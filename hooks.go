@@ -0,0 +1,88 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// Hooks are called at fixed points as orders are executed by Submit,
+// SubmitStop and fired stops. Any of them may be nil. Hooks run on the
+// submitting goroutine; PreMatch and Fill may edit the order or trade they
+// are given, and PostMatch and Reject the report, including its
+// Annotations.
+type Hooks struct {
+	// PreMatch runs after validation, before o matches. An error rejects
+	// the order.
+	PreMatch func(side Side, o *Order) error
+	// Fill runs for each trade before it is applied or published. An error
+	// stops matching there: the trade does not happen and whatever is left
+	// of the taker does not rest. Fill runs with the book locked, once the
+	// maker is known to be current, so it must not call the book.
+	Fill func(taker, maker *Order, t *TradeEvent) error
+	// PostMatch runs once the order has matched and any remainder rested.
+	PostMatch func(r *ExecutionReport)
+	// Reject runs when an order is rejected, for any reason.
+	Reject func(r *ExecutionReport)
+}
+
+// WithHooks appends h to the book's hooks. Hooks run in the order they
+// were added, and the first error from a PreMatch or Fill wins.
+func WithHooks(h Hooks) Option {
+	return func(ob *OrderBook) {
+		ob.hooks = append(ob.hooks, h)
+	}
+}
+
+func (ob *OrderBook) preMatch(side Side, o *Order) error {
+	for _, h := range ob.hooks {
+		if h.PreMatch != nil {
+			if err := h.PreMatch(side, o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ob *OrderBook) fillHooks(taker, maker *Order, t *TradeEvent) error {
+	for _, h := range ob.hooks {
+		if h.Fill != nil {
+			if err := h.Fill(taker, maker, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ob *OrderBook) postMatch(r *ExecutionReport) {
+	for _, h := range ob.hooks {
+		if h.PostMatch != nil {
+			h.PostMatch(r)
+		}
+	}
+}
+
+// reject marks the report rejected with err, logs it and runs the Reject
+// hooks.
+func (ob *OrderBook) reject(r *ExecutionReport, err error) {
+	r.Status, r.Err = StatusRejected, err
+	ob.eventLock.Lock()
+	if ob.logger != nil {
+		ob.logger.Debug("orderbook: reject", "side", r.Side.String(), "key", r.OrderId, "error", err.Error())
+	}
+	ob.eventLock.Unlock()
+	for _, h := range ob.hooks {
+		if h.Reject != nil {
+			h.Reject(r)
+		}
+	}
+}
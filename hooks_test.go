@@ -0,0 +1,120 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	errHalted := errors.New("halted")
+	errSelfTrade := errors.New("self trade")
+	var calls []string
+	ob := NewOrderBook(WithHooks(Hooks{
+		PreMatch: func(side Side, o *Order) error {
+			calls = append(calls, "pre:"+o.OrderId)
+			if o.Account == "halted" {
+				return errHalted
+			}
+			return nil
+		},
+		Fill: func(taker, maker *Order, tr *TradeEvent) error {
+			calls = append(calls, "fill:"+maker.OrderId)
+			if taker.Account != "" && taker.Account == maker.Account {
+				return errSelfTrade
+			}
+			return nil
+		},
+		PostMatch: func(r *ExecutionReport) {
+			calls = append(calls, "post:"+r.OrderId)
+			r.Annotations = map[string]string{"checked": "yes"}
+		},
+		Reject: func(r *ExecutionReport) {
+			calls = append(calls, "reject:"+r.OrderId)
+		},
+	}))
+
+	a := NewOrder(101, 1, "a")
+	b := NewOrder(102, 1, "b")
+	b.Account = "acct"
+	ob.Submit(a, Sell)
+	ob.Submit(b, Sell)
+
+	x := NewOrder(102, 3, "x")
+	x.Account = "acct"
+	report := ob.Submit(x, Buy)
+	if report.Filled != 1 || report.Err != errSelfTrade || report.Resting {
+		t.Errorf("Expected x to fill against a then stop short of its own b, got %+v", report)
+	}
+	if report.Annotations["checked"] != "yes" {
+		t.Errorf("Expected PostMatch to annotate the report, got %v", report.Annotations)
+	}
+	if _, ok := ob.AskBook.Get("b"); !ok {
+		t.Errorf("Expected the vetoed maker to keep resting")
+	}
+
+	h := NewOrder(100, 1, "h")
+	h.Account = "halted"
+	if report := ob.Submit(h, Sell); report.Status != StatusRejected || report.Err != errHalted {
+		t.Errorf("Expected PreMatch to reject h, got %+v", report)
+	}
+	ob.Submit(NewOrder(0, 1, "bad"), Sell)
+
+	expected := []string{"pre:a", "post:a", "pre:b", "post:b", "pre:x", "fill:a", "fill:b", "post:x",
+		"pre:h", "reject:h", "reject:bad"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected call %d to be %s, got %s", i, expected[i], calls[i])
+		}
+	}
+}
+
+func TestFillHookSeesOnlyAppliedTrades(t *testing.T) {
+	var seen []string
+	canceled := make(chan bool, 1)
+	var ob *OrderBook
+	ob = NewOrderBook(WithHooks(Hooks{
+		Fill: func(taker, maker *Order, tr *TradeEvent) error {
+			// a cancel racing the fill must not land between the hooks
+			// and the fill; give it the chance to
+			go func() { canceled <- ob.Cancel(maker.OrderId) }()
+			select {
+			case ok := <-canceled:
+				canceled <- ok
+			case <-time.After(20 * time.Millisecond):
+			}
+			return nil
+		},
+	}), WithHooks(Hooks{
+		Fill: func(taker, maker *Order, tr *TradeEvent) error {
+			seen = append(seen, maker.OrderId)
+			return nil
+		},
+	}))
+	ob.Submit(NewOrder(100, 2, "a"), Sell)
+
+	report := ob.Submit(NewOrder(100, 1, "x"), Buy)
+	if len(seen) != len(report.Trades) || report.Filled != 1 {
+		t.Errorf("Expected the hooks to see only the trade that happened, saw %v, got %+v", seen, report)
+	}
+	<-canceled
+	if ob.AskBook.Len() != 0 {
+		t.Errorf("Expected the cancel to take the remainder of a after the fill")
+	}
+}
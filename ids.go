@@ -0,0 +1,235 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"crypto/rand"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IDGenerator hands out order ids. Implementations must be safe for
+// concurrent use and never repeat an id.
+type IDGenerator interface {
+	NextID() string
+}
+
+// WithIDGenerator makes Submit, SubmitStop and Batch inserts take an id
+// from g for orders that arrive without one.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(ob *OrderBook) {
+		ob.ids.gen = g
+	}
+}
+
+// assignId gives o a generated id if it has none and a generator is set.
+func (ob *OrderBook) assignId(o *Order) {
+	if o.OrderId == "" && ob.ids.gen != nil {
+		o.OrderId = ob.ids.gen.NextID()
+	}
+}
+
+type monotonicIDs struct {
+	prefix string
+	n      uint64
+}
+
+// MonotonicIDs returns a generator of prefix followed by 1, 2, 3...
+func MonotonicIDs(prefix string) IDGenerator {
+	return &monotonicIDs{prefix: prefix}
+}
+
+func (g *monotonicIDs) NextID() string {
+	return g.prefix + strconv.FormatUint(atomic.AddUint64(&g.n, 1), 10)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulids struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	entropy io.Reader
+	ms      uint64
+	last    [16]byte
+}
+
+// ULIDs returns a generator of 26 character ULIDs: a millisecond timestamp
+// from now followed by 80 random bits read from entropy. Ids generated in
+// the same millisecond, or after the clock steps back, increment the last
+// one, so they always sort in generation order. A nil now uses the wall
+// clock and a nil entropy crypto/rand.
+func ULIDs(now func() time.Time, entropy io.Reader) IDGenerator {
+	if now == nil {
+		now = time.Now
+	}
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+	return &ulids{now: now, entropy: entropy}
+}
+
+func (g *ulids) NextID() string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if ms := uint64(g.now().UnixMilli()); ms > g.ms {
+		g.ms = ms
+		for i := 0; i < 6; i++ {
+			g.last[i] = byte(ms >> (40 - 8*i))
+		}
+		if _, err := io.ReadFull(g.entropy, g.last[6:]); err == nil {
+			return encodeULID(g.last)
+		}
+		// keep the previous random bits; incrementing them stays unique
+	}
+	for i := 15; i >= 6; i-- {
+		if g.last[i]++; g.last[i] != 0 {
+			break
+		}
+	}
+	return encodeULID(g.last)
+}
+
+// encodeULID writes the 128 bits of id as 26 Crockford base32 digits, the
+// first of which carries only the top two bits.
+func encodeULID(id [16]byte) string {
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		var carry uint16
+		for j := 0; j < 16; j++ {
+			v := carry<<8 | uint16(id[j])
+			id[j], carry = byte(v/32), v%32
+		}
+		out[i] = crockford[carry]
+	}
+	return string(out[:])
+}
+
+type snowflakeIDs struct {
+	lock  sync.Mutex
+	node  int64
+	epoch time.Time
+	now   func() time.Time
+	ms    int64
+	seq   int64
+}
+
+// SnowflakeIDs returns a generator of decimal snowflake ids: milliseconds
+// since epoch in the top 41 bits, node in the next 10 and a per-millisecond
+// sequence in the low 12. More than 4096 ids in a millisecond borrow the
+// next one rather than wait, and a clock that steps back is ignored, so ids
+// always increase. A nil now uses the wall clock.
+func SnowflakeIDs(node int64, epoch time.Time, now func() time.Time) IDGenerator {
+	if now == nil {
+		now = time.Now
+	}
+	return &snowflakeIDs{node: node & 0x3ff, epoch: epoch, now: now}
+}
+
+func (g *snowflakeIDs) NextID() string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if ms := g.now().Sub(g.epoch).Milliseconds(); ms > g.ms {
+		g.ms, g.seq = ms, 0
+	} else if g.seq++; g.seq > 0xfff {
+		g.ms, g.seq = g.ms+1, 0
+	}
+	return strconv.FormatInt(g.ms<<22|g.node<<12|g.seq, 10)
+}
+
+// idMap pairs the client order ids of resting orders with the ids the
+// book keys them by.
+type idMap struct {
+	gen      IDGenerator
+	lock     sync.Mutex
+	size     int64
+	byClient map[string]string
+	byOrder  map[string]string
+}
+
+// track binds the client ids of pushed orders and forgets those of orders
+// that leave the book.
+func (m *idMap) track(bids, asks []change) {
+	for _, changes := range [][]change{bids, asks} {
+		for _, c := range changes {
+			switch c.op {
+			case opPush, opReplace:
+				if o := c.node.Peek(); o != nil && o.ClientOrderId != "" {
+					m.bind(c.node.Key, o.ClientOrderId)
+				} else if c.op == opReplace {
+					m.unbind(c.node.Key)
+				}
+			case opRemove, opPop:
+				m.unbind(c.node.Key)
+			}
+		}
+	}
+}
+
+func (m *idMap) bind(orderId, clientId string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.byOrder == nil {
+		m.byClient = make(map[string]string)
+		m.byOrder = make(map[string]string)
+	}
+	if prev, ok := m.byOrder[orderId]; ok && m.byClient[prev] == orderId {
+		delete(m.byClient, prev)
+	}
+	m.byOrder[orderId] = clientId
+	m.byClient[clientId] = orderId
+	atomic.StoreInt64(&m.size, int64(len(m.byOrder)))
+}
+
+func (m *idMap) unbind(orderId string) {
+	if atomic.LoadInt64(&m.size) == 0 {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if clientId, ok := m.byOrder[orderId]; ok {
+		delete(m.byOrder, orderId)
+		if m.byClient[clientId] == orderId {
+			delete(m.byClient, clientId)
+		}
+	}
+	atomic.StoreInt64(&m.size, int64(len(m.byOrder)))
+}
+
+// OrderIdFor returns the id of the resting order that was entered with
+// clientId. When several resting orders share a client id, the latest
+// entered wins.
+func (ob *OrderBook) OrderIdFor(clientId string) (string, bool) {
+	ob.ids.lock.Lock()
+	defer ob.ids.lock.Unlock()
+
+	orderId, ok := ob.ids.byClient[clientId]
+	return orderId, ok
+}
+
+// ClientOrderIdFor returns the client id the resting order with the given
+// id was entered with.
+func (ob *OrderBook) ClientOrderIdFor(orderId string) (string, bool) {
+	ob.ids.lock.Lock()
+	defer ob.ids.lock.Unlock()
+
+	clientId, ok := ob.ids.byOrder[orderId]
+	return clientId, ok
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIDGenerators(t *testing.T) {
+	mono := MonotonicIDs("o-")
+	if a, b := mono.NextID(), mono.NextID(); a != "o-1" || b != "o-2" {
+		t.Errorf("Expected o-1 and o-2, got %s and %s", a, b)
+	}
+
+	fixed := func() time.Time { return time.UnixMilli(1469918176385) }
+	ulid := ULIDs(fixed, bytes.NewReader(make([]byte, 10)))
+	first, second := ulid.NextID(), ulid.NextID()
+	if len(first) != 26 || first[:10] != "01ARYZ6S41" {
+		t.Errorf("Expected a 26 character ULID with time 01ARYZ6S41, got %s", first)
+	}
+	if first != "01ARYZ6S410000000000000000" || second != "01ARYZ6S410000000000000001" {
+		t.Errorf("Expected ids in the same millisecond to increment, got %s then %s", first, second)
+	}
+
+	epoch := time.Unix(0, 0)
+	snow := SnowflakeIDs(3, epoch, func() time.Time { return epoch.Add(5 * time.Millisecond) })
+	var last int64
+	for i := 0; i < 4097; i++ {
+		id, err := strconv.ParseInt(snow.NextID(), 10, 64)
+		if err != nil || id <= last {
+			t.Fatalf("Expected increasing snowflake ids, got %d after %d (%v)", id, last, err)
+		}
+		last = id
+	}
+	if ms, node := last>>22, last>>12&0x3ff; ms != 6 || node != 3 {
+		t.Errorf("Expected the 4097th id to borrow millisecond 6 on node 3, got %d on %d", ms, node)
+	}
+}
+
+func TestClientOrderIds(t *testing.T) {
+	ob := NewOrderBook(WithIDGenerator(MonotonicIDs("x")))
+	o := NewOrder(100, 1, "")
+	o.ClientOrderId = "mine"
+	report := ob.Submit(o, Buy)
+	if report.Err != nil || report.OrderId != "x1" || report.ClientOrderId != "mine" {
+		t.Fatalf("Expected order x1 for client id mine, got %+v", report)
+	}
+	if id, ok := ob.OrderIdFor("mine"); !ok || id != "x1" {
+		t.Errorf("Expected mine to map to x1, got %s (%t)", id, ok)
+	}
+	if id, ok := ob.ClientOrderIdFor("x1"); !ok || id != "mine" {
+		t.Errorf("Expected x1 to map to mine, got %s (%t)", id, ok)
+	}
+
+	cmds := []Command{{Op: OpInsert, Side: Sell, Order: NewOrder(101, 1, "")}}
+	if _, err := ob.Batch(cmds); err != nil || cmds[0].Order.OrderId != "" {
+		t.Errorf("Expected batch insert to leave the command as it was, got %q (%v)", cmds[0].Order.OrderId, err)
+	}
+	if _, ok := ob.AskBook.Get("x2"); !ok {
+		t.Errorf("Expected batch insert to be given x2")
+	}
+	rejected := []Command{
+		{Op: OpInsert, Side: Sell, Order: NewOrder(102, 1, "")},
+		{Op: OpInsert, Side: Sell, Order: NewOrder(0, 1, "bad")},
+	}
+	if _, err := ob.Batch(rejected); err == nil {
+		t.Errorf("Expected a batch with an invalid order to be rejected")
+	}
+	if report := ob.Submit(NewOrder(99, 1, ""), Buy); report.OrderId != "x3" {
+		t.Errorf("Expected a rejected batch to use no ids, got %s next", report.OrderId)
+	}
+
+	ob.Cancel("x1")
+	if _, ok := ob.OrderIdFor("mine"); ok {
+		t.Errorf("Expected client id to be forgotten once its order left the book")
+	}
+	if _, ok := ob.ClientOrderIdFor("x1"); ok {
+		t.Errorf("Expected order id to be forgotten once it left the book")
+	}
+
+	plain := NewOrderBook()
+	if report := plain.Submit(NewOrder(100, 1, ""), Buy); report.Err != ErrMissingOrderId {
+		t.Errorf("Expected a book without a generator to require ids, got %v", report.Err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// OrderIndex maps the keys of a side's resting orders to their nodes. A
+// side uses its OrdersMap unless the book is built WithOrderIndex. Calls
+// are made under the side's lock.
+type OrderIndex interface {
+	Get(key string) (*Node, bool)
+	Put(key string, n *Node)
+	Delete(key string)
+	Len() int
+	// Range calls fn for each node, in no particular order, until it
+	// returns false.
+	Range(fn func(*Node) bool)
+}
+
+// WithOrderIndex gives each side the index newIndex returns in place of
+// its OrdersMap, which is then left nil. Go's own map is already a swiss
+// table, and sharding it measured slower for lookups and removals alike,
+// so this is for indexes with other trade-offs; BenchmarkOrderIndex
+// measures one against the default.
+func WithOrderIndex(newIndex func() OrderIndex) Option {
+	return func(ob *OrderBook) {
+		for _, sb := range []*SideBook{&ob.AskBook.SideBook, &ob.BidBook.SideBook} {
+			sb.index = newIndex()
+			sb.OrdersMap = nil
+		}
+	}
+}
+
+// Migrate moves the resting orders of both sides into the indexes
+// newIndex returns, or back into OrdersMaps when it is nil, under both
+// sides' locks. Only the index changes: priority is kept and no events are
+// published, so a running book can switch without being rebuilt.
+func (ob *OrderBook) Migrate(newIndex func() OrderIndex) {
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	for _, sb := range []*SideBook{&ob.BidBook.SideBook, &ob.AskBook.SideBook} {
+		var index OrderIndex
+		m := make(map[string]*Node, sb.size())
+		if newIndex != nil {
+			index, m = newIndex(), nil
+		}
+		sb.each(func(n *Node) bool {
+			if index != nil {
+				index.Put(n.Key, n)
+			} else {
+				m[n.Key] = n
+			}
+			return true
+		})
+		sb.index, sb.OrdersMap = index, m
+	}
+}
+
+// get, put, del, size and each reach the side's index, or its OrdersMap
+// directly when it has none.
+func (sb *SideBook) get(key string) (*Node, bool) {
+	if sb.index != nil {
+		return sb.index.Get(key)
+	}
+	n, ok := sb.OrdersMap[key]
+	return n, ok
+}
+
+// lookup is get under the lock.
+func (sb *SideBook) lookup(key string) (*Node, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.get(key)
+}
+
+func (sb *SideBook) put(n *Node) {
+	if sb.index != nil {
+		sb.index.Put(n.Key, n)
+		return
+	}
+	sb.OrdersMap[n.Key] = n
+}
+
+func (sb *SideBook) del(key string) {
+	if sb.index != nil {
+		sb.index.Delete(key)
+		return
+	}
+	delete(sb.OrdersMap, key)
+}
+
+func (sb *SideBook) size() int {
+	if sb.index != nil {
+		return sb.index.Len()
+	}
+	return len(sb.OrdersMap)
+}
+
+func (sb *SideBook) each(fn func(*Node) bool) {
+	if sb.index != nil {
+		sb.index.Range(fn)
+		return
+	}
+	for _, n := range sb.OrdersMap {
+		if !fn(n) {
+			return
+		}
+	}
+}
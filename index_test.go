@@ -0,0 +1,128 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestOrderIndex(t *testing.T) {
+	ob := NewOrderBook(WithOrderIndex(func() OrderIndex { return make(mapIndex) }))
+	if ob.BidBook.OrdersMap != nil {
+		t.Errorf("Expected no OrdersMap alongside a custom index")
+	}
+	for i := 0; i < 20; i++ {
+		ob.Submit(NewOrder(float64(90+i%5), 1, "b"+strconv.Itoa(i)), Buy)
+	}
+	ob.Submit(NewOrder(94, 1, "b0"), Buy) // replaces the resting b0
+	ob.Cancel("b1")
+	ob.Submit(NewOrder(90, 2.5, "x"), Sell)
+
+	if n := ob.BidBook.index.Len(); n != 17 || ob.BidBook.Len() != 17 {
+		t.Errorf("Expected 17 bids indexed, got %d of %d", n, ob.BidBook.Len())
+	}
+	if _, ok := ob.BidBook.Get("b1"); ok {
+		t.Errorf("Expected the cancelled b1 to be gone")
+	}
+	if n, ok := ob.BidBook.Get("b2"); !ok || n.Peek().Price != 92 {
+		t.Errorf("Expected b2 resting at 92, got %+v %v", n, ok)
+	}
+	if err := ob.Verify(); err != nil {
+		t.Errorf("Expected the book to verify, got %v", err)
+	}
+	if m := ob.MemoryStats(); m.Orders != 17 {
+		t.Errorf("Expected memory stats over 17 orders, got %d", m.Orders)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 0; i < 10; i++ {
+		ob.Submit(NewOrder(float64(90+i%3), 1, "b"+strconv.Itoa(i)), Buy)
+	}
+	before := ob.Entries(Buy)
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+
+	ob.Migrate(func() OrderIndex { return make(mapIndex) })
+	if ob.BidBook.OrdersMap != nil || ob.BidBook.index.Len() != 10 {
+		t.Fatalf("Expected the bids to move into the new index")
+	}
+	if after := ob.Entries(Buy); !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected priority to be kept, got %+v", after)
+	}
+	if len(sink.diffs) != 0 || len(sink.quotes) != 0 {
+		t.Errorf("Expected no events from a migration, got %d diffs and %d quotes", len(sink.diffs), len(sink.quotes))
+	}
+	ob.Cancel("b3")
+	ob.Migrate(nil)
+	if ob.BidBook.index != nil || len(ob.BidBook.OrdersMap) != 9 {
+		t.Errorf("Expected the bids back in an OrdersMap, got %d", len(ob.BidBook.OrdersMap))
+	}
+	if err := ob.Verify(); err != nil {
+		t.Errorf("Expected the book to verify, got %v", err)
+	}
+}
+
+// mapIndex is a plain map as an OrderIndex, for comparison.
+type mapIndex map[string]*Node
+
+func (m mapIndex) Get(key string) (*Node, bool) {
+	n, ok := m[key]
+	return n, ok
+}
+
+func (m mapIndex) Put(key string, n *Node) { m[key] = n }
+func (m mapIndex) Delete(key string)       { delete(m, key) }
+func (m mapIndex) Len() int                { return len(m) }
+
+func (m mapIndex) Range(fn func(*Node) bool) {
+	for _, n := range m {
+		if !fn(n) {
+			return
+		}
+	}
+}
+
+// BenchmarkOrderIndex cycles an order through a deep book keyed by its
+// OrdersMap and by an OrderIndex over the same kind of map, so the cost of
+// going through the interface shows before an index of its own is tried.
+func BenchmarkOrderIndex(b *testing.B) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"ordersmap", nil},
+		{"index", []Option{WithOrderIndex(func() OrderIndex { return make(mapIndex) })}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			ob := NewOrderBook(tt.opts...)
+			orders := make([]Order, 100000)
+			for i := range orders {
+				orders[i] = NewOrder(float64(100+i), 1, strconv.Itoa(i))
+				n := NewNode(orders[i].OrderId, &orders[i], 1)
+				ob.AskBook.Push(&n)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := orders[i%len(orders)].OrderId
+				n, _ := ob.AskBook.Get(key)
+				ob.AskBook.Remove(key)
+				ob.AskBook.Push(n)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sort"
+)
+
+// Uncross is the price at which a crossed book would match the most
+// volume, as an auction would set it.
+type Uncross struct {
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume"`
+	// Surplus is the bid quantity left unmatched at Price, or the ask
+	// quantity as a negative number.
+	Surplus float64 `json:"surplus"`
+}
+
+// IndicativeEvent is published whenever the book's uncross changes.
+// Crossed is false once the book no longer crosses. It carries no
+// sequence, since it only restates the book.
+type IndicativeEvent struct {
+	Uncross
+	Crossed bool `json:"crossed"`
+}
+
+// IndicativeSink is implemented by event sinks that want the indicative
+// uncross after every change that moves it.
+type IndicativeSink interface {
+	Indicative(*IndicativeEvent)
+}
+
+// Indicative returns the uncross of the book as it stands, and false when
+// the best bid does not reach the best ask. Effective (weighted) prices
+// are used throughout.
+func (ob *OrderBook) Indicative() (Uncross, bool) {
+	ob.BidBook.lock.Lock()
+	bids := levels(ob.BidBook.sortedNodes(), 0)
+	ob.AskBook.lock.Lock()
+	asks := levels(ob.AskBook.sortedNodes(), 0)
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	return uncross(bids, asks)
+}
+
+// IndicativePrice returns the price that would match the most volume
+// right now, and false when the book is not crossed.
+func (ob *OrderBook) IndicativePrice() (float64, bool) {
+	u, ok := ob.Indicative()
+	return u.Price, ok
+}
+
+// IndicativeVolume returns the quantity that would match at the
+// indicative price, or zero when the book is not crossed.
+func (ob *OrderBook) IndicativeVolume() float64 {
+	u, _ := ob.Indicative()
+	return u.Volume
+}
+
+// uncross picks, among the prices of the crossed levels, the one matching
+// the most volume, then the one leaving the smallest surplus. Remaining
+// ties take the middle of the tied prices.
+func uncross(bids, asks []Level) (Uncross, bool) {
+	if len(bids) == 0 || len(asks) == 0 || bids[0].Price < asks[0].Price {
+		return Uncross{}, false
+	}
+	var prices []float64
+	for _, l := range bids {
+		if l.Price >= asks[0].Price {
+			prices = append(prices, l.Price)
+		}
+	}
+	for _, l := range asks {
+		if l.Price <= bids[0].Price {
+			prices = append(prices, l.Price)
+		}
+	}
+	sort.Float64s(prices)
+
+	var best Uncross
+	var low, high float64
+	found := false
+	for i, p := range prices {
+		if i > 0 && p == prices[i-1] {
+			continue
+		}
+		var demand, supply float64
+		for _, l := range bids {
+			if l.Price < p {
+				break
+			}
+			demand += l.Quantity
+		}
+		for _, l := range asks {
+			if l.Price > p {
+				break
+			}
+			supply += l.Quantity
+		}
+		u := Uncross{p, demand, demand - supply}
+		if supply < demand {
+			u.Volume = supply
+		}
+		switch {
+		case !found || u.Volume > best.Volume ||
+			u.Volume == best.Volume && math.Abs(u.Surplus) < math.Abs(best.Surplus):
+			best, low, high, found = u, p, p, true
+		case u.Volume == best.Volume && math.Abs(u.Surplus) == math.Abs(best.Surplus):
+			high = p
+		}
+	}
+	if high != low {
+		best.Price = (low + high) / 2
+	}
+	return best, true
+}
+
+// publishIndicative sends the uncross to indicative sinks when it differs
+// from the last one sent. The caller holds eventLock.
+func (ob *OrderBook) publishIndicative() {
+	var sinks []IndicativeSink
+	for _, s := range ob.sinks {
+		if is, ok := s.(IndicativeSink); ok {
+			sinks = append(sinks, is)
+		}
+	}
+	if len(sinks) == 0 {
+		return
+	}
+	u, crossed := ob.Indicative()
+	e := IndicativeEvent{u, crossed}
+	if ob.uncrossed == e {
+		return
+	}
+	ob.uncrossed = e
+	ob.delivering.Store(true)
+	defer ob.delivering.Store(false)
+	for _, s := range sinks {
+		s.Indicative(&e)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+type indicativeSink struct {
+	recordingSink
+	events []IndicativeEvent
+}
+
+func (s *indicativeSink) Indicative(e *IndicativeEvent) { s.events = append(s.events, *e) }
+
+func TestIndicative(t *testing.T) {
+	ob := NewOrderBook(WithoutMatching())
+	sink := &indicativeSink{}
+	ob.AddSink(sink)
+	if _, ok := ob.IndicativePrice(); ok {
+		t.Errorf("Expected no indicative price on an empty book")
+	}
+	ob.Submit(NewOrder(102, 3, "b1"), Buy)
+	ob.Submit(NewOrder(101, 2, "b2"), Buy)
+	ob.Submit(NewOrder(100, 5, "b3"), Buy)
+	ob.Submit(NewOrder(99, 4, "a1"), Sell)
+	ob.Submit(NewOrder(100, 2, "a2"), Sell)
+	ob.Submit(NewOrder(101, 6, "a3"), Sell)
+
+	u, ok := ob.Indicative()
+	if !ok || u.Price != 100 || u.Volume != 6 || u.Surplus != 4 {
+		t.Errorf("Expected to uncross 6 at 100 with 4 bid left, got %+v (%t)", u, ok)
+	}
+	if ob.IndicativeVolume() != 6 {
+		t.Errorf("Expected indicative volume 6, got %f", ob.IndicativeVolume())
+	}
+
+	// the bids alone do not cross and a3 does not move the uncross, so
+	// only a1 and a2 publish
+	if len(sink.events) != 2 || sink.events[0].Price != 101 || sink.events[0].Volume != 4 {
+		t.Fatalf("Expected a1 to uncross 4 at 101 and a2 to follow, got %+v", sink.events)
+	}
+	if last := sink.events[1]; !last.Crossed || last.Uncross != u {
+		t.Errorf("Expected the last event to match Indicative, got %+v", last)
+	}
+
+	for _, id := range []string{"a1", "a2", "a3"} {
+		ob.Cancel(id)
+	}
+	if last := sink.events[len(sink.events)-1]; last.Crossed {
+		t.Errorf("Expected an uncrossed event once the asks are gone, got %+v", last)
+	}
+}
+
+func TestUncrossTie(t *testing.T) {
+	u, ok := uncross([]Level{{101, 5}}, []Level{{100, 5}})
+	if !ok || u.Price != 100.5 || u.Volume != 5 || u.Surplus != 0 {
+		t.Errorf("Expected a tie between 100 and 101 to uncross at 100.5, got %+v", u)
+	}
+}
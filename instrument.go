@@ -0,0 +1,90 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrTickSize = errors.New("orderbook: price is not a multiple of the tick size")
+	ErrLotSize  = errors.New("orderbook: quantity is not a multiple of the lot size")
+)
+
+// Instrument describes what a book trades. Prices are in Quote currency
+// per unit of Base, and one unit of quantity is Multiplier units of Base,
+// so derivatives can quote per contract. Zero Tick and Lot accept any
+// price and quantity; a zero Multiplier counts as 1.
+type Instrument struct {
+	Symbol     string  `json:"symbol"`
+	Base       string  `json:"base"`
+	Quote      string  `json:"quote"`
+	Tick       float64 `json:"tick,omitempty"`
+	Lot        float64 `json:"lot,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// Notional returns the value of qty at price in the quote currency.
+func (i Instrument) Notional(price, qty float64) float64 {
+	if i.Multiplier == 0 {
+		return price * qty
+	}
+	return price * qty * i.Multiplier
+}
+
+func multipleOf(v, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+	k := v / step
+	return math.Abs(k-math.Round(k)) < 1e-9*math.Max(1, math.Abs(k))
+}
+
+// Validate checks o against the tick and lot sizes.
+func (i Instrument) Validate(o *Order) error {
+	if !multipleOf(o.Price, i.Tick) {
+		return ErrTickSize
+	}
+	if !multipleOf(o.Quantity, i.Lot) {
+		return ErrLotSize
+	}
+	return nil
+}
+
+// passive rounds price to a tick away from the opposite side: bids down
+// and asks up.
+func (i Instrument) passive(side Side, price float64) float64 {
+	if i.Tick <= 0 || multipleOf(price, i.Tick) {
+		return price
+	}
+	if side == Buy {
+		return math.Floor(price/i.Tick) * i.Tick
+	}
+	return math.Ceil(price/i.Tick) * i.Tick
+}
+
+// WithInstrument attaches i to the book. Submitted orders must then fit
+// its tick and lot sizes, pegged prices are rounded passively onto the
+// tick, and notionals, fees and MaxNotional limits use
+// its multiplier.
+func WithInstrument(i Instrument) Option {
+	return func(ob *OrderBook) {
+		ob.instrument = i
+	}
+}
+
+func (ob *OrderBook) Instrument() Instrument {
+	return ob.instrument
+}
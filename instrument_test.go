@@ -0,0 +1,62 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	es := Instrument{Symbol: "ESZ9", Base: "ES", Quote: "USD", Tick: 0.25, Lot: 1, Multiplier: 50}
+	ob := NewOrderBook(WithInstrument(es), WithFees(Fees{TakerBps: 1}), WithValidators(MaxNotional(1e6)))
+	if ob.Instrument() != es {
+		t.Errorf("Expected the attached instrument, got %+v", ob.Instrument())
+	}
+
+	cases := []struct {
+		order Order
+		err   error
+	}{
+		{NewOrder(3000.25, 2, "a"), nil},
+		{NewOrder(3000.1, 1, "b"), ErrTickSize},
+		{NewOrder(3001, 0.5, "c"), ErrLotSize},
+		{NewOrder(3001, 10, "d"), &RejectError{}},
+	}
+	for _, c := range cases {
+		r := ob.Submit(c.order, Sell)
+		var reject *RejectError
+		if _, isReject := c.err.(*RejectError); isReject {
+			if !errors.As(r.Err, &reject) || reject.Value != 3001*10*50 {
+				t.Errorf("Expected %s rejected on notional with the multiplier, got %v", c.order.OrderId, r.Err)
+			}
+		} else if !errors.Is(r.Err, c.err) {
+			t.Errorf("Expected %v for %s, got %v", c.err, c.order.OrderId, r.Err)
+		}
+	}
+
+	if n := ob.Notional(Sell); n != 3000.25*2*50 {
+		t.Errorf("Expected notional with the multiplier, got %g", n)
+	}
+	r := ob.Submit(NewOrder(3000.25, 1, "t"), Buy)
+	if len(r.Trades) != 1 || r.Trades[0].TakerFee != 3000.25*50*1e-4 {
+		t.Errorf("Expected the taker fee on the contract notional, got %+v", r.Trades)
+	}
+
+	ob.Submit(NewOrder(2999, 1, "bid"), Buy)
+	ob.SubmitPeg(NewOrder(0, 1, "mid"), Buy, Peg{Type: PegMidpoint})
+	if o, _, _ := ob.Lookup("mid"); o.Price != 2999.5 {
+		t.Errorf("Expected the midpoint peg rounded down onto the tick, got %g", o.Price)
+	}
+}
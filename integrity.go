@@ -0,0 +1,168 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IntegrityError lists what Verify found wrong with a side's heap and
+// indexes.
+type IntegrityError struct {
+	Side     Side
+	Problems []string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("orderbook: %s side is inconsistent: %s", e.Side, strings.Join(e.Problems, "; "))
+}
+
+// Verify checks that each side's OrdersMap and heap hold the same nodes,
+// that every node's recorded heap index is where it sits, that the heap
+// property holds, and that the age and level indexes agree with them. It
+// returns the first inconsistent side as an *IntegrityError.
+func (ob *OrderBook) Verify() error {
+	for _, side := range []Side{Buy, Sell} {
+		b := ob.Side(side)
+		b.lock.Lock()
+		problems := b.verify()
+		b.lock.Unlock()
+		if len(problems) > 0 {
+			return &IntegrityError{side, problems}
+		}
+	}
+	return nil
+}
+
+// verify returns the side's inconsistencies. The caller holds the lock.
+func (sb *SideBook) verify() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		if len(problems) < 10 {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	h := sb.Orders.BaseHeap
+	if len(h) != sb.size() {
+		add("heap holds %d nodes, map %d", len(h), sb.size())
+	}
+	var indexed int
+	var quantity float64
+	for i, n := range h {
+		if n.index != i {
+			add("node %s at %d records index %d", n.Key, i, n.index)
+		}
+		if m, ok := sb.get(n.Key); !ok || m != n {
+			add("node %s at %d is not the one mapped to its key", n.Key, i)
+		}
+		if i > 0 && sb.Orders.Less(i, (i-1)/2) {
+			add("node %s at %d ranks ahead of its parent", n.Key, i)
+		}
+		if n.indexed {
+			indexed++
+			quantity += n.qty
+		}
+	}
+	if len(sb.byAge) != len(h) {
+		add("age index holds %d nodes, heap %d", len(sb.byAge), len(h))
+	}
+	for i, n := range sb.byAge {
+		if n.ageIndex != i {
+			add("node %s at age %d records %d", n.Key, i, n.ageIndex)
+		}
+	}
+	var levelled int
+	for _, l := range sb.levels.byPrice {
+		levelled += len(l.orders)
+	}
+	if levelled != indexed {
+		add("levels hold %d nodes, %d are indexed", levelled, indexed)
+	}
+	if math.Abs(quantity-sb.levels.quantity) > 1e-9*math.Max(1, quantity) {
+		add("levels total %v, nodes %v", sb.levels.quantity, quantity)
+	}
+	return problems
+}
+
+// Repair rebuilds every index of a side from its OrdersMap, which is
+// taken as the record of what rests, keeping arrival order. It reports
+// whether anything needed rebuilding.
+func (ob *OrderBook) Repair() bool {
+	var repaired bool
+	for _, side := range []Side{Buy, Sell} {
+		b := ob.Side(side)
+		b.lock.Lock()
+		if len(b.verify()) > 0 {
+			b.rebuild()
+			repaired = true
+		}
+		b.lock.Unlock()
+	}
+	return repaired
+}
+
+// rebuild refills the heap, age and level indexes from the map. The
+// caller holds the lock.
+func (sb *SideBook) rebuild() {
+	nodes := make([]*Node, 0, sb.size())
+	sb.each(func(n *Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].seq < nodes[j].seq })
+	sb.Orders.BaseHeap = sb.Orders.BaseHeap[:0]
+	sb.byAge = sb.byAge[:0]
+	for _, l := range sb.levels.byPrice {
+		l.orders = l.orders[:0]
+	}
+	sb.levels = newLevelIndex(len(nodes))
+	for i, n := range nodes {
+		n.index, n.ageIndex = i, i
+		sb.Orders.BaseHeap = append(sb.Orders.BaseHeap, n)
+		sb.byAge = append(sb.byAge, n)
+		sb.levels.add(n)
+	}
+	heap.Init(&sb.Orders)
+	heap.Init(&sb.byAge)
+}
+
+// Watchdog runs Verify every interval until ctx is done. A divergence is
+// logged to the book's Logger, passed to onCorrupt when it is set, and
+// repaired.
+func (ob *OrderBook) Watchdog(ctx context.Context, interval time.Duration, onCorrupt func(error)) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			if err := ob.Verify(); err != nil {
+				if ob.logger != nil {
+					ob.logger.Debug("orderbook: repairing", "error", err.Error())
+				}
+				if onCorrupt != nil {
+					onCorrupt(err)
+				}
+				ob.Repair()
+			}
+		}
+	}
+}
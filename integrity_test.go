@@ -0,0 +1,98 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifyRepair(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(98, 2, "b2"), Buy)
+	ob.Submit(NewOrder(97, 3, "b3"), Buy)
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	if err := ob.Verify(); err != nil {
+		t.Fatalf("Expected a consistent book, got %v", err)
+	}
+	if ob.Repair() {
+		t.Errorf("Expected nothing to repair")
+	}
+
+	// Swap the heap's entries without updating their indexes.
+	h := ob.BidBook.Orders.BaseHeap
+	h[0], h[2] = h[2], h[0]
+	var ierr *IntegrityError
+	if err := ob.Verify(); !errors.As(err, &ierr) || ierr.Side != Buy {
+		t.Fatalf("Expected an IntegrityError on the buy side, got %v", err)
+	}
+	if !ob.Repair() {
+		t.Errorf("Expected the buy side to be repaired")
+	}
+	if err := ob.Verify(); err != nil {
+		t.Errorf("Expected a consistent book after repair, got %v", err)
+	}
+	if o := ob.BidBook.Peek(); o == nil || o.OrderId != "b1" {
+		t.Errorf("Expected b1 back at the top, got %+v", o)
+	}
+	if price, size, ok := ob.BestBid(); !ok || price != 99 || size != 1 {
+		t.Errorf("Expected 1 bid at 99, got %v %v %v", price, size, ok)
+	}
+
+	// Drop a node from the heap that is still mapped.
+	ob.AskBook.Orders.BaseHeap = ob.AskBook.Orders.BaseHeap[:0]
+	if err := ob.Verify(); !errors.As(err, &ierr) || ierr.Side != Sell {
+		t.Fatalf("Expected an IntegrityError on the sell side, got %v", err)
+	}
+	ob.Repair()
+	if o := ob.AskBook.Peek(); o == nil || o.OrderId != "a1" {
+		t.Errorf("Expected a1 restored from the map, got %+v", o)
+	}
+	if err := ob.Verify(); err != nil {
+		t.Errorf("Expected a consistent book after repair, got %v", err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(98, 1, "b2"), Buy)
+	ob.BidBook.Orders.BaseHeap[0].index = 1
+
+	var lock sync.Mutex
+	var found []error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ob.Watchdog(ctx, time.Millisecond, func(err error) {
+		lock.Lock()
+		found = append(found, err)
+		lock.Unlock()
+	})
+	deadline := time.Now().Add(time.Second)
+	for ob.Verify() != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := ob.Verify(); err != nil {
+		t.Fatalf("Expected the watchdog to repair the book, got %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(found) == 0 {
+		t.Errorf("Expected the watchdog to report the divergence")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"sync"
+)
+
+// DepthSnapshot is the full aggregated depth of a book as of Sequence.
+type DepthSnapshot struct {
+	Bids     []Level `json:"bids"`
+	Asks     []Level `json:"asks"`
+	Sequence uint64  `json:"sequence"`
+}
+
+// l2State follows a remote book's depth stream.
+type l2State struct {
+	lock    sync.Mutex
+	tracker SequenceTracker
+	synced  bool
+	buffer  []DepthDiff
+}
+
+// levelKey is the key of the single order representing a level in a book
+// maintained from L2 data.
+func levelKey(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// ApplySnapshot replaces the book with the snapshot's levels, then
+// replays the buffered diffs newer than it in sequence order. If the
+// oldest of those does not follow on from the snapshot a *GapError is
+// returned and the book waits for a newer snapshot. Books with a Capacity
+// refuse L2 updates with ErrCapacity.
+func (ob *OrderBook) ApplySnapshot(s DepthSnapshot) error {
+	if ob.capacity != nil {
+		return ErrCapacity
+	}
+	ob.l2.lock.Lock()
+	defer ob.l2.lock.Unlock()
+
+	ob.setLevels(s.Bids, s.Asks, true)
+	ob.l2.tracker.Resume(s.Sequence)
+	ob.l2.synced = true
+	buffered := ob.l2.buffer
+	ob.l2.buffer = nil
+	for i, d := range buffered {
+		if err := ob.applyDiff(d); err != nil {
+			ob.l2.buffer = append(ob.l2.buffer, buffered[i+1:]...)
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyDiff applies a depth update from a stream numbered consecutively.
+// Until ApplySnapshot has been called, and again after a gap, diffs are
+// buffered. Stale diffs are ignored; a gap returns a *GapError, after
+// which the caller should fetch a new snapshot.
+func (ob *OrderBook) ApplyDiff(d DepthDiff) error {
+	if ob.capacity != nil {
+		return ErrCapacity
+	}
+	ob.l2.lock.Lock()
+	defer ob.l2.lock.Unlock()
+
+	return ob.applyDiff(d)
+}
+
+func (ob *OrderBook) applyDiff(d DepthDiff) error {
+	if !ob.l2.synced {
+		ob.l2.buffer = append(ob.l2.buffer, d)
+		return nil
+	}
+	apply, err := ob.l2.tracker.Observe(d.Sequence)
+	if err != nil {
+		ob.l2.synced = false
+		ob.l2.buffer = append(ob.l2.buffer, d)
+		return err
+	}
+	if apply {
+		ob.setLevels(d.Bids, d.Asks, false)
+	}
+	return nil
+}
+
+// Synced reports whether the book has a snapshot and no gap since.
+func (ob *OrderBook) Synced() bool {
+	ob.l2.lock.Lock()
+	defer ob.l2.lock.Unlock()
+
+	return ob.l2.synced
+}
+
+// setLevels sets the quantity of each level under both sides' locks,
+// clearing the book first when reset is set, and publishes the result as
+// one diff. Zero quantities remove the level.
+func (ob *OrderBook) setLevels(bids, asks []Level, reset bool) {
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	for side, lvls := range [][]Level{bids, asks} {
+		b := ob.Side(Side(side))
+		if reset {
+			b.cancelWhere(func(*Order) bool { return true })
+			b.load(lvls)
+			continue
+		}
+		for _, l := range lvls {
+			key := levelKey(l.Price)
+			_, ok := b.get(key)
+			switch {
+			case l.Quantity == 0:
+				b.cancel(key)
+			case ok:
+				b.amend(key, 0, l.Quantity)
+			default:
+				o := NewOrder(l.Price, l.Quantity, key)
+				n := NewNode(key, &o, 1)
+				b.push(&n)
+			}
+		}
+	}
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	ob.publishPending(true, true, false)
+}
+
+// load rests lvls on an empty side as level orders in one bulk load. A
+// repeated price takes the last quantity given and zero quantities are
+// skipped. The caller holds the lock.
+func (sb *SideBook) load(lvls []Level) {
+	index := make(map[float64]int, len(lvls))
+	var unique []Level
+	for _, l := range lvls {
+		if i, ok := index[l.Price]; ok {
+			unique[i] = l
+			continue
+		}
+		index[l.Price] = len(unique)
+		unique = append(unique, l)
+	}
+	nodes := make([]*Node, 0, len(unique))
+	for _, l := range unique {
+		if l.Quantity == 0 {
+			continue
+		}
+		key := levelKey(l.Price)
+		o := NewOrder(l.Price, l.Quantity, key)
+		n := NewNode(key, &o, 1)
+		nodes = append(nodes, &n)
+	}
+	sb.loadNodes(nodes)
+}
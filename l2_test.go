@@ -0,0 +1,107 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestL2Sync(t *testing.T) {
+	ob := NewOrderBook()
+	diffs := []DepthDiff{
+		{Bids: []Level{{99, 5}}, Sequence: 10}, // older than the snapshot
+		{Bids: []Level{{99, 0}, {98, 1}}, Sequence: 11},
+		{Asks: []Level{{101, 2}}, Sequence: 12},
+	}
+	for _, d := range diffs {
+		if err := ob.ApplyDiff(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ob.Synced() || ob.BidBook.Len() != 0 {
+		t.Fatalf("Expected diffs to be buffered before the snapshot")
+	}
+
+	err := ob.ApplySnapshot(DepthSnapshot{
+		Bids:     []Level{{99, 3}, {97, 4}},
+		Asks:     []Level{{101, 1}, {102, 1}},
+		Sequence: 10,
+	})
+	if err != nil || !ob.Synced() {
+		t.Fatalf("Expected sync, got %v", err)
+	}
+	bids, asks := ob.Depth(0)
+	if !reflect.DeepEqual(bids, []Level{{98, 1}, {97, 4}}) || !reflect.DeepEqual(asks, []Level{{101, 2}, {102, 1}}) {
+		t.Errorf("Expected buffered diffs replayed on the snapshot, got %v %v", bids, asks)
+	}
+
+	if err := ob.ApplyDiff(DepthDiff{Asks: []Level{{103, 1}}, Sequence: 14}); err == nil {
+		t.Fatalf("Expected a gap error")
+	} else if _, ok := err.(*GapError); !ok {
+		t.Fatalf("Expected a *GapError, got %v", err)
+	}
+	if ob.Synced() {
+		t.Errorf("Expected a gap to require a new snapshot")
+	}
+	ob.ApplyDiff(DepthDiff{Asks: []Level{{102, 0}}, Sequence: 15})
+	if err := ob.ApplySnapshot(DepthSnapshot{Bids: []Level{{99, 1}}, Asks: []Level{{101, 1}}, Sequence: 13}); err != nil {
+		t.Fatal(err)
+	}
+	bids, asks = ob.Depth(0)
+	if !reflect.DeepEqual(bids, []Level{{99, 1}}) || !reflect.DeepEqual(asks, []Level{{101, 1}, {103, 1}}) {
+		t.Errorf("Expected the new snapshot plus diffs 14 and 15, got %v %v", bids, asks)
+	}
+}
+
+func TestApplySnapshotEvents(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(50, 1, "stale"))
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	ob.ApplySnapshot(DepthSnapshot{Bids: []Level{{99, 1}}, Asks: []Level{{101, 1}}, Sequence: 1})
+	if len(sink.diffs) != 1 {
+		t.Fatalf("Expected one diff for the snapshot, got %d", len(sink.diffs))
+	}
+	if d := sink.diffs[0]; !reflect.DeepEqual(d.Bids, []Level{{50, 0}, {99, 1}}) {
+		t.Errorf("Expected the snapshot to clear old levels, got %v", d.Bids)
+	}
+}
+
+func TestApplySnapshotBulkLoad(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(50, 1, "stale"), Buy)
+	var bids []Level
+	for i := 0; i < 100; i++ {
+		bids = append(bids, Level{Price: float64(1 + (i*37)%100), Quantity: 1})
+	}
+	bids = append(bids, Level{Price: 99, Quantity: 7}, Level{Price: 3, Quantity: 0})
+	if err := ob.ApplySnapshot(DepthSnapshot{Bids: bids, Sequence: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ob.OrderCount(Buy) != 99 {
+		t.Errorf("Expected 99 levels after deduplicating and dropping zeroes, got %d", ob.OrderCount(Buy))
+	}
+	if o, _, _ := ob.Lookup("99"); o.Quantity != 7 {
+		t.Errorf("Expected the last quantity for a repeated price, got %v", o.Quantity)
+	}
+	last := 101.0
+	for ob.BidBook.Len() > 0 {
+		n := ob.BidBook.Pop()
+		if n.Peek().Price >= last {
+			t.Fatalf("Expected bids to pop best first, got %v after %v", n.Peek().Price, last)
+		}
+		last = n.Peek().Price
+	}
+}
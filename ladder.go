@@ -0,0 +1,64 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "sort"
+
+// NextLevelAbove returns the aggregated level on side at the lowest
+// effective price strictly above price, and false if there is none.
+func (ob *OrderBook) NextLevelAbove(price float64, side Side) (Level, bool) {
+	return ob.Side(side).nextLevel(price, true)
+}
+
+// NextLevelBelow returns the aggregated level on side at the highest
+// effective price strictly below price, and false if there is none.
+func (ob *OrderBook) NextLevelBelow(price float64, side Side) (Level, bool) {
+	return ob.Side(side).nextLevel(price, false)
+}
+
+// NthLevel returns the nth aggregated level on side counting from the
+// best, which is level 0, and false if the side has no more than n levels.
+func (ob *OrderBook) NthLevel(side Side, n int) (Level, bool) {
+	b := ob.Side(side)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	prices := b.levels.prices
+	if n < 0 || n >= len(prices) {
+		return Level{}, false
+	}
+	if side == Buy {
+		n = len(prices) - 1 - n
+	}
+	return Level{prices[n], b.levels.quantityAt(prices[n])}, true
+}
+
+// nextLevel finds the nearest level above or below price by a binary
+// search of the sorted level prices.
+func (sb *SideBook) nextLevel(price float64, above bool) (Level, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	prices := sb.levels.prices
+	var i int
+	if above {
+		i = sort.Search(len(prices), func(i int) bool { return prices[i] > price })
+	} else {
+		i = sort.SearchFloat64s(prices, price) - 1
+	}
+	if i < 0 || i >= len(prices) {
+		return Level{}, false
+	}
+	return Level{prices[i], sb.levels.quantityAt(prices[i])}, true
+}
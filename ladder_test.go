@@ -0,0 +1,51 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestLadder(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(99, 2, "b2"), Buy)
+	ob.Submit(NewOrder(97, 3, "b3"), Buy)
+	ob.Submit(NewOrder(95, 1, "b4"), Buy)
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)
+	ob.Submit(NewOrder(104, 2, "a2"), Sell)
+
+	tests := []struct {
+		name     string
+		got      func() (Level, bool)
+		expected Level
+		ok       bool
+	}{
+		{"bid above 96", func() (Level, bool) { return ob.NextLevelAbove(96, Buy) }, Level{97, 3}, true},
+		{"bid above 97", func() (Level, bool) { return ob.NextLevelAbove(97, Buy) }, Level{99, 3}, true},
+		{"bid above 99", func() (Level, bool) { return ob.NextLevelAbove(99, Buy) }, Level{}, false},
+		{"bid below 99", func() (Level, bool) { return ob.NextLevelBelow(99, Buy) }, Level{97, 3}, true},
+		{"bid below 95", func() (Level, bool) { return ob.NextLevelBelow(95, Buy) }, Level{}, false},
+		{"ask above mid", func() (Level, bool) { return ob.NextLevelAbove(100, Sell) }, Level{101, 1}, true},
+		{"ask below 110", func() (Level, bool) { return ob.NextLevelBelow(110, Sell) }, Level{104, 2}, true},
+		{"best bid", func() (Level, bool) { return ob.NthLevel(Buy, 0) }, Level{99, 3}, true},
+		{"third bid", func() (Level, bool) { return ob.NthLevel(Buy, 2) }, Level{95, 1}, true},
+		{"past the bids", func() (Level, bool) { return ob.NthLevel(Buy, 3) }, Level{}, false},
+		{"second ask", func() (Level, bool) { return ob.NthLevel(Sell, 1) }, Level{104, 2}, true},
+		{"negative", func() (Level, bool) { return ob.NthLevel(Sell, -1) }, Level{}, false},
+	}
+	for _, tt := range tests {
+		if l, ok := tt.got(); l != tt.expected || ok != tt.ok {
+			t.Errorf("Expected %s to be %+v %v, got %+v %v", tt.name, tt.expected, tt.ok, l, ok)
+		}
+	}
+}
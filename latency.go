@@ -0,0 +1,155 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// subBits sets the histogram's precision: each power of two is split into
+// 2^subBits linear buckets, bounding the relative error at about 3%.
+const (
+	subBits    = 5
+	subBuckets = 1 << subBits
+	numBuckets = (64 - subBits) * subBuckets
+)
+
+// Histogram is a log-linear histogram of durations in the style of HDR
+// histograms. Recording is lock-free and safe for concurrent use.
+type Histogram struct {
+	counts [numBuckets]uint64
+	count  uint64
+	sum    uint64
+	max    uint64
+}
+
+func bucketOf(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - 1 - subBits
+	return (e+1)*subBuckets + int(v>>uint(e)-subBuckets)
+}
+
+// bucketMax returns the largest value counted in bucket i.
+func bucketMax(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+	e := uint(i/subBuckets - 1)
+	m := uint64(i%subBuckets + subBuckets)
+	return (m+1)<<e - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	v := uint64(d)
+	atomic.AddUint64(&h.counts[bucketOf(v)], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddUint64(&h.sum, v)
+	for {
+		max := atomic.LoadUint64(&h.max)
+		if v <= max || atomic.CompareAndSwapUint64(&h.max, max, v) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+func (h *Histogram) Sum() time.Duration {
+	return time.Duration(atomic.LoadUint64(&h.sum))
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(atomic.LoadUint64(&h.max))
+}
+
+func (h *Histogram) Mean() time.Duration {
+	n := h.Count()
+	if n == 0 {
+		return 0
+	}
+	return h.Sum() / time.Duration(n)
+}
+
+// Quantile returns the duration at or below which fraction q of the
+// recordings fall, to within the histogram's precision.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	n := h.Count()
+	if n == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+	max := atomic.LoadUint64(&h.max)
+	var seen uint64
+	for i := range h.counts {
+		if seen += atomic.LoadUint64(&h.counts[i]); seen >= rank {
+			if v := bucketMax(i); v < max {
+				return time.Duration(v)
+			}
+			break
+		}
+	}
+	return time.Duration(max)
+}
+
+type LatencyOp int
+
+const (
+	LatencyPush LatencyOp = iota
+	LatencyMatch
+	LatencyRemove
+)
+
+var LatencyOps = []LatencyOp{LatencyPush, LatencyMatch, LatencyRemove}
+
+func (op LatencyOp) String() string {
+	return [...]string{"push", "match", "remove"}[op]
+}
+
+type latencies [3]Histogram
+
+func (l *latencies) since(op LatencyOp, start time.Time) {
+	l[op].Record(time.Since(start))
+}
+
+// WithLatency times every Push, Match and Remove, including publishing
+// the events they cause, into a histogram per operation.
+func WithLatency() Option {
+	return func(ob *OrderBook) {
+		ob.latency = &latencies{}
+		ob.AskBook.latency = ob.latency
+		ob.BidBook.latency = ob.latency
+	}
+}
+
+// Latency returns the histogram for op, or nil unless the book was built
+// WithLatency.
+func (ob *OrderBook) Latency(op LatencyOp) *Histogram {
+	if ob.latency == nil {
+		return nil
+	}
+	return &ob.latency[op]
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	if h.Count() != 1000 || h.Max() != time.Millisecond {
+		t.Errorf("Expected 1000 recordings up to 1ms, got %d up to %v", h.Count(), h.Max())
+	}
+	if mean := h.Mean(); mean != 500500*time.Nanosecond {
+		t.Errorf("Expected mean 500.5us, got %v", mean)
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999, 1} {
+		want := q * float64(time.Millisecond)
+		if got := float64(h.Quantile(q)); math.Abs(got-want)/want > 0.04 {
+			t.Errorf("Expected quantile %g within 4%% of %v, got %v", q, time.Duration(want), time.Duration(got))
+		}
+	}
+	if (&Histogram{}).Quantile(0.5) != 0 {
+		t.Errorf("Expected an empty histogram to report zero")
+	}
+	for _, v := range []uint64{0, 31, 32, 33, 1000, 1 << 40, math.MaxUint64} {
+		if i := bucketOf(v); bucketMax(i) < v || (i > 0 && bucketMax(i-1) >= v) {
+			t.Errorf("Expected %d to fall in bucket %d ending at %d", v, i, bucketMax(i))
+		}
+	}
+}
+
+func TestLatency(t *testing.T) {
+	if NewOrderBook().Latency(LatencyPush) != nil {
+		t.Errorf("Expected no histograms without WithLatency")
+	}
+	ob := NewOrderBook(WithLatency())
+	ob.Submit(NewOrder(101, 1, "a"), Sell)
+	ob.Submit(NewOrder(101, 2, "b"), Buy)
+	ob.Cancel("b")
+	counts := map[LatencyOp]uint64{LatencyPush: 2, LatencyMatch: 2, LatencyRemove: 1}
+	for op, n := range counts {
+		if got := ob.Latency(op).Count(); got != n {
+			t.Errorf("Expected %d %s timings, got %d", n, op, got)
+		}
+	}
+}
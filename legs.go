@@ -0,0 +1,198 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNoLegs          = errors.New("orderbook: a multi-leg order needs legs")
+	ErrLegsUnfillable  = errors.New("orderbook: not every leg can fill within its limit")
+	ErrLegsIncomplete  = errors.New("orderbook: a leg fell short after others had filled")
+	ErrLegsNotMatching = errors.New("orderbook: a leg's book does not match orders")
+)
+
+// Leg is one order of a multi-leg order, for the tenant's book for Symbol.
+type Leg struct {
+	Symbol string `json:"symbol"`
+	Side   Side   `json:"side"`
+	Order  Order  `json:"order"`
+}
+
+// SubmitLegs fills every leg of a multi-leg order in full, each within its
+// limit, or none of them; remainders never rest. The legs' books are held
+// under their match locks, taken in symbol order, from the check that all
+// legs can fill until the last has matched, so no other order can trade
+// in between. Cancels and pushes do not take the match lock, though: if
+// one takes liquidity a checked leg needed, the legs already filled stand
+// and ErrLegsIncomplete is returned with every leg's report. Legs are
+// admitted like Submit, and their stops run once all have matched. legs is
+// left as it is.
+func (m *BookManager) SubmitLegs(name string, legs []Leg) ([]ExecutionReport, error) {
+	if len(legs) == 0 {
+		return nil, ErrNoLegs
+	}
+	legs = append([]Leg(nil), legs...)
+	books := make([]*OrderBook, len(legs))
+	for i, l := range legs {
+		ob, err := m.Book(name, l.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		if ob.noMatching {
+			return nil, ErrLegsNotMatching
+		}
+		books[i] = ob
+	}
+	reports := make([]ExecutionReport, len(legs))
+	for i := range legs {
+		l, ob := &legs[i], books[i]
+		ob.assignId(&l.Order)
+		reports[i] = ExecutionReport{OrderId: l.Order.OrderId, ClientOrderId: l.Order.ClientOrderId,
+			Side: l.Side, Remaining: l.Order.Quantity}
+		err := ob.admit(l.Side, &l.Order)
+		if err == nil {
+			err = ob.preMatch(l.Side, &l.Order)
+		}
+		if err != nil {
+			ob.reject(&reports[i], err)
+			return reports, err
+		}
+	}
+
+	held := lockBooks(legs, books)
+	received := make([]time.Duration, len(legs))
+	if !legsFillable(legs, books) {
+		unlockBooks(held)
+		for i := range reports {
+			books[i].reject(&reports[i], ErrLegsUnfillable)
+		}
+		return reports, ErrLegsUnfillable
+	}
+	var err error
+	for i := range legs {
+		l, ob, r := &legs[i], books[i], &reports[i]
+		received[i] = ob.monotonic()
+		var hookErr error
+		r.Trades, hookErr = ob.match(l.Side, &l.Order)
+		ob.fill(&l.Order, r)
+		if l.Order.Quantity > 0 {
+			r.Status, r.Err = StatusCanceled, ErrLegsIncomplete
+			if r.Filled > 0 {
+				r.Status = StatusPartiallyFilled
+			}
+			err = ErrLegsIncomplete
+		} else if hookErr != nil {
+			r.Err = hookErr
+		}
+		ob.postMatch(r)
+		if err != nil {
+			break
+		}
+	}
+	unlockBooks(held)
+
+	for i := range reports {
+		r := &reports[i]
+		if len(r.Trades) > 0 {
+			r.FillLatency = fillLatency(r.Trades, received[i])
+			r.Triggered = books[i].runStops(tradePrices(r.Trades))
+		}
+	}
+	return reports, err
+}
+
+// lockBooks takes the match lock of each distinct book the legs use, in
+// symbol order, and returns the books held.
+func lockBooks(legs []Leg, books []*OrderBook) []*OrderBook {
+	bySymbol := make(map[string]*OrderBook)
+	for i, l := range legs {
+		bySymbol[l.Symbol] = books[i]
+	}
+	symbols := make([]string, 0, len(bySymbol))
+	for s := range bySymbol {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	held := make([]*OrderBook, len(symbols))
+	for i, s := range symbols {
+		held[i] = bySymbol[s]
+		held[i].lockMatching()
+	}
+	return held
+}
+
+func unlockBooks(held []*OrderBook) {
+	for i := len(held) - 1; i >= 0; i-- {
+		held[i].unlockMatching()
+	}
+}
+
+// legsFillable reports whether every leg can fill in full against its
+// book as it stands. Legs on the same book and side share its liquidity,
+// so they are checked together at the least aggressive of their limits.
+// The caller holds the books' match locks.
+func legsFillable(legs []Leg, books []*OrderBook) bool {
+	type want struct {
+		ob    *OrderBook
+		side  Side
+		limit float64
+		qty   float64
+	}
+	var wants []*want
+	for i, l := range legs {
+		limit := l.Order.Price * books[i].weight(&l.Order)
+		var w *want
+		for _, other := range wants {
+			if other.ob == books[i] && other.side == l.Side {
+				w = other
+			}
+		}
+		if w == nil {
+			w = &want{ob: books[i], side: l.Side, limit: limit}
+			wants = append(wants, w)
+		} else if l.Side == Buy {
+			w.limit = math.Min(w.limit, limit)
+		} else {
+			w.limit = math.Max(w.limit, limit)
+		}
+		w.qty += l.Order.Quantity
+	}
+	for _, w := range wants {
+		if w.ob.fillable(w.side, w.limit) < w.qty {
+			return false
+		}
+	}
+	return true
+}
+
+// fillable returns the quantity resting opposite side that an order
+// limited to the effective price limit could reach.
+func (ob *OrderBook) fillable(side Side, limit float64) float64 {
+	b := ob.Side(side.Opposite())
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var qty float64
+	for price := range b.levels.byPrice {
+		if permits(side, limit, price) {
+			qty += b.levels.quantityAt(price)
+		}
+	}
+	return qty
+}
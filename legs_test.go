@@ -0,0 +1,69 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestSubmitLegs(t *testing.T) {
+	m := NewBookManager()
+	m.AddTenant("acme", TenantQuota{})
+	btc, _ := m.CreateBook("acme", "BTCUSD", WithIDGenerator(MonotonicIDs("g")))
+	eth, _ := m.CreateBook("acme", "ETHUSD")
+	btc.Submit(NewOrder(100, 5, "a1"), Sell)
+	eth.Submit(NewOrder(50, 2, "b1"), Buy)
+
+	legs := []Leg{
+		{"BTCUSD", Buy, NewOrder(100, 3, "")},
+		{"ETHUSD", Sell, NewOrder(50, 2, "x2")},
+	}
+	reports, err := m.SubmitLegs("acme", legs)
+	if err != nil || len(reports) != 2 || reports[0].Filled != 3 || reports[1].Filled != 2 {
+		t.Fatalf("Expected both legs filled, got %v %+v", err, reports)
+	}
+	if reports[0].OrderId != "g1" || legs[0].Order.OrderId != "" || legs[0].Order.Quantity != 3 {
+		t.Errorf("Expected leg g1 reported and the legs left as they were, got %s and %+v", reports[0].OrderId, legs[0].Order)
+	}
+
+	tests := []struct {
+		name string
+		legs []Leg
+		err  error
+	}{
+		{"a leg with no liquidity", []Leg{
+			{"BTCUSD", Buy, NewOrder(100, 1, "y1")},
+			{"ETHUSD", Sell, NewOrder(50, 1, "y2")},
+		}, ErrLegsUnfillable},
+		{"a leg beyond its limit", []Leg{
+			{"BTCUSD", Buy, NewOrder(99, 1, "y1")},
+		}, ErrLegsUnfillable},
+		{"legs sharing a side", []Leg{
+			{"BTCUSD", Buy, NewOrder(100, 1, "y1")},
+			{"BTCUSD", Buy, NewOrder(100, 2, "y2")},
+		}, ErrLegsUnfillable},
+		{"an unknown book", []Leg{{"SOLUSD", Buy, NewOrder(100, 1, "y1")}}, ErrUnknownSymbol},
+		{"an invalid leg", []Leg{{"BTCUSD", Buy, NewOrder(100, 0, "y1")}}, ErrInvalidQuantity},
+		{"no legs", nil, ErrNoLegs},
+	}
+	for _, tt := range tests {
+		if _, err := m.SubmitLegs("acme", tt.legs); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+	if o, _, ok := btc.Lookup("a1"); !ok || o.Quantity != 2 {
+		t.Errorf("Expected the refused legs to leave a1 at 2, got %+v", o)
+	}
+	if btc.BidBook.Len()+eth.AskBook.Len() != 0 {
+		t.Errorf("Expected no leg to rest")
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// level holds the nodes resting at one effective price in time priority.
+type level struct {
+	price  float64
+	orders []*Node
+}
+
+// levelPool recycles emptied levels, so a price that keeps emptying and
+// refilling does not allocate.
+var levelPool = sync.Pool{New: func() interface{} { return new(level) }}
+
+// levelIndex maps effective prices to their levels. Nodes whose Item has
+// no order are not indexed. It also keeps the side's total quantity and
+// notional current as nodes come and go, so neither needs a scan.
+type levelIndex struct {
+	byPrice map[float64]*level
+	// prices holds the keys of byPrice in ascending order.
+	prices   []float64
+	quantity float64
+	notional float64
+}
+
+func newLevelIndex(size int) levelIndex {
+	return levelIndex{byPrice: make(map[float64]*level, size)}
+}
+
+func (li *levelIndex) add(n *Node) {
+	o := n.Peek()
+	if o == nil {
+		n.indexed = false
+		return
+	}
+	n.price, n.qty, n.indexed = o.Price*n.Weight, o.Quantity, true
+	li.quantity += n.qty
+	li.notional += n.price * n.qty
+	l, ok := li.byPrice[n.price]
+	if !ok {
+		l = levelPool.Get().(*level)
+		l.price = n.price
+		li.byPrice[n.price] = l
+		i := sort.SearchFloat64s(li.prices, n.price)
+		li.prices = append(li.prices, 0)
+		copy(li.prices[i+1:], li.prices[i:])
+		li.prices[i] = n.price
+	}
+	i := sort.Search(len(l.orders), func(i int) bool { return l.orders[i].seq > n.seq })
+	l.orders = append(l.orders, nil)
+	copy(l.orders[i+1:], l.orders[i:])
+	l.orders[i] = n
+}
+
+func (li *levelIndex) remove(n *Node) {
+	if !n.indexed {
+		return
+	}
+	n.indexed = false
+	l, ok := li.byPrice[n.price]
+	if !ok {
+		return
+	}
+	li.quantity -= n.qty
+	li.notional -= n.price * n.qty
+	i := sort.Search(len(l.orders), func(i int) bool { return l.orders[i].seq >= n.seq })
+	if i < len(l.orders) && l.orders[i] == n {
+		copy(l.orders[i:], l.orders[i+1:])
+		l.orders[len(l.orders)-1] = nil
+		l.orders = l.orders[:len(l.orders)-1]
+	}
+	if len(l.orders) == 0 {
+		delete(li.byPrice, n.price)
+		if i := sort.SearchFloat64s(li.prices, n.price); i < len(li.prices) && li.prices[i] == n.price {
+			li.prices = append(li.prices[:i], li.prices[i+1:]...)
+		}
+		l.orders = l.orders[:0]
+		levelPool.Put(l)
+	}
+	if len(li.byPrice) == 0 {
+		// Reset rather than carry rounding left over from the running sums.
+		li.quantity, li.notional = 0, 0
+	}
+}
+
+// move re-indexes n after its effective price may have changed.
+func (li *levelIndex) move(n *Node) {
+	li.remove(n)
+	li.add(n)
+}
+
+// resize updates the totals after n's quantity changed in place.
+func (li *levelIndex) resize(n *Node) {
+	if !n.indexed {
+		return
+	}
+	qty := n.Peek().Quantity
+	li.quantity += qty - n.qty
+	li.notional += n.price * (qty - n.qty)
+	n.qty = qty
+}
+
+// eachLevel calls fn with the side's levels priced from min to max, best
+// first, until it returns false. Only those levels are visited. The
+// caller holds the lock.
+func (sb *SideBook) eachLevel(min, max float64, fn func(*level) bool) {
+	prices := sb.levels.prices
+	prices = prices[sort.SearchFloat64s(prices, min):]
+	prices = prices[:sort.Search(len(prices), func(i int) bool { return prices[i] > max })]
+	for i := range prices {
+		p := prices[i]
+		if sb.side == Buy {
+			p = prices[len(prices)-1-i]
+		}
+		if !fn(sb.levels.byPrice[p]) {
+			return
+		}
+	}
+}
+
+func (li *levelIndex) level(price float64) (*level, bool) {
+	l, ok := li.byPrice[price]
+	return l, ok
+}
+
+func (li *levelIndex) quantityAt(price float64) float64 {
+	var total float64
+	if l, ok := li.byPrice[price]; ok {
+		for _, n := range l.orders {
+			total += n.Peek().Quantity
+		}
+	}
+	return total
+}
+
+type QueuePosition struct {
+	Side     Side
+	Price    float64 // effective price of the order's level
+	Orders   int     // resting orders ahead at the same price
+	Quantity float64 // quantity ahead at the same price
+}
+
+// position counts the orders ahead of n in the level, ranked by c when it
+// is set.
+func (l *level) position(n *Node, c Comparator) (int, float64) {
+	orders := l.orders
+	if c != nil {
+		orders = append([]*Node(nil), orders...)
+		byComparator(orders, c)
+	}
+	var ahead float64
+	for i, other := range orders {
+		if other == n {
+			return i, ahead
+		}
+		ahead += other.Peek().Quantity
+	}
+	return len(orders), ahead
+}
+
+// QueuePosition reports how much resting interest is ahead of the order
+// with the given key at its price level.
+func (ob *OrderBook) QueuePosition(key string) (QueuePosition, bool) {
+	if pos, ok := ob.BidBook.queuePosition(key); ok {
+		return pos, true
+	}
+	return ob.AskBook.queuePosition(key)
+}
+
+// Reduce decreases the quantity of a resting order without affecting its
+// priority, removing it once nothing remains. It returns the remaining
+// quantity and whether the order was found.
+func (ob *OrderBook) Reduce(key string, qty float64) (float64, bool) {
+	if remaining, ok := ob.BidBook.reduce(key, qty); ok {
+		return remaining, true
+	}
+	return ob.AskBook.reduce(key, qty)
+}
+
+func (ob *OrderBook) OrderCount(side Side) int {
+	return ob.Side(side).Len()
+}
+
+// LevelCount returns the number of distinct effective prices on side.
+func (ob *OrderBook) LevelCount(side Side) int {
+	return ob.Side(side).levelCount()
+}
+
+// BestBid returns the best bid price and the aggregate quantity resting
+// at it.
+func (ob *OrderBook) BestBid() (price, size float64, ok bool) {
+	if ob.view != nil {
+		return ob.viewBest(Buy)
+	}
+	return ob.BidBook.best()
+}
+
+func (ob *OrderBook) BestAsk() (price, size float64, ok bool) {
+	if ob.view != nil {
+		return ob.viewBest(Sell)
+	}
+	return ob.AskBook.best()
+}
+
+// Notional returns the sum of effective price times quantity on side,
+// scaled by the instrument's multiplier.
+func (ob *OrderBook) Notional(side Side) float64 {
+	return ob.instrument.Notional(ob.Side(side).notional(), 1)
+}
+
+// Entry is a copy of a resting order together with its book key and
+// weight.
+type Entry struct {
+	Side   Side      `json:"side"`
+	Key    string    `json:"key"`
+	Order  Order     `json:"order"`
+	Weight float64   `json:"weight"`
+	Time   time.Time `json:"time"`
+}
+
+// Entries returns copies of every resting order on side in priority order.
+// Pushing them back in the same order reproduces the side exactly.
+func (ob *OrderBook) Entries(side Side) []Entry {
+	if ob.view != nil {
+		return ob.viewEntries(side)
+	}
+	b := ob.Side(side)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return entries(side, b.sortedNodes())
+}
+
+func entries(side Side, nodes BaseHeap) []Entry {
+	entries := make([]Entry, 0, len(nodes))
+	for _, n := range nodes {
+		if o := n.Peek(); o != nil {
+			entries = append(entries, Entry{side, n.Key, *o, n.Weight, n.Time})
+		}
+	}
+	return entries
+}
+
+// Restore pushes entries onto their sides in the order given.
+func (ob *OrderBook) Restore(entries []Entry) {
+	for _, e := range entries {
+		o := e.Order
+		n := NewNode(e.Key, &o, e.Weight)
+		n.Time = e.Time
+		ob.Side(e.Side).Push(&n)
+	}
+}
+
+// Depth returns up to n aggregated levels per side, best first. A
+// non-positive n returns every level.
+func (ob *OrderBook) Depth(n int) (bids, asks []Level) {
+	if ob.view != nil {
+		return ob.viewDepth(n)
+	}
+	return ob.BidBook.depth(n), ob.AskBook.depth(n)
+}
+
+// depth returns up to n of the side's levels, best first, read from the
+// level index. A non-positive n returns every level.
+func (sb *SideBook) depth(n int) []Level {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	var lvls []Level
+	sb.eachLevel(math.Inf(-1), math.Inf(1), func(l *level) bool {
+		if n > 0 && len(lvls) == n {
+			return false
+		}
+		lvls = append(lvls, Level{l.price, sb.levels.quantityAt(l.price)})
+		return true
+	})
+	return lvls
+}
+
+// bucket merges levels, best first, into buckets of width size, keeping
+// at most n. Bid prices round down to their bucket and ask prices up, so a
+// bucketed quote is never better than the book.
+func bucket(lvls []Level, size float64, n int, up bool) []Level {
+	var out []Level
+	for _, l := range lvls {
+		k := l.Price / size
+		if up {
+			k = math.Ceil(k - 1e-9)
+		} else {
+			k = math.Floor(k + 1e-9)
+		}
+		price := math.Round(k*size*1e9) / 1e9
+		if len(out) > 0 && out[len(out)-1].Price == price {
+			out[len(out)-1].Quantity += l.Quantity
+			continue
+		}
+		if n > 0 && len(out) == n {
+			break
+		}
+		out = append(out, Level{price, l.Quantity})
+	}
+	return out
+}
+
+// BucketDepth is Depth with levels merged into price buckets of width
+// size, up to n buckets per side. The book itself is unaffected.
+func (ob *OrderBook) BucketDepth(n int, size float64) (bids, asks []Level) {
+	if size <= 0 {
+		return ob.Depth(n)
+	}
+	bids, asks = ob.Depth(0)
+	return bucket(bids, size, n, false), bucket(asks, size, n, true)
+}
+
+// levelRank returns how many of the side's levels rank ahead of the
+// effective price, by a binary search of the sorted level prices.
+func (sb *SideBook) levelRank(price float64) int {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	prices := sb.levels.prices
+	if sb.side == Buy {
+		return len(prices) - sort.Search(len(prices), func(i int) bool { return prices[i] > price })
+	}
+	return sort.SearchFloat64s(prices, price)
+}
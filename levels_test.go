@@ -0,0 +1,211 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTimePriority(t *testing.T) {
+	ob := NewOrderBook()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		o := NewOrder(100, 1, id)
+		node := NewNode(id, &o, 1)
+		ob.BidBook.Push(&node)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if n := ob.BidBook.Pop(); n.Key != id {
+			t.Errorf("Expected %s next at an equal price, got %s", id, n.Key)
+		}
+	}
+}
+
+func TestQueuePosition(t *testing.T) {
+	ob := NewOrderBook()
+	orders := []Order{
+		NewOrder(100, 5, "a"),
+		NewOrder(100, 3, "b"),
+		NewOrder(99, 7, "c"),
+		NewOrder(100, 2, "d"),
+	}
+	for i := range orders {
+		node := NewNode(orders[i].OrderId, &orders[i], 1)
+		ob.BidBook.Push(&node)
+	}
+
+	tests := []struct {
+		Name     string
+		Key      string
+		Orders   int
+		Quantity float64
+		Mutate   func()
+	}{
+		{"front", "a", 0, 0, nil},
+		{"behind-two", "d", 2, 8, nil},
+		{"other-level", "c", 0, 0, nil},
+		{"after-partial-fill", "d", 2, 6, func() {
+			sell := NewOrder(100, 2, "x")
+			ob.Match(Sell, &sell)
+		}},
+		{"after-cancel", "d", 1, 3, func() { ob.BidBook.Remove("a") }},
+		{"after-reprice", "d", 0, 0, func() {
+			n, _ := ob.BidBook.Get("b")
+			n.Peek().Price = 99
+			ob.BidBook.Fix("b")
+		}},
+		{"reprice-keeps-arrival", "b", 0, 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Mutate != nil {
+				test.Mutate()
+			}
+			pos, ok := ob.QueuePosition(test.Key)
+			if !ok {
+				t.Fatalf("Expected %s to be resting", test.Key)
+			}
+			if pos.Side != Buy || pos.Orders != test.Orders || pos.Quantity != test.Quantity {
+				t.Errorf("Expected %d orders and %f ahead, got %+v", test.Orders, test.Quantity, pos)
+			}
+		})
+	}
+	if _, ok := ob.QueuePosition("a"); ok {
+		t.Errorf("Expected no position for a canceled order")
+	}
+}
+
+func TestReduce(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	asks := []Order{NewOrder(101, 5, "a"), NewOrder(101, 3, "b"), NewOrder(102, 1, "c")}
+	for i := range asks {
+		node := NewNode(asks[i].OrderId, &asks[i], 1)
+		ob.AskBook.Push(&node)
+	}
+	sink.diffs = nil
+
+	if remaining, ok := ob.Reduce("a", 2); !ok || remaining != 3 {
+		t.Errorf("Expected 3 remaining, got %f (found %t)", remaining, ok)
+	}
+	if pos, _ := ob.QueuePosition("b"); pos.Orders != 1 || pos.Quantity != 3 {
+		t.Errorf("Expected a to keep priority ahead of b, got %+v", pos)
+	}
+	if remaining, ok := ob.Reduce("a", 5); !ok || remaining != 0 {
+		t.Errorf("Expected a to be exhausted, got %f (found %t)", remaining, ok)
+	}
+	if _, ok := ob.AskBook.Get("a"); ok {
+		t.Errorf("Expected a to be removed once exhausted")
+	}
+	if _, ok := ob.Reduce("missing", 1); ok {
+		t.Errorf("Expected unknown order not to be found")
+	}
+
+	expected := []Level{{101, 6}, {101, 3}}
+	if len(sink.diffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, got %d", len(expected), len(sink.diffs))
+	}
+	for i, d := range sink.diffs {
+		if len(d.Asks) != 1 || d.Asks[0] != expected[i] {
+			t.Errorf("Expected diff %d to set %+v, got %+v", i, expected[i], d.Asks)
+		}
+	}
+	bids, asks2 := ob.Activity()
+	if bids.Reduces != 0 || asks2.Reduces != 1 || asks2.Cancels != 1 {
+		t.Errorf("Expected one reduce and one cancel on the ask side, got %+v", asks2)
+	}
+}
+
+func TestDiffOnReprice(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	o := NewOrder(100, 2, "a")
+	node := NewNode("a", &o, 1)
+	ob.BidBook.Push(&node)
+	o.Price = 101
+	ob.BidBook.Fix("a")
+
+	d := sink.diffs[len(sink.diffs)-1]
+	expected := []Level{{100, 0}, {101, 2}}
+	if len(d.Bids) != 2 || d.Bids[0] != expected[0] || d.Bids[1] != expected[1] {
+		t.Errorf("Expected reprice to clear 100 and set 101, got %+v", d.Bids)
+	}
+}
+
+func TestLevelGetters(t *testing.T) {
+	ob := NewOrderBook()
+	if _, _, ok := ob.BestBid(); ok {
+		t.Errorf("Expected no best bid on an empty book")
+	}
+	bids := []Order{NewOrder(100, 2, "a"), NewOrder(100, 3, "b"), NewOrder(99, 1, "c")}
+	for i := range bids {
+		node := NewNode(bids[i].OrderId, &bids[i], 1)
+		ob.BidBook.Push(&node)
+	}
+	ask := NewOrder(50, 4, "d")
+	node := NewNode("d", &ask, 2)
+	ob.AskBook.Push(&node)
+
+	if ob.OrderCount(Buy) != 3 || ob.OrderCount(Sell) != 1 {
+		t.Errorf("Expected 3 bids and 1 ask, got %d and %d", ob.OrderCount(Buy), ob.OrderCount(Sell))
+	}
+	if ob.LevelCount(Buy) != 2 || ob.LevelCount(Sell) != 1 {
+		t.Errorf("Expected 2 bid levels and 1 ask level, got %d and %d", ob.LevelCount(Buy), ob.LevelCount(Sell))
+	}
+	if price, size, ok := ob.BestBid(); !ok || price != 100 || size != 5 {
+		t.Errorf("Expected best bid 5 at 100, got %f at %f", size, price)
+	}
+	if price, size, ok := ob.BestAsk(); !ok || price != 100 || size != 4 {
+		t.Errorf("Expected best ask 4 at weighted 100, got %f at %f", size, price)
+	}
+	if n := ob.Notional(Buy); n != 599 {
+		t.Errorf("Expected bid notional 599, got %f", n)
+	}
+	if n := ob.Notional(Sell); n != 400 {
+		t.Errorf("Expected ask notional 400, got %f", n)
+	}
+}
+
+func TestBucketDepth(t *testing.T) {
+	ob := NewOrderBook()
+	for i, p := range []float64{100.3, 100.1, 99.9, 99.4} {
+		ob.PushOrder(Buy, NewOrder(p, float64(i+1), fmt.Sprintf("b%d", i)))
+	}
+	for i, p := range []float64{100.6, 100.7, 101, 101.2} {
+		ob.PushOrder(Sell, NewOrder(p, float64(i+1), fmt.Sprintf("a%d", i)))
+	}
+	tests := []struct {
+		Name string
+		N    int
+		Size float64
+		Bids []Level
+		Asks []Level
+	}{
+		{"half", 0, 0.5, []Level{{100, 3}, {99.5, 3}, {99, 4}}, []Level{{101, 6}, {101.5, 4}}},
+		{"tenth", 2, 0.1, []Level{{100.3, 1}, {100.1, 2}}, []Level{{100.6, 1}, {100.7, 2}}},
+		{"unit-limited", 1, 1, []Level{{100, 3}}, []Level{{101, 6}}},
+		{"none", 1, 0, []Level{{100.3, 1}}, []Level{{100.6, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bids, asks := ob.BucketDepth(test.N, test.Size)
+			if !reflect.DeepEqual(bids, test.Bids) || !reflect.DeepEqual(asks, test.Asks) {
+				t.Errorf("Expected %v %v, got %v %v", test.Bids, test.Asks, bids, asks)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// Logger receives structured debug records as alternating key/value
+// pairs. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+}
+
+func WithLogger(l Logger) Option {
+	return func(ob *OrderBook) {
+		ob.SetLogger(l)
+	}
+}
+
+// SetLogger enables debug logging of every mutation on this book. A nil
+// Logger disables it.
+func (ob *OrderBook) SetLogger(l Logger) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	ob.logger = l
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ob := NewOrderBook(WithLogger(logger))
+
+	bid := NewOrder(100, 2, "a")
+	node := NewNode("a", &bid, 1)
+	ob.BidBook.Push(&node)
+	replacement := NewOrder(101, 2, "a")
+	node2 := NewNode("a", &replacement, 1)
+	ob.BidBook.Push(&node2)
+	ob.BidBook.Remove("a")
+	ob.BidBook.Remove("missing")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		`msg="orderbook: push" side=buy key=a price=100 quantity=2 weight=1`,
+		`msg="orderbook: remove" side=buy key=a price=100`,
+		`msg="orderbook: replace" side=buy key=a price=101`,
+		`msg="orderbook: remove" side=buy key=a price=101`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d log lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, expected[i]) {
+			t.Errorf("Expected log line %q to contain %q", line, expected[i])
+		}
+	}
+
+	buf.Reset()
+	ob.SetLogger(nil)
+	ob.BidBook.Push(&node)
+	if buf.Len() != 0 {
+		t.Errorf("Expected logging to be disabled, got %q", buf.String())
+	}
+}
+
+func TestLoggerTradesAndRejects(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ob := NewOrderBook(WithLogger(logger))
+	ob.Submit(NewOrder(100, 2, "a"), Sell)
+	ob.Submit(NewOrder(100, 1, "b"), Buy)
+	ob.Submit(NewOrder(100, 0, "c"), Buy)
+
+	for _, expected := range []string{
+		`msg="orderbook: trade" side=buy maker=a taker=b price=100 quantity=1`,
+		`msg="orderbook: reject" side=buy key=c error="` + ErrInvalidQuantity.Error() + `"`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+var (
+	ErrTenantExists   = errors.New("orderbook: tenant already exists")
+	ErrUnknownTenant  = errors.New("orderbook: unknown tenant")
+	ErrSymbolExists   = errors.New("orderbook: tenant already has a book for the symbol")
+	ErrUnknownSymbol  = errors.New("orderbook: tenant has no book for the symbol")
+	ErrTenantQuota    = errors.New("orderbook: tenant quota exceeded")
+	ErrForeignAccount = errors.New("orderbook: account belongs to another tenant")
+)
+
+// orderFootprint is the estimated memory a resting order holds: its node
+// and order, before strings and the index entries pointing at it.
+const orderFootprint = int64(unsafe.Sizeof(Node{}) + unsafe.Sizeof(Order{}))
+
+// TenantQuota bounds what one tenant may hold; zero leaves a dimension
+// unbounded. Memory is estimated at a fixed footprint per resting order.
+type TenantQuota struct {
+	Books  int
+	Orders int
+	Memory int64
+}
+
+// TenantUsage is what a tenant holds across its books.
+type TenantUsage struct {
+	Books  int
+	Orders int
+	Memory int64
+}
+
+type tenant struct {
+	name  string
+	quota TenantQuota
+	books map[string]*OrderBook
+	sinks []EventSink
+}
+
+// BookManager keeps books by symbol within tenants, for deployments that
+// serve several. Tenants are namespaces: the same symbol names a separate
+// book in each, each book's events reach only its own tenant's sinks and
+// streams, and an account belongs to the first tenant it is assigned to
+// or trades under. Submits to a tenant's books are checked against its
+// quota and account ownership by a Validator added to each book.
+type BookManager struct {
+	lock     sync.Mutex
+	tenants  map[string]*tenant
+	accounts map[string]string
+}
+
+func NewBookManager() *BookManager {
+	return &BookManager{tenants: make(map[string]*tenant), accounts: make(map[string]string)}
+}
+
+// AddTenant registers a tenant with its quota.
+func (m *BookManager) AddTenant(name string, quota TenantQuota) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.tenants[name]; ok {
+		return ErrTenantExists
+	}
+	m.tenants[name] = &tenant{name: name, quota: quota, books: make(map[string]*OrderBook)}
+	return nil
+}
+
+// SetQuota replaces a tenant's quota. Orders already resting over it are
+// left alone.
+func (m *BookManager) SetQuota(name string, quota TenantQuota) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return ErrUnknownTenant
+	}
+	t.quota = quota
+	return nil
+}
+
+// AssignAccount gives account to a tenant ahead of its first order.
+func (m *BookManager) AssignAccount(name, account string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.tenants[name]; !ok {
+		return ErrUnknownTenant
+	}
+	return m.claim(name, account)
+}
+
+// claim assigns account to the tenant unless another owns it. The caller
+// holds the lock.
+func (m *BookManager) claim(name, account string) error {
+	if owner, ok := m.accounts[account]; ok && owner != name {
+		return ErrForeignAccount
+	}
+	m.accounts[account] = name
+	return nil
+}
+
+// CreateBook creates the tenant's book for symbol, built with opts, and
+// adds the tenant's sinks to it.
+func (m *BookManager) CreateBook(name, symbol string, opts ...Option) (*OrderBook, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	switch {
+	case !ok:
+		return nil, ErrUnknownTenant
+	case t.books[symbol] != nil:
+		return nil, ErrSymbolExists
+	case t.quota.Books > 0 && len(t.books) >= t.quota.Books:
+		return nil, ErrTenantQuota
+	}
+	check := ValidatorFunc(func(_ *OrderBook, _ Side, o *Order) error {
+		return m.admit(t, o)
+	})
+	ob := NewOrderBook(append(opts, WithValidators(check))...)
+	for _, s := range t.sinks {
+		ob.AddSink(s)
+	}
+	t.books[symbol] = ob
+	return ob, nil
+}
+
+// admit checks an order for one of t's books against account ownership
+// and t's quota, counting it as if it will rest.
+func (m *BookManager) admit(t *tenant, o *Order) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if o.Account != "" {
+		if err := m.claim(t.name, o.Account); err != nil {
+			return err
+		}
+	}
+	u := t.usage()
+	if t.quota.Orders > 0 && u.Orders >= t.quota.Orders ||
+		t.quota.Memory > 0 && u.Memory+orderFootprint > t.quota.Memory {
+		return ErrTenantQuota
+	}
+	return nil
+}
+
+func (t *tenant) usage() TenantUsage {
+	u := TenantUsage{Books: len(t.books)}
+	for _, ob := range t.books {
+		u.Orders += ob.BidBook.Len() + ob.AskBook.Len()
+	}
+	u.Memory = int64(u.Orders) * orderFootprint
+	return u
+}
+
+// Book returns the tenant's book for symbol.
+func (m *BookManager) Book(name, symbol string) (*OrderBook, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	ob, ok := t.books[symbol]
+	if !ok {
+		return nil, ErrUnknownSymbol
+	}
+	return ob, nil
+}
+
+// RemoveBook drops the tenant's book for symbol from the manager.
+func (m *BookManager) RemoveBook(name, symbol string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return ErrUnknownTenant
+	}
+	if _, ok := t.books[symbol]; !ok {
+		return ErrUnknownSymbol
+	}
+	delete(t.books, symbol)
+	return nil
+}
+
+// Symbols returns the symbols of the tenant's books, sorted.
+func (m *BookManager) Symbols(name string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return nil
+	}
+	symbols := make([]string, 0, len(t.books))
+	for s := range t.books {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Usage returns what the tenant holds across its books.
+func (m *BookManager) Usage(name string) (TenantUsage, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return TenantUsage{}, ErrUnknownTenant
+	}
+	return t.usage(), nil
+}
+
+// AddSink adds s to every book the tenant has and creates from now on,
+// and to no other tenant's.
+func (m *BookManager) AddSink(name string, s EventSink) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.tenants[name]
+	if !ok {
+		return ErrUnknownTenant
+	}
+	t.sinks = append(t.sinks, s)
+	for _, ob := range t.books {
+		ob.AddSink(s)
+	}
+	return nil
+}
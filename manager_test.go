@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestBookManager(t *testing.T) {
+	m := NewBookManager()
+	m.AddTenant("acme", TenantQuota{Books: 2, Orders: 3})
+	m.AddTenant("globex", TenantQuota{Memory: 2 * orderFootprint})
+	if err := m.AddTenant("acme", TenantQuota{}); err != ErrTenantExists {
+		t.Errorf("Expected ErrTenantExists, got %v", err)
+	}
+
+	acmeSink, globexSink := &recordingSink{}, &recordingSink{}
+	m.AddSink("acme", acmeSink)
+	acme, _ := m.CreateBook("acme", "BTCUSD")
+	m.CreateBook("acme", "ETHUSD")
+	globex, err := m.CreateBook("globex", "BTCUSD")
+	if err != nil || globex == acme {
+		t.Fatalf("Expected each tenant its own BTCUSD book, got %v", err)
+	}
+	m.AddSink("globex", globexSink)
+	if _, err := m.CreateBook("acme", "SOLUSD"); err != ErrTenantQuota {
+		t.Errorf("Expected the third acme book to exceed its quota, got %v", err)
+	}
+	if _, err := m.CreateBook("acme", "BTCUSD"); err != ErrSymbolExists {
+		t.Errorf("Expected ErrSymbolExists, got %v", err)
+	}
+	if _, err := m.CreateBook("initech", "BTCUSD"); err != ErrUnknownTenant {
+		t.Errorf("Expected ErrUnknownTenant, got %v", err)
+	}
+	if syms := m.Symbols("acme"); len(syms) != 2 || syms[0] != "BTCUSD" || syms[1] != "ETHUSD" {
+		t.Errorf("Expected acme's symbols, got %v", syms)
+	}
+
+	order := func(price float64, id, account string) Order {
+		o := NewOrder(price, 1, id)
+		o.Account = account
+		return o
+	}
+	eth, _ := m.Book("acme", "ETHUSD")
+	tests := []struct {
+		book    *OrderBook
+		order   Order
+		side    Side
+		err     error
+		message string
+	}{
+		{acme, order(100, "a1", "alice"), Buy, nil, "alice joins acme"},
+		{acme, order(99, "a2", "alice"), Buy, nil, "second order"},
+		{eth, order(10, "a3", ""), Buy, nil, "third order across acme's books"},
+		{acme, order(98, "a4", "alice"), Buy, ErrTenantQuota, "fourth order over acme's quota"},
+		{globex, order(100, "g1", "alice"), Buy, ErrForeignAccount, "alice belongs to acme"},
+		{globex, order(100, "g1", "bob"), Buy, nil, "bob joins globex"},
+		{globex, order(99, "g2", "bob"), Buy, nil, "second globex order"},
+		{globex, order(98, "g3", "bob"), Buy, ErrTenantQuota, "third order over globex's memory"},
+	}
+	for _, tt := range tests {
+		if r := tt.book.Submit(tt.order, tt.side); r.Err != tt.err {
+			t.Errorf("Expected %v for %s, got %v", tt.err, tt.message, r.Err)
+		}
+	}
+	if u, _ := m.Usage("acme"); u != (TenantUsage{Books: 2, Orders: 3, Memory: 3 * orderFootprint}) {
+		t.Errorf("Expected acme to hold 3 orders in 2 books, got %+v", u)
+	}
+	if len(acmeSink.diffs) != 3 || len(globexSink.diffs) != 2 {
+		t.Errorf("Expected each tenant's sink to see only its own books, got %d and %d diffs",
+			len(acmeSink.diffs), len(globexSink.diffs))
+	}
+
+	acme.Cancel("a2")
+	if r := acme.Submit(order(98, "a4", "alice"), Buy); r.Err != nil {
+		t.Errorf("Expected room after a cancel, got %v", r.Err)
+	}
+	if err := m.AssignAccount("globex", "alice"); err != ErrForeignAccount {
+		t.Errorf("Expected ErrForeignAccount, got %v", err)
+	}
+	if err := m.RemoveBook("acme", "ETHUSD"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Book("acme", "ETHUSD"); err != ErrUnknownSymbol {
+		t.Errorf("Expected ErrUnknownSymbol after removal, got %v", err)
+	}
+}
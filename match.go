@@ -0,0 +1,224 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// errStale is returned by trade when the resting order changed after it
+// was read, so that matching reads the side again.
+var errStale = errors.New("orderbook: resting order changed while matching")
+
+// matching serializes matching, together with resting what is left of the
+// order, so that concurrent orders neither fill the same resting quantity
+// twice nor rest crossing each other. Work that may submit orders in turn,
+// placing bracket exits and settling alerts and pegs, is held until the
+// lock is released.
+type matching struct {
+	lock  sync.Mutex
+	exits []*group // guarded by lock
+
+	state     sync.Mutex
+	busy      bool
+	unsettled bool
+}
+
+func (ob *OrderBook) lockMatching() {
+	ob.matching.lock.Lock()
+	ob.matching.state.Lock()
+	ob.matching.busy = true
+	ob.matching.state.Unlock()
+}
+
+// unlockMatching releases the match lock, then runs what was held.
+func (ob *OrderBook) unlockMatching() {
+	exits := ob.matching.exits
+	ob.matching.exits = nil
+	ob.matching.state.Lock()
+	unsettled := ob.matching.unsettled
+	ob.matching.busy, ob.matching.unsettled = false, false
+	ob.matching.state.Unlock()
+	ob.matching.lock.Unlock()
+
+	if unsettled {
+		ob.settle()
+	}
+	for _, g := range exits {
+		ob.placeExits(g.id, g.bracket)
+	}
+}
+
+// holdSettle reports whether a match is under way, in which case settle
+// runs once it ends.
+func (ob *OrderBook) holdSettle() bool {
+	ob.matching.state.Lock()
+	defer ob.matching.state.Unlock()
+
+	if ob.matching.busy {
+		ob.matching.unsettled = true
+	}
+	return ob.matching.busy
+}
+
+// Side returns the book for one side.
+func (ob *OrderBook) Side(side Side) *SideBook {
+	if side == Buy {
+		return &ob.BidBook.SideBook
+	}
+	return &ob.AskBook.SideBook
+}
+
+// permits reports whether an order on side limited to limit may trade at
+// price.
+func permits(side Side, limit, price float64) bool {
+	if side == Buy {
+		return limit >= price
+	}
+	return limit <= price
+}
+
+// Match fills o, an incoming order on side, against the opposite side for
+// as long as its limit, weighted like a resting order from its venue,
+// crosses the best resting (weighted) price. Trades execute at the resting
+// order's price, or as WithExecutionPrice sets, and are published ahead of
+// the book change they cause. Each trade moves quantity from Quantity to
+// Filled on both orders; any remainder of o is left to the caller to rest
+// or discard. Within a price level orders fill in time priority unless the
+// book has an Allocator. Matching is serialized across goroutines, so Fill
+// hooks must not submit orders to the book.
+func (ob *OrderBook) Match(side Side, o *Order) []TradeEvent {
+	ob.lockMatching()
+	defer ob.unlockMatching()
+
+	trades, _ := ob.match(side, o)
+	return trades
+}
+
+// match is Match returning the error of a Fill hook that stopped it. The
+// caller holds the match lock.
+func (ob *OrderBook) match(side Side, o *Order) ([]TradeEvent, error) {
+	if ob.latency != nil {
+		defer ob.latency.since(LatencyMatch, time.Now())
+	}
+	opposite := ob.Side(side.Opposite())
+	limit := o.Price * ob.weight(o)
+	if ref := ob.execution.reference; ref != nil {
+		mid, ok := ref.Midpoint()
+		if !ok || !permits(side, limit, mid) {
+			return nil, nil
+		}
+		limit, ob.execution.mid = mid, mid
+	}
+	var trades []TradeEvent
+	for o.Quantity > 0 {
+		n, maker, price, ok := opposite.head()
+		if !ok || !permits(side, limit, price) {
+			break
+		}
+		if ob.allocator == nil {
+			qty := math.Min(o.Quantity, maker.Quantity)
+			trade, err := ob.trade(side, o, n, maker, qty)
+			if err == errStale {
+				continue
+			}
+			if err != nil {
+				return trades, err
+			}
+			trades = append(trades, trade)
+			continue
+		}
+		nodes, makers := opposite.levelOrders(price)
+		orders := make([]*Order, len(nodes))
+		resting := make([]float64, len(nodes))
+		var total float64
+		for i := range makers {
+			orders[i] = &makers[i]
+			resting[i] = makers[i].Quantity
+			total += resting[i]
+		}
+		filled := len(trades)
+		stale := false
+		for i, qty := range ob.allocate(o, math.Min(o.Quantity, total), orders, resting) {
+			if qty <= 0 || stale {
+				continue
+			}
+			trade, err := ob.trade(side, o, nodes[i], makers[i], qty)
+			if err == errStale {
+				stale = true
+				continue
+			}
+			if err != nil {
+				return trades, err
+			}
+			trades = append(trades, trade)
+		}
+		if len(trades) == filled && !stale {
+			break // nothing more can be allocated at this level
+		}
+	}
+	return trades, nil
+}
+
+// trade fills qty of o against the resting node n, whose order read as
+// maker, publishing the trade ahead of the book change it causes. It
+// returns errStale if the order has changed since.
+func (ob *OrderBook) trade(side Side, o *Order, n *Node, seen Order, qty float64) (TradeEvent, error) {
+	opposite := ob.Side(side.Opposite())
+	m := seen
+	maker := &m
+	trade := TradeEvent{Price: ob.tradePrice(side, o, maker), Quantity: qty, Side: side,
+		MakerId: maker.OrderId, TakerId: o.OrderId, Time: opposite.clock(), Monotonic: ob.monotonic()}
+	trade.TakerImprovement, trade.MakerImprovement = improvements(side, o.Price, maker.Price, trade.Price)
+	if ob.fees != nil {
+		notional := math.Abs(ob.instrument.Notional(trade.Price, trade.Quantity))
+		trade.MakerFee = ob.fees.Fee(maker.Account, Maker, notional)
+		trade.TakerFee = ob.fees.Fee(o.Account, Taker, notional)
+	}
+	// the hooks see only a trade that will happen, so the maker is checked
+	// and filled under one hold of the side lock; the fill, the trade and
+	// the change it causes go out together, so no other change to the side
+	// is published in between
+	ob.eventLock.Lock()
+	opposite.lock.Lock()
+	if !opposite.current(n, seen) {
+		opposite.lock.Unlock()
+		ob.eventLock.Unlock()
+		return trade, errStale
+	}
+	if err := ob.fillHooks(o, maker, &trade); err != nil {
+		opposite.lock.Unlock()
+		ob.eventLock.Unlock()
+		return trade, err
+	}
+	done := opposite.fill(n, qty)
+	opposite.lock.Unlock()
+	ob.publishTrade(&trade)
+	bids, asks := ob.takePending(side == Sell, side == Buy)
+	ob.publishChanges(bids, asks, false)
+	ob.eventLock.Unlock()
+	ob.settleChanges(bids, asks)
+
+	o.Quantity, o.Filled = ob.units.sub(o.Quantity, qty), ob.units.add(o.Filled, qty)
+	if done {
+		ob.statuses.set(n.Key, StatusFilled)
+	}
+	if g := ob.completeMember(seen.OrderId, done); g != nil {
+		ob.matching.exits = append(ob.matching.exits, g)
+	}
+	return trade, nil
+}
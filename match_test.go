@@ -0,0 +1,128 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	asks := []Order{
+		NewOrder(101, 1, "a"),
+		NewOrder(102, 2, "b"),
+		NewOrder(104, 5, "c"),
+	}
+	for i := range asks {
+		node := NewNode(asks[i].OrderId, &asks[i], 1)
+		ob.AskBook.Push(&node)
+	}
+
+	buy := NewOrder(102, 4, "x")
+	trades := ob.Match(Buy, &buy)
+	expected := []TradeEvent{{Price: 101, Quantity: 1}, {Price: 102, Quantity: 2}}
+	if len(trades) != len(expected) {
+		t.Fatalf("Expected %d trades, got %d", len(expected), len(trades))
+	}
+	for i, trade := range trades {
+		if trade.Price != expected[i].Price || trade.Quantity != expected[i].Quantity {
+			t.Errorf("Expected trade %+v, got %+v", expected[i], trade)
+		}
+		if trade.Sequence != sink.trades[i].Sequence {
+			t.Errorf("Expected returned trade to carry published sequence %d, got %d", sink.trades[i].Sequence, trade.Sequence)
+		}
+	}
+	if buy.Quantity != 1 {
+		t.Errorf("Expected 1 unfilled, got %f", buy.Quantity)
+	}
+	if ob.AskBook.Len() != 1 || ob.AskBook.Peek().Price != 104 {
+		t.Errorf("Expected only the 104 ask to remain")
+	}
+
+	sell := NewOrder(103, 1, "y")
+	if trades := ob.Match(Sell, &sell); len(trades) != 0 {
+		t.Errorf("Expected sell against an empty bid book not to trade")
+	}
+	partial := NewOrder(110, 2, "z")
+	ob.Match(Buy, &partial)
+	if ob.AskBook.Peek().Quantity != 3 {
+		t.Errorf("Expected partially filled maker to keep 3, got %f", ob.AskBook.Peek().Quantity)
+	}
+}
+
+func TestTradeDetails(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook(WithClock(StepClock(start, time.Second)))
+	ob.Submit(NewOrder(101, 1, "a"), Sell) // stamped at start
+	ob.Submit(NewOrder(102, 1, "b"), Sell) // start+1s
+
+	report := ob.Submit(NewOrder(102, 2, "x"), Buy)
+	if len(report.Trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(report.Trades))
+	}
+	for i, maker := range []string{"a", "b"} {
+		trade := report.Trades[i]
+		if trade.TradeId != uint64(i+1) || trade.Side != Buy || trade.MakerId != maker || trade.TakerId != "x" {
+			t.Errorf("Expected trade %d to be x buying from %s, got %+v", i+1, maker, trade)
+		}
+		if expected := start.Add(time.Duration(2+i) * time.Second); !trade.Time.Equal(expected) {
+			t.Errorf("Expected trade %d at %v, got %v", i+1, expected, trade.Time)
+		}
+	}
+}
+
+func TestMatchConcurrent(t *testing.T) {
+	// yielding mid-trade lets the other goroutines in where a fill could
+	// race another for the same resting order
+	ob := NewOrderBook(WithHooks(Hooks{Fill: func(*Order, *Order, *TradeEvent) error {
+		runtime.Gosched()
+		return nil
+	}}))
+	for i := 0; i < 2000; i++ {
+		ob.Submit(NewOrder(float64(100+i%100), 1, "b"+strconv.Itoa(i)), Buy)
+	}
+	var wg sync.WaitGroup
+	filled := make([]float64, 16)
+	for g := range filled {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				r := ob.Submit(NewOrder(100, 1, strconv.Itoa(g)+"-"+strconv.Itoa(i)), Sell)
+				filled[g] += r.Filled
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var total float64
+	for _, f := range filled {
+		total += f
+	}
+	if total != 2000 {
+		t.Errorf("Expected 2000 units filled against 2000 resting, got %v", total)
+	}
+	if n := ob.BidBook.Len(); n != 0 {
+		t.Errorf("Expected every bid to fill, got %d left", n)
+	}
+	if n := ob.AskBook.Len(); n != 1200 {
+		t.Errorf("Expected 1200 sells to rest, got %d", n)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "unsafe"
+
+// Per-entry overheads for the structures pointing at a resting order or
+// level, estimated for 64-bit platforms: a map bucket slot holds the key
+// header, the pointer and a tophash byte, rounded up.
+const (
+	mapEntryBytes = int64(unsafe.Sizeof("") + unsafe.Sizeof(uintptr(0)) + 8)
+	slotBytes     = int64(unsafe.Sizeof(uintptr(0)))
+	levelBytes    = int64(unsafe.Sizeof(level{})+unsafe.Sizeof(float64(0))) + slotBytes + 8
+)
+
+// MemoryStats estimates the memory the book's resting orders hold: their
+// nodes and orders, the string data they point at, counted once however
+// many orders share it, and their entries in the order map, the heaps and
+// the level index. Slack in slices and maps is left out.
+type MemoryStats struct {
+	Orders int   `json:"orders"`
+	Levels int   `json:"levels"`
+	Bytes  int64 `json:"bytes"`
+	// PerOrder is Bytes over Orders, or zero for an empty book.
+	PerOrder float64 `json:"perOrder"`
+	// Strings is the part of Bytes taken by string data.
+	Strings int64 `json:"strings"`
+}
+
+// MemoryStats returns an estimate of the memory held by the book's resting
+// orders, read under both sides' locks.
+func (ob *OrderBook) MemoryStats() MemoryStats {
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	var m MemoryStats
+	seen := make(map[*byte]bool)
+	str := func(s string) {
+		if p := unsafe.StringData(s); len(s) > 0 && !seen[p] {
+			seen[p] = true
+			m.Strings += int64(len(s))
+		}
+	}
+	for _, side := range []Side{Buy, Sell} {
+		sb := ob.Side(side)
+		m.Levels += len(sb.levels.byPrice)
+		sb.each(func(n *Node) bool {
+			m.Orders++
+			m.Bytes += int64(unsafe.Sizeof(*n)) + mapEntryBytes + 3*slotBytes
+			str(n.Key)
+			if o := n.Peek(); o != nil {
+				m.Bytes += int64(unsafe.Sizeof(*o))
+				for _, s := range []string{o.OrderId, o.Venue, o.Account, o.Session, o.ClientOrderId} {
+					str(s)
+				}
+			}
+			return true
+		})
+	}
+	m.Bytes += m.Strings + int64(m.Levels)*levelBytes
+	if m.Orders > 0 {
+		m.PerOrder = float64(m.Bytes) / float64(m.Orders)
+	}
+	return m
+}
+
+// WithInternedStrings keeps one copy of each distinct key, OrderId,
+// ClientOrderId, Venue, Account and Session string among the orders pushed
+// onto the book, so orders decoded separately but sharing an account or
+// venue share its bytes too. Each side keeps every string it has seen for the life of the
+// book, so it suits books whose accounts and ids repeat rather than ones
+// with an unbounded stream of fresh ids.
+func WithInternedStrings() Option {
+	return func(ob *OrderBook) {
+		ob.AskBook.strings = make(map[string]string)
+		ob.BidBook.strings = make(map[string]string)
+	}
+}
+
+func (sb *SideBook) intern(s string) string {
+	if s == "" {
+		return s
+	}
+	if c, ok := sb.strings[s]; ok {
+		return c
+	}
+	sb.strings[s] = s
+	return s
+}
+
+// internNode interns n's key and its order's strings, under sb's lock.
+func (sb *SideBook) internNode(n *Node) {
+	n.Key = sb.intern(n.Key)
+	if o := n.Peek(); o != nil {
+		o.OrderId = sb.intern(o.OrderId)
+		o.Venue = sb.intern(o.Venue)
+		o.Account = sb.intern(o.Account)
+		o.Session = sb.intern(o.Session)
+		o.ClientOrderId = sb.intern(o.ClientOrderId)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStats(t *testing.T) {
+	if m := NewOrderBook().MemoryStats(); m != (MemoryStats{}) {
+		t.Errorf("Expected no memory for an empty book, got %+v", m)
+	}
+
+	fill := func(ob *OrderBook) MemoryStats {
+		for i := 0; i < 10; i++ {
+			o := NewOrder(float64(100+i%2), 1, "order"+strconv.Itoa(i))
+			// decoded orders each carry their own copy of the account
+			o.Account = strings.Repeat("a", 16)
+			ob.Submit(o, Buy)
+		}
+		return ob.MemoryStats()
+	}
+	plain, interned := fill(NewOrderBook()), fill(NewOrderBook(WithInternedStrings()))
+	if plain.Orders != 10 || plain.Levels != 2 || plain.PerOrder != float64(plain.Bytes)/10 {
+		t.Errorf("Expected 10 orders over 2 levels, got %+v", plain)
+	}
+	if plain.Strings != 10*6+10*16 {
+		t.Errorf("Expected each account counted separately, got %d bytes of strings", plain.Strings)
+	}
+	if interned.Strings != 10*6+16 || interned.Bytes != plain.Bytes-9*16 {
+		t.Errorf("Expected interning to keep one account, got %+v against %+v", interned, plain)
+	}
+}
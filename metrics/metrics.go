@@ -0,0 +1,192 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exports orderbook gauges and counters in the Prometheus
+// text exposition format.
+//
+// Collector is an http.Handler meant to be mounted at /metrics and scraped
+// directly, which keeps the Prometheus client library out of this
+// module's dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+type Collector struct {
+	lock  sync.Mutex
+	books map[string]*orderbook.OrderBook
+}
+
+func NewCollector() *Collector {
+	return &Collector{books: make(map[string]*orderbook.OrderBook)}
+}
+
+// Register adds a book to the collector under the given label.
+func (c *Collector) Register(name string, ob *orderbook.OrderBook) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.books[name] = ob
+}
+
+func (c *Collector) Unregister(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.books, name)
+}
+
+// WithCollector registers the book with c as it is constructed by
+// orderbook.NewOrderBook.
+func WithCollector(c *Collector, name string) orderbook.Option {
+	return func(ob *orderbook.OrderBook) {
+		c.Register(name, ob)
+	}
+}
+
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(ob *orderbook.OrderBook, side string) float64
+	sided bool
+}
+
+var collected = []metric{
+	{"orderbook_orders", "gauge", "Resting orders per side.", func(ob *orderbook.OrderBook, side string) float64 {
+		if side == "bid" {
+			return float64(ob.BidBook.Len())
+		}
+		return float64(ob.AskBook.Len())
+	}, true},
+	{"orderbook_depth", "gauge", "Resting quantity per side.", func(ob *orderbook.OrderBook, side string) float64 {
+		s := ob.Stats()
+		if side == "bid" {
+			return s.BidVolume
+		}
+		return s.AskVolume
+	}, true},
+	{"orderbook_spread", "gauge", "Best ask minus best bid, NaN without a two-sided market.", func(ob *orderbook.OrderBook, _ string) float64 {
+		if spread, ok := ob.Spread(); ok {
+			return spread
+		}
+		return math.NaN()
+	}, false},
+	{"orderbook_volume", "gauge", "Total resting quantity.", func(ob *orderbook.OrderBook, _ string) float64 {
+		return ob.Volume()
+	}, false},
+	{"orderbook_event_backlog", "gauge", "Events waiting in the book's channels.", func(ob *orderbook.OrderBook, _ string) float64 {
+		return float64(ob.Backlog())
+	}, false},
+	{"orderbook_inserts_total", "counter", "Orders pushed under a new key.", func(ob *orderbook.OrderBook, side string) float64 {
+		return float64(activity(ob, side).Inserts)
+	}, true},
+	{"orderbook_replaces_total", "counter", "Orders pushed over an existing key.", func(ob *orderbook.OrderBook, side string) float64 {
+		return float64(activity(ob, side).Replaces)
+	}, true},
+	{"orderbook_cancels_total", "counter", "Resting orders removed.", func(ob *orderbook.OrderBook, side string) float64 {
+		return float64(activity(ob, side).Cancels)
+	}, true},
+}
+
+func activity(ob *orderbook.OrderBook, side string) orderbook.Activity {
+	bids, asks := ob.Activity()
+	if side == "bid" {
+		return bids
+	}
+	return asks
+}
+
+// WriteTo writes every metric for every registered book to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.lock.Lock()
+	names := make([]string, 0, len(c.books))
+	books := make(map[string]*orderbook.OrderBook, len(c.books))
+	for name, ob := range c.books {
+		names = append(names, name)
+		books[name] = ob
+	}
+	c.lock.Unlock()
+	sort.Strings(names)
+
+	var total int64
+	write := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, args...)
+		total += int64(n)
+		return err
+	}
+	for _, m := range collected {
+		if err := write("# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+			return total, err
+		}
+		for _, name := range names {
+			sides := []string{""}
+			if m.sided {
+				sides = []string{"bid", "ask"}
+			}
+			for _, side := range sides {
+				labels := fmt.Sprintf("book=%q", name)
+				if side != "" {
+					labels += fmt.Sprintf(",side=%q", side)
+				}
+				if err := write("%s{%s} %g\n", m.name, labels, m.value(books[name], side)); err != nil {
+					return total, err
+				}
+			}
+		}
+	}
+	return total, writeLatency(write, names, books)
+}
+
+var quantiles = []float64{0.5, 0.9, 0.99, 0.999}
+
+// writeLatency writes a summary of each operation's latency for the books
+// built with orderbook.WithLatency.
+func writeLatency(write func(string, ...interface{}) error, names []string, books map[string]*orderbook.OrderBook) error {
+	const name = "orderbook_latency_seconds"
+	if err := write("# HELP %s %s\n# TYPE %s summary\n", name, "Latency of book operations.", name); err != nil {
+		return err
+	}
+	for _, book := range names {
+		for _, op := range orderbook.LatencyOps {
+			h := books[book].Latency(op)
+			if h == nil {
+				continue
+			}
+			labels := fmt.Sprintf("book=%q,op=%q", book, op.String())
+			for _, q := range quantiles {
+				if err := write("%s{%s,quantile=\"%g\"} %g\n", name, labels, q, h.Quantile(q).Seconds()); err != nil {
+					return err
+				}
+			}
+			if err := write("%s_sum{%s} %g\n%s_count{%s} %d\n", name, labels, h.Sum().Seconds(), name, labels, h.Count()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WriteTo(w)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	ob := orderbook.NewOrderBook(WithCollector(c, "BTCUSD"))
+
+	orders := []orderbook.Order{
+		orderbook.NewOrder(101, 2, "a"),
+		orderbook.NewOrder(102, 1, "b"),
+		orderbook.NewOrder(103, 1, "b"),
+	}
+	for i := range orders {
+		node := orderbook.NewNode(orders[i].OrderId, &orders[i], 1)
+		ob.AskBook.Push(&node)
+	}
+	bid := orderbook.NewOrder(100, 4, "c")
+	node := orderbook.NewNode("c", &bid, 1)
+	ob.BidBook.Push(&node)
+	ob.AskBook.Remove("a")
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	expected := []string{
+		"# TYPE orderbook_orders gauge",
+		`orderbook_orders{book="BTCUSD",side="ask"} 1`,
+		`orderbook_orders{book="BTCUSD",side="bid"} 1`,
+		"# TYPE orderbook_depth gauge",
+		`orderbook_depth{book="BTCUSD",side="ask"} 1`,
+		`orderbook_depth{book="BTCUSD",side="bid"} 4`,
+		`orderbook_spread{book="BTCUSD"} 3`,
+		`orderbook_volume{book="BTCUSD"} 5`,
+		`orderbook_inserts_total{book="BTCUSD",side="ask"} 2`,
+		`orderbook_replaces_total{book="BTCUSD",side="ask"} 1`,
+		`orderbook_cancels_total{book="BTCUSD",side="ask"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics output to contain %q", line)
+		}
+	}
+}
+
+func TestLatencySummary(t *testing.T) {
+	c := NewCollector()
+	ob := orderbook.NewOrderBook(WithCollector(c, "BTCUSD"), orderbook.WithLatency())
+	ob.PushOrder(orderbook.Sell, orderbook.NewOrder(101, 1, "a"))
+	ob.AskBook.Remove("a")
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	expected := []string{
+		"# TYPE orderbook_latency_seconds summary",
+		`orderbook_latency_seconds_count{book="BTCUSD",op="push"} 1`,
+		`orderbook_latency_seconds_count{book="BTCUSD",op="remove"} 1`,
+		`orderbook_latency_seconds_count{book="BTCUSD",op="match"} 0`,
+		`orderbook_latency_seconds{book="BTCUSD",op="push",quantile="0.99"}`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics output to contain %q", line)
+		}
+	}
+}
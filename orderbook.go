@@ -15,7 +15,14 @@ package orderbook
 
 import (
 	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Item interface {
@@ -34,9 +41,24 @@ type Book interface {
 
 type Node struct {
 	Item
-	Key    string
-	Weight float64
-	index  int
+	Key      string
+	Weight   float64
+	Time     time.Time // entry time, set on Push when zero
+	index    int
+	ageIndex int     // position in its side's age index
+	seq      uint64  // arrival order, breaks ties between equal prices
+	price    float64 // effective price the node is indexed under
+	qty      float64 // quantity the node is counted with in its side's totals
+	indexed  bool
+}
+
+// detach returns a copy of n whose order is also a copy.
+func detach(n *Node) *Node {
+	c := *n
+	if o := n.Peek(); o != nil {
+		c.Item = copyOrder(o)
+	}
+	return &c
 }
 
 func NewNode(key string, i Item, weight float64) Node {
@@ -47,11 +69,105 @@ func NewNode(key string, i Item, weight float64) Node {
 	}
 }
 
+var nodePool = sync.Pool{New: func() interface{} { return new(Node) }}
+
+// AcquireNode is NewNode drawing from a pool, for callers that push and
+// pop at a high rate. Hand the node back with ReleaseNode once it is off
+// the book and nothing refers to it.
+func AcquireNode(key string, i Item, weight float64) *Node {
+	n := nodePool.Get().(*Node)
+	*n = NewNode(key, i, weight)
+	return n
+}
+
+func ReleaseNode(n *Node) {
+	*n = Node{}
+	nodePool.Put(n)
+}
+
+type Side int
+
+const (
+	Buy Side = iota
+	Sell
+)
+
+func (s Side) Opposite() Side {
+	return 1 - s
+}
+
+func (s Side) String() string {
+	if s == Buy {
+		return "buy"
+	}
+	return "sell"
+}
+
+func ParseSide(s string) (Side, error) {
+	switch strings.ToLower(s) {
+	case "buy", "bid", "b":
+		return Buy, nil
+	case "sell", "ask", "s":
+		return Sell, nil
+	}
+	return 0, fmt.Errorf("orderbook: unknown side %q", s)
+}
+
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Side) UnmarshalText(text []byte) error {
+	side, err := ParseSide(string(text))
+	if err == nil {
+		*s = side
+	}
+	return err
+}
+
+// Order is a limit order. Quantity is what remains open; Filled is the
+// quantity the matcher has executed so far.
 type Order struct {
 	Price    float64 `json:"price"`
 	Quantity float64 `json:"quantity"`
+	Filled   float64 `json:"filled,omitempty"`
 	OrderId  string  `json:"orderId"`
-	Country  string  `json:"country"`
+	// Venue tags where the order came from, such as the exchange or
+	// currency of a consolidated book. It was called Country, and JSON
+	// still carries it under "country" as well.
+	Venue   string `json:"venue,omitempty"`
+	Account string `json:"account,omitempty"`
+	Session string `json:"session,omitempty"`
+	// ClientOrderId is the caller's own id for the order; see OrderIdFor.
+	ClientOrderId string `json:"clientOrderId,omitempty"`
+	// Category ranks the order within its level under a Comparator.
+	Category Category `json:"category,omitempty"`
+}
+
+type orderJSON Order
+
+// MarshalJSON writes Venue under "country" too, for consumers reading the
+// field by its former name.
+func (o Order) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		orderJSON
+		Country string `json:"country,omitempty"`
+	}{orderJSON(o), o.Venue})
+}
+
+// UnmarshalJSON reads Venue from "country" when "venue" is absent.
+func (o *Order) UnmarshalJSON(data []byte) error {
+	v := struct {
+		*orderJSON
+		Country string `json:"country"`
+	}{orderJSON: (*orderJSON)(o)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if o.Venue == "" {
+		o.Venue = v.Country
+	}
+	return nil
 }
 
 func (o *Order) Peek() *Order {
@@ -67,40 +183,91 @@ func NewOrder(price float64, quantity float64, orderId string) Order {
 }
 
 type Quote struct {
-	Ask *Order `json:"ask,omitempty"`
-	Bid *Order `json:"bid,omitempty"`
+	Ask      *Order `json:"ask,omitempty"`
+	Bid      *Order `json:"bid,omitempty"`
+	Sequence uint64 `json:"sequence"`
 }
 
 type TradeEvent struct {
 	Price    float64
 	Quantity float64
+	Sequence uint64
+	MakerFee float64   `json:",omitempty"`
+	TakerFee float64   `json:",omitempty"`
+	TradeId  uint64    // numbers the book's trades from 1
+	Side     Side      // side of the incoming (aggressor) order
+	MakerId  string    // OrderId of the resting order
+	TakerId  string    // OrderId of the incoming order
+	Time     time.Time // from the book's clock
+	// Monotonic is the reading of the book's monotonic clock, to the
+	// nanosecond, when the trade was made.
+	Monotonic time.Duration `json:",omitempty"`
+	// Improvements are how much better than their own prices the taker
+	// and maker traded, per unit.
+	TakerImprovement float64 `json:",omitempty"`
+	MakerImprovement float64 `json:",omitempty"`
 }
 
 type BaseHeap []*Node
-type AskOrders struct {
-	BaseHeap
-}
-type BidOrders struct {
+
+// SideOrders is a heap of nodes ordered best first for its side: highest
+// effective price for bids, lowest for asks, earliest arrival within a
+// price.
+type SideOrders struct {
 	BaseHeap
+	side  Side
+	ahead Comparator
 }
 type OrdersMap map[string]*Node
 
-func (ob AskOrders) Less(i, j int) bool {
-	left := ob.BaseHeap[i].Peek()
-	right := ob.BaseHeap[j].Peek()
-	if left == nil && right == nil {
-		return false
-	} else if left != nil && right == nil {
-		return true
-	} else if left == nil && right != nil {
-		return false
+// Activity counts the mutations applied to a book. Replaces are pushes
+// over an existing key; Cancels are removals of a resting order, including
+// reductions to zero.
+type Activity struct {
+	Inserts  uint64
+	Replaces uint64
+	Reduces  uint64
+	Cancels  uint64
+}
+
+// Level is the aggregate quantity resting at a single effective
+// (weighted) price.
+type Level struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+func levels(nodes BaseHeap, n int) []Level {
+	var lvls []Level
+	for _, node := range nodes {
+		o := node.Peek()
+		if o == nil {
+			continue
+		}
+		price := o.Price * node.Weight
+		if len(lvls) > 0 && lvls[len(lvls)-1].Price == price {
+			lvls[len(lvls)-1].Quantity += o.Quantity
+			continue
+		}
+		if n > 0 && len(lvls) == n {
+			break
+		}
+		lvls = append(lvls, Level{price, o.Quantity})
+	}
+	return lvls
+}
+
+// better reports whether effective price a ranks ahead of b.
+func (so SideOrders) better(a, b float64) bool {
+	if so.side == Buy {
+		return a > b
 	}
-	return left.Price*ob.BaseHeap[i].Weight < right.Price*ob.BaseHeap[j].Weight
+	return a < b
 }
 
-func (ob BidOrders) Less(i, j int) bool {
-	left := ob.BaseHeap[i].Peek()
-	right := ob.BaseHeap[j].Peek()
+func (so SideOrders) Less(i, j int) bool {
+	left := so.BaseHeap[i].Peek()
+	right := so.BaseHeap[j].Peek()
 	if left == nil && right == nil {
 		return false
 	} else if left != nil && right == nil {
@@ -108,7 +275,18 @@ func (ob BidOrders) Less(i, j int) bool {
 	} else if left == nil && right != nil {
 		return false
 	}
-	return left.Price*ob.BaseHeap[i].Weight > right.Price*ob.BaseHeap[j].Weight
+	lp, rp := left.Price*so.BaseHeap[i].Weight, right.Price*so.BaseHeap[j].Weight
+	if lp == rp {
+		if so.ahead != nil {
+			if so.ahead(left, right) {
+				return true
+			} else if so.ahead(right, left) {
+				return false
+			}
+		}
+		return so.BaseHeap[i].seq < so.BaseHeap[j].seq
+	}
+	return so.better(lp, rp)
 }
 
 func (h BaseHeap) Len() int { return len(h) }
@@ -130,199 +308,891 @@ func (h *BaseHeap) Pop() interface{} {
 	return x
 }
 
-type BidBook struct {
-	Orders BidOrders
+// SideBook holds the resting orders of one side of an OrderBook, best
+// first.
+type SideBook struct {
+	side   Side
+	Orders SideOrders
 	OrdersMap
-	lock sync.Mutex
+	lock       sync.Mutex
+	onChange   func(Side)
+	pending    []change
+	activity   Activity
+	levels     levelIndex
+	byAge      ageHeap
+	arrivals   uint64
+	safe       bool
+	duplicates DuplicatePolicy
+	units      units
+	negative   bool
+	latency    *latencies
+	now        func() time.Time
+	strings    map[string]string
+	index      OrderIndex
+	rolling    rolling
+}
+
+func (sb *SideBook) init(side Side) {
+	sb.side, sb.Orders.side = side, side
+	heap.Init(&sb.Orders)
+	sb.OrdersMap = make(OrdersMap)
+	sb.levels = newLevelIndex(0)
+}
+
+func (sb *SideBook) Side() Side {
+	return sb.side
 }
 
-func (bb *BidBook) Peek() *Order {
-	if bb.Len() > 0 {
-		return bb.Orders.BaseHeap[0].Peek()
+// Peek returns the best order, or nil. It does not take the side's lock;
+// use TopOfBook while other goroutines may be changing the side.
+func (sb *SideBook) Peek() *Order {
+	if sb.Len() > 0 {
+		if sb.safe {
+			return copyOrder(sb.Orders.BaseHeap[0].Peek())
+		}
+		return sb.Orders.BaseHeap[0].Peek()
 	} else {
 		return nil
 	}
 }
 
-func (bb *BidBook) Len() int {
-	return bb.Orders.Len()
+// PeekOrder returns a copy of the best order.
+func (sb *SideBook) PeekOrder() (Order, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	if n := sb.top(); n != nil && n.Peek() != nil {
+		return *n.Peek(), true
+	}
+	return Order{}, false
 }
 
-func (bb *BidBook) Push(n *Node) {
-	bb.Remove(n.Key) // ensure Key does not already exist
-	bb.lock.Lock()
-	defer bb.lock.Unlock()
+// TopOfBook returns a copy of the best order and how many orders rest on
+// the side, read together under its lock so that, unlike Peek and Len
+// called in turn, they agree while other goroutines push and pop. ok is
+// false when the side holds no order.
+func (sb *SideBook) TopOfBook() (o Order, orders int, ok bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	heap.Push(&bb.Orders, n)
-	bb.OrdersMap[n.Key] = n
+	return sb.topOfBook()
 }
 
-func (bb *BidBook) Pop() *Node {
-	bb.lock.Lock()
-	defer bb.lock.Unlock()
+func (sb *SideBook) topOfBook() (Order, int, bool) {
+	if n := sb.top(); n != nil && n.Peek() != nil {
+		return *n.Peek(), sb.Len(), true
+	}
+	return Order{}, sb.Len(), false
+}
 
-	node := heap.Pop(&bb.Orders).(*Node)
-	delete(bb.OrdersMap, node.Key)
+func (sb *SideBook) Len() int {
+	return sb.Orders.Len()
+}
+
+func (sb *SideBook) top() *Node {
+	if sb.Len() > 0 {
+		return sb.Orders.BaseHeap[0]
+	}
+	return nil
+}
+
+// Push rests n, handling a key that is already resting by the side's
+// DuplicatePolicy. Duplicates it rejects are dropped; use Insert to be
+// told about them.
+func (sb *SideBook) Push(n *Node) {
+	sb.Insert(n)
+}
+
+// Insert is Push returning ErrDuplicateOrder when n is rejected as a
+// duplicate.
+func (sb *SideBook) Insert(n *Node) error {
+	if sb.latency != nil {
+		defer sb.latency.since(LatencyPush, time.Now())
+	}
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.push(n)
+}
+
+func (sb *SideBook) push(n *Node) error {
+	op := opPush
+	if prev, ok := sb.get(n.Key); ok {
+		switch sb.duplicates {
+		case RejectDuplicates:
+			return ErrDuplicateOrder
+		case AmendDuplicates:
+			if o := n.Peek(); o != nil && prev.Peek() != nil {
+				sb.amend(n.Key, o.Price, o.Quantity)
+				return nil
+			}
+		}
+		sb.remove(n.Key)
+		op = opReplace
+		sb.activity.Replaces++
+	} else {
+		sb.activity.Inserts++
+	}
+	if sb.strings != nil {
+		sb.internNode(n)
+	}
+	sb.arrivals++
+	n.seq = sb.arrivals
+	now := sb.clock()
+	if n.Time.IsZero() {
+		n.Time = now
+	}
+	if op == opPush {
+		sb.countAdd(now)
+	}
+	heap.Push(&sb.Orders, n)
+	heap.Push(&sb.byAge, n)
+	sb.put(n)
+	sb.levels.add(n)
+	sb.record(op, n, n.price)
+	return nil
+}
+
+func (sb *SideBook) Pop() *Node {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	node := heap.Pop(&sb.Orders).(*Node)
+	heap.Remove(&sb.byAge, node.ageIndex)
+	sb.del(node.Key)
+	sb.levels.remove(node)
+	sb.record(opPop, node, node.price)
 	return node
 }
 
-func (bb *BidBook) Get(key string) (*Node, bool) {
-	n, ok := bb.OrdersMap[key]
+// head returns the best node with a copy of its order and its effective
+// price, read together under the lock.
+func (sb *SideBook) head() (*Node, Order, float64, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	n := sb.top()
+	if n == nil || n.Peek() == nil {
+		return nil, Order{}, 0, false
+	}
+	return n, *n.Peek(), n.Peek().Price * n.Weight, true
+}
+
+// current reports whether the order at n still rests as seen. The caller
+// holds the lock.
+func (sb *SideBook) current(n *Node, seen Order) bool {
+	m, ok := sb.get(n.Key)
+	return ok && m == n && n.Peek() != nil && *n.Peek() == seen
+}
+
+// fill takes qty from the order at n, removing it, like a Pop, once
+// nothing is left, and reports whether the order is done. The caller holds
+// the lock, has checked the order is current and publishes the change once
+// it has published the trade.
+func (sb *SideBook) fill(n *Node, qty float64) (done bool) {
+	o := n.Peek()
+	o.Quantity, o.Filled = sb.units.sub(o.Quantity, qty), sb.units.add(o.Filled, qty)
+	if o.Quantity > 0 {
+		sb.fix(n)
+		return false
+	}
+	heap.Remove(&sb.Orders, n.index)
+	heap.Remove(&sb.byAge, n.ageIndex)
+	sb.del(n.Key)
+	sb.levels.remove(n)
+	sb.record(opPop, n, n.price)
+	return true
+}
+
+// levelOrders returns the nodes resting at an effective price in time
+// priority, with copies of their orders.
+func (sb *SideBook) levelOrders(price float64) ([]*Node, []Order) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	l, ok := sb.levels.level(price)
+	if !ok {
+		return nil, nil
+	}
+	nodes := append([]*Node(nil), l.orders...)
+	if sb.Orders.ahead != nil {
+		byComparator(nodes, sb.Orders.ahead)
+	}
+	orders := make([]Order, len(nodes))
+	for i, n := range nodes {
+		orders[i] = *n.Peek()
+	}
+	return nodes, orders
+}
+
+func (sb *SideBook) Get(key string) (*Node, bool) {
+	n, ok := sb.get(key)
+	if ok && sb.safe {
+		n = detach(n)
+	}
 	return n, ok
 }
 
-func (bb *BidBook) Remove(key string) {
-	bb.lock.Lock()
-	defer bb.lock.Unlock()
+func (sb *SideBook) Remove(key string) {
+	if sb.latency != nil {
+		defer sb.latency.since(LatencyRemove, time.Now())
+	}
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	if n, ok := bb.Get(key); ok {
-		heap.Remove(&bb.Orders, n.index)
-		delete(bb.OrdersMap, key)
+	sb.cancel(key)
+}
+
+func (sb *SideBook) cancel(key string) bool {
+	if sb.remove(key) {
+		sb.activity.Cancels++
+		sb.countCancel()
+		return true
 	}
+	return false
 }
 
-func (bb *BidBook) Fix(key string) {
-	bb.lock.Lock()
-	defer bb.lock.Unlock()
+func (sb *SideBook) remove(key string) bool {
+	n, ok := sb.get(key)
+	if ok {
+		heap.Remove(&sb.Orders, n.index)
+		heap.Remove(&sb.byAge, n.ageIndex)
+		sb.del(key)
+		sb.levels.remove(n)
+		sb.record(opRemove, n, n.price)
+	}
+	return ok
+}
+
+func (sb *SideBook) Fix(key string) {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	if n, ok := bb.Get(key); ok {
-		heap.Fix(&bb.Orders, n.index)
+	if n, ok := sb.get(key); ok {
+		sb.fix(n)
 	}
 }
 
-func (bb *BidBook) volume() float64 {
-	var total float64 = 0
-	for _, node := range bb.Orders.BaseHeap {
-		total += node.Peek().Quantity
+// UpdatePrice sets the price of the order at key and re-fixes it under the
+// lock, as mutating the order and calling Fix would, so the change is
+// published with the order already in place. The order keeps its time
+// priority. It reports whether an order was found and updated; prices
+// Submit would refuse, honouring WithNegativePrices, are refused.
+func (sb *SideBook) UpdatePrice(key string, price float64) bool {
+	if !validPrice(price, sb.negative) {
+		return false
 	}
-	return total
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	n, ok := sb.get(key)
+	if !ok || n.Peek() == nil {
+		return false
+	}
+	n.Peek().Price = price
+	sb.fix(n)
+	return true
 }
 
-type AskBook struct {
-	Orders AskOrders
-	OrdersMap
-	lock sync.Mutex
+// UpdateWeight is UpdatePrice for the node's weight, which must be finite
+// and positive.
+func (sb *SideBook) UpdateWeight(key string, weight float64) bool {
+	if !validWeight(weight) {
+		return false
+	}
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	n, ok := sb.get(key)
+	if !ok {
+		return false
+	}
+	n.Weight = weight
+	sb.fix(n)
+	return true
 }
 
-func (ab *AskBook) Peek() *Order {
-	if ab.Len() > 0 {
-		return ab.Orders.BaseHeap[0].Peek()
-	} else {
-		return nil
+// UpdateQuantity is UpdatePrice for the order's quantity. Raising the
+// quantity loses time priority, as Amend does.
+func (sb *SideBook) UpdateQuantity(key string, qty float64) bool {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.amend(key, 0, qty)
+}
+
+func (sb *SideBook) fix(n *Node) {
+	prev, indexed := n.price, n.indexed
+	heap.Fix(&sb.Orders, n.index)
+	sb.levels.move(n)
+	if indexed && prev != n.price {
+		sb.record(opFix, n, prev)
 	}
+	sb.record(opFix, n, n.price)
 }
 
-func (ab *AskBook) Len() int {
-	return ab.Orders.Len()
+func (sb *SideBook) reduce(key string, qty float64) (float64, bool) {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	n, ok := sb.get(key)
+	if !ok || n.Peek() == nil {
+		return 0, false
+	}
+	o := n.Peek()
+	if qty <= 0 {
+		return o.Quantity, true
+	}
+	if o.Quantity = sb.units.sub(o.Quantity, qty); o.Quantity <= 0 {
+		o.Quantity = 0
+		sb.remove(key)
+		sb.activity.Cancels++
+		sb.countCancel()
+		return 0, true
+	}
+	sb.levels.resize(n)
+	sb.activity.Reduces++
+	sb.record(opReduce, n, n.price)
+	return o.Quantity, true
 }
 
-func (ab *AskBook) Push(n *Node) {
-	ab.Remove(n.Key) // ensure Key does not already exist
-	ab.lock.Lock()
-	defer ab.lock.Unlock()
+func (sb *SideBook) queuePosition(key string) (QueuePosition, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	heap.Push(&ab.Orders, n)
-	ab.OrdersMap[n.Key] = n
+	n, ok := sb.get(key)
+	if !ok || !n.indexed {
+		return QueuePosition{}, false
+	}
+	l, _ := sb.levels.level(n.price)
+	orders, qty := l.position(n, sb.Orders.ahead)
+	return QueuePosition{sb.side, n.price, orders, qty}, true
 }
 
-func (ab *AskBook) Pop() *Node {
-	ab.lock.Lock()
-	defer ab.lock.Unlock()
+func (sb *SideBook) levelCount() int {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	node := heap.Pop(&ab.Orders).(*Node)
-	delete(ab.OrdersMap, node.Key)
-	return node
+	return len(sb.levels.byPrice)
 }
 
-func (ab *AskBook) Get(key string) (*Node, bool) {
-	n, ok := ab.OrdersMap[key]
-	return n, ok
+func (sb *SideBook) best() (float64, float64, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.bestLocked()
+}
+
+// bestLocked is best for callers that hold the lock.
+func (sb *SideBook) bestLocked() (float64, float64, bool) {
+	n := sb.top()
+	if n == nil || !n.indexed {
+		return 0, 0, false
+	}
+	return n.price, sb.levels.quantityAt(n.price), true
+}
+
+func (sb *SideBook) notional() float64 {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.levels.notional
+}
+
+// count returns the number of resting orders match accepts.
+func (sb *SideBook) count(match func(*Order) bool) int {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	var n int
+	sb.each(func(node *Node) bool {
+		if o := node.Peek(); o != nil && match(o) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// reprice moves the order at key to price and restores its priority.
+func (sb *SideBook) reprice(key string, price float64) {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	n, ok := sb.get(key)
+	if !ok || n.Peek() == nil || n.Peek().Price == price {
+		return
+	}
+	n.Peek().Price = price
+	prev := n.price
+	heap.Fix(&sb.Orders, n.index)
+	sb.levels.move(n)
+	sb.record(opFix, n, prev)
+	sb.record(opFix, n, n.price)
+}
+
+// bestWhere returns the best price among the resting orders match
+// accepts.
+func (sb *SideBook) bestWhere(match func(*Node) bool) (float64, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	var best float64
+	var found bool
+	sb.each(func(n *Node) bool {
+		if o := n.Peek(); o != nil && match(n) && (!found || sb.Orders.better(o.Price, best)) {
+			best, found = o.Price, true
+		}
+		return true
+	})
+	return best, found
+}
+
+// copies returns copies of the resting nodes, holding copies of their
+// orders, in arrival order.
+func (sb *SideBook) copies() []Node {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	nodes := sb.byArrival()
+	copies := make([]Node, len(nodes))
+	for i, n := range nodes {
+		copies[i] = *n
+		o := *n.Peek()
+		copies[i].Item = &o
+	}
+	return copies
+}
+
+// byArrival returns the resting nodes in arrival order, so walks over the
+// side do not depend on map iteration order. The caller holds the lock.
+func (sb *SideBook) byArrival() []*Node {
+	nodes := make([]*Node, len(sb.Orders.BaseHeap))
+	copy(nodes, sb.Orders.BaseHeap)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].seq < nodes[j].seq })
+	return nodes
 }
 
-func (ab *AskBook) Remove(key string) {
-	ab.lock.Lock()
-	defer ab.lock.Unlock()
+// keysWhere returns the keys of the resting orders match accepts, in
+// arrival order.
+func (sb *SideBook) keysWhere(match func(*Order) bool) []string {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
 
-	if n, ok := ab.Get(key); ok {
-		heap.Remove(&ab.Orders, n.index)
-		delete(ab.OrdersMap, key)
+	var keys []string
+	for _, node := range sb.byArrival() {
+		if o := node.Peek(); o != nil && match(o) {
+			keys = append(keys, node.Key)
+		}
 	}
+	return keys
 }
 
-func (ab *AskBook) Fix(key string) {
-	ab.lock.Lock()
-	defer ab.lock.Unlock()
+// cancelWhere cancels and returns the resting orders match accepts, in
+// arrival order. The caller holds the lock.
+func (sb *SideBook) cancelWhere(match func(*Order) bool) []Order {
+	var cancelled []Order
+	for _, node := range sb.byArrival() {
+		if o := node.Peek(); o != nil && match(o) {
+			cancelled = append(cancelled, *o)
+			sb.cancel(node.Key)
+		}
+	}
+	return cancelled
+}
 
-	if n, ok := ab.Get(key); ok {
-		heap.Fix(&ab.Orders, n.index)
+func (sb *SideBook) clock() time.Time {
+	if sb.now != nil {
+		return sb.now()
 	}
+	return time.Now().UTC().Round(0)
 }
 
-func (ab *AskBook) volume() float64 {
-	var total float64 = 0
-	for _, node := range ab.Orders.BaseHeap {
-		total += node.Peek().Quantity
+func (sb *SideBook) counters() Activity {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.activity
+}
+
+// record queues a change for publication once the lock is released,
+// along with the aggregate quantity now resting at price.
+func (sb *SideBook) record(op bookOp, n *Node, price float64) {
+	if sb.onChange != nil {
+		sb.pending = append(sb.pending, newChange(op, n, price, sb.levels.quantityAt(price)))
+	}
+}
+
+// flush has the book publish the changes pending on the side.
+func (sb *SideBook) flush() {
+	if sb.onChange != nil {
+		sb.onChange(sb.side)
+	}
+}
+
+// recycle hands back the buffer of published changes for reuse.
+func (sb *SideBook) recycle(changes []change) {
+	if cap(changes) > 0 {
+		clear(changes)
+		sb.lock.Lock()
+		if sb.pending == nil {
+			sb.pending = changes[:0]
+		}
+		sb.lock.Unlock()
+	}
+}
+
+func (sb *SideBook) takePending() []change {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	if len(sb.pending) == 0 {
+		return nil
+	}
+	changes := sb.pending
+	sb.pending = nil
+	return changes
+}
+
+// amend sets the price and quantity of the order at key, leaving either
+// unchanged when zero. Raising the quantity or moving the price sends the
+// order to the back of its new level.
+func (sb *SideBook) amend(key string, price, qty float64) bool {
+	n, ok := sb.get(key)
+	if !ok || n.Peek() == nil {
+		return false
+	}
+	o := n.Peek()
+	prev := n.price
+	sb.levels.remove(n)
+	if (price != 0 && price != o.Price) || qty > o.Quantity {
+		sb.arrivals++
+		n.seq = sb.arrivals
+	}
+	if price != 0 {
+		o.Price = price
+	}
+	if qty != 0 {
+		o.Quantity = qty
+	}
+	heap.Fix(&sb.Orders, n.index)
+	heap.Fix(&sb.byAge, n.ageIndex)
+	sb.levels.add(n)
+	sb.activity.Replaces++
+	if prev != n.price {
+		sb.record(opFix, n, prev)
 	}
-	return total
+	sb.record(opFix, n, n.price)
+	return true
+}
+
+func (sb *SideBook) volume() float64 {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.levels.quantity
+}
+
+func (sb *SideBook) sorted() BaseHeap {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.sortedNodes()
+}
+
+// sortedNodes is sorted for callers that hold the lock.
+func (sb *SideBook) sortedNodes() BaseHeap {
+	nodes := make(BaseHeap, len(sb.Orders.BaseHeap))
+	copy(nodes, sb.Orders.BaseHeap)
+	sort.SliceStable(nodes, SideOrders{nodes, sb.side, sb.Orders.ahead}.Less)
+	return nodes
+}
+
+// BidBook and AskBook are the two sides of an OrderBook.
+type BidBook struct {
+	SideBook
+}
+
+type AskBook struct {
+	SideBook
 }
 
 type OrderBook struct {
 	AskBook
 	BidBook
-	quotes     chan *Quote
-	buyEvents  chan *TradeEvent
-	sellEvents chan *TradeEvent
+	sequence   uint64
+	eventLock  sync.Mutex
+	lastAsk    quoted
+	lastBid    quoted
+	uncrossed  IndicativeEvent
+	sinks      []EventSink
+	logger     Logger
+	noMatching bool
+	validators []Validator
+	hooks      []Hooks
+	allocator  Allocator
+	fees       FeeSchedule
+	stops      stopBook
+	pegs       pegBook
+	groups     groupBook
+	sessions   sessionBook
+	l2         l2State
+	bbo        *bboRing
+	conflation time.Duration
+	units      units
+	statuses   statusBook
+	ids        idMap
+	view       *viewState
+	capacity   *Capacity
+	ages       *ageAlert
+	instrument Instrument
+	rates      RateProvider
+	latency    *latencies
+	lastTrade  float64
+	traded     bool
+	tradeIds   uint64
+	tape       *tape
+	trades     rolling
+	streams    streams
+	alerts     alertBook
+	negative   bool
+	codec      Codec
+	execution  executionRule
+	limiter    *rateLimiter
+	mono       func() time.Duration
+	fallback   fallbackQuote
+	fairValue  FairValueModel
+	matching   matching
+	delivering atomic.Bool
 }
 
 func (ob *OrderBook) Init() {
-	heap.Init(&ob.AskBook.Orders)
-	heap.Init(&ob.BidBook.Orders)
-	ob.AskBook.OrdersMap = make(OrdersMap)
-	ob.BidBook.OrdersMap = make(OrdersMap)
-	ob.quotes = make(chan *Quote)
-	ob.buyEvents = make(chan *TradeEvent)
-	ob.sellEvents = make(chan *TradeEvent)
+	ob.AskBook.init(Sell)
+	ob.BidBook.init(Buy)
+	ob.AskBook.onChange = ob.changed
+	ob.BidBook.onChange = ob.changed
+	ob.streams.buffer = DefaultStreamBuffer
 }
 
+// Copy pushes copies of src's resting orders onto dst in their original
+// arrival order, so orders tied on price keep their relative priority and
+// partly filled orders their Filled quantity. src's pending stops, order
+// groups and last trade are copied too, skipping stops and groups whose
+// ids dst already uses, and dst takes src's sequence number last, so that
+// its next event follows on from src's.
 func Copy(src, dst *OrderBook) {
-	for _, n := range src.AskBook.OrdersMap {
-		n := *n
-		o := *n.Peek()
-		n.Item = &o
-		dst.AskBook.Push(&n)
+	for _, side := range []Side{Sell, Buy} {
+		copies := src.Side(side).copies()
+		for i := range copies {
+			dst.Side(side).Push(&copies[i])
+		}
 	}
-	for _, n := range src.BidBook.OrdersMap {
-		n := *n
-		o := *n.Peek()
-		n.Item = &o
-		dst.BidBook.Push(&n)
+	for _, p := range src.stops.pending() {
+		dst.stops.insert(p)
+	}
+	for _, g := range src.groups.list() {
+		dst.groups.add(g)
+	}
+	src.eventLock.Lock()
+	last, traded := src.lastTrade, src.traded
+	src.eventLock.Unlock()
+	dst.eventLock.Lock()
+	dst.lastTrade, dst.traded = last, traded
+	dst.eventLock.Unlock()
+	atomic.StoreUint64(&dst.sequence, src.Sequence())
+}
+
+// Equal reports whether a and b hold the same state: the same resting
+// orders, entry times and weights in the same arrival order on each side,
+// the same pending stops and order groups, the same last trade and the
+// same sequence number. It is meant for checking a replica or a restored
+// book against its source once both are quiet.
+func Equal(a, b *OrderBook) bool {
+	if a.Sequence() != b.Sequence() {
+		return false
+	}
+	for _, side := range []Side{Sell, Buy} {
+		x, y := a.Side(side).copies(), b.Side(side).copies()
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i].Key != y[i].Key || x[i].Weight != y[i].Weight || !x[i].Time.Equal(y[i].Time) ||
+				*x[i].Peek() != *y[i].Peek() {
+				return false
+			}
+		}
+	}
+	x, y := a.stops.pending(), b.stops.pending()
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if *x[i] != *y[i] {
+			return false
+		}
+	}
+	if !equalGroups(a.groups.list(), b.groups.list()) {
+		return false
+	}
+	at, atraded := a.LastTrade()
+	bt, btraded := b.LastTrade()
+	return at == bt && atraded == btraded
+}
+
+type Option func(*OrderBook)
+
+// WithPresize sizes each side's heap and indexes up front for the given
+// number of resting orders, so filling the book to that depth does not
+// grow them.
+func WithPresize(orders int) Option {
+	return func(ob *OrderBook) {
+		for _, sb := range []*SideBook{&ob.AskBook.SideBook, &ob.BidBook.SideBook} {
+			sb.Orders.BaseHeap = make(BaseHeap, 0, orders)
+			sb.OrdersMap = make(OrdersMap, orders)
+			sb.levels = newLevelIndex(orders)
+			sb.byAge = make(ageHeap, 0, orders)
+		}
+	}
+}
+
+// WithSafeMode makes Peek and Get on either side return copies, so callers
+// cannot reorder the book by mutating an order without calling Fix.
+func WithSafeMode() Option {
+	return func(ob *OrderBook) {
+		ob.AskBook.safe = true
+		ob.BidBook.safe = true
 	}
 }
 
-func NewOrderBook() *OrderBook {
+func NewOrderBook(opts ...Option) *OrderBook {
 	ob := OrderBook{}
 	ob.Init()
+	for _, opt := range opts {
+		opt(&ob)
+	}
 	return &ob
 }
 
-func (ob OrderBook) Midpoint() float64 {
-	if !ob.HasBoth() {
-		return 0
+// Sequence returns the sequence number of the most recent event stamped
+// by the book.
+func (ob *OrderBook) Sequence() uint64 {
+	return atomic.LoadUint64(&ob.sequence)
+}
+
+func (ob *OrderBook) nextSequence() uint64 {
+	return atomic.AddUint64(&ob.sequence, 1)
+}
+
+// tops returns the best ask and bid prices read together under both
+// sides' locks, and false when either side is empty.
+func (ob *OrderBook) tops() (ask, bid float64, ok bool) {
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	a, b := ob.AskBook.top(), ob.BidBook.top()
+	if a == nil || b == nil || a.Peek() == nil || b.Peek() == nil {
+		return 0, 0, false
 	}
-	return (float64(ob.AskBook.Peek().Price) + float64(ob.BidBook.Peek().Price)) / 2
+	return a.Peek().Price, b.Peek().Price, true
 }
 
-func (ob OrderBook) Spread() float64 {
-	if !ob.HasBoth() {
-		return 0
+// Midpoint returns the mean of the best ask and bid, and false when either
+// side is empty.
+func (ob *OrderBook) Midpoint() (float64, bool) {
+	ask, bid, ok := ob.tops()
+	if !ok {
+		return 0, false
 	}
-	return (float64(ob.AskBook.Peek().Price) - float64(ob.BidBook.Peek().Price))
+	return (ask + bid) / 2, true
 }
 
-func (ob OrderBook) HasBoth() bool {
-	return ob.AskBook.Len() > 0 && ob.BidBook.Len() > 0
+// Spread returns the best ask less the best bid, and false when either
+// side is empty.
+func (ob *OrderBook) Spread() (float64, bool) {
+	ask, bid, ok := ob.tops()
+	if !ok {
+		return 0, false
+	}
+	return ask - bid, true
 }
 
-func (ob OrderBook) Volume() float64 {
-	return ob.AskBook.volume() + ob.BidBook.volume()
+// SpreadBps returns the spread in basis points of the midpoint's
+// magnitude, and false when either side is empty or the midpoint is zero.
+func (ob *OrderBook) SpreadBps() (float64, bool) {
+	ask, bid, ok := ob.tops()
+	if !ok {
+		return 0, false
+	}
+	mid := math.Abs(ask+bid) / 2
+	if mid == 0 {
+		return 0, false
+	}
+	return (ask - bid) / mid * 10000, true
+}
+
+func (ob *OrderBook) HasBoth() bool {
+	t := ob.TopOfBook()
+	return t.HasAsk && t.HasBid
+}
+
+// Top is the best order and order count of each side at one instant.
+type Top struct {
+	Ask       Order `json:"ask"`
+	Bid       Order `json:"bid"`
+	AskOrders int   `json:"askOrders"`
+	BidOrders int   `json:"bidOrders"`
+	HasAsk    bool  `json:"hasAsk"`
+	HasBid    bool  `json:"hasBid"`
+}
+
+// TopOfBook reads both sides' best orders and counts together under both
+// sides' locks, so trading loops never act on one side's top from before
+// a pop and the other's from after.
+func (ob *OrderBook) TopOfBook() Top {
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	var t Top
+	t.Ask, t.AskOrders, t.HasAsk = ob.AskBook.topOfBook()
+	t.Bid, t.BidOrders, t.HasBid = ob.BidBook.topOfBook()
+	return t
+}
+
+// Volume returns the total resting quantity on both sides. It is kept up
+// to date as orders change, so it costs the same however deep the book is.
+// An order edited in place is counted at its new quantity once Fix is
+// called.
+func (ob *OrderBook) Volume() float64 {
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	return ob.AskBook.levels.quantity + ob.BidBook.levels.quantity
+}
+
+func (ob *OrderBook) Activity() (bids, asks Activity) {
+	return ob.BidBook.counters(), ob.AskBook.counters()
+}
+
+// Backlog returns the number of events waiting in the book's streams.
+func (ob *OrderBook) Backlog() int {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	return ob.streams.backlog()
 }
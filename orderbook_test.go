@@ -14,7 +14,11 @@
 package orderbook
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -123,3 +127,366 @@ func TestCopy(t *testing.T) {
 		t.Errorf("Expected source node weight to be unaltered. Expected %f, got %f", 1.0, srcNode.Weight)
 	}
 }
+
+func TestEqual(t *testing.T) {
+	src := NewOrderBook()
+	src.Submit(NewOrder(101, 5, "a1"), Sell)
+	src.Submit(NewOrder(101, 2, "a2"), Sell)
+	src.Submit(NewOrder(99, 4, "b1"), Buy)
+	src.Submit(NewOrder(101, 3, "x"), Buy)
+	src.Submit(NewOrder(100, 1, "tp"), Sell)
+	src.SubmitStop(Stop{Order: NewOrder(0, 1, "sl"), Side: Sell, StopPrice: 95, TrailAmount: 2})
+	if err := src.LinkOCO("g", "tp", "sl"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewOrderBook()
+	Copy(src, dst)
+	if !Equal(src, dst) {
+		t.Fatalf("Expected a copy to equal its source")
+	}
+	if o, _, _ := dst.Lookup("a1"); o.Quantity != 2 || o.Filled != 3 {
+		t.Errorf("Expected a1 to keep its partial fill, got %+v", o)
+	}
+	if dst.Sequence() != src.Sequence() {
+		t.Errorf("Expected sequence %d, got %d", src.Sequence(), dst.Sequence())
+	}
+	for _, ob := range []*OrderBook{src, dst} {
+		ob.Submit(NewOrder(100, 1, "y"), Buy)
+	}
+	if len(dst.Stops()) != 0 {
+		t.Errorf("Expected the copied OCO link to cancel sl, got %+v", dst.Stops())
+	}
+	if !Equal(src, dst) {
+		t.Errorf("Expected the copy to follow its source through the same flow")
+	}
+
+	tests := []struct {
+		name   string
+		change func(ob *OrderBook)
+	}{
+		{"a cancel", func(ob *OrderBook) { ob.Cancel("b1") }},
+		{"a repriced order", func(ob *OrderBook) { ob.Side(Sell).UpdatePrice("a2", 102) }},
+		{"a reweighted order", func(ob *OrderBook) { ob.Side(Sell).UpdateWeight("a2", 2) }},
+		{"a stop", func(ob *OrderBook) { ob.SubmitStop(Stop{Order: NewOrder(0, 1, "s"), Side: Buy, StopPrice: 110}) }},
+		{"a group", func(ob *OrderBook) { ob.LinkOCO("g2", "a1", "b1") }},
+	}
+	for _, tt := range tests {
+		cp := NewOrderBook()
+		Copy(src, cp)
+		tt.change(cp)
+		// compare the change itself, not the events it published
+		cp.sequence = src.Sequence()
+		if Equal(src, cp) || Equal(cp, src) {
+			t.Errorf("Expected %s to make the books unequal", tt.name)
+		}
+	}
+	cp := NewOrderBook()
+	Copy(src, cp)
+	cp.nextSequence()
+	if Equal(src, cp) {
+		t.Errorf("Expected a later sequence number to make the books unequal")
+	}
+}
+
+func TestPeekOrder(t *testing.T) {
+	ob := NewOrderBook()
+	if _, ok := ob.AskBook.PeekOrder(); ok {
+		t.Errorf("Expected no order from an empty book")
+	}
+	ask := NewOrder(101, 1, "a")
+	node := NewNode("a", &ask, 1)
+	ob.AskBook.Push(&node)
+	o, ok := ob.AskBook.PeekOrder()
+	if !ok || o.OrderId != "a" {
+		t.Fatalf("Expected order a, got %+v", o)
+	}
+	o.Price = 1
+	if ob.AskBook.Peek().Price != 101 {
+		t.Errorf("Expected PeekOrder to return a copy")
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	ob := NewOrderBook(WithSafeMode())
+	bids := []Order{NewOrder(100, 1, "a"), NewOrder(99, 1, "b")}
+	for i := range bids {
+		node := NewNode(bids[i].OrderId, &bids[i], 1)
+		ob.BidBook.Push(&node)
+	}
+	ob.BidBook.Peek().Price = 1
+	if n, ok := ob.BidBook.Get("b"); ok {
+		n.Peek().Price = 200
+		n.Weight = 5
+	}
+	if ob.BidBook.Peek().Price != 100 {
+		t.Errorf("Expected best bid to stay at 100, got %f", ob.BidBook.Peek().Price)
+	}
+	if n, _ := ob.BidBook.Get("b"); n.Peek().Price != 99 || n.Weight != 1 {
+		t.Errorf("Expected b to be unaltered, got %f weight %f", n.Peek().Price, n.Weight)
+	}
+	ob.BidBook.Remove("a")
+	if ob.BidBook.Len() != 1 || ob.BidBook.Peek().OrderId != "b" {
+		t.Errorf("Expected Remove to act on the live book in safe mode")
+	}
+}
+
+func TestSpreadAndMidpoint(t *testing.T) {
+	ob := NewOrderBook()
+	if _, ok := ob.Spread(); ok {
+		t.Errorf("Expected no spread on an empty book")
+	}
+	bid := NewOrder(99.5, 1, "b")
+	bidNode := NewNode("b", &bid, 1)
+	ob.BidBook.Push(&bidNode)
+	if _, ok := ob.Midpoint(); ok {
+		t.Errorf("Expected no midpoint on a one-sided book")
+	}
+	ask := NewOrder(100.5, 1, "a")
+	askNode := NewNode("a", &ask, 1)
+	ob.AskBook.Push(&askNode)
+
+	if mid, ok := ob.Midpoint(); !ok || mid != 100 {
+		t.Errorf("Expected midpoint 100, got %f", mid)
+	}
+	if spread, ok := ob.Spread(); !ok || spread != 1 {
+		t.Errorf("Expected spread 1, got %f", spread)
+	}
+	if bps, ok := ob.SpreadBps(); !ok || bps != 100 {
+		t.Errorf("Expected 100bps, got %f", bps)
+	}
+
+	ask.Price = 99.5
+	ob.AskBook.Fix("a")
+	if spread, ok := ob.Spread(); !ok || spread != 0 {
+		t.Errorf("Expected a genuine zero spread to be reported, got %f (%t)", spread, ok)
+	}
+}
+
+func TestSideBook(t *testing.T) {
+	ob := NewOrderBook()
+	orders := []Order{NewOrder(100, 1, "a"), NewOrder(101, 1, "b"), NewOrder(101, 1, "c")}
+	for _, side := range []Side{Buy, Sell} {
+		for i := range orders {
+			o := orders[i]
+			node := NewNode(o.OrderId, &o, 1)
+			ob.Side(side).Push(&node)
+		}
+	}
+	if ob.Side(Buy) != &ob.BidBook.SideBook || ob.Side(Sell) != &ob.AskBook.SideBook {
+		t.Fatalf("Expected Side to return the embedded side books")
+	}
+	if ob.Side(Buy).Side() != Buy || ob.Side(Sell).Side() != Sell {
+		t.Errorf("Expected side books to know their side")
+	}
+	if id := ob.Side(Buy).Peek().OrderId; id != "b" {
+		t.Errorf("Expected best bid b, got %s", id)
+	}
+	if id := ob.Side(Sell).Peek().OrderId; id != "a" {
+		t.Errorf("Expected best ask a, got %s", id)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	ob := NewOrderBook()
+	for i, o := range []Order{NewOrder(100, 2, "a"), NewOrder(101, 3, "b"), NewOrder(99, 1, "c")} {
+		o := o
+		node := NewNode(o.OrderId, &o, 1)
+		if i == 2 {
+			ob.BidBook.Push(&node)
+		} else {
+			ob.AskBook.Push(&node)
+		}
+	}
+	check := func(name string, volume, asks float64) {
+		t.Helper()
+		if v := ob.Volume(); v != volume {
+			t.Errorf("%s: expected volume %f, got %f", name, volume, v)
+		}
+		if n := ob.Notional(Sell); n != asks {
+			t.Errorf("%s: expected ask notional %f, got %f", name, asks, n)
+		}
+	}
+	check("push", 6, 503)
+
+	ob.Reduce("b", 1)
+	check("reduce", 5, 402)
+
+	ob.Batch([]Command{{Op: OpAmend, Side: Sell, Order: Order{OrderId: "a", Price: 102, Quantity: 4}}})
+	check("amend", 7, 610)
+
+	buy := NewOrder(101, 1, "x")
+	ob.Match(Buy, &buy)
+	check("match", 6, 509)
+
+	ob.AskBook.Remove("a")
+	ob.AskBook.Remove("b")
+	check("remove", 1, 0)
+}
+
+func TestAggregatesConcurrent(t *testing.T) {
+	ob := NewOrderBook()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("%d-%d", w, i)
+				ask, bid := NewOrder(101, 1, key), NewOrder(99, 1, key)
+				askNode, bidNode := NewNode(key, &ask, 1), NewNode(key, &bid, 1)
+				ob.AskBook.Push(&askNode)
+				ob.BidBook.Push(&bidNode)
+				ob.AskBook.Remove(key)
+				ob.BidBook.Remove(key)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if mid, ok := ob.Midpoint(); ok && mid != 100 {
+				t.Errorf("Expected midpoint 100, got %f", mid)
+			}
+			if v := ob.Volume(); v < 0 || v > 8 {
+				t.Errorf("Expected volume within 0..8, got %f", v)
+			}
+		}
+	}()
+	wg.Wait()
+	if v := ob.Volume(); v != 0 {
+		t.Errorf("Expected an emptied book to have no volume, got %f", v)
+	}
+}
+
+func TestUpdatePriceWeightAndQuantity(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	for _, o := range []Order{NewOrder(101, 1, "a"), NewOrder(102, 2, "b")} {
+		o := o
+		n := NewNode(o.OrderId, &o, 1)
+		ob.AskBook.Push(&n)
+	}
+	diffs := len(sink.diffs)
+
+	if !ob.AskBook.UpdatePrice("b", 100) {
+		t.Fatalf("Expected b to be found")
+	}
+	if price, size, ok := ob.BestAsk(); !ok || price != 100 || size != 2 {
+		t.Errorf("Expected 2 offered at 100, got %v at %v (%v)", size, price, ok)
+	}
+	if len(sink.diffs) != diffs+1 {
+		t.Fatalf("Expected one diff for the update, got %d", len(sink.diffs)-diffs)
+	}
+	if d := sink.diffs[len(sink.diffs)-1]; len(d.Asks) != 2 {
+		t.Errorf("Expected the diff to move b from 102 to 100, got %+v", d.Asks)
+	}
+
+	if !ob.AskBook.UpdateWeight("a", 0.5) {
+		t.Fatalf("Expected a to be found")
+	}
+	if price, _, ok := ob.BestAsk(); !ok || price != 50.5 {
+		t.Errorf("Expected a to lead at an effective 50.5, got %v %v", price, ok)
+	}
+	if !ob.AskBook.UpdateQuantity("b", 5) {
+		t.Fatalf("Expected b to be found")
+	}
+	if v := ob.Volume(); v != 6 {
+		t.Errorf("Expected volume 6 after resizing b, got %v", v)
+	}
+	if ob.AskBook.UpdatePrice("missing", 1) || ob.AskBook.UpdateWeight("missing", 1) || ob.AskBook.UpdateQuantity("missing", 1) {
+		t.Errorf("Expected no order to be found at an unknown key")
+	}
+
+	negative := NewOrderBook(WithNegativePrices())
+	negative.PushOrder(Sell, NewOrder(1, 1, "a"))
+	tests := []struct {
+		name   string
+		update func() bool
+		ok     bool
+	}{
+		{"a NaN price", func() bool { return ob.AskBook.UpdatePrice("a", math.NaN()) }, false},
+		{"an infinite price", func() bool { return ob.AskBook.UpdatePrice("a", math.Inf(1)) }, false},
+		{"a zero spot price", func() bool { return ob.AskBook.UpdatePrice("a", 0) }, false},
+		{"a negative spot price", func() bool { return ob.AskBook.UpdatePrice("a", -1) }, false},
+		{"a negative price where allowed", func() bool { return negative.AskBook.UpdatePrice("a", -1) }, true},
+		{"a NaN price where negatives are allowed", func() bool { return negative.AskBook.UpdatePrice("a", math.NaN()) }, false},
+		{"a NaN weight", func() bool { return ob.AskBook.UpdateWeight("a", math.NaN()) }, false},
+		{"an infinite weight", func() bool { return ob.AskBook.UpdateWeight("a", math.Inf(1)) }, false},
+		{"a zero weight", func() bool { return ob.AskBook.UpdateWeight("a", 0) }, false},
+		{"a negative weight", func() bool { return ob.AskBook.UpdateWeight("a", -1) }, false},
+	}
+	for _, tt := range tests {
+		if ok := tt.update(); ok != tt.ok {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.ok, ok)
+		}
+	}
+	if price, _, ok := ob.BestAsk(); !ok || price != 50.5 {
+		t.Errorf("Expected refused updates to leave a at an effective 50.5, got %v", price)
+	}
+	if err := ob.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOrderVenueJSON(t *testing.T) {
+	o := NewOrder(100, 1, "a")
+	o.Venue = "XNAS"
+	data, _ := json.Marshal(o)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if fields["venue"] != "XNAS" || fields["country"] != "XNAS" {
+		t.Errorf("Expected the venue under both names, got %s", data)
+	}
+	if data, _ := json.Marshal(NewOrder(100, 1, "b")); strings.Contains(string(data), "country") {
+		t.Errorf("Expected no venue fields without a venue, got %s", data)
+	}
+
+	tests := []struct {
+		input, venue string
+	}{
+		{`{"price":1,"quantity":1,"orderId":"a","country":"GB"}`, "GB"},
+		{`{"price":1,"quantity":1,"orderId":"a","venue":"XLON"}`, "XLON"},
+		{`{"price":1,"quantity":1,"orderId":"a","venue":"XLON","country":"GB"}`, "XLON"},
+	}
+	for _, tt := range tests {
+		var decoded Order
+		if err := json.Unmarshal([]byte(tt.input), &decoded); err != nil || decoded.Venue != tt.venue || decoded.OrderId != "a" {
+			t.Errorf("Expected venue %s from %s, got %+v (%v)", tt.venue, tt.input, decoded, err)
+		}
+	}
+}
+
+func TestTopOfBook(t *testing.T) {
+	ob := NewOrderBook()
+	if top := ob.TopOfBook(); top != (Top{}) || ob.HasBoth() {
+		t.Errorf("Expected an empty top for an empty book, got %+v", top)
+	}
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(98, 1, "b2"), Buy)
+	ob.Submit(NewOrder(101, 2, "a1"), Sell)
+	top := ob.TopOfBook()
+	if !top.HasBid || top.Bid.OrderId != "b1" || top.BidOrders != 2 || !top.HasAsk || top.Ask.OrderId != "a1" || top.AskOrders != 1 {
+		t.Errorf("Expected b1 of 2 bids and a1 of 1 ask, got %+v", top)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			o := NewOrder(100, 1, fmt.Sprint("x", i))
+			n := NewNode(o.OrderId, &o, 1)
+			ob.BidBook.Push(&n)
+			ob.BidBook.Pop()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if o, n, ok := ob.BidBook.TopOfBook(); !ok || n < 2 || o.OrderId == "" {
+			t.Fatalf("Expected a best bid among at least 2, got %+v of %d (%v)", o, n, ok)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package paper is a simulated venue for developing trading bots against:
+// an orderbook.OrderBook whose orders belong to accounts, with both sides
+// of every fill and their fees booked to the accounts' positions.
+package paper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+	"github.com/laneshetron/go-orderbook/accounts"
+)
+
+var (
+	ErrAccountExists  = errors.New("paper: account already open")
+	ErrUnknownAccount = errors.New("paper: unknown account")
+	ErrUnknownOrder   = errors.New("paper: no such order for account")
+)
+
+// Exchange is a paper-trading venue over a single book. Book is exposed
+// for market data, such as Quotes, Trades and Depth, but orders must go
+// through the Exchange for their fills to be booked.
+type Exchange struct {
+	Book   *orderbook.OrderBook
+	Ledger *accounts.Ledger
+
+	lock     sync.Mutex
+	accounts map[string]bool
+	owners   map[string]string // resting OrderId to account
+}
+
+// NewExchange returns an Exchange over a new book built with opts, such
+// as orderbook.WithFees. Orders submitted without an id are numbered by
+// orderbook.MonotonicIDs unless opts set another generator. Positions are
+// marked at the book's midpoint.
+func NewExchange(opts ...orderbook.Option) *Exchange {
+	opts = append([]orderbook.Option{orderbook.WithIDGenerator(orderbook.MonotonicIDs("paper-"))}, opts...)
+	ob := orderbook.NewOrderBook(opts...)
+	return &Exchange{
+		Book:     ob,
+		Ledger:   accounts.NewLedger(accounts.Midpoint(ob)),
+		accounts: make(map[string]bool),
+		owners:   make(map[string]string),
+	}
+}
+
+func (ex *Exchange) OpenAccount(account string) error {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	if ex.accounts[account] {
+		return ErrAccountExists
+	}
+	ex.accounts[account] = true
+	return nil
+}
+
+// Submit places order on side for account, which must be open. The
+// taker's trades and fees are booked to it and the passive side of each
+// to the account of the resting order, as are those of the stops its
+// trades trigger.
+func (ex *Exchange) Submit(account string, order orderbook.Order, side orderbook.Side) orderbook.ExecutionReport {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	if !ex.accounts[account] {
+		return orderbook.ExecutionReport{OrderId: order.OrderId, ClientOrderId: order.ClientOrderId,
+			Side: side, Status: orderbook.StatusRejected, Remaining: order.Quantity, Err: ErrUnknownAccount}
+	}
+	order.Account = account
+	report := ex.Book.Submit(order, side)
+	ex.book(account, report)
+	return report
+}
+
+// SubmitStop holds s for account, which must be open, until it triggers.
+// Its fills are booked to account like those of Submit.
+func (ex *Exchange) SubmitStop(account string, s orderbook.Stop) orderbook.ExecutionReport {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	if !ex.accounts[account] {
+		return orderbook.ExecutionReport{OrderId: s.OrderId, ClientOrderId: s.ClientOrderId,
+			Side: s.Side, Status: orderbook.StatusRejected, Remaining: s.Quantity, Err: ErrUnknownAccount}
+	}
+	s.Account = account
+	report := ex.Book.SubmitStop(s)
+	if report.Status != orderbook.StatusRejected {
+		ex.owners[report.OrderId] = account
+	}
+	ex.triggered(report.Triggered)
+	return report
+}
+
+// book books the fills of report, taken by account, and of the stops it
+// triggered, and tracks which orders still rest. The caller holds the
+// lock.
+func (ex *Exchange) book(account string, report orderbook.ExecutionReport) {
+	ex.Ledger.Apply(account, report)
+	for _, t := range report.Trades {
+		maker, ok := ex.owners[t.MakerId]
+		if !ok {
+			continue
+		}
+		ex.Ledger.Fill(maker, report.Side.Opposite(), t.Price, t.Quantity)
+		ex.Ledger.Charge(maker, t.MakerFee)
+		if _, _, ok := ex.Book.Lookup(t.MakerId); !ok {
+			delete(ex.owners, t.MakerId)
+		}
+	}
+	if report.Resting {
+		ex.owners[report.OrderId] = account
+	}
+	ex.triggered(report.Triggered)
+}
+
+// triggered books the reports of fired stops to the accounts that placed
+// them. The caller holds the lock.
+func (ex *Exchange) triggered(reports []orderbook.ExecutionReport) {
+	for _, r := range reports {
+		owner, ok := ex.owners[r.OrderId]
+		if !ok {
+			continue
+		}
+		delete(ex.owners, r.OrderId)
+		ex.book(owner, r)
+	}
+}
+
+// Cancel cancels one of account's resting orders or pending stops.
+func (ex *Exchange) Cancel(account, orderId string) error {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	if !ex.accounts[account] {
+		return ErrUnknownAccount
+	}
+	if ex.owners[orderId] != account {
+		return ErrUnknownOrder
+	}
+	delete(ex.owners, orderId)
+	if !ex.Book.Cancel(orderId) && !ex.Book.CancelStop(orderId) {
+		return ErrUnknownOrder
+	}
+	return nil
+}
+
+// Orders returns account's resting orders ordered by id.
+func (ex *Exchange) Orders(account string) []orderbook.Order {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	var orders []orderbook.Order
+	for id, owner := range ex.owners {
+		if owner != account {
+			continue
+		}
+		if o, _, ok := ex.Book.Lookup(id); ok {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderId < orders[j].OrderId })
+	return orders
+}
+
+func (ex *Exchange) Position(account string) (accounts.Position, error) {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	if !ex.accounts[account] {
+		return accounts.Position{}, ErrUnknownAccount
+	}
+	return ex.Ledger.Position(account), nil
+}
+
+// Positions returns the position of every open account ordered by
+// account, flat ones included.
+func (ex *Exchange) Positions() []accounts.Position {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+
+	ps := make([]accounts.Position, 0, len(ex.accounts))
+	for account := range ex.accounts {
+		ps = append(ps, ex.Ledger.Position(account))
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Account < ps[j].Account })
+	return ps
+}
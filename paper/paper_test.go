@@ -0,0 +1,107 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package paper
+
+import (
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestExchange(t *testing.T) {
+	ex := NewExchange(orderbook.WithFees(orderbook.Fees{MakerFixed: -0.5, TakerFixed: 1}))
+	for _, a := range []string{"maker", "taker"} {
+		if err := ex.OpenAccount(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ex.OpenAccount("maker"); err != ErrAccountExists {
+		t.Errorf("Expected ErrAccountExists, got %v", err)
+	}
+	if r := ex.Submit("nobody", orderbook.Order{Price: 100, Quantity: 1}, orderbook.Buy); r.Err != ErrUnknownAccount {
+		t.Errorf("Expected ErrUnknownAccount, got %v", r.Err)
+	}
+
+	ask := ex.Submit("maker", orderbook.Order{Price: 101, Quantity: 5}, orderbook.Sell)
+	if !ask.Resting || ask.OrderId == "" {
+		t.Fatalf("Expected the ask to rest with a generated id, got %+v", ask)
+	}
+	if orders := ex.Orders("maker"); len(orders) != 1 || orders[0].Account != "maker" {
+		t.Errorf("Expected one resting order for maker, got %+v", orders)
+	}
+	if err := ex.Cancel("taker", ask.OrderId); err != ErrUnknownOrder {
+		t.Errorf("Expected taker not to cancel maker's order, got %v", err)
+	}
+
+	r := ex.Submit("taker", orderbook.Order{Price: 101, Quantity: 3}, orderbook.Buy)
+	if r.Filled != 3 {
+		t.Fatalf("Expected 3 filled, got %+v", r)
+	}
+	ex.Submit("taker", orderbook.Order{Price: 99, Quantity: 1}, orderbook.Buy)
+
+	maker, _ := ex.Position("maker")
+	taker, _ := ex.Position("taker")
+	if maker.Quantity != -3 || maker.AvgPrice != 101 || maker.Realized != 0.5 {
+		t.Errorf("Expected maker short 3 at 101 with a 0.5 rebate, got %+v", maker)
+	}
+	if taker.Quantity != 3 || taker.AvgPrice != 101 || taker.Realized != -1 {
+		t.Errorf("Expected taker long 3 at 101 less a fee of 1, got %+v", taker)
+	}
+	if realized, unrealized := ex.Ledger.PnL("taker"); realized != -1 || unrealized != -3 {
+		t.Errorf("Expected taker P&L of -1 realized and -3 marked at 100, got %v %v", realized, unrealized)
+	}
+
+	if err := ex.Cancel("maker", ask.OrderId); err != nil {
+		t.Errorf("Expected maker to cancel its order, got %v", err)
+	}
+	if err := ex.Cancel("maker", ask.OrderId); err != ErrUnknownOrder {
+		t.Errorf("Expected a second cancel to fail, got %v", err)
+	}
+	if ps := ex.Positions(); len(ps) != 2 || ps[0].Account != "maker" || ps[1].Account != "taker" {
+		t.Errorf("Expected positions for both accounts, got %+v", ps)
+	}
+	if _, err := ex.Position("nobody"); err != ErrUnknownAccount {
+		t.Errorf("Expected ErrUnknownAccount, got %v", err)
+	}
+}
+
+func TestExchangeStops(t *testing.T) {
+	ex := NewExchange()
+	for _, a := range []string{"maker", "taker", "stopper"} {
+		ex.OpenAccount(a)
+	}
+	ex.Submit("maker", orderbook.Order{Price: 100, Quantity: 1}, orderbook.Sell)
+	ex.Submit("maker", orderbook.Order{Price: 102, Quantity: 2}, orderbook.Sell)
+	stop := ex.SubmitStop("stopper", orderbook.Stop{Order: orderbook.Order{Quantity: 2, OrderId: "s1"},
+		Side: orderbook.Buy, StopPrice: 100})
+	if stop.Err != nil {
+		t.Fatalf("Expected the stop to be held, got %v", stop.Err)
+	}
+
+	r := ex.Submit("taker", orderbook.Order{Price: 100, Quantity: 1}, orderbook.Buy)
+	if len(r.Triggered) != 1 || r.Triggered[0].Filled != 2 {
+		t.Fatalf("Expected the stop to fire and fill 2, got %+v", r.Triggered)
+	}
+	stopper, _ := ex.Position("stopper")
+	maker, _ := ex.Position("maker")
+	if stopper.Quantity != 2 || stopper.AvgPrice != 102 {
+		t.Errorf("Expected stopper long 2 at 102, got %+v", stopper)
+	}
+	if maker.Quantity != -3 {
+		t.Errorf("Expected maker short 3 across both fills, got %+v", maker)
+	}
+	if err := ex.Cancel("stopper", "s1"); err != ErrUnknownOrder {
+		t.Errorf("Expected the fired stop to be gone, got %v", err)
+	}
+}
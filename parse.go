@@ -0,0 +1,210 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrMissingField = errors.New("orderbook: field is required")
+	ErrDecimal      = errors.New("orderbook: not a plain decimal number")
+	ErrPrecision    = errors.New("orderbook: too many decimal places")
+	ErrOutOfRange   = errors.New("orderbook: value out of range")
+)
+
+// ParseLimits bounds the orders ParseOrder accepts: at most
+// PriceDecimals and QuantityDecimals digits after the decimal point, and
+// prices and quantities no greater than MaxPrice and MaxQuantity, where
+// those are positive. MaxPrice bounds the magnitude of negative prices.
+type ParseLimits struct {
+	PriceDecimals    int
+	QuantityDecimals int
+	MaxPrice         float64
+	MaxQuantity      float64
+	// NegativePrices accepts zero and negative prices, for books made
+	// WithNegativePrices.
+	NegativePrices bool
+}
+
+// DefaultParseLimits allows 9 decimal places and any size.
+var DefaultParseLimits = ParseLimits{PriceDecimals: 9, QuantityDecimals: maxQuantityPrecision}
+
+// FieldError is what is wrong with one field of a parsed order.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + strings.TrimPrefix(e.Err.Error(), "orderbook: ")
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// ValidationError lists every field of an order that failed to parse or
+// validate.
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Error()
+	}
+	return "orderbook: invalid order: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap makes errors.Is and errors.As see through to each field's error.
+func (e ValidationError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, f := range e {
+		errs[i] = f
+	}
+	return errs
+}
+
+// orderFields are the raw fields of an order before validation.
+type orderFields struct {
+	OrderId       string          `json:"orderId"`
+	Side          string          `json:"side"`
+	Price         decimalField    `json:"price"`
+	Quantity      decimalField    `json:"quantity"`
+	Account       string          `json:"account"`
+	Venue         string          `json:"venue"`
+	Country       string          `json:"country"` // the former name of venue
+	Session       string          `json:"session"`
+	ClientOrderId string          `json:"clientOrderId"`
+	Category      json.RawMessage `json:"category"`
+}
+
+// decimalField takes a JSON number or string as its literal text, so
+// decimal places are counted as written.
+type decimalField string
+
+func (d *decimalField) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = decimalField(s)
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	*d = decimalField(data)
+	return nil
+}
+
+// ParseOrder parses an order encoded as a JSON object with the field
+// names of Order plus "side", rejecting unknown fields. Prices and
+// quantities may be JSON numbers or decimal strings. Every problem found is
+// reported in a ValidationError.
+func ParseOrder(data []byte, limits ParseLimits) (Order, Side, error) {
+	var f orderFields
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return Order{}, 0, fmt.Errorf("orderbook: %v", err)
+	}
+	return f.validate(limits)
+}
+
+// ParseOrderRecord parses a CSV record of side, order id, price and
+// quantity, with an optional account, as ParseOrder does.
+func ParseOrderRecord(record []string, limits ParseLimits) (Order, Side, error) {
+	if len(record) < 4 || len(record) > 5 {
+		return Order{}, 0, fmt.Errorf("orderbook: expected 4 or 5 fields, got %d", len(record))
+	}
+	f := orderFields{Side: record[0], OrderId: record[1], Price: decimalField(record[2]), Quantity: decimalField(record[3])}
+	if len(record) == 5 {
+		f.Account = record[4]
+	}
+	return f.validate(limits)
+}
+
+func (f *orderFields) validate(limits ParseLimits) (Order, Side, error) {
+	var errs ValidationError
+	fail := func(field string, err error) {
+		errs = append(errs, &FieldError{field, err})
+	}
+
+	o := Order{OrderId: strings.TrimSpace(f.OrderId), Account: f.Account, Venue: f.Venue,
+		Session: f.Session, ClientOrderId: f.ClientOrderId}
+	if o.Venue == "" {
+		o.Venue = f.Country
+	}
+	if o.OrderId == "" {
+		fail("orderId", ErrMissingOrderId)
+	}
+	side, err := ParseSide(strings.TrimSpace(f.Side))
+	if f.Side == "" {
+		fail("side", ErrMissingField)
+	} else if err != nil {
+		fail("side", err)
+	}
+	var perr, qerr error
+	if o.Price, perr = parseDecimal(string(f.Price), limits.PriceDecimals, limits.MaxPrice); perr == nil && !(o.Price > 0) && !limits.NegativePrices {
+		perr = ErrInvalidPrice
+	}
+	if perr != nil {
+		fail("price", perr)
+	}
+	if o.Quantity, qerr = parseDecimal(string(f.Quantity), limits.QuantityDecimals, limits.MaxQuantity); qerr == nil && !(o.Quantity > 0) {
+		qerr = ErrInvalidQuantity
+	}
+	if qerr != nil {
+		fail("quantity", qerr)
+	}
+	if len(f.Category) > 0 {
+		if err := json.Unmarshal(f.Category, &o.Category); err != nil {
+			fail("category", ErrInvalidCategory)
+		}
+	}
+	if len(errs) > 0 {
+		return Order{}, 0, errs
+	}
+	return o, side, nil
+}
+
+// parseDecimal parses s, which must be digits with at most one decimal
+// point and an optional leading sign, with no more than decimals
+// significant places after the point and no greater in magnitude than max
+// when that is positive.
+func parseDecimal(s string, decimals int, max float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrMissingField
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return 0, ErrDecimal
+	}
+	if len(strings.TrimRight(frac, "0")) > decimals {
+		return 0, ErrPrecision
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(v, 0) || max > 0 && math.Abs(v) > max {
+		return 0, ErrOutOfRange
+	}
+	return v, nil
+}
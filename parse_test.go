@@ -0,0 +1,101 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOrder(t *testing.T) {
+	o, side, err := ParseOrder([]byte(`{"orderId":"a","side":"buy","price":"100.25","quantity":1.5,"account":"acct","category":"customer"}`),
+		DefaultParseLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Order{Price: 100.25, Quantity: 1.5, OrderId: "a", Account: "acct", Category: Customer}
+	if o != expected || side != Buy {
+		t.Errorf("Expected %+v on the buy side, got %+v on %s", expected, o, side)
+	}
+
+	limits := ParseLimits{PriceDecimals: 2, QuantityDecimals: 0, MaxQuantity: 1000}
+	tests := []struct {
+		input  string
+		fields map[string]error
+	}{
+		{`{"orderId":"a","side":"sell","price":"1.50","quantity":"10"}`, nil},
+		{`{"side":"sideways","price":"abc","quantity":-1}`, map[string]error{
+			"orderId": ErrMissingOrderId, "side": nil, "price": ErrDecimal, "quantity": ErrInvalidQuantity}},
+		{`{"orderId":"a","side":"buy","price":1.005,"quantity":2000}`, map[string]error{
+			"price": ErrPrecision, "quantity": ErrOutOfRange}},
+		{`{"orderId":"a","price":"1e3","quantity":"0.5","category":"vip"}`, map[string]error{
+			"side": ErrMissingField, "price": ErrDecimal, "quantity": ErrPrecision, "category": ErrInvalidCategory}},
+		{`{"orderId":"a","side":"buy","quantity":1}`, map[string]error{"price": ErrMissingField}},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseOrder([]byte(tt.input), limits)
+		if tt.fields == nil {
+			if err != nil {
+				t.Errorf("Expected %s to parse, got %v", tt.input, err)
+			}
+			continue
+		}
+		var verr ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Expected a ValidationError for %s, got %v", tt.input, err)
+			continue
+		}
+		if len(verr) != len(tt.fields) {
+			t.Errorf("Expected %d field errors for %s, got %v", len(tt.fields), tt.input, verr)
+		}
+		for _, f := range verr {
+			want, ok := tt.fields[f.Field]
+			if !ok || want != nil && !errors.Is(f, want) {
+				t.Errorf("Expected %s for %s in %s, got %v", want, f.Field, tt.input, f.Err)
+			}
+		}
+	}
+
+	if _, _, err := ParseOrder([]byte(`{"orderId":"a","side":"buy","price":1,"quantity":1,"extra":1}`), limits); err == nil {
+		t.Errorf("Expected unknown fields to be rejected")
+	}
+	if _, _, err := ParseOrder([]byte(`{"orderId":"a","side":"buy","price":0,"quantity":1}`), limits); !errors.Is(err, ErrInvalidPrice) {
+		t.Errorf("Expected ErrInvalidPrice, got %v", err)
+	}
+}
+
+func TestParseOrderRecord(t *testing.T) {
+	o, side, err := ParseOrderRecord([]string{"sell", "a", "101.5", "3", "acct"}, DefaultParseLimits)
+	if err != nil || side != Sell || o.Price != 101.5 || o.Quantity != 3 || o.Account != "acct" {
+		t.Errorf("Expected a sell of 3 at 101.5 for acct, got %+v %s %v", o, side, err)
+	}
+	if _, _, err := ParseOrderRecord([]string{"sell", "a", "101.5"}, DefaultParseLimits); err == nil {
+		t.Errorf("Expected a short record to be rejected")
+	}
+	_, _, err = ParseOrderRecord([]string{"buy", " ", "NaN", "Inf"}, DefaultParseLimits)
+	if verr, ok := err.(ValidationError); !ok || len(verr) != 3 {
+		t.Errorf("Expected errors for the id, price and quantity, got %v", err)
+	}
+}
+
+func TestParseOrderVenue(t *testing.T) {
+	for _, input := range []string{
+		`{"orderId":"a","side":"buy","price":1,"quantity":1,"venue":"XNAS"}`,
+		`{"orderId":"a","side":"buy","price":1,"quantity":1,"country":"XNAS"}`,
+	} {
+		if o, _, err := ParseOrder([]byte(input), DefaultParseLimits); err != nil || o.Venue != "XNAS" {
+			t.Errorf("Expected venue XNAS from %s, got %+v (%v)", input, o, err)
+		}
+	}
+}
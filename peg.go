@@ -0,0 +1,196 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrNoPegReference = errors.New("orderbook: no reference price to peg to")
+
+type PegType int
+
+const (
+	// PegMidpoint floats with the midpoint.
+	PegMidpoint PegType = iota
+	// PegPrimary floats with the best price on the order's own side.
+	PegPrimary
+)
+
+// Peg prices an order at its reference plus Offset, capped at Limit when
+// it is non-zero: never above it for buys, never below it for sells.
+// Reference prices are taken from the unpegged orders only.
+type Peg struct {
+	Type   PegType `json:"type"`
+	Offset float64 `json:"offset"`
+	Limit  float64 `json:"limit,omitempty"`
+}
+
+func (p Peg) price(side Side, bid, ask float64, hasBid, hasAsk bool) (float64, bool) {
+	var ref float64
+	switch {
+	case p.Type == PegMidpoint && hasBid && hasAsk:
+		ref = (bid + ask) / 2
+	case p.Type == PegPrimary && side == Buy && hasBid:
+		ref = bid
+	case p.Type == PegPrimary && side == Sell && hasAsk:
+		ref = ask
+	default:
+		return 0, false
+	}
+	price := ref + p.Offset
+	if p.Limit != 0 && ((side == Buy && price > p.Limit) || (side == Sell && price < p.Limit)) {
+		price = p.Limit
+	}
+	return price, true
+}
+
+// pegged is a pegged order; node is nil while it is being submitted.
+type pegged struct {
+	Peg
+	node *Node
+}
+
+// pegBook tracks resting pegged orders per side. Its lock is held while
+// repricing. A repeg asked for while one is running, from another
+// goroutine or through the change hook by the reprices themselves, is
+// left to the running one, which makes another pass for it.
+type pegBook struct {
+	lock  sync.Mutex
+	sides [2]map[string]*pegged
+
+	state   sync.Mutex
+	running bool
+	again   bool
+}
+
+// begin reports whether the caller should run the repeg, or marks the
+// running one to go again.
+func (pb *pegBook) begin() bool {
+	pb.state.Lock()
+	defer pb.state.Unlock()
+
+	if pb.running {
+		pb.again = true
+		return false
+	}
+	pb.running = true
+	return true
+}
+
+// end reports whether another pass was asked for meanwhile.
+func (pb *pegBook) end() bool {
+	pb.state.Lock()
+	defer pb.state.Unlock()
+
+	if pb.again {
+		pb.again = false
+		return true
+	}
+	pb.running = false
+	return false
+}
+
+func (ob *OrderBook) references() (bid, ask float64, hasBid, hasAsk bool) {
+	unpegged := func(side Side) func(*Node) bool {
+		return func(n *Node) bool {
+			p, ok := ob.pegs.sides[side][n.Key]
+			return !ok || (p.node != nil && p.node != n)
+		}
+	}
+	bid, hasBid = ob.BidBook.bestWhere(unpegged(Buy))
+	ask, hasAsk = ob.AskBook.bestWhere(unpegged(Sell))
+	return
+}
+
+// SubmitPeg prices order from peg and submits it. If it rests, the book
+// reprices it whenever its reference moves; repricing re-ranks the order
+// but does not match it. With no reference price the order enters at
+// Limit, or is rejected if there is none.
+func (ob *OrderBook) SubmitPeg(order Order, side Side, peg Peg) ExecutionReport {
+	ob.pegs.lock.Lock()
+	bid, ask, hasBid, hasAsk := ob.references()
+	price, ok := peg.price(side, bid, ask, hasBid, hasAsk)
+	switch {
+	case ok:
+		order.Price = ob.instrument.passive(side, price)
+	case peg.Limit != 0:
+		order.Price = peg.Limit
+	default:
+		ob.pegs.lock.Unlock()
+		return ExecutionReport{OrderId: order.OrderId, Side: side, Remaining: order.Quantity,
+			Status: StatusRejected, Err: ErrNoPegReference}
+	}
+	if ob.pegs.sides[side] == nil {
+		ob.pegs.sides[side] = make(map[string]*pegged)
+	}
+	p := &pegged{Peg: peg}
+	ob.pegs.sides[side][order.OrderId] = p
+	ob.pegs.lock.Unlock()
+
+	report := ob.Submit(order, side)
+
+	ob.pegs.lock.Lock()
+	defer ob.pegs.lock.Unlock()
+	if n, ok := ob.Side(side).lookup(order.OrderId); ok && report.Resting {
+		p.node = n
+	} else {
+		delete(ob.pegs.sides[side], order.OrderId)
+	}
+	return report
+}
+
+// repeg reprices every pegged order against the current references,
+// forgetting those no longer resting.
+func (ob *OrderBook) repeg() {
+	if !ob.pegs.begin() {
+		return
+	}
+	for again := true; again; again = ob.pegs.end() {
+		ob.pegs.lock.Lock()
+		ob.repegOnce()
+		ob.pegs.lock.Unlock()
+	}
+}
+
+// repegOnce is one pass of repeg. The caller holds the pegs' lock.
+func (ob *OrderBook) repegOnce() {
+	if len(ob.pegs.sides[Buy])+len(ob.pegs.sides[Sell]) == 0 {
+		return
+	}
+	bid, ask, hasBid, hasAsk := ob.references()
+	for side, pegs := range ob.pegs.sides {
+		b := ob.Side(Side(side))
+		keys := make([]string, 0, len(pegs))
+		for key := range pegs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p := pegs[key]
+			if p.node == nil {
+				continue
+			}
+			if n, ok := b.lookup(key); !ok || n != p.node {
+				delete(pegs, key)
+				continue
+			}
+			if price, ok := p.price(Side(side), bid, ask, hasBid, hasAsk); ok {
+				b.reprice(key, ob.instrument.passive(Side(side), price))
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPeg(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(98, 1, "b1"))
+	ob.PushOrder(Sell, NewOrder(102, 1, "a1"))
+
+	if r := ob.SubmitPeg(NewOrder(0, 1, "mid"), Buy, Peg{Type: PegMidpoint}); !r.Resting {
+		t.Fatalf("Expected midpoint peg to rest, got %+v", r)
+	}
+	ob.SubmitPeg(NewOrder(0, 1, "primary"), Sell, Peg{Type: PegPrimary, Offset: 1})
+	ob.SubmitPeg(NewOrder(0, 1, "capped"), Buy, Peg{Type: PegMidpoint, Offset: 1, Limit: 99.5})
+
+	price := func(side Side, key string) float64 {
+		n, _ := ob.Side(side).Get(key)
+		return n.Peek().Price
+	}
+	if price(Buy, "mid") != 100 || price(Sell, "primary") != 103 || price(Buy, "capped") != 99.5 {
+		t.Errorf("Expected pegs at 100, 103 and capped 99.5, got %g, %g and %g",
+			price(Buy, "mid"), price(Sell, "primary"), price(Buy, "capped"))
+	}
+	if ob.BidBook.Peek().OrderId != "mid" {
+		t.Errorf("Expected the midpoint peg at the top of the bids")
+	}
+
+	ob.PushOrder(Buy, NewOrder(100.5, 1, "b2"))
+	ob.PushOrder(Sell, NewOrder(101, 1, "a2"))
+	if price(Buy, "mid") != 100.75 || price(Sell, "primary") != 102 || price(Buy, "capped") != 99.5 {
+		t.Errorf("Expected pegs to follow to 100.75 and 102, got %g and %g", price(Buy, "mid"), price(Sell, "primary"))
+	}
+	if ob.BidBook.Peek().OrderId != "mid" || ob.AskBook.Peek().OrderId != "a2" {
+		t.Errorf("Expected books re-ranked around the pegs")
+	}
+	checkLevels(t, "bids", ob.BidBook.Orders.BaseHeap, ob.BidBook.levels)
+	checkLevels(t, "asks", ob.AskBook.Orders.BaseHeap, ob.AskBook.levels)
+
+	ob.Cancel("mid")
+	ob.Cancel("b2")
+	if _, ok := ob.pegs.sides[Buy]["mid"]; ok {
+		t.Errorf("Expected cancelled peg to be forgotten")
+	}
+
+	empty := NewOrderBook()
+	if r := empty.SubmitPeg(NewOrder(0, 1, "x"), Buy, Peg{Type: PegMidpoint}); r.Err != ErrNoPegReference {
+		t.Errorf("Expected no reference rejection, got %v", r.Err)
+	}
+}
+
+func TestPegConcurrentRepeg(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(98, 1, "b1"))
+	ob.PushOrder(Sell, NewOrder(102, 1, "a1"))
+	ob.SubmitPeg(NewOrder(0, 1, "mid"), Buy, Peg{Type: PegMidpoint})
+
+	// hold the pegs as a SubmitPeg in progress would while the reference moves
+	ob.pegs.lock.Lock()
+	done := make(chan struct{})
+	go func() {
+		ob.PushOrder(Buy, NewOrder(100, 1, "b2"))
+		close(done)
+	}()
+	for {
+		if _, ok := ob.BidBook.lookup("b2"); ok {
+			break
+		}
+		runtime.Gosched()
+	}
+	ob.pegs.lock.Unlock()
+	<-done
+
+	if o, _, _ := ob.Lookup("mid"); o.Price != 101 {
+		t.Errorf("Expected the peg to follow the new bid to 101, got %g", o.Price)
+	}
+}
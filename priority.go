@@ -0,0 +1,209 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+)
+
+// Category is the kind of participant behind an order, for exchange rules
+// that rank participants within a price level.
+type Category uint8
+
+const (
+	Unclassified Category = iota
+	Customer
+	Proprietary
+	MarketMaker
+)
+
+var ErrInvalidCategory = errors.New("orderbook: invalid category")
+
+var categoryNames = []string{"", "customer", "proprietary", "market-maker"}
+
+func (c Category) String() string {
+	if int(c) < len(categoryNames) {
+		return categoryNames[c]
+	}
+	return "unknown"
+}
+
+func ParseCategory(s string) (Category, error) {
+	for i, name := range categoryNames {
+		if s == name {
+			return Category(i), nil
+		}
+	}
+	return 0, ErrInvalidCategory
+}
+
+func (c Category) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *Category) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseCategory(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Comparator reports whether a ranks ahead of b, two orders resting at
+// the same effective price. Orders it ranks neither way keep time
+// priority.
+type Comparator func(a, b *Order) bool
+
+// WithComparator ranks orders within each price level with c ahead of
+// time priority, both for matching and for the order an Allocator sees
+// them in.
+func WithComparator(c Comparator) Option {
+	return func(ob *OrderBook) {
+		ob.AskBook.Orders.ahead = c
+		ob.BidBook.Orders.ahead = c
+	}
+}
+
+func categoryRank(categories []Category, c Category) int {
+	for i, ahead := range categories {
+		if c == ahead {
+			return i
+		}
+	}
+	return len(categories)
+}
+
+// CategoryPriority ranks orders of the given categories ahead of the
+// rest, in the order given, such as customer orders ahead of proprietary
+// ones with CategoryPriority(Customer).
+func CategoryPriority(categories ...Category) Comparator {
+	return func(a, b *Order) bool {
+		return categoryRank(categories, a.Category) < categoryRank(categories, b.Category)
+	}
+}
+
+// DisplayedPriority ranks displayed orders ahead of hidden ones, as
+// venues do for the undisplayed quantity of their orders. hidden reports
+// which orders are not displayed, since the book itself shows every order.
+func DisplayedPriority(hidden func(*Order) bool) Comparator {
+	return func(a, b *Order) bool {
+		return !hidden(a) && hidden(b)
+	}
+}
+
+// OrderAllocator is implemented by Allocators that need the orders being
+// filled: taker is the incoming order and resting the orders at the
+// level, in priority order, with their quantities in quantities.
+type OrderAllocator interface {
+	Allocator
+	AllocateOrders(taker *Order, qty float64, resting []*Order, quantities []float64) []float64
+}
+
+type tiered struct {
+	first func(taker, resting *Order) bool
+	then  Allocator
+}
+
+func (a tiered) Allocate(qty float64, resting []float64) []float64 {
+	return a.then.Allocate(qty, resting)
+}
+
+// AllocateOrders fills the first tier in priority order, then splits what
+// is left among the rest with then.
+func (a tiered) AllocateOrders(taker *Order, qty float64, resting []*Order, quantities []float64) []float64 {
+	shares := make([]float64, len(resting))
+	var rest []int
+	for i, o := range resting {
+		if !a.first(taker, o) {
+			rest = append(rest, i)
+			continue
+		}
+		if qty > 0 {
+			shares[i] = math.Min(qty, quantities[i])
+			qty -= shares[i]
+		}
+	}
+	if qty <= 0 || len(rest) == 0 {
+		return shares
+	}
+	left := make([]float64, len(rest))
+	for j, i := range rest {
+		left[j] = quantities[i]
+	}
+	for j, s := range allocateWith(a.then, taker, qty, pick(resting, rest), left) {
+		shares[rest[j]] = s
+	}
+	return shares
+}
+
+func pick(orders []*Order, indices []int) []*Order {
+	out := make([]*Order, len(indices))
+	for j, i := range indices {
+		out[j] = orders[i]
+	}
+	return out
+}
+
+// CategoryFirst fills the orders of the given categories at a level
+// first, in priority order, and splits the rest of the fill among the
+// other orders with then, or in time priority when then is nil.
+func CategoryFirst(then Allocator, categories ...Category) Allocator {
+	if then == nil {
+		then = FIFO
+	}
+	return tiered{func(_, o *Order) bool { return categoryRank(categories, o.Category) < len(categories) }, then}
+}
+
+// BrokerPriority fills the resting orders of the taker's own Account
+// first, as broker preferencing rules do, and splits the rest with then.
+// Orders without an Account are never preferred.
+func BrokerPriority(then Allocator) Allocator {
+	if then == nil {
+		then = FIFO
+	}
+	return tiered{func(taker, o *Order) bool { return o.Account != "" && o.Account == taker.Account }, then}
+}
+
+// DisplayedFirst fills the displayed orders at a level first, in priority
+// order, and splits the rest of the fill among the hidden ones with then,
+// or in time priority when then is nil.
+func DisplayedFirst(then Allocator, hidden func(*Order) bool) Allocator {
+	if then == nil {
+		then = FIFO
+	}
+	return tiered{func(_, o *Order) bool { return !hidden(o) }, then}
+}
+
+// allocateWith asks a for shares of qty, passing the orders along when it
+// is an OrderAllocator.
+func allocateWith(a Allocator, taker *Order, qty float64, resting []*Order, quantities []float64) []float64 {
+	if oa, ok := a.(OrderAllocator); ok {
+		return oa.AllocateOrders(taker, qty, resting, quantities)
+	}
+	return a.Allocate(qty, quantities)
+}
+
+// byComparator sorts nodes of one level with c, keeping time priority
+// among equals.
+func byComparator(nodes []*Node, c Comparator) {
+	sort.SliceStable(nodes, func(i, j int) bool { return c(nodes[i].Peek(), nodes[j].Peek()) })
+}
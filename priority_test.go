@@ -0,0 +1,120 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		fills map[string]float64
+	}{
+		{"time", nil, map[string]float64{"prop": 2, "mm": 1}},
+		{"customer first", []Option{WithComparator(CategoryPriority(Customer))},
+			map[string]float64{"cust": 2, "prop": 1}},
+		{"customer then market maker", []Option{WithComparator(CategoryPriority(Customer, MarketMaker))},
+			map[string]float64{"cust": 2, "mm": 1}},
+		{"customer first, pro rata after", []Option{WithAllocator(CategoryFirst(ProRata, Customer)), WithQuantityPrecision(0)},
+			map[string]float64{"cust": 2, "prop": 1}},
+		{"broker", []Option{WithAllocator(BrokerPriority(nil))},
+			map[string]float64{"mm": 1, "prop": 2}},
+		{"displayed first", []Option{WithComparator(DisplayedPriority(func(o *Order) bool { return o.OrderId == "prop" }))},
+			map[string]float64{"mm": 1, "cust": 2}},
+		{"displayed first, pro rata after", []Option{WithAllocator(DisplayedFirst(ProRata, func(o *Order) bool { return o.OrderId != "mm" })),
+			WithQuantityPrecision(0)},
+			map[string]float64{"mm": 1, "prop": 1, "cust": 1}},
+	}
+	for _, tt := range tests {
+		ob := NewOrderBook(tt.opts...)
+		for _, o := range []struct {
+			id       string
+			qty      float64
+			category Category
+			account  string
+		}{
+			{"prop", 2, Proprietary, ""},
+			{"mm", 1, MarketMaker, "broker"},
+			{"cust", 2, Customer, ""},
+		} {
+			order := NewOrder(100, o.qty, o.id)
+			order.Category, order.Account = o.category, o.account
+			ob.Submit(order, Sell)
+		}
+		x := NewOrder(100, 3, "x")
+		x.Account = "broker"
+		report := ob.Submit(x, Buy)
+		fills := map[string]float64{}
+		for _, trade := range report.Trades {
+			fills[trade.MakerId] += trade.Quantity
+		}
+		if len(fills) != len(tt.fills) {
+			t.Errorf("%s: expected fills %v, got %v", tt.name, tt.fills, fills)
+			continue
+		}
+		for key, qty := range tt.fills {
+			if fills[key] != qty {
+				t.Errorf("%s: expected %s to fill %f, got %f", tt.name, key, qty, fills[key])
+			}
+		}
+	}
+}
+
+func TestPriorityQueuePosition(t *testing.T) {
+	ob := NewOrderBook(WithComparator(CategoryPriority(Customer)))
+	for _, o := range []struct {
+		id       string
+		qty      float64
+		category Category
+	}{
+		{"prop", 2, Proprietary},
+		{"mm", 1, MarketMaker},
+		{"cust", 3, Customer},
+	} {
+		order := NewOrder(100, o.qty, o.id)
+		order.Category = o.category
+		ob.Submit(order, Sell)
+	}
+	tests := []struct {
+		id     string
+		orders int
+		qty    float64
+	}{
+		{"cust", 0, 0},
+		{"prop", 1, 3},
+		{"mm", 2, 5},
+	}
+	for _, tt := range tests {
+		pos, ok := ob.QueuePosition(tt.id)
+		if !ok || pos.Orders != tt.orders || pos.Quantity != tt.qty {
+			t.Errorf("Expected %s behind %d orders for %v, got %+v", tt.id, tt.orders, tt.qty, pos)
+		}
+	}
+}
+
+func TestCategoryJSON(t *testing.T) {
+	o := NewOrder(100, 1, "a")
+	o.Category = MarketMaker
+	data, _ := json.Marshal(o)
+	var decoded Order
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Category != MarketMaker {
+		t.Errorf("Expected the category to round trip, got %v from %s (%v)", decoded.Category, data, err)
+	}
+	if _, err := ParseCategory("broker"); err != ErrInvalidCategory {
+		t.Errorf("Expected ErrInvalidCategory, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+)
+
+const maxQuantityPrecision = 9
+
+var ErrQuantityPrecision = errors.New("orderbook: quantity is not a whole number of base units")
+
+// units converts quantities to and from int64 base units of 10^-precision.
+// A zero scale leaves quantities as plain floats.
+type units struct {
+	scale float64
+}
+
+// WithQuantityPrecision makes the book count quantities in whole base units
+// of 10^-decimals, so 0 trades whole units only. Orders with finer
+// quantities are rejected on entry, and fills are subtracted in base units
+// so partial fills do not accumulate float drift. decimals is capped at 9.
+func WithQuantityPrecision(decimals int) Option {
+	return func(ob *OrderBook) {
+		if decimals < 0 {
+			decimals = 0
+		} else if decimals > maxQuantityPrecision {
+			decimals = maxQuantityPrecision
+		}
+		u := units{math.Pow10(decimals)}
+		ob.units, ob.AskBook.units, ob.BidBook.units = u, u, u
+	}
+}
+
+func (u units) toUnits(q float64) (int64, bool) {
+	if u.scale == 0 {
+		return 0, false
+	}
+	v := math.Round(q * u.scale)
+	if math.Abs(q*u.scale-v) > 1e-6 || math.Abs(v) > math.MaxInt64/2 {
+		return 0, false
+	}
+	return int64(v), true
+}
+
+func (u units) fromUnits(v int64) float64 {
+	return float64(v) / u.scale
+}
+
+// check returns ErrQuantityPrecision if a precision is set and q is not a
+// whole number of base units.
+func (u units) check(q float64) error {
+	if _, ok := u.toUnits(q); u.scale != 0 && !ok {
+		return ErrQuantityPrecision
+	}
+	return nil
+}
+
+// sub returns a less b, exactly in base units when a precision is set.
+func (u units) sub(a, b float64) float64 {
+	if u.scale == 0 {
+		return a - b
+	}
+	return u.fromUnits(int64(math.Round(a*u.scale)) - int64(math.Round(b*u.scale)))
+}
+
+func (u units) add(a, b float64) float64 {
+	return u.sub(a, -b)
+}
+
+// BaseUnits returns q as a whole number of base units, and false when the
+// book has no quantity precision or q is not a whole number of units.
+func (ob *OrderBook) BaseUnits(q float64) (int64, bool) {
+	return ob.units.toUnits(q)
+}
+
+// FromBaseUnits returns the quantity of v base units. Without a quantity
+// precision a base unit is 1.
+func (ob *OrderBook) FromBaseUnits(v int64) float64 {
+	if ob.units.scale == 0 {
+		return float64(v)
+	}
+	return ob.units.fromUnits(v)
+}
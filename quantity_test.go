@@ -0,0 +1,61 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuantityPrecision(t *testing.T) {
+	ob := NewOrderBook(WithQuantityPrecision(1))
+	cases := []struct {
+		qty float64
+		err error
+	}{
+		{0.3, nil},
+		{2, nil},
+		{0.25, ErrQuantityPrecision},
+	}
+	for _, c := range cases {
+		if r := ob.Submit(NewOrder(100, c.qty, "q"), Sell); !errors.Is(r.Err, c.err) {
+			t.Errorf("Expected %v for quantity %g, got %v", c.err, c.qty, r.Err)
+		}
+		ob.Cancel("q")
+	}
+	if _, err := ob.Batch([]Command{{Op: OpAmend, Side: Sell, Order: Order{OrderId: "q", Quantity: 0.05}}}); !errors.Is(err, ErrQuantityPrecision) {
+		t.Errorf("Expected amend to a fractional unit to be rejected, got %v", err)
+	}
+
+	// 0.1 + 0.2 drifts as floats; in base units the fills are exact.
+	ob.Submit(NewOrder(100, 0.3, "a"), Sell)
+	ob.Submit(NewOrder(100, 0.1, "b1"), Buy)
+	r := ob.Submit(NewOrder(100, 0.2, "b2"), Buy)
+	if r.Status != StatusFilled || r.Filled != 0.2 {
+		t.Errorf("Expected b2 filled for 0.2, got %v %g", r.Status, r.Filled)
+	}
+	if ob.AskBook.Len() != 0 {
+		t.Errorf("Expected no dust left on the ask, got %g", ob.AskBook.Peek().Quantity)
+	}
+
+	if v, ok := ob.BaseUnits(1.7); !ok || v != 17 {
+		t.Errorf("Expected 17 base units, got %d %v", v, ok)
+	}
+	if q := ob.FromBaseUnits(17); q != 1.7 {
+		t.Errorf("Expected 1.7, got %g", q)
+	}
+	if _, ok := NewOrderBook().BaseUnits(1); ok {
+		t.Errorf("Expected no base units without a precision")
+	}
+}
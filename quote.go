@@ -0,0 +1,58 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "math"
+
+// MultiQuote holds copies of the best orders on each side in priority
+// order, best first.
+type MultiQuote struct {
+	Asks     []Order `json:"asks"`
+	Bids     []Order `json:"bids"`
+	Sequence uint64  `json:"sequence"`
+}
+
+// QuoteN returns the top n orders per side, including their OrderId and
+// Venue. Changes to the returned orders do not affect the book.
+func (ob *OrderBook) QuoteN(n int) *MultiQuote {
+	return &MultiQuote{
+		Asks:     ob.AskBook.topOrders(n),
+		Bids:     ob.BidBook.topOrders(n),
+		Sequence: ob.Sequence(),
+	}
+}
+
+// topOrders returns copies of up to n of the side's best orders, walking the
+// level index and ranking each level by the book's comparator.
+func (sb *SideBook) topOrders(n int) []Order {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	orders := make([]Order, 0, n)
+	sb.eachLevel(math.Inf(-1), math.Inf(1), func(l *level) bool {
+		nodes := l.orders
+		if sb.Orders.ahead != nil {
+			nodes = append([]*Node(nil), nodes...)
+			byComparator(nodes, sb.Orders.ahead)
+		}
+		for _, node := range nodes {
+			if len(orders) == n {
+				return false
+			}
+			orders = append(orders, *node.Peek())
+		}
+		return true
+	})
+	return orders
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteN(t *testing.T) {
+	ob := NewOrderBook()
+	bids := []Order{
+		{Price: 99, Quantity: 1, OrderId: "a", Venue: "US"},
+		{Price: 100, Quantity: 2, OrderId: "b", Venue: "GB"},
+		{Price: 98, Quantity: 3, OrderId: "c", Venue: "JP"},
+		{Price: 100, Quantity: 4, OrderId: "d", Venue: "DE"},
+	}
+	for i := range bids {
+		node := NewNode(bids[i].OrderId, &bids[i], 1)
+		ob.BidBook.Push(&node)
+	}
+	ask := Order{Price: 101, Quantity: 1, OrderId: "e", Venue: "US"}
+	node := NewNode("e", &ask, 1)
+	ob.AskBook.Push(&node)
+
+	q := ob.QuoteN(3)
+	expected := []string{"b", "d", "a"}
+	if len(q.Bids) != len(expected) {
+		t.Fatalf("Expected %d bids, got %d", len(expected), len(q.Bids))
+	}
+	for i, id := range expected {
+		if q.Bids[i].OrderId != id {
+			t.Errorf("Expected bid %d to be %s, got %s", i, id, q.Bids[i].OrderId)
+		}
+	}
+	if len(q.Asks) != 1 || q.Asks[0].Venue != "US" {
+		t.Errorf("Expected the single ask with its venue, got %+v", q.Asks)
+	}
+	if q.Sequence != ob.Sequence() {
+		t.Errorf("Expected quote sequence %d, got %d", ob.Sequence(), q.Sequence)
+	}
+	q.Bids[0].Price = 1
+	if ob.BidBook.Peek().Price != 100 {
+		t.Errorf("Expected QuoteN to return copies")
+	}
+}
+
+func TestQuoteNMatchesPriority(t *testing.T) {
+	ob := NewOrderBook(WithComparator(CategoryPriority(Customer)))
+	for i, o := range []struct {
+		id       string
+		price    float64
+		weight   float64
+		category Category
+	}{
+		{"prop", 100, 1, Proprietary},
+		{"weighted", 50, 2, Proprietary},
+		{"cust", 100, 1, Customer},
+		{"low", 99, 1, Customer},
+		{"high", 101, 0.5, MarketMaker},
+	} {
+		order := NewOrder(o.price, float64(i+1), o.id)
+		order.Category = o.category
+		node := NewNode(o.id, &order, o.weight)
+		ob.BidBook.Push(&node)
+	}
+	q := ob.QuoteN(4)
+	expected := []string{"cust", "prop", "weighted", "low"}
+	if len(q.Bids) != len(expected) {
+		t.Fatalf("Expected %d bids, got %d", len(expected), len(q.Bids))
+	}
+	for i, id := range expected {
+		if q.Bids[i].OrderId != id {
+			t.Errorf("Expected bid %d to be %s, got %s", i, id, q.Bids[i].OrderId)
+		}
+	}
+	bids, _ := ob.Depth(0)
+	if want := levels(ob.BidBook.sorted(), 0); !reflect.DeepEqual(bids, want) {
+		t.Errorf("Expected depth %v, got %v", want, bids)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sync"
+)
+
+// QuoterConfig sets what a Quoter quotes and how far it must move first.
+type QuoterConfig struct {
+	// Size, when positive, quotes on each side the price needed to fill
+	// Size in full, with Size as the quantity; a side that cannot fill it
+	// is left out. Otherwise the best level and its size are quoted.
+	Size float64
+	// A side is requoted when its price moves by more than PriceThreshold
+	// or, for a top-of-book quote, its size by more than SizeThreshold, or
+	// when it appears or empties.
+	PriceThreshold float64
+	SizeThreshold  float64
+}
+
+// Quoter derives quotes from a book's aggregated depth rather than its
+// top orders, and publishes one only when it has moved by more than the
+// configured thresholds. The orders in its quotes stand for a level, or
+// for Size at a price, so they carry no OrderId.
+type Quoter struct {
+	book    *OrderBook
+	config  QuoterConfig
+	publish func(*Quote)
+
+	lock     sync.Mutex
+	ask, bid quoted
+}
+
+// NewQuoter returns a Quoter over ob calling publish with each quote, and
+// adds it to ob as a sink so it requotes as the book changes.
+func NewQuoter(ob *OrderBook, config QuoterConfig, publish func(*Quote)) *Quoter {
+	q := &Quoter{book: ob, config: config, publish: publish}
+	q.Update()
+	ob.AddSink(q)
+	return q
+}
+
+// Current returns a quote of the book as it stands, thresholds aside.
+func (q *Quoter) Current() *Quote {
+	ask, bid := q.sides()
+	return &Quote{Ask: ask, Bid: bid, Sequence: q.book.Sequence()}
+}
+
+func (q *Quoter) sides() (ask, bid *Order) {
+	if q.config.Size <= 0 {
+		if price, size, ok := q.book.BestAsk(); ok {
+			ask = &Order{Price: price, Quantity: size}
+		}
+		if price, size, ok := q.book.BestBid(); ok {
+			bid = &Order{Price: price, Quantity: size}
+		}
+		return ask, bid
+	}
+	bids, asks := q.book.Depth(0)
+	return sizedPrice(asks, q.config.Size), sizedPrice(bids, q.config.Size)
+}
+
+// sizedPrice returns the worst price among lvls, best first, needed to
+// fill size, or nil when they hold less.
+func sizedPrice(lvls []Level, size float64) *Order {
+	var total float64
+	for _, l := range lvls {
+		if total += l.Quantity; total >= size {
+			return &Order{Price: l.Price, Quantity: size}
+		}
+	}
+	return nil
+}
+
+// moved reports whether o differs from the last quoted side by more than
+// the thresholds.
+func (q *Quoter) moved(last *quoted, o *Order) bool {
+	if o == nil || !last.ok {
+		return o != nil || last.ok
+	}
+	return math.Abs(o.Price-last.order.Price) > q.config.PriceThreshold ||
+		math.Abs(o.Quantity-last.order.Quantity) > q.config.SizeThreshold
+}
+
+// Update requotes from the book as it stands, publishing if either side
+// has moved past the thresholds. A side that has not moved keeps its last
+// quoted value in the new quote.
+func (q *Quoter) Update() {
+	q.lock.Lock()
+	ask, bid := q.sides()
+	if !q.moved(&q.ask, ask) && !q.moved(&q.bid, bid) {
+		q.lock.Unlock()
+		return
+	}
+	if q.moved(&q.ask, ask) {
+		q.ask.set(ask)
+	}
+	if q.moved(&q.bid, bid) {
+		q.bid.set(bid)
+	}
+	quote := &Quote{Sequence: q.book.Sequence()}
+	if q.ask.ok {
+		quote.Ask = copyOrder(&q.ask.order)
+	}
+	if q.bid.ok {
+		quote.Bid = copyOrder(&q.bid.order)
+	}
+	q.lock.Unlock()
+
+	if q.publish != nil {
+		q.publish(quote)
+	}
+}
+
+func (q *Quoter) Quote(*Quote)      { q.Update() }
+func (q *Quoter) Trade(*TradeEvent) {}
+func (q *Quoter) Diff(*DepthDiff)   { q.Update() }
@@ -0,0 +1,86 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func quotedSide(o *Order) (float64, float64, bool) {
+	if o == nil {
+		return 0, 0, false
+	}
+	return o.Price, o.Quantity, true
+}
+
+func TestQuoterThresholds(t *testing.T) {
+	ob := NewOrderBook()
+	var quotes []*Quote
+	NewQuoter(ob, QuoterConfig{PriceThreshold: 0.5, SizeThreshold: 2}, func(q *Quote) { quotes = append(quotes, q) })
+	if len(quotes) != 0 {
+		t.Fatalf("Expected no quote for an empty book, got %d", len(quotes))
+	}
+
+	ob.Submit(NewOrder(100, 1, "b1"), Buy)    // bid appears
+	ob.Submit(NewOrder(100, 1, "b2"), Buy)    // size +1, under the threshold
+	ob.Submit(NewOrder(100.25, 1, "b3"), Buy) // price +0.25, under the threshold
+	ob.Submit(NewOrder(101, 5, "a1"), Sell)   // ask appears
+	ob.Submit(NewOrder(101, 3, "a2"), Sell)   // size +3
+	ob.Cancel("b3")                           // back to 100 with size 2, neither past the threshold
+
+	cases := []struct {
+		bid, bidSize float64
+		hasBid       bool
+		ask, askSize float64
+		hasAsk       bool
+	}{
+		{100, 1, true, 0, 0, false},
+		{100, 1, true, 101, 5, true},
+		{100, 1, true, 101, 8, true},
+	}
+	if len(quotes) != len(cases) {
+		t.Fatalf("Expected %d quotes, got %d", len(cases), len(quotes))
+	}
+	for i, c := range cases {
+		bp, bs, bok := quotedSide(quotes[i].Bid)
+		ap, as, aok := quotedSide(quotes[i].Ask)
+		if bp != c.bid || bs != c.bidSize || bok != c.hasBid || ap != c.ask || as != c.askSize || aok != c.hasAsk {
+			t.Errorf("Expected quote %d to be %+v, got bid %v %v %v ask %v %v %v", i, c, bp, bs, bok, ap, as, aok)
+		}
+	}
+}
+
+func TestQuoterSize(t *testing.T) {
+	ob := NewOrderBook()
+	var last *Quote
+	q := NewQuoter(ob, QuoterConfig{Size: 5}, func(q *Quote) { last = q })
+	ob.Submit(NewOrder(101, 2, "a1"), Sell)
+	if last != nil {
+		t.Errorf("Expected no quote until a side can fill 5, got %+v", last)
+	}
+	ob.Submit(NewOrder(102, 2, "a2"), Sell)
+	ob.Submit(NewOrder(103, 2, "a3"), Sell)
+	ob.Submit(NewOrder(99, 10, "b1"), Buy)
+	if p, s, ok := quotedSide(last.Ask); !ok || p != 103 || s != 5 {
+		t.Errorf("Expected 5 offered at 103, got %v %v %v", p, s, ok)
+	}
+	if p, s, ok := quotedSide(last.Bid); !ok || p != 99 || s != 5 {
+		t.Errorf("Expected 5 bid at 99, got %v %v %v", p, s, ok)
+	}
+	ob.Cancel("a2")
+	if last.Ask != nil {
+		t.Errorf("Expected the ask to drop out once it cannot fill 5, got %+v", last.Ask)
+	}
+	if c := q.Current(); c.Ask != nil || c.Bid == nil || c.Bid.Price != 99 {
+		t.Errorf("Expected the current quote to match, got %+v", c)
+	}
+}
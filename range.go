@@ -0,0 +1,62 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// RangeQuery returns copies of the orders resting on side at effective
+// prices from min to max inclusive, best first and in priority order
+// within a price. Only the levels in range are visited, not every order.
+func (ob *OrderBook) RangeQuery(side Side, min, max float64) []Order {
+	b := ob.Side(side)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	nodes := b.inRange(min, max)
+	orders := make([]Order, len(nodes))
+	for i, n := range nodes {
+		orders[i] = *n.Peek()
+	}
+	return orders
+}
+
+// CancelRange cancels the orders RangeQuery would return and returns
+// copies of them. A diff is published per cancelled order once the lock
+// is released.
+func (ob *OrderBook) CancelRange(side Side, min, max float64) []Order {
+	b := ob.Side(side)
+	b.lock.Lock()
+	var cancelled []Order
+	for _, n := range b.inRange(min, max) {
+		cancelled = append(cancelled, *n.Peek())
+		b.cancel(n.Key)
+	}
+	b.lock.Unlock()
+
+	ob.publishPending(side == Buy, side == Sell, true)
+	return cancelled
+}
+
+// inRange returns the indexed nodes priced from min to max, best first.
+// The caller holds the lock.
+func (sb *SideBook) inRange(min, max float64) []*Node {
+	var nodes []*Node
+	sb.eachLevel(min, max, func(l *level) bool {
+		start := len(nodes)
+		nodes = append(nodes, l.orders...)
+		if sb.Orders.ahead != nil {
+			byComparator(nodes[start:], sb.Orders.ahead)
+		}
+		return true
+	})
+	return nodes
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestRangeQuery(t *testing.T) {
+	ob := NewOrderBook()
+	for i, p := range []float64{95, 97, 99, 97, 100} {
+		ob.Submit(NewOrder(p, 1, "b"+string(rune('0'+i))), Buy)
+	}
+	ob.Submit(NewOrder(101, 1, "a0"), Sell)
+	ob.Submit(NewOrder(103, 1, "a1"), Sell)
+
+	tests := []struct {
+		side     Side
+		min, max float64
+		expected []string
+	}{
+		{Buy, 96, 99, []string{"b2", "b1", "b3"}},
+		{Buy, 100, 100, []string{"b4"}},
+		{Buy, 101, 110, nil},
+		{Sell, 0, 1000, []string{"a0", "a1"}},
+	}
+	for _, tt := range tests {
+		orders := ob.RangeQuery(tt.side, tt.min, tt.max)
+		var ids []string
+		for _, o := range orders {
+			ids = append(ids, o.OrderId)
+		}
+		if len(ids) != len(tt.expected) {
+			t.Errorf("Expected %v for %s %v-%v, got %v", tt.expected, tt.side, tt.min, tt.max, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.expected[i] {
+				t.Errorf("Expected %v for %s %v-%v, got %v", tt.expected, tt.side, tt.min, tt.max, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestCancelRange(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	ob.Submit(NewOrder(95, 1, "b0"), Buy)
+	ob.Submit(NewOrder(97, 1, "b1"), Buy)
+	ob.Submit(NewOrder(97, 2, "b2"), Buy)
+	sink.diffs = nil
+
+	cancelled := ob.CancelRange(Buy, 96, 98)
+	if len(cancelled) != 2 || cancelled[0].OrderId != "b1" || cancelled[1].OrderId != "b2" {
+		t.Errorf("Expected b1 and b2 cancelled, got %+v", cancelled)
+	}
+	if ob.OrderCount(Buy) != 1 || len(sink.diffs) != 2 {
+		t.Errorf("Expected one order left and 2 diffs, got %d and %d", ob.OrderCount(Buy), len(sink.diffs))
+	}
+}
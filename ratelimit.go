@@ -0,0 +1,126 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimits throttle each account's traffic the way an exchange would.
+// Zero leaves a limit off.
+type RateLimits struct {
+	// OrdersPerSecond caps the orders an account may submit in any
+	// rolling second.
+	OrdersPerSecond int
+	// CancelRatio caps an account's cancels and replaces per new order
+	// over RatioWindow, one minute if zero. A replace is a Submit reusing
+	// the id of one of its resting orders; an amend-down, which keeps the
+	// price and only lowers the quantity, costs the matcher nothing and is
+	// not counted.
+	CancelRatio float64
+	RatioWindow time.Duration
+}
+
+// WithRateLimits enforces l at Submit, rejecting orders from accounts
+// over a limit with a *RejectError, reported to Reject hooks like any
+// other rejection. Orders without an Account are not limited.
+func WithRateLimits(l RateLimits) Option {
+	return func(ob *OrderBook) {
+		if l.RatioWindow <= 0 {
+			l.RatioWindow = time.Minute
+		}
+		ob.limiter = &rateLimiter{limits: l, accounts: make(map[string]*traffic)}
+	}
+}
+
+type rateLimiter struct {
+	limits RateLimits
+
+	lock     sync.Mutex
+	accounts map[string]*traffic
+}
+
+// traffic is an account's recent activity, oldest first.
+type traffic struct {
+	orders  []time.Time
+	news    []time.Time
+	cancels []time.Time
+}
+
+func since(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func (t *rateLimiter) traffic(account string, now time.Time) *traffic {
+	a, ok := t.accounts[account]
+	if !ok {
+		a = &traffic{}
+		t.accounts[account] = a
+	}
+	a.orders = since(a.orders, now.Add(-time.Second))
+	cutoff := now.Add(-t.limits.RatioWindow)
+	a.news, a.cancels = since(a.news, cutoff), since(a.cancels, cutoff)
+	return a
+}
+
+// admit checks o against the limits and, if it is admitted, counts it.
+// prev is the account's resting order o would replace, if any.
+func (t *rateLimiter) admit(o *Order, prev *Order, now time.Time) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	a := t.traffic(o.Account, now)
+	if l := t.limits.OrdersPerSecond; l > 0 && len(a.orders) >= l {
+		return &RejectError{RejectRate, float64(l), float64(len(a.orders) + 1)}
+	}
+	if l := t.limits.CancelRatio; l > 0 && len(a.cancels) > 0 {
+		if r := float64(len(a.cancels)) / float64(max(len(a.news), 1)); r > l {
+			return &RejectError{RejectCancelRatio, l, r}
+		}
+	}
+	a.orders = append(a.orders, now)
+	switch {
+	case prev == nil:
+		a.news = append(a.news, now)
+	case prev.Price != o.Price || o.Quantity >= prev.Quantity:
+		a.cancels = append(a.cancels, now)
+	}
+	return nil
+}
+
+// cancelled counts a cancel by account.
+func (t *rateLimiter) cancelled(account string, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	a := t.traffic(account, now)
+	a.cancels = append(a.cancels, now)
+}
+
+// rateLimited applies the rate limits, if any, to o arriving on side.
+func (ob *OrderBook) rateLimited(side Side, o *Order) error {
+	if ob.limiter == nil || o.Account == "" {
+		return nil
+	}
+	var prev *Order
+	if r, s, ok := ob.Lookup(o.OrderId); ok && s == side && r.Account == o.Account {
+		prev = &r
+	}
+	return ob.limiter.admit(o, prev, ob.BidBook.clock())
+}
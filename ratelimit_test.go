@@ -0,0 +1,71 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"testing"
+	"time"
+)
+
+func rejectReason(r ExecutionReport) (RejectReason, bool) {
+	if e, ok := r.Err.(*RejectError); ok {
+		return e.Reason, true
+	}
+	return 0, false
+}
+
+func TestRateLimitOrders(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var rejected []ExecutionReport
+	ob := NewOrderBook(WithClock(func() time.Time { return now }), WithRateLimits(RateLimits{OrdersPerSecond: 2}),
+		WithHooks(Hooks{Reject: func(r *ExecutionReport) { rejected = append(rejected, *r) }}))
+	submit := func(id string) ExecutionReport {
+		return ob.Submit(Order{Price: 100, Quantity: 1, OrderId: id, Account: "acct"}, Buy)
+	}
+	submit("1")
+	submit("2")
+	if reason, ok := rejectReason(submit("3")); !ok || reason != RejectRate {
+		t.Errorf("Expected the third order in a second to be rate limited, got %v", reason)
+	}
+	if r := ob.Submit(NewOrder(100, 1, "anon"), Buy); r.Status == StatusRejected {
+		t.Errorf("Expected orders without an account not to be limited, got %v", r.Err)
+	}
+	if len(rejected) != 1 {
+		t.Errorf("Expected one rejection event, got %d", len(rejected))
+	}
+	now = now.Add(time.Second)
+	if r := submit("3"); r.Status == StatusRejected {
+		t.Errorf("Expected the window to roll over, got %v", r.Err)
+	}
+}
+
+func TestRateLimitCancelRatio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	ob := NewOrderBook(WithClock(func() time.Time { return now }), WithRateLimits(RateLimits{CancelRatio: 1}))
+	submit := func(id string, price, qty float64) ExecutionReport {
+		now = now.Add(time.Millisecond)
+		return ob.Submit(Order{Price: price, Quantity: qty, OrderId: id, Account: "acct"}, Buy)
+	}
+	submit("a", 100, 5)
+	submit("a", 100, 3) // amend-down, not counted
+	submit("a", 100, 3) // replace at the same size
+	ob.Cancel("a")      // second cancel, ratio 2 per new order
+	if reason, ok := rejectReason(submit("b", 100, 1)); !ok || reason != RejectCancelRatio {
+		t.Errorf("Expected the account to be throttled on its cancel ratio, got %v", reason)
+	}
+	now = now.Add(time.Minute)
+	if r := submit("b", 100, 1); r.Status == StatusRejected {
+		t.Errorf("Expected the ratio window to roll over, got %v", r.Err)
+	}
+}
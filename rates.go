@@ -0,0 +1,117 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// RateProvider supplies the weight for orders from a venue, as given by
+// Order.Venue, such as the rate of the currency it quotes in, so a
+// consolidated book ranks them by their value in a common currency.
+type RateProvider interface {
+	Rate(venue string) (float64, bool)
+}
+
+// RateTable is a RateProvider whose rates are set by hand. Books built
+// WithRates on a RateTable re-weight their orders whenever a rate is set.
+type RateTable struct {
+	lock     sync.Mutex
+	rates    map[string]float64
+	watchers []func(venue string)
+}
+
+func (t *RateTable) Rate(venue string) (float64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	r, ok := t.rates[venue]
+	return r, ok
+}
+
+func (t *RateTable) Set(venue string, rate float64) {
+	t.lock.Lock()
+	if t.rates == nil {
+		t.rates = make(map[string]float64)
+	}
+	t.rates[venue] = rate
+	watchers := t.watchers
+	t.lock.Unlock()
+
+	for _, w := range watchers {
+		w(venue)
+	}
+}
+
+// OnChange registers fn to be called with the venue of every rate set.
+func (t *RateTable) OnChange(fn func(venue string)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.watchers = append(t.watchers, fn)
+}
+
+// WithRates weights orders entering through Submit, PushOrder and Batch
+// by p's rate for their venue, or 1 when it has none. If p also has an
+// OnChange method, like RateTable, the book calls Reweight on every
+// change.
+func WithRates(p RateProvider) Option {
+	return func(ob *OrderBook) {
+		ob.rates = p
+		if n, ok := p.(interface{ OnChange(func(string)) }); ok {
+			n.OnChange(func(venue string) { ob.Reweight(venue) })
+		}
+	}
+}
+
+func (ob *OrderBook) weight(o *Order) float64 {
+	if ob.rates == nil {
+		return 1
+	}
+	if r, ok := ob.rates.Rate(o.Venue); ok {
+		return r
+	}
+	return 1
+}
+
+// reweight sets the weight of every resting order in venue and restores
+// their priority, returning how many changed.
+func (sb *SideBook) reweight(venue string, weight float64) int {
+	defer sb.flush()
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	var changed int
+	for _, n := range sb.byArrival() {
+		if o := n.Peek(); o == nil || o.Venue != venue || n.Weight == weight {
+			continue
+		}
+		n.Weight = weight
+		prev := n.price
+		heap.Fix(&sb.Orders, n.index)
+		sb.levels.move(n)
+		sb.record(opFix, n, prev)
+		sb.record(opFix, n, n.price)
+		changed++
+	}
+	return changed
+}
+
+// Reweight applies the current rate for venue to its resting orders on
+// both sides and returns how many were re-weighted.
+func (ob *OrderBook) Reweight(venue string) int {
+	w := ob.weight(&Order{Venue: venue})
+	return ob.BidBook.reweight(venue, w) + ob.AskBook.reweight(venue, w)
+}
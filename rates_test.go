@@ -0,0 +1,53 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestRates(t *testing.T) {
+	rates := &RateTable{}
+	rates.Set("EUR", 1.1)
+	ob := NewOrderBook(WithRates(rates))
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+
+	ob.Submit(Order{Price: 100, Quantity: 1, OrderId: "usd", Venue: "USD"}, Sell)
+	ob.Submit(Order{Price: 95, Quantity: 1, OrderId: "eur", Venue: "EUR"}, Sell)
+	if o := ob.AskBook.Peek(); o.OrderId != "usd" {
+		t.Errorf("Expected the USD ask ahead of 95 EUR at 1.1, got %s", o.OrderId)
+	}
+
+	diffs := len(sink.diffs)
+	rates.Set("EUR", 1.0)
+	if o := ob.AskBook.Peek(); o.OrderId != "eur" {
+		t.Errorf("Expected the EUR ask to move ahead once re-weighted, got %s", o.OrderId)
+	}
+	if len(sink.diffs) != diffs+1 {
+		t.Errorf("Expected one diff for the re-weight, got %d", len(sink.diffs)-diffs)
+	}
+	if n := ob.Reweight("EUR"); n != 0 {
+		t.Errorf("Expected nothing to change without a new rate, got %d", n)
+	}
+
+	// a EUR buy at 96 is worth 96 and crosses the EUR ask at 95
+	r := ob.Submit(Order{Price: 96, Quantity: 1, OrderId: "b", Venue: "EUR"}, Buy)
+	if len(r.Trades) != 1 || r.Trades[0].Price != 95 {
+		t.Errorf("Expected a trade at 95, got %+v", r.Trades)
+	}
+	rates.Set("GBP", 1.25)
+	r = ob.Submit(Order{Price: 81, Quantity: 1, OrderId: "g", Venue: "GBP"}, Buy)
+	if len(r.Trades) != 1 || r.Trades[0].Price != 100 {
+		t.Errorf("Expected 81 GBP at 1.25 to cross the 100 USD ask, got %+v", r.Trades)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"math"
+)
+
+// Divergence describes the first command on which a book and its
+// reference disagreed.
+type Divergence struct {
+	Step    int    `json:"step"` // 1-based count of commands applied
+	Op      string `json:"op"`
+	OrderId string `json:"orderId"`
+	Reason  string `json:"reason"`
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("orderbook: step %d (%s %s) diverged: %s", d.Step, d.Op, d.OrderId, d.Reason)
+}
+
+// refOrder is a resting order of a refBook.
+type refOrder struct {
+	id         string
+	price, qty float64
+	seq        uint64
+}
+
+// refBook is a deliberately naive price-time book: unsorted slices
+// scanned in full for the best order on every step.
+type refBook struct {
+	sides [2][]refOrder
+	seq   uint64
+}
+
+// best returns the index of the best order on side, or -1.
+func (b *refBook) best(side Side) int {
+	best := -1
+	for i, o := range b.sides[side] {
+		if best < 0 {
+			best = i
+			continue
+		}
+		p := b.sides[side][best]
+		better := o.price > p.price
+		if side == Sell {
+			better = o.price < p.price
+		}
+		if better || o.price == p.price && o.seq < p.seq {
+			best = i
+		}
+	}
+	return best
+}
+
+func (b *refBook) remove(side Side, id string) bool {
+	for i, o := range b.sides[side] {
+		if o.id == id {
+			b.sides[side] = append(b.sides[side][:i], b.sides[side][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *refBook) rest(side Side, id string, price, qty float64) {
+	b.remove(side, id)
+	b.seq++
+	b.sides[side] = append(b.sides[side], refOrder{id, price, qty, b.seq})
+}
+
+// submit matches o and rests the remainder, returning its trades, or
+// false if it is invalid.
+func (b *refBook) submit(o Order, side Side) ([]TradeEvent, bool) {
+	if o.OrderId == "" || !(o.Price > 0) || math.IsInf(o.Price, 0) || !(o.Quantity > 0) || math.IsInf(o.Quantity, 0) {
+		return nil, false
+	}
+	var trades []TradeEvent
+	opposite := side.Opposite()
+	for o.Quantity > 0 {
+		i := b.best(opposite)
+		if i < 0 {
+			break
+		}
+		m := &b.sides[opposite][i]
+		if side == Buy && o.Price < m.price || side == Sell && o.Price > m.price {
+			break
+		}
+		qty := math.Min(o.Quantity, m.qty)
+		trades = append(trades, TradeEvent{Price: m.price, Quantity: qty, MakerId: m.id, TakerId: o.OrderId})
+		o.Quantity -= qty
+		if m.qty -= qty; m.qty <= 0 {
+			b.remove(opposite, m.id)
+		}
+	}
+	if o.Quantity > 0 {
+		b.rest(side, o.OrderId, o.Price, o.Quantity)
+	}
+	return trades, true
+}
+
+// sorted returns side best first.
+func (b *refBook) sorted(side Side) []refOrder {
+	rest := refBook{}
+	rest.sides[side] = append([]refOrder(nil), b.sides[side]...)
+	var sorted []refOrder
+	for i := rest.best(side); i >= 0; i = rest.best(side) {
+		sorted = append(sorted, rest.sides[side][i])
+		rest.sides[side] = append(rest.sides[side][:i], rest.sides[side][i+1:]...)
+	}
+	return sorted
+}
+
+// Reconciler applies the same commands to a book and to a slow, obviously
+// correct reference book, comparing trades and both sides after each one,
+// and reports the first divergence. The reference implements plain
+// price-time priority only, so books built with options that change
+// matching, such as allocators, comparators, weights, negative prices or
+// capacity limits, are expected to diverge. After a divergence the
+// reference is abandoned and commands go to the book alone.
+type Reconciler struct {
+	Book *OrderBook
+
+	ref   refBook
+	step  int
+	first *Divergence
+}
+
+// NewReconciler returns a Reconciler over ob, seeding the reference with
+// the orders already resting on it.
+func NewReconciler(ob *OrderBook) *Reconciler {
+	r := &Reconciler{Book: ob}
+	for _, side := range []Side{Buy, Sell} {
+		for _, e := range ob.Entries(side) {
+			r.ref.rest(side, e.Key, e.Order.Price, e.Order.Quantity)
+		}
+	}
+	return r
+}
+
+// Divergence returns the first divergence found, or nil.
+func (r *Reconciler) Divergence() *Divergence {
+	return r.first
+}
+
+func (r *Reconciler) diverge(op, id, format string, args ...interface{}) error {
+	r.first = &Divergence{Step: r.step, Op: op, OrderId: id, Reason: fmt.Sprintf(format, args...)}
+	return r.first
+}
+
+// Submit submits o to both books. The error is the first divergence, on
+// this step or an earlier one.
+func (r *Reconciler) Submit(o Order, side Side) (ExecutionReport, error) {
+	r.step++
+	report := r.Book.Submit(o, side)
+	if r.first != nil {
+		return report, r.first
+	}
+	trades, ok := r.ref.submit(o, side)
+	if rejected := report.Status == StatusRejected; rejected == ok {
+		return report, r.diverge("submit", o.OrderId, "book rejected %v, reference rejected %v", rejected, !ok)
+	}
+	if len(trades) != len(report.Trades) {
+		return report, r.diverge("submit", o.OrderId, "book made %d trades, reference %d", len(report.Trades), len(trades))
+	}
+	for i, t := range report.Trades {
+		want := trades[i]
+		if t.Price != want.Price || t.Quantity != want.Quantity || t.MakerId != want.MakerId {
+			return report, r.diverge("submit", o.OrderId, "trade %d is %v of %s at %v, reference %v of %s at %v",
+				i, t.Quantity, t.MakerId, t.Price, want.Quantity, want.MakerId, want.Price)
+		}
+	}
+	return report, r.compare("submit", o.OrderId)
+}
+
+// Cancel cancels orderId on both books.
+func (r *Reconciler) Cancel(orderId string) (bool, error) {
+	r.step++
+	ok := r.Book.Cancel(orderId)
+	if r.first != nil {
+		return ok, r.first
+	}
+	want := r.ref.remove(Buy, orderId) || r.ref.remove(Sell, orderId)
+	if ok != want {
+		return ok, r.diverge("cancel", orderId, "book cancelled %v, reference %v", ok, want)
+	}
+	return ok, r.compare("cancel", orderId)
+}
+
+// Apply runs an insert as a Submit and a cancel as a Cancel; amends are
+// not modelled by the reference.
+func (r *Reconciler) Apply(c Command) error {
+	switch c.Op {
+	case OpInsert:
+		_, err := r.Submit(c.Order, c.Side)
+		return err
+	case OpCancel:
+		_, err := r.Cancel(c.Order.OrderId)
+		return err
+	}
+	return fmt.Errorf("orderbook: reconciler cannot apply %v", c.Op)
+}
+
+// compare checks both sides of the book against the reference, order by
+// order in priority order.
+func (r *Reconciler) compare(op, id string) error {
+	for _, side := range []Side{Buy, Sell} {
+		got, want := r.Book.Entries(side), r.ref.sorted(side)
+		if len(got) != len(want) {
+			return r.diverge(op, id, "%s side has %d orders, reference %d", side, len(got), len(want))
+		}
+		for i, e := range got {
+			w := want[i]
+			if e.Key != w.id || e.Order.Price != w.price || e.Order.Quantity != w.qty {
+				return r.diverge(op, id, "%s order %d is %s %v@%v, reference %s %v@%v",
+					side, i, e.Key, e.Order.Quantity, e.Order.Price, w.id, w.qty, w.price)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestReconciler(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(100, 1, "seed"), Buy)
+	r := NewReconciler(ob)
+	rng := rand.New(rand.NewSource(7))
+	var ids []string
+	for i := 0; i < 2000; i++ {
+		if len(ids) > 0 && rng.Intn(4) == 0 {
+			id := ids[rng.Intn(len(ids))]
+			if _, err := r.Cancel(id); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		id := strconv.Itoa(i % 300) // ids are reused to exercise replacement
+		side := Side(rng.Intn(2))
+		o := NewOrder(float64(95+rng.Intn(11)), float64(1+rng.Intn(5)), id)
+		if rng.Intn(50) == 0 {
+			o.Quantity = 0
+		}
+		if _, err := r.Submit(o, side); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if r.Divergence() != nil {
+		t.Errorf("Expected no divergence, got %v", r.Divergence())
+	}
+}
+
+func TestReconcilerDivergence(t *testing.T) {
+	r := NewReconciler(NewOrderBook(WithoutMatching()))
+	if _, err := r.Submit(NewOrder(100, 1, "a"), Sell); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Submit(NewOrder(101, 1, "b"), Buy)
+	d, ok := err.(*Divergence)
+	if !ok || d.Step != 2 || d.OrderId != "b" {
+		t.Fatalf("Expected a divergence at step 2 on b, got %v", err)
+	}
+	if _, err := r.Cancel("a"); err != d {
+		t.Errorf("Expected later steps to keep returning the first divergence, got %v", err)
+	}
+	if err := r.Apply(Command{Op: OpAmend}); err == nil {
+		t.Errorf("Expected amends to be refused")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const barWidth = 30
+
+// RenderDepth writes a text ladder of the top n levels per side, asks
+// above bids with the best prices meeting at the spread, and a bar for
+// each level scaled to the largest size shown.
+func (ob *OrderBook) RenderDepth(w io.Writer, n int) error {
+	bids, asks := ob.Depth(n)
+	var max float64
+	for _, l := range append(append([]Level{}, bids...), asks...) {
+		if l.Quantity > max {
+			max = l.Quantity
+		}
+	}
+	bar := func(q float64) string {
+		if max == 0 {
+			return ""
+		}
+		return strings.Repeat("#", int(q/max*barWidth+0.5))
+	}
+
+	var b strings.Builder
+	for i := len(asks) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "ask %14g %14g  %s\n", asks[i].Price, asks[i].Quantity, bar(asks[i].Quantity))
+	}
+	if spread, ok := ob.Spread(); ok {
+		fmt.Fprintf(&b, "--- spread %g\n", spread)
+	} else {
+		b.WriteString("---\n")
+	}
+	for _, l := range bids {
+		fmt.Fprintf(&b, "bid %14g %14g  %s\n", l.Price, l.Quantity, bar(l.Quantity))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dumpDepth is the number of levels per side shown by String.
+const dumpDepth = 10
+
+// Dump writes a two-column ladder of the top depth levels per side, bids
+// on the left and asks on the right, followed by the total size shown on
+// each side and the spread. A non-positive depth shows every level.
+func (ob *OrderBook) Dump(w io.Writer, depth int) error {
+	bids, asks := ob.Depth(depth)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%14s %14s | %-14s %14s\n", "bid size", "bid", "ask", "ask size")
+	var bidTotal, askTotal float64
+	for i := 0; i < len(bids) || i < len(asks); i++ {
+		var bid, ask [2]string
+		if i < len(bids) {
+			bid = [2]string{fmt.Sprint(bids[i].Quantity), fmt.Sprint(bids[i].Price)}
+			bidTotal += bids[i].Quantity
+		}
+		if i < len(asks) {
+			ask = [2]string{fmt.Sprint(asks[i].Price), fmt.Sprint(asks[i].Quantity)}
+			askTotal += asks[i].Quantity
+		}
+		fmt.Fprintf(&b, "%14s %14s | %-14s %14s\n", bid[0], bid[1], ask[0], ask[1])
+	}
+	fmt.Fprintf(&b, "%14g %14s | %-14s %14g\n", bidTotal, "total", "total", askTotal)
+	if len(bids) > 0 && len(asks) > 0 {
+		fmt.Fprintf(&b, "spread %g\n", asks[0].Price-bids[0].Price)
+	} else {
+		b.WriteString("spread -\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String returns the Dump of the top levels of the book.
+func (ob *OrderBook) String() string {
+	var b strings.Builder
+	ob.Dump(&b, dumpDepth)
+	return b.String()
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderDepth(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(99, 2, "b1"))
+	ob.PushOrder(Buy, NewOrder(98, 4, "b2"))
+	ob.PushOrder(Sell, NewOrder(101, 1, "a1"))
+	ob.PushOrder(Sell, NewOrder(102, 3, "a2"))
+
+	var buf bytes.Buffer
+	if err := ob.RenderDepth(&buf, 2); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	prefixes := []string{"ask", "ask", "---", "bid", "bid"}
+	if len(lines) != len(prefixes) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(prefixes), buf.String())
+	}
+	for i, p := range prefixes {
+		if !strings.HasPrefix(lines[i], p) {
+			t.Errorf("Expected line %d to start with %q, got %q", i, p, lines[i])
+		}
+	}
+	if !strings.Contains(lines[0], "102") || !strings.Contains(lines[4], "98") {
+		t.Errorf("Expected outer levels at the edges, got:\n%s", buf.String())
+	}
+	if lines[2] != "--- spread 2" {
+		t.Errorf("Expected spread line, got %q", lines[2])
+	}
+	if strings.Count(lines[4], "#") != barWidth {
+		t.Errorf("Expected the largest level to have a full bar, got %q", lines[4])
+	}
+}
+
+func TestDump(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(99, 2, "b1"))
+	ob.PushOrder(Buy, NewOrder(98, 4, "b2"))
+	ob.PushOrder(Buy, NewOrder(98, 1, "b3"))
+	ob.PushOrder(Sell, NewOrder(101.5, 1, "a1"))
+
+	var buf bytes.Buffer
+	if err := ob.Dump(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected header, 2 levels, totals and spread, got:\n%s", buf.String())
+	}
+	if f := strings.Fields(lines[1]); strings.Join(f, " ") != "2 99 | 101.5 1" {
+		t.Errorf("Expected best levels on the first row, got %q", lines[1])
+	}
+	if f := strings.Fields(lines[2]); strings.Join(f, " ") != "5 98 |" {
+		t.Errorf("Expected bid-only second row, got %q", lines[2])
+	}
+	if f := strings.Fields(lines[3]); strings.Join(f, " ") != "7 total | total 1" {
+		t.Errorf("Expected totals row, got %q", lines[3])
+	}
+	if lines[4] != "spread 2.5" {
+		t.Errorf("Expected spread line, got %q", lines[4])
+	}
+	if ob.String() != buf.String() {
+		t.Errorf("Expected String to match Dump, got:\n%s", ob.String())
+	}
+	if s := NewOrderBook().String(); !strings.HasSuffix(s, "spread -\n") {
+		t.Errorf("Expected empty book to have no spread, got:\n%s", s)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "errors"
+
+var (
+	ErrUnknownOrder   = errors.New("orderbook: no resting order with this id")
+	ErrReplaceCrosses = errors.New("orderbook: replacement would cross the opposite side")
+)
+
+// Replace swaps the resting order oldId for o, on the same side, under
+// both sides' locks, so no reader sees the book with neither order or
+// with both. o keeps oldId if it has no id. The swap keeps oldId's time
+// priority when o has the same id and effective price and no more
+// quantity, and reports whether it did; otherwise o joins the back of its
+// level. Replacements are validated like Submit would, by the book's
+// validators too, but never match: one that would cross is refused with
+// ErrReplaceCrosses and the old order stays.
+func (ob *OrderBook) Replace(oldId string, o Order) (bool, error) {
+	if o.OrderId == "" {
+		o.OrderId = oldId
+	}
+	if err := ob.validate(&o); err != nil {
+		return false, err
+	}
+	_, side, ok := ob.Lookup(oldId)
+	if !ok {
+		return false, ErrUnknownOrder
+	}
+	for _, v := range ob.validators {
+		if err := v.Validate(ob, side, &o); err != nil {
+			return false, err
+		}
+	}
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	retained, err := ob.replace(oldId, side, o)
+	ob.AskBook.lock.Unlock()
+	ob.BidBook.lock.Unlock()
+
+	if err == nil {
+		ob.publishPending(true, true, false)
+	}
+	return retained, err
+}
+
+// replace is Replace for callers holding both locks, with oldId resting
+// on side.
+func (ob *OrderBook) replace(oldId string, side Side, o Order) (bool, error) {
+	b := ob.Side(side)
+	old, ok := b.get(oldId)
+	if !ok || old.Peek() == nil {
+		return false, ErrUnknownOrder
+	}
+	prev := old.Peek()
+	if o.OrderId != oldId {
+		if _, ok := b.get(o.OrderId); ok {
+			return false, ErrDuplicateOrder
+		}
+	}
+	weight := ob.weight(&o)
+	top := ob.Side(side.Opposite()).top()
+	if top != nil && top.Peek() != nil && permits(side, o.Price*weight, top.Peek().Price*top.Weight) {
+		return false, ErrReplaceCrosses
+	}
+	if o.OrderId == oldId && o.Price*weight == prev.Price*old.Weight && o.Quantity <= prev.Quantity {
+		client := prev.ClientOrderId
+		o.Filled = prev.Filled
+		*prev = o
+		old.Weight = weight
+		b.amend(oldId, o.Price, o.Quantity)
+		if o.ClientOrderId != client {
+			ob.ids.unbind(oldId)
+			if o.ClientOrderId != "" {
+				ob.ids.bind(oldId, o.ClientOrderId)
+			}
+		}
+		return true, nil
+	}
+	b.cancel(oldId)
+	n := NewNode(o.OrderId, &o, weight)
+	return false, b.push(&n)
+}
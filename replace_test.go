@@ -0,0 +1,131 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(100, 5, "a"), Buy)
+	ob.Submit(NewOrder(100, 1, "b"), Buy)
+	ob.Submit(NewOrder(105, 1, "s"), Sell)
+
+	tests := []struct {
+		old      string
+		order    Order
+		retained bool
+		err      error
+	}{
+		{"a", NewOrder(100, 3, "a"), true, nil},  // amend down keeps priority
+		{"a", NewOrder(100, 4, "a"), false, nil}, // growing goes to the back
+		{"b", NewOrder(101, 1, ""), false, nil},  // an empty id keeps the old one
+		{"b", NewOrder(106, 1, "b"), false, ErrReplaceCrosses},
+		{"x", NewOrder(100, 1, "x"), false, ErrUnknownOrder},
+		{"b", NewOrder(100, 0, "b"), false, ErrInvalidQuantity},
+		{"b", NewOrder(100, 1, "a"), false, ErrDuplicateOrder},
+	}
+	for i, tt := range tests {
+		retained, err := ob.Replace(tt.old, tt.order)
+		if retained != tt.retained || err != tt.err {
+			t.Errorf("Expected replace %d to give %v %v, got %v %v", i, tt.retained, tt.err, retained, err)
+		}
+	}
+	if o, _, ok := ob.Lookup("b"); !ok || o.Price != 101 {
+		t.Errorf("Expected b to rest at 101 after the failed replaces, got %+v", o)
+	}
+	ob.Replace("b", NewOrder(100, 2, "c"))
+	if pos, _ := ob.QueuePosition("c"); pos.Orders != 1 {
+		t.Errorf("Expected c behind a at 100, got %+v", pos)
+	}
+	if _, _, ok := ob.Lookup("b"); ok {
+		t.Errorf("Expected b to be gone once replaced by c")
+	}
+}
+
+func TestReplaceAtomic(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(100, 1, "a"), Buy)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			ob.Replace("a", NewOrder(float64(100+i%2), 1, "a"))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if s := ob.Stats(); s.BidOrders != 1 {
+			t.Fatalf("Expected exactly one bid throughout, got %d", s.BidOrders)
+		}
+	}
+	wg.Wait()
+}
+
+func TestReplaceValidators(t *testing.T) {
+	ob := NewOrderBook(WithValidators(MaxQuantity(5)))
+	ob.Submit(NewOrder(100, 2, "a"), Buy)
+
+	_, err := ob.Replace("a", NewOrder(100, 6, "a"))
+	if re, ok := err.(*RejectError); !ok || re.Reason != RejectMaxQuantity {
+		t.Errorf("Expected the risk limit to refuse the replace, got %v", err)
+	}
+	if o, _, ok := ob.Lookup("a"); !ok || o.Quantity != 2 {
+		t.Errorf("Expected a to stay at 2 after the refused replace, got %+v", o)
+	}
+}
+
+func TestReplaceWeighted(t *testing.T) {
+	rates := &RateTable{}
+	rates.Set("EUR", 2)
+	ob := NewOrderBook(WithRates(rates))
+	a := NewOrder(50, 1, "a")
+	a.Venue = "EUR"
+	ob.Submit(a, Buy)
+	ob.Submit(NewOrder(100, 1, "b"), Buy)
+
+	if retained, err := ob.Replace("a", NewOrder(100, 1, "a")); !retained || err != nil {
+		t.Errorf("Expected a to keep its place at the same effective price, got %v %v", retained, err)
+	}
+	if pos, _ := ob.QueuePosition("a"); pos.Orders != 0 {
+		t.Errorf("Expected a still ahead of b, got %+v", pos)
+	}
+	a.Price = 100
+	if retained, _ := ob.Replace("a", a); retained {
+		t.Errorf("Expected a new effective price to lose priority")
+	}
+}
+
+func TestReplaceClientId(t *testing.T) {
+	ob := NewOrderBook()
+	a := NewOrder(100, 2, "a")
+	a.ClientOrderId = "first"
+	ob.Submit(a, Buy)
+
+	a.ClientOrderId = "second"
+	if retained, err := ob.Replace("a", a); !retained || err != nil {
+		t.Fatalf("Expected the replace to keep priority, got %v %v", retained, err)
+	}
+	if id, ok := ob.OrderIdFor("second"); !ok || id != "a" {
+		t.Errorf("Expected second to map to a, got %q %v", id, ok)
+	}
+	if _, ok := ob.OrderIdFor("first"); ok {
+		t.Errorf("Expected first to be forgotten")
+	}
+	if id, _ := ob.ClientOrderIdFor("a"); id != "second" {
+		t.Errorf("Expected a to carry second, got %q", id)
+	}
+}
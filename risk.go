@@ -0,0 +1,126 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"math"
+)
+
+// Validator checks an order before Submit matches or rests it. A non-nil
+// error rejects the order and is returned in the ExecutionReport.
+type Validator interface {
+	Validate(ob *OrderBook, side Side, o *Order) error
+}
+
+type ValidatorFunc func(ob *OrderBook, side Side, o *Order) error
+
+func (f ValidatorFunc) Validate(ob *OrderBook, side Side, o *Order) error {
+	return f(ob, side, o)
+}
+
+// WithValidators appends to the chain of validators Submit runs, in order,
+// after its own checks.
+func WithValidators(vs ...Validator) Option {
+	return func(ob *OrderBook) {
+		ob.validators = append(ob.validators, vs...)
+	}
+}
+
+type RejectReason int
+
+const (
+	RejectMaxQuantity RejectReason = iota
+	RejectMaxNotional
+	RejectPriceBand
+	RejectOpenOrders
+	RejectRate
+	RejectCancelRatio
+)
+
+func (r RejectReason) String() string {
+	return [...]string{"max_quantity", "max_notional", "price_band", "open_orders", "rate", "cancel_ratio"}[r]
+}
+
+func (r RejectReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// RejectError is returned by the built-in validators, with the limit that
+// was breached and the order's value against it.
+type RejectError struct {
+	Reason RejectReason `json:"reason"`
+	Limit  float64      `json:"limit"`
+	Value  float64      `json:"value"`
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("orderbook: rejected (%s): %g exceeds limit %g", e.Reason, e.Value, e.Limit)
+}
+
+func MaxQuantity(limit float64) Validator {
+	return ValidatorFunc(func(_ *OrderBook, _ Side, o *Order) error {
+		if o.Quantity > limit {
+			return &RejectError{RejectMaxQuantity, limit, o.Quantity}
+		}
+		return nil
+	})
+}
+
+func MaxNotional(limit float64) Validator {
+	return ValidatorFunc(func(ob *OrderBook, _ Side, o *Order) error {
+		if v := math.Abs(ob.instrument.Notional(o.Price, o.Quantity)); v > limit {
+			return &RejectError{RejectMaxNotional, limit, v}
+		}
+		return nil
+	})
+}
+
+// PriceBand rejects orders priced more than bps basis points away from
+// the midpoint, or from the best price of whichever side is quoted. Orders
+// are accepted when the book is empty.
+func PriceBand(bps float64) Validator {
+	return ValidatorFunc(func(ob *OrderBook, _ Side, o *Order) error {
+		ref, ok := ob.Midpoint()
+		if !ok {
+			if t := ob.TopOfBook(); t.HasBid {
+				ref, ok = t.Bid.Price, true
+			} else if t.HasAsk {
+				ref, ok = t.Ask.Price, true
+			}
+		}
+		if !ok || ref == 0 {
+			return nil
+		}
+		if away := math.Abs(o.Price-ref) / math.Abs(ref) * 1e4; away > bps {
+			return &RejectError{RejectPriceBand, bps, away}
+		}
+		return nil
+	})
+}
+
+// MaxOpenOrders limits the number of orders an account may have resting
+// across both sides. Orders without an Account are not limited.
+func MaxOpenOrders(limit int) Validator {
+	return ValidatorFunc(func(ob *OrderBook, _ Side, o *Order) error {
+		if o.Account == "" {
+			return nil
+		}
+		mine := func(r *Order) bool { return r.Account == o.Account && r.OrderId != o.OrderId }
+		if n := ob.BidBook.count(mine) + ob.AskBook.count(mine); n >= limit {
+			return &RejectError{RejectOpenOrders, float64(limit), float64(n + 1)}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidators(t *testing.T) {
+	ob := NewOrderBook(WithValidators(
+		MaxQuantity(10),
+		MaxNotional(500),
+		PriceBand(500),
+		MaxOpenOrders(2),
+	))
+	ob.PushOrder(Buy, Order{Price: 99, Quantity: 1, OrderId: "b1", Account: "x"})
+	ob.PushOrder(Sell, Order{Price: 101, Quantity: 1, OrderId: "a1", Account: "x"})
+
+	tests := []struct {
+		Name   string
+		Order  Order
+		Reason RejectReason
+		Reject bool
+	}{
+		{"ok", Order{Price: 100.5, Quantity: 1, OrderId: "o1", Account: "y"}, 0, false},
+		{"too-large", Order{Price: 100, Quantity: 11, OrderId: "o2"}, RejectMaxQuantity, true},
+		{"notional", Order{Price: 100, Quantity: 6, OrderId: "o3"}, RejectMaxNotional, true},
+		{"fat-finger", Order{Price: 110, Quantity: 1, OrderId: "o4"}, RejectPriceBand, true},
+		{"open-orders", Order{Price: 98, Quantity: 1, OrderId: "o5", Account: "x"}, RejectOpenOrders, true},
+		{"replace-own", Order{Price: 98, Quantity: 1, OrderId: "b1", Account: "x"}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			report := ob.Submit(test.Order, Buy)
+			var rej *RejectError
+			if errors.As(report.Err, &rej) != test.Reject {
+				t.Fatalf("Expected reject %t, got %v", test.Reject, report.Err)
+			}
+			if test.Reject && (rej.Reason != test.Reason || report.Status != StatusRejected) {
+				t.Errorf("Expected %s rejection, got %v with status %s", test.Reason, rej, report.Status)
+			}
+		})
+	}
+}
+
+func TestValidatorFunc(t *testing.T) {
+	errHalted := errors.New("halted")
+	ob := NewOrderBook(WithValidators(ValidatorFunc(func(*OrderBook, Side, *Order) error {
+		return errHalted
+	})))
+	if report := ob.Submit(NewOrder(100, 1, "a"), Sell); report.Err != errHalted || ob.AskBook.Len() != 0 {
+		t.Errorf("Expected custom rejection, got %v", report.Err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "time"
+
+// rollingSeconds is how far back activity is kept, in one-second buckets.
+const rollingSeconds = 300
+
+// WindowStats counts activity over a recent window of the book's clock.
+// Adds and Cancels count orders resting and removed as Activity counts
+// Inserts and Cancels; Trades and Volume count matches.
+type WindowStats struct {
+	Adds    int     `json:"adds"`
+	Cancels int     `json:"cancels"`
+	Trades  int     `json:"trades"`
+	Volume  float64 `json:"volume"`
+}
+
+type rollingBucket struct {
+	second int64
+	WindowStats
+}
+
+// rolling keeps WindowStats per second of the last rollingSeconds,
+// overwriting each bucket as its second comes round again. Buckets are
+// allocated with the first activity. Activity stamped earlier than what a
+// bucket already holds, as from a clock set back, is not kept.
+type rolling struct {
+	buckets []rollingBucket
+}
+
+func (r *rolling) bucket(t time.Time) *WindowStats {
+	if r.buckets == nil {
+		r.buckets = make([]rollingBucket, rollingSeconds)
+	}
+	sec := t.Unix()
+	b := &r.buckets[uint64(sec)%rollingSeconds]
+	switch {
+	case b.second > sec:
+		return &WindowStats{}
+	case b.second < sec:
+		*b = rollingBucket{second: sec}
+	}
+	return &b.WindowStats
+}
+
+// sum adds to w the buckets of the seconds (now-d, now], counting the
+// current second in full.
+func (r *rolling) sum(w *WindowStats, now time.Time, d time.Duration) {
+	if r.buckets == nil {
+		return
+	}
+	sec := now.Unix()
+	for s := sec - int64(d/time.Second) + 1; s <= sec; s++ {
+		b := &r.buckets[uint64(s)%rollingSeconds]
+		if b.second == s {
+			w.Adds += b.Adds
+			w.Cancels += b.Cancels
+			w.Trades += b.Trades
+			w.Volume += b.Volume
+		}
+	}
+}
+
+func (sb *SideBook) countAdd(t time.Time) { sb.rolling.bucket(t).Adds++ }
+func (sb *SideBook) countCancel()         { sb.rolling.bucket(sb.clock()).Cancels++ }
+
+// window sums both sides' adds and cancels and the book's trades over the
+// last d. The caller holds the event lock and both sides' locks.
+func (ob *OrderBook) window(now time.Time, d time.Duration) WindowStats {
+	var w WindowStats
+	ob.BidBook.rolling.sum(&w, now, d)
+	ob.AskBook.rolling.sum(&w, now, d)
+	ob.trades.sum(&w, now, d)
+	return w
+}
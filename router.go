@@ -0,0 +1,159 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"sort"
+)
+
+// Venue is a book a Router can send orders to.
+type Venue struct {
+	Name string
+	Book *OrderBook
+}
+
+// Router splits orders across venues for the best all-in price, taking
+// the cheapest liquidity first across every venue's opposite side, taker
+// fees included.
+type Router struct {
+	Venues []Venue
+}
+
+func NewRouter(venues ...Venue) *Router {
+	return &Router{Venues: venues}
+}
+
+// ChildReport is the report of one child order a Router submitted.
+type ChildReport struct {
+	Venue string `json:"venue"`
+	ExecutionReport
+}
+
+// RouteReport aggregates the fills of a routed order's children.
+type RouteReport struct {
+	OrderId      string        `json:"orderId"`
+	Side         Side          `json:"side"`
+	Children     []ChildReport `json:"children"`
+	Filled       float64       `json:"filled"`
+	Remaining    float64       `json:"remaining"`
+	AveragePrice float64       `json:"averagePrice"`
+	Fees         float64       `json:"fees"`
+}
+
+// RouteLeg is the part of a plan sent to one venue.
+type RouteLeg struct {
+	Venue    string  `json:"venue"`
+	Price    float64 `json:"price"` // limit: the worst price taken there
+	Quantity float64 `json:"quantity"`
+}
+
+type routeLevel struct {
+	venue    int
+	price    float64
+	quantity float64
+	cost     float64 // price per unit with the taker fee, signed to sort best first
+}
+
+// Plan returns the legs Route would submit for o on side, venues in the
+// order they were added. An order priced at zero or an infinity has no
+// limit.
+func (r *Router) Plan(o Order, side Side) []RouteLeg {
+	var lvls []routeLevel
+	for i, v := range r.Venues {
+		bids, asks := v.Book.Depth(0)
+		opposite := asks
+		if side == Sell {
+			opposite = bids
+		}
+		for _, l := range opposite {
+			if o.Price != 0 && !math.IsInf(o.Price, 0) && (side == Buy && l.Price > o.Price || side == Sell && l.Price < o.Price) {
+				break
+			}
+			var fee float64
+			if v.Book.fees != nil && l.Quantity > 0 {
+				notional := math.Abs(v.Book.instrument.Notional(l.Price, l.Quantity))
+				fee = v.Book.fees.Fee(o.Account, Taker, notional) / l.Quantity
+			}
+			cost := l.Price + fee
+			if side == Sell {
+				cost = -(l.Price - fee)
+			}
+			lvls = append(lvls, routeLevel{i, l.Price, l.Quantity, cost})
+		}
+	}
+	sort.SliceStable(lvls, func(i, j int) bool { return lvls[i].cost < lvls[j].cost })
+
+	legs := make([]*RouteLeg, len(r.Venues))
+	left := o.Quantity
+	for _, l := range lvls {
+		if left <= 0 {
+			break
+		}
+		qty := math.Min(left, l.quantity)
+		if legs[l.venue] == nil {
+			legs[l.venue] = &RouteLeg{Venue: r.Venues[l.venue].Name}
+		}
+		leg := legs[l.venue]
+		leg.Quantity += qty
+		if leg.Price == 0 || side == Buy && l.price > leg.Price || side == Sell && l.price < leg.Price {
+			leg.Price = l.price
+		}
+		left -= qty
+	}
+	var plan []RouteLeg
+	for _, leg := range legs {
+		if leg != nil {
+			plan = append(plan, *leg)
+		}
+	}
+	return plan
+}
+
+// Route submits o's legs as child orders, ids suffixed with their venue,
+// and cancels whatever of them does not fill at once, so nothing rests.
+// Quantity no venue could fill is left in Remaining.
+func (r *Router) Route(o Order, side Side) RouteReport {
+	report := RouteReport{OrderId: o.OrderId, Side: side, Remaining: o.Quantity}
+	var value float64
+	for _, leg := range r.Plan(o, side) {
+		child := o
+		child.OrderId, child.Price, child.Quantity = o.OrderId+"-"+leg.Venue, leg.Price, leg.Quantity
+		book := r.venue(leg.Venue)
+		cr := book.Submit(child, side)
+		if cr.Resting {
+			book.Cancel(cr.OrderId)
+		}
+		report.Children = append(report.Children, ChildReport{leg.Venue, cr})
+		for _, t := range cr.Trades {
+			value += t.Price * t.Quantity
+		}
+		report.Filled += cr.Filled
+		report.Fees += cr.Fees
+	}
+	report.Remaining = o.Quantity - report.Filled
+	if report.Filled > 0 {
+		report.AveragePrice = value / report.Filled
+	}
+	return report
+}
+
+func (r *Router) venue(name string) *OrderBook {
+	for _, v := range r.Venues {
+		if v.Name == name {
+			return v.Book
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "testing"
+
+func TestRouter(t *testing.T) {
+	a := NewOrderBook()
+	a.Submit(NewOrder(100, 2, "a1"), Sell)
+	a.Submit(NewOrder(102, 5, "a2"), Sell)
+	// b is a tick cheaper at 101 but charges a taker fee of 2 a unit there.
+	b := NewOrderBook(WithFees(Fees{TakerBps: 200}))
+	b.Submit(NewOrder(101, 3, "b1"), Sell)
+	b.Submit(NewOrder(99, 1, "b2"), Sell)
+	r := NewRouter(Venue{"a", a}, Venue{"b", b})
+
+	plan := r.Plan(Order{Price: 102, Quantity: 6, OrderId: "x"}, Buy)
+	if len(plan) != 2 || plan[0] != (RouteLeg{"a", 102, 5}) || plan[1] != (RouteLeg{"b", 99, 1}) {
+		t.Errorf("Expected 5 from a up to 102 and 1 from b at 99, got %+v", plan)
+	}
+
+	rr := r.Route(Order{Price: 102, Quantity: 20, OrderId: "x"}, Buy)
+	if rr.Filled != 11 || rr.Remaining != 9 || len(rr.Children) != 2 {
+		t.Errorf("Expected 11 filled across both venues and 9 left, got %+v", rr)
+	}
+	if a.OrderCount(Buy) != 0 || b.OrderCount(Buy) != 0 {
+		t.Errorf("Expected no child order to rest")
+	}
+	if rr.Children[1].OrderId != "x-b" || rr.Fees == 0 {
+		t.Errorf("Expected children suffixed by venue and b's fees counted, got %+v", rr)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "fmt"
+
+// DepthDiff carries the levels that changed in a single book update. A
+// level with zero Quantity has been removed.
+type DepthDiff struct {
+	Bids     []Level `json:"bids"`
+	Asks     []Level `json:"asks"`
+	Sequence uint64  `json:"sequence"`
+}
+
+type GapError struct {
+	Expected uint64
+	Got      uint64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("orderbook: sequence gap, expected %d got %d", e.Expected, e.Got)
+}
+
+// SequenceTracker follows the sequence numbers of a single event stream on
+// the consuming side. After a gap the consumer should fetch a snapshot and
+// call Resume with the snapshot's sequence; buffered events at or below it
+// are then reported as stale.
+type SequenceTracker struct {
+	last    uint64
+	started bool
+}
+
+// Observe reports whether the event stamped seq should be applied. Stale
+// and duplicate events return false with a nil error; a gap returns a
+// *GapError and leaves the tracker unchanged until Resume is called.
+func (t *SequenceTracker) Observe(seq uint64) (bool, error) {
+	if !t.started {
+		t.last, t.started = seq, true
+		return true, nil
+	}
+	switch {
+	case seq <= t.last:
+		return false, nil
+	case seq != t.last+1:
+		return false, &GapError{Expected: t.last + 1, Got: seq}
+	}
+	t.last = seq
+	return true, nil
+}
+
+func (t *SequenceTracker) Resume(seq uint64) {
+	t.last, t.started = seq, true
+}
+
+func (t *SequenceTracker) Last() uint64 {
+	return t.last
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	steps := []struct {
+		Seq    uint64
+		Resume bool
+		Apply  bool
+		Gap    bool
+	}{
+		{5, false, true, false},
+		{6, false, true, false},
+		{6, false, false, false},
+		{9, false, false, true},
+		{7, false, true, false},
+		{12, true, false, false},
+		{11, false, false, false},
+		{13, false, true, false},
+	}
+	var tracker SequenceTracker
+	for _, step := range steps {
+		t.Run(fmt.Sprintf("seq-%d", step.Seq), func(t *testing.T) {
+			if step.Resume {
+				tracker.Resume(step.Seq)
+				return
+			}
+			apply, err := tracker.Observe(step.Seq)
+			if apply != step.Apply {
+				t.Errorf("Expected apply %t, got %t", step.Apply, apply)
+			}
+			if _, ok := err.(*GapError); ok != step.Gap {
+				t.Errorf("Expected gap %t, got %v", step.Gap, err)
+			}
+		})
+	}
+	if tracker.Last() != 13 {
+		t.Errorf("Expected last sequence 13, got %d", tracker.Last())
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type session struct {
+	timeout time.Duration
+	last    time.Time
+}
+
+type sessionBook struct {
+	lock     sync.Mutex
+	sessions map[string]*session
+}
+
+// StartSession registers a session that must Heartbeat at least every
+// timeout, or have its orders cancelled by ExpireSessions. Orders join a
+// session through Order.Session; a zero timeout never expires.
+func (ob *OrderBook) StartSession(id string, timeout time.Duration) {
+	ob.sessions.lock.Lock()
+	defer ob.sessions.lock.Unlock()
+
+	if ob.sessions.sessions == nil {
+		ob.sessions.sessions = make(map[string]*session)
+	}
+	ob.sessions.sessions[id] = &session{timeout, ob.BidBook.clock()}
+}
+
+// Heartbeat marks the session alive and reports whether it is registered.
+func (ob *OrderBook) Heartbeat(id string) bool {
+	ob.sessions.lock.Lock()
+	defer ob.sessions.lock.Unlock()
+
+	s, ok := ob.sessions.sessions[id]
+	if ok {
+		s.last = ob.BidBook.clock()
+	}
+	return ok
+}
+
+// EndSession unregisters the session and cancels its orders, returning
+// how many were cancelled.
+func (ob *OrderBook) EndSession(id string) int {
+	ob.sessions.lock.Lock()
+	delete(ob.sessions.sessions, id)
+	ob.sessions.lock.Unlock()
+
+	return ob.CancelSession(id)
+}
+
+// CancelSession cancels every resting order and pending stop tagged with
+// the session in a single batch, and returns how many were cancelled. The
+// session itself stays registered.
+func (ob *OrderBook) CancelSession(id string) int {
+	if id == "" {
+		return 0
+	}
+	mine := func(o *Order) bool { return o.Session == id }
+	var cmds []Command
+	for _, side := range []Side{Buy, Sell} {
+		for _, key := range ob.Side(side).keysWhere(mine) {
+			cmds = append(cmds, Command{Op: OpCancel, Side: side, Order: Order{OrderId: key}})
+		}
+	}
+	applied, _ := ob.Batch(cmds)
+	var n int
+	for _, ok := range applied {
+		if ok {
+			n++
+		}
+	}
+	for _, s := range ob.Stops() {
+		if s.Session == id && ob.CancelStop(s.OrderId) {
+			n++
+		}
+	}
+	return n
+}
+
+// ExpireSessions ends every session that has not heartbeated within its
+// timeout as of now, and returns their ids in order.
+func (ob *OrderBook) ExpireSessions(now time.Time) []string {
+	ob.sessions.lock.Lock()
+	var stale []string
+	for id, s := range ob.sessions.sessions {
+		if s.timeout > 0 && now.Sub(s.last) > s.timeout {
+			stale = append(stale, id)
+		}
+	}
+	ob.sessions.lock.Unlock()
+
+	sort.Strings(stale)
+	for _, id := range stale {
+		ob.EndSession(id)
+	}
+	return stale
+}
+
+// WatchSessions calls ExpireSessions every interval until ctx is done.
+func (ob *OrderBook) WatchSessions(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			ob.ExpireSessions(ob.BidBook.clock())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCancelSession(t *testing.T) {
+	ob := NewOrderBook()
+	sink := &recordingSink{}
+	ob.AddSink(sink)
+	ob.PushOrder(Buy, Order{Price: 99, Quantity: 1, OrderId: "b1", Session: "mm"})
+	ob.PushOrder(Buy, Order{Price: 98, Quantity: 1, OrderId: "b2"})
+	ob.PushOrder(Sell, Order{Price: 101, Quantity: 1, OrderId: "a1", Session: "mm"})
+	ob.SubmitStop(Stop{Order: Order{Quantity: 1, OrderId: "s1", Session: "mm"}, Side: Sell, StopPrice: 90})
+	diffs := len(sink.diffs)
+
+	if n := ob.CancelSession("mm"); n != 3 {
+		t.Errorf("Expected 3 cancelled, got %d", n)
+	}
+	if ob.BidBook.Len() != 1 || ob.AskBook.Len() != 0 || len(ob.Stops()) != 0 {
+		t.Errorf("Expected only b2 to remain")
+	}
+	if len(sink.diffs) != diffs+1 {
+		t.Errorf("Expected a single diff for the session cancel, got %d", len(sink.diffs)-diffs)
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook()
+	ob.BidBook.now = func() time.Time { return now }
+	ob.StartSession("a", time.Second)
+	ob.StartSession("b", time.Second)
+	ob.StartSession("forever", 0)
+	ob.PushOrder(Buy, Order{Price: 99, Quantity: 1, OrderId: "a1", Session: "a"})
+	ob.PushOrder(Buy, Order{Price: 98, Quantity: 1, OrderId: "b1", Session: "b"})
+
+	now = now.Add(800 * time.Millisecond)
+	if !ob.Heartbeat("b") || ob.Heartbeat("unknown") {
+		t.Errorf("Expected heartbeats to report registration")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if expired := ob.ExpireSessions(now); !reflect.DeepEqual(expired, []string{"a"}) {
+		t.Errorf("Expected only a to expire, got %v", expired)
+	}
+	if _, ok := ob.BidBook.Get("a1"); ok {
+		t.Errorf("Expected the expired session's order to be cancelled")
+	}
+	if _, ok := ob.BidBook.Get("b1"); !ok {
+		t.Errorf("Expected the live session's order to remain")
+	}
+	if ob.Heartbeat("a") {
+		t.Errorf("Expected the expired session to be unregistered")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package simulation
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+const (
+	Add    = "add"
+	Cancel = "cancel"
+)
+
+// Event is a single entry of an order flow.
+type Event struct {
+	Time     time.Time      `json:"time"`
+	Action   string         `json:"action"`
+	Side     orderbook.Side `json:"side"`
+	Id       string         `json:"id"`
+	Price    float64        `json:"price,omitempty"`
+	Quantity float64        `json:"quantity,omitempty"`
+	// Own marks an order of the strategy under test rather than of the
+	// recorded flow.
+	Own bool `json:"own,omitempty"`
+}
+
+// ReadJSONL reads one JSON encoded Event per line.
+func ReadJSONL(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("simulation: line %d: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// ReadCSV reads events from CSV with the header
+// time,action,side,id,price,quantity where time is RFC 3339.
+func ReadCSV(r io.Reader) ([]Event, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for i, rec := range records {
+		if i == 0 && rec[0] == "time" {
+			continue
+		}
+		if len(rec) != 6 {
+			return nil, fmt.Errorf("simulation: record %d: expected 6 fields, got %d", i+1, len(rec))
+		}
+		e := Event{Action: rec[1], Id: rec[3]}
+		if e.Time, err = time.Parse(time.RFC3339Nano, rec[0]); err != nil {
+			return nil, fmt.Errorf("simulation: record %d: %v", i+1, err)
+		}
+		if e.Side, err = orderbook.ParseSide(rec[2]); err != nil {
+			return nil, fmt.Errorf("simulation: record %d: %v", i+1, err)
+		}
+		if rec[4] != "" {
+			if e.Price, err = strconv.ParseFloat(rec[4], 64); err != nil {
+				return nil, fmt.Errorf("simulation: record %d: %v", i+1, err)
+			}
+		}
+		if rec[5] != "" {
+			if e.Quantity, err = strconv.ParseFloat(rec[5], 64); err != nil {
+				return nil, fmt.Errorf("simulation: record %d: %v", i+1, err)
+			}
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+type SyntheticConfig struct {
+	Start      time.Time
+	Interval   time.Duration
+	Mid        float64
+	Tick       float64
+	Levels     int     // limit prices fall within Levels ticks of the mid
+	MaxQty     float64 // quantities are uniform in (0, MaxQty], whole units
+	CancelRate float64 // probability an event cancels a live order
+	Drift      float64 // per-event standard deviation of the mid, in ticks
+}
+
+// Synthetic generates n events from a random walk around cfg.Mid. The same
+// seed always produces the same flow.
+func Synthetic(seed int64, n int, cfg SyntheticConfig) []Event {
+	if cfg.Tick <= 0 {
+		cfg.Tick = 0.01
+	}
+	if cfg.Levels < 1 {
+		cfg.Levels = 1
+	}
+	if cfg.MaxQty < 1 {
+		cfg.MaxQty = 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+	mid := cfg.Mid
+	var live []Event
+	events := make([]Event, 0, n)
+	for i := 0; i < n; i++ {
+		t := cfg.Start.Add(time.Duration(i) * cfg.Interval)
+		if len(live) > 0 && rng.Float64() < cfg.CancelRate {
+			j := rng.Intn(len(live))
+			e := live[j]
+			live = append(live[:j], live[j+1:]...)
+			events = append(events, Event{Time: t, Action: Cancel, Side: e.Side, Id: e.Id})
+			continue
+		}
+		mid += rng.NormFloat64() * cfg.Drift * cfg.Tick
+		side := orderbook.Side(rng.Intn(2))
+		offset := float64(rng.Intn(cfg.Levels)+1) * cfg.Tick
+		// a quarter of orders are priced through the mid and take liquidity
+		if rng.Intn(4) == 0 {
+			offset = -offset
+		}
+		price := mid - offset
+		if side == orderbook.Sell {
+			price = mid + offset
+		}
+		e := Event{
+			Time:     t,
+			Action:   Add,
+			Side:     side,
+			Id:       strconv.Itoa(i),
+			Price:    math.Round(price/cfg.Tick) * cfg.Tick,
+			Quantity: float64(rng.Intn(int(cfg.MaxQty)) + 1),
+		}
+		live = append(live, e)
+		events = append(events, e)
+	}
+	return events
+}
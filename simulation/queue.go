@@ -0,0 +1,149 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package simulation
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// shadow is an own order resting outside the book. ahead holds the orders
+// that were resting at its price when it arrived, which keep their
+// priority over it for as long as they rest.
+type shadow struct {
+	Event
+	ahead map[string]bool
+}
+
+// through reports whether a trade at price, by a maker on the shadow's
+// side, reached past the shadow's limit.
+func (o *shadow) through(price float64) bool {
+	if o.Side == orderbook.Buy {
+		return price < o.Price
+	}
+	return price > o.Price
+}
+
+// applyOwn places or cancels an own order at e.Time. An add that crosses
+// fills at once against the depth on the other side, which stays on the
+// book for the recorded flow; what is left joins the queue at its price
+// behind every order resting there.
+func (s *Simulator) applyOwn(e Event) []Fill {
+	switch e.Action {
+	case Add:
+		o := &shadow{Event: e, ahead: make(map[string]bool)}
+		fills := s.takeOwn(o)
+		if o.Quantity <= 0 {
+			return fills
+		}
+		for _, entry := range s.Book.Entries(e.Side) {
+			if entry.Order.Price == e.Price {
+				o.ahead[entry.Key] = true
+			}
+		}
+		s.own = append(s.own, o)
+		return fills
+	case Cancel:
+		for i, o := range s.own {
+			if o.Id == e.Id && o.Side == e.Side {
+				s.own = append(s.own[:i], s.own[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// takeOwn fills o against the opposite side's depth up to its limit.
+func (s *Simulator) takeOwn(o *shadow) []Fill {
+	bids, asks := s.Book.Depth(0)
+	lvls := asks
+	if o.Side == orderbook.Sell {
+		lvls = bids
+	}
+	var fills []Fill
+	for _, l := range lvls {
+		if o.Quantity <= 0 || o.Side == orderbook.Buy && l.Price > o.Price ||
+			o.Side == orderbook.Sell && l.Price < o.Price {
+			break
+		}
+		q := math.Min(o.Quantity, l.Quantity)
+		o.Quantity -= q
+		fills = append(fills, Fill{Own: true,
+			TradeEvent: orderbook.TradeEvent{Price: l.Price, Quantity: q, Side: o.Side, TakerId: o.Id, Time: o.Time},
+		})
+	}
+	return fills
+}
+
+// queueFills fills own orders from the trades of one recorded event. A
+// trade on an own order's side fills it when it prints through the limit,
+// or at the limit against a maker that queued behind it; either way the
+// own order takes no more than the trade's quantity.
+func (s *Simulator) queueFills(trades []Fill) []Fill {
+	var fills []Fill
+	for _, t := range trades {
+		left := t.Quantity
+		for _, o := range s.own {
+			if left <= 0 {
+				break
+			}
+			if o.Side == t.Side || !(o.through(t.Price) || t.Price == o.Price && !o.ahead[t.MakerId]) {
+				continue
+			}
+			q := math.Min(o.Quantity, left)
+			o.Quantity -= q
+			left -= q
+			fills = append(fills, Fill{Own: true,
+				TradeEvent: orderbook.TradeEvent{Price: o.Price, Quantity: q, Side: t.Side,
+					MakerId: o.Id, TakerId: t.TakerId, Time: t.Time},
+			})
+		}
+	}
+	live := s.own[:0]
+	for _, o := range s.own {
+		if o.Quantity > 0 {
+			live = append(live, o)
+		}
+	}
+	s.own = live
+	return fills
+}
+
+// schedule returns events in the order they reach the book, own events
+// retimed by the entry and cancel latencies. Ties keep the input order.
+func (s *Simulator) schedule(events []Event) []Event {
+	if s.EntryLatency == 0 && s.CancelLatency == 0 {
+		return events
+	}
+	out := append([]Event(nil), events...)
+	for i, e := range out {
+		if !e.Own {
+			continue
+		}
+		var d time.Duration
+		switch e.Action {
+		case Add:
+			d = s.EntryLatency
+		case Cancel:
+			d = s.CancelLatency
+		}
+		out[i].Time = e.Time.Add(d)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
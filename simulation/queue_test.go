@@ -0,0 +1,72 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func queueFlow() []Event {
+	at := func(s float64) time.Time {
+		return time.Date(2019, 6, 1, 9, 30, 0, 0, time.UTC).Add(time.Duration(s * float64(time.Second)))
+	}
+	return []Event{
+		{Time: at(0), Action: Add, Side: orderbook.Sell, Id: "a", Price: 101, Quantity: 2},
+		{Time: at(0), Action: Add, Side: orderbook.Buy, Id: "b", Price: 100, Quantity: 1},
+		{Time: at(1), Action: Add, Side: orderbook.Buy, Id: "me", Price: 100, Quantity: 3, Own: true},
+		{Time: at(2), Action: Add, Side: orderbook.Sell, Id: "c", Price: 100, Quantity: 2},
+		{Time: at(3), Action: Add, Side: orderbook.Sell, Id: "d", Price: 99, Quantity: 1},
+		{Time: at(4), Action: Cancel, Side: orderbook.Buy, Id: "me", Own: true},
+		{Time: at(5), Action: Add, Side: orderbook.Sell, Id: "e", Price: 100, Quantity: 5},
+	}
+}
+
+func TestQueueModel(t *testing.T) {
+	cases := []struct {
+		name          string
+		entry, cancel time.Duration
+		fills         []float64 // own fill quantities
+		takers        []string
+	}{
+		// b is ahead and takes c's first unit; c's rest and d reach me
+		{"no latency", 0, 0, []float64{1, 1}, []string{"c", "d"}},
+		// the cancel lands after e, which fills what was left
+		{"slow cancel", 0, 2 * time.Second, []float64{1, 1, 1}, []string{"c", "d", "e"}},
+		// me arrives after c rests and takes it, then queues alone
+		{"slow entry", 1500 * time.Millisecond, 0, []float64{1, 1}, []string{"me", "d"}},
+	}
+	for _, c := range cases {
+		sim := New(orderbook.NewOrderBook(), 0)
+		sim.EntryLatency, sim.CancelLatency = c.entry, c.cancel
+		res := sim.Run(queueFlow())
+		if len(res.Own) != len(c.fills) {
+			t.Errorf("%s: Expected %d own fills, got %+v", c.name, len(c.fills), res.Own)
+			continue
+		}
+		for i, f := range res.Own {
+			if f.Quantity != c.fills[i] || f.TakerId != c.takers[i] || f.Price != 100 || !f.Own {
+				t.Errorf("%s: Expected own fill %d of %v taken by %s, got %+v", c.name, i, c.fills[i], c.takers[i], f)
+			}
+		}
+		if res.Stats.Events != 5 || res.Stats.Fills != 1 || len(res.Fills) != 1 {
+			t.Errorf("%s: Expected own orders to stay out of the stats, got %+v", c.name, res.Stats)
+		}
+		if _, ok := sim.Book.BidBook.Get("me"); ok {
+			t.Errorf("%s: Expected own orders to stay off the book", c.name)
+		}
+	}
+}
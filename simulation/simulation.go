@@ -0,0 +1,209 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulation replays order flow through an orderbook.OrderBook and
+// collects the resulting fills and book statistics, for backtesting.
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// Fill is a trade of the replay. Its Time is the event's, not the
+// book's clock, and its Side is the aggressor's.
+type Fill struct {
+	// Own marks the fill of an own order, estimated by the queue model.
+	Own bool
+	orderbook.TradeEvent
+}
+
+type Stats struct {
+	Events     int
+	Adds       int
+	Cancels    int
+	Fills      int
+	Volume     float64 // matched quantity
+	MeanSpread float64 // over events where both sides were quoted
+	MaxSpread  float64
+	BidOrders  int // resting at the end of the run
+	AskOrders  int
+}
+
+type Result struct {
+	Fills []Fill
+	Stats Stats
+	// Own holds the fills of own orders, which Fills and Stats leave out.
+	Own []Fill
+}
+
+type Simulator struct {
+	Book *orderbook.OrderBook
+	// Speed scales the gaps between event timestamps: 1 replays in real
+	// time, 10 ten times faster. Zero replays as fast as possible.
+	Speed float64
+	Sleep func(time.Duration)
+	// EntryLatency and CancelLatency delay own adds and cancels on their
+	// way to the book, so they reach it behind the recorded events in
+	// between: a cancel can lose the race with a fill.
+	EntryLatency  time.Duration
+	CancelLatency time.Duration
+	// Clock, when set, is moved to each event's time before the event is
+	// applied, so a book built with orderbook.WithClock(Clock.Now) keeps
+	// event time whatever the Speed.
+	Clock *Clock
+	// ExpireSessions expires the book's sessions at each event's time,
+	// as WatchSessions would have while the flow was recorded.
+	ExpireSessions bool
+
+	own []*shadow
+}
+
+// Clock is the simulated time a Simulator replays events in. The zero
+// value reads the zero time until the first event.
+type Clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// NewClock returns a Clock reading start until it is moved.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to t. It never moves it back.
+func (c *Clock) Set(t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+func New(ob *orderbook.OrderBook, speed float64) *Simulator {
+	return &Simulator{Book: ob, Speed: speed, Sleep: time.Sleep}
+}
+
+func (s *Simulator) wait(prev, next time.Time) {
+	if s.Speed <= 0 || prev.IsZero() || !next.After(prev) {
+		return
+	}
+	s.Sleep(time.Duration(float64(next.Sub(prev)) / s.Speed))
+}
+
+// Apply routes a single event to the book: adds match against the
+// opposite side and rest any remainder, cancels remove the order from its
+// side. The fills returned include those of own orders the event's trades
+// reach.
+//
+// Own events are kept off the book, so the recorded flow plays out as it
+// did. An own order instead waits in a queue model behind the orders
+// resting at its price when it arrived, and fills from trades that would
+// have reached it.
+func (s *Simulator) Apply(e Event) []Fill {
+	if e.Own {
+		return s.applyOwn(e)
+	}
+	switch e.Action {
+	case Add:
+		o := orderbook.NewOrder(e.Price, e.Quantity, e.Id)
+		var fills []Fill
+		for _, trade := range s.Book.Match(e.Side, &o) {
+			// simulated time, not the book's clocks
+			trade.Time, trade.Monotonic = e.Time, 0
+			fills = append(fills, Fill{TradeEvent: trade})
+		}
+		if o.Quantity > 0 {
+			n := orderbook.NewNode(e.Id, &o, 1)
+			s.Book.Side(e.Side).Push(&n)
+		}
+		trades := fills[:len(fills):len(fills)]
+		if o.Quantity > 0 {
+			// the remainder rests past any own orders it crosses, so
+			// let it reach them as a trade would
+			trades = append(trades, Fill{TradeEvent: orderbook.TradeEvent{Price: e.Price,
+				Quantity: o.Quantity, Side: e.Side, TakerId: e.Id, Time: e.Time}})
+		}
+		return append(fills, s.queueFills(trades)...)
+	case Cancel:
+		s.Book.Side(e.Side).Remove(e.Id)
+	}
+	return nil
+}
+
+// Run replays events in order, pacing them according to Speed. Own
+// events are first delayed by the latencies.
+func (s *Simulator) Run(events []Event) Result {
+	var res Result
+	var prev time.Time
+	var spreads float64
+	var quoted int
+	for _, e := range s.schedule(events) {
+		s.wait(prev, e.Time)
+		prev = e.Time
+		if s.Clock != nil && !e.Time.IsZero() {
+			s.Clock.Set(e.Time)
+		}
+		if s.ExpireSessions && !e.Time.IsZero() {
+			s.Book.ExpireSessions(e.Time)
+		}
+
+		var fills []Fill
+		for _, f := range s.Apply(e) {
+			if f.Own {
+				res.Own = append(res.Own, f)
+			} else {
+				fills = append(fills, f)
+			}
+		}
+		res.Fills = append(res.Fills, fills...)
+		if e.Own {
+			continue
+		}
+		res.Stats.Events++
+		switch e.Action {
+		case Add:
+			res.Stats.Adds++
+		case Cancel:
+			res.Stats.Cancels++
+		}
+		for _, f := range fills {
+			res.Stats.Fills++
+			res.Stats.Volume += f.Quantity
+		}
+		if spread, ok := s.Book.Spread(); ok {
+			spreads += spread
+			quoted++
+			if spread > res.Stats.MaxSpread {
+				res.Stats.MaxSpread = spread
+			}
+		}
+	}
+	if quoted > 0 {
+		res.Stats.MeanSpread = spreads / float64(quoted)
+	}
+	res.Stats.BidOrders = s.Book.BidBook.Len()
+	res.Stats.AskOrders = s.Book.AskBook.Len()
+	return res
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package simulation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+const flowCSV = `time,action,side,id,price,quantity
+2019-06-01T09:30:00Z,add,sell,a,101,2
+2019-06-01T09:30:01Z,add,sell,b,102,2
+2019-06-01T09:30:02Z,add,buy,c,99,1
+2019-06-01T09:30:04Z,add,buy,d,102,3
+2019-06-01T09:30:05Z,cancel,buy,c,,
+`
+
+const flowJSONL = `{"time":"2019-06-01T09:30:00Z","action":"add","side":"sell","id":"a","price":101,"quantity":2}
+{"time":"2019-06-01T09:30:01Z","action":"add","side":"sell","id":"b","price":102,"quantity":2}
+{"time":"2019-06-01T09:30:02Z","action":"add","side":"buy","id":"c","price":99,"quantity":1}
+
+{"time":"2019-06-01T09:30:04Z","action":"add","side":"buy","id":"d","price":102,"quantity":3}
+{"time":"2019-06-01T09:30:05Z","action":"cancel","side":"buy","id":"c"}
+`
+
+func TestReplay(t *testing.T) {
+	fromCSV, err := ReadCSV(strings.NewReader(flowCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSONL, err := ReadJSONL(strings.NewReader(flowJSONL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromCSV, fromJSONL) {
+		t.Fatalf("Expected CSV and JSONL flows to decode identically:\n%+v\n%+v", fromCSV, fromJSONL)
+	}
+
+	var slept []time.Duration
+	sim := New(orderbook.NewOrderBook(), 2)
+	sim.Sleep = func(d time.Duration) { slept = append(slept, d) }
+	res := sim.Run(fromCSV)
+
+	expectedSleeps := []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, time.Second, 500 * time.Millisecond}
+	if !reflect.DeepEqual(slept, expectedSleeps) {
+		t.Errorf("Expected sleeps %v at 2x, got %v", expectedSleeps, slept)
+	}
+	if len(res.Fills) != 2 || res.Fills[0].Price != 101 || res.Fills[1].Price != 102 || res.Fills[1].TakerId != "d" {
+		t.Errorf("Expected d to sweep a and b, got %+v", res.Fills)
+	}
+	expected := Stats{Events: 5, Adds: 4, Cancels: 1, Fills: 2, Volume: 3, MeanSpread: 2.5, MaxSpread: 3, BidOrders: 0, AskOrders: 1}
+	if res.Stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, res.Stats)
+	}
+}
+
+func TestSyntheticDeterministic(t *testing.T) {
+	cfg := SyntheticConfig{Interval: time.Millisecond, Mid: 100, Tick: 0.5, Levels: 5, MaxQty: 10, CancelRate: 0.3, Drift: 1}
+	a := New(orderbook.NewOrderBook(), 0).Run(Synthetic(42, 2000, cfg))
+	b := New(orderbook.NewOrderBook(), 0).Run(Synthetic(42, 2000, cfg))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected identical results for the same seed")
+	}
+	if a.Stats.Fills == 0 || a.Stats.Cancels == 0 {
+		t.Errorf("Expected synthetic flow to produce fills and cancels, got %+v", a.Stats)
+	}
+	c := New(orderbook.NewOrderBook(), 0).Run(Synthetic(43, 2000, cfg))
+	if reflect.DeepEqual(a.Stats, c.Stats) {
+		t.Errorf("Expected a different seed to produce a different run")
+	}
+}
+
+func TestSimulatedClock(t *testing.T) {
+	events, err := ReadCSV(strings.NewReader(flowCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := events[0].Time
+	clock := NewClock(start)
+	ob := orderbook.NewOrderBook(orderbook.WithClock(clock.Now))
+	ob.StartSession("s", 2*time.Second)
+	o := orderbook.NewOrder(90, 1, "mine")
+	o.Session = "s"
+	ob.Submit(o, orderbook.Buy)
+
+	sim := New(ob, 0)
+	sim.Clock, sim.ExpireSessions = clock, true
+	sim.Sleep = func(d time.Duration) { t.Errorf("Expected no sleeps as fast as possible, got %v", d) }
+	sim.Run(events)
+
+	if now := clock.Now(); !now.Equal(events[len(events)-1].Time) {
+		t.Errorf("Expected the clock at the last event, got %v", now)
+	}
+	if n, ok := ob.Side(orderbook.Sell).Get("b"); !ok || !n.Time.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected b to rest stamped with its event time, got %+v", n)
+	}
+	if _, _, ok := ob.Lookup("mine"); ok {
+		t.Errorf("Expected the session to expire in simulated time")
+	}
+	clock.Set(start)
+	if now := clock.Now(); !now.Equal(events[len(events)-1].Time) {
+		t.Errorf("Expected the clock not to move back, got %v", now)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sinks publishes orderbook events to message brokers.
+//
+// Broker clients are accepted through the small Publisher and
+// KafkaProducer interfaces so that this package carries no client
+// dependencies: a *nats.Conn satisfies Publisher as-is, and Kafka clients
+// need a one-line wrapper.
+package sinks
+
+import (
+	"encoding/json"
+	"strconv"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// Encoder serializes events. JSON is provided, any orderbook.Codec will
+// do, and protobuf or other formats can be plugged in by implementing
+// Encode.
+type Encoder interface {
+	Encode(v interface{}) ([]byte, error)
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+var JSON Encoder = jsonEncoder{}
+
+type versionedEncoder struct{}
+
+func (versionedEncoder) Encode(v interface{}) ([]byte, error) {
+	return orderbook.Seal(v)
+}
+
+// Versioned wraps each event in an orderbook.Envelope, so consumers can
+// read it with orderbook.Open across library upgrades.
+var Versioned Encoder = versionedEncoder{}
+
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+type PublisherFunc func(subject string, data []byte) error
+
+func (f PublisherFunc) Publish(subject string, data []byte) error {
+	return f(subject, data)
+}
+
+type KafkaProducer interface {
+	Produce(topic string, key, value []byte) error
+}
+
+type Subjects struct {
+	Quotes string
+	Trades string
+	Diffs  string
+}
+
+// Sink implements orderbook.EventSink on top of a Publisher. Events whose
+// subject is empty are not published. Publish and encode failures are
+// passed to OnError when set.
+type Sink struct {
+	Publisher Publisher
+	Encoder   Encoder
+	Subjects  Subjects
+	OnError   func(error)
+}
+
+// NewNATSSink publishes to <prefix>.quotes, <prefix>.trades and
+// <prefix>.diffs.
+func NewNATSSink(conn Publisher, prefix string, enc Encoder) *Sink {
+	return &Sink{
+		Publisher: conn,
+		Encoder:   enc,
+		Subjects: Subjects{
+			Quotes: prefix + ".quotes",
+			Trades: prefix + ".trades",
+			Diffs:  prefix + ".diffs",
+		},
+	}
+}
+
+// NewKafkaSink publishes to <prefix>-quotes, <prefix>-trades and
+// <prefix>-diffs, keying each message by its sequence number.
+func NewKafkaSink(producer KafkaProducer, prefix string, enc Encoder) *Sink {
+	return &Sink{
+		Publisher: kafkaPublisher{producer},
+		Encoder:   enc,
+		Subjects: Subjects{
+			Quotes: prefix + "-quotes",
+			Trades: prefix + "-trades",
+			Diffs:  prefix + "-diffs",
+		},
+	}
+}
+
+type kafkaPublisher struct {
+	producer KafkaProducer
+}
+
+func (p kafkaPublisher) Publish(topic string, data []byte) error {
+	return p.producer.Produce(topic, nil, data)
+}
+
+func (p kafkaPublisher) publishKeyed(topic string, key, data []byte) error {
+	return p.producer.Produce(topic, key, data)
+}
+
+func (s *Sink) publish(subject string, seq uint64, v interface{}) {
+	if subject == "" {
+		return
+	}
+	data, err := s.Encoder.Encode(v)
+	if err == nil {
+		if kp, ok := s.Publisher.(kafkaPublisher); ok {
+			err = kp.publishKeyed(subject, []byte(strconv.FormatUint(seq, 10)), data)
+		} else {
+			err = s.Publisher.Publish(subject, data)
+		}
+	}
+	if err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+func (s *Sink) Quote(q *orderbook.Quote) {
+	s.publish(s.Subjects.Quotes, q.Sequence, q)
+}
+
+func (s *Sink) Trade(e *orderbook.TradeEvent) {
+	s.publish(s.Subjects.Trades, e.Sequence, e)
+}
+
+func (s *Sink) Diff(d *orderbook.DepthDiff) {
+	s.publish(s.Subjects.Diffs, d.Sequence, d)
+}
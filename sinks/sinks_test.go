@@ -0,0 +1,113 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sinks
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+type message struct {
+	Subject string
+	Key     string
+	Data    []byte
+}
+
+type recorder struct {
+	messages []message
+	err      error
+}
+
+func (r *recorder) Publish(subject string, data []byte) error {
+	r.messages = append(r.messages, message{subject, "", data})
+	return r.err
+}
+
+func (r *recorder) Produce(topic string, key, value []byte) error {
+	r.messages = append(r.messages, message{topic, string(key), value})
+	return r.err
+}
+
+func TestNATSSink(t *testing.T) {
+	r := &recorder{}
+	ob := orderbook.NewOrderBook()
+	ob.AddSink(NewNATSSink(r, "book.BTCUSD", JSON))
+
+	ask := orderbook.NewOrder(101, 2, "a")
+	bid := orderbook.NewOrder(99, 1, "b")
+	askNode := orderbook.NewNode("a", &ask, 1)
+	bidNode := orderbook.NewNode("b", &bid, 1)
+	ob.AskBook.Push(&askNode)
+	ob.BidBook.Push(&bidNode)
+
+	bySubject := make(map[string][]message)
+	for _, m := range r.messages {
+		bySubject[m.Subject] = append(bySubject[m.Subject], m)
+	}
+	quotes, diffs := bySubject["book.BTCUSD.quotes"], bySubject["book.BTCUSD.diffs"]
+	if len(quotes) != 2 || len(diffs) != 2 {
+		t.Fatalf("Expected 2 quotes and 2 diffs published, got %d and %d", len(quotes), len(diffs))
+	}
+	var q orderbook.Quote
+	if err := json.Unmarshal(quotes[1].Data, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Sequence != 4 || q.Ask == nil || q.Bid == nil || q.Bid.Price != 99 {
+		t.Errorf("Expected second quote to carry both sides, got %+v", q)
+	}
+	var d orderbook.DepthDiff
+	if err := json.Unmarshal(diffs[1].Data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Sequence != 3 || len(d.Bids) != 1 || d.Bids[0] != (orderbook.Level{Price: 99, Quantity: 1}) {
+		t.Errorf("Expected diff adding the 99 bid level, got %+v", d)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	r := &recorder{err: errors.New("broker down")}
+	var errs []error
+	sink := NewKafkaSink(r, "btcusd", JSON)
+	sink.OnError = func(err error) { errs = append(errs, err) }
+	sink.Trade(&orderbook.TradeEvent{Price: 100, Quantity: 1, Sequence: 7})
+
+	if len(r.messages) != 1 || r.messages[0].Subject != "btcusd-trades" || r.messages[0].Key != "7" {
+		t.Errorf("Expected trade keyed by sequence on btcusd-trades, got %+v", r.messages)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected publish error to reach OnError, got %v", errs)
+	}
+}
+
+func TestVersionedEncoder(t *testing.T) {
+	r := &recorder{}
+	ob := orderbook.NewOrderBook()
+	ob.AddSink(NewNATSSink(r, "book", Versioned))
+	ob.Submit(orderbook.NewOrder(99, 1, "b"), orderbook.Buy)
+
+	for _, m := range r.messages {
+		if m.Subject != "book.diffs" {
+			continue
+		}
+		var d orderbook.DepthDiff
+		if err := orderbook.Open(m.Data, &d); err != nil || len(d.Bids) != 1 {
+			t.Errorf("Expected an enveloped diff, got %+v %v", d, err)
+		}
+		return
+	}
+	t.Errorf("Expected a diff to be published, got %+v", r.messages)
+}
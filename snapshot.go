@@ -0,0 +1,148 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+// BookSnapshot is the aggregated depth of a book together with its
+// resting orders, bids then asks in priority order. Snapshots built from
+// L2 data alone may leave Orders empty.
+type BookSnapshot struct {
+	DepthSnapshot
+	Orders []Entry `json:"orders,omitempty"`
+}
+
+// Snapshot captures both sides of the book at once. Changes not yet
+// published are published first, so its Sequence is that of the last
+// event for the state it holds. A sink calling Snapshot while events are
+// being delivered cannot wait for that, and may get a snapshot that a
+// diff for the captured state still follows.
+func (ob *OrderBook) Snapshot() *BookSnapshot {
+	if ob.delivering.Load() {
+		if !ob.eventLock.TryLock() {
+			return ob.snapshot()
+		}
+	} else {
+		ob.eventLock.Lock()
+	}
+	bids, asks := ob.takePending(true, true)
+	ob.publishChanges(bids, asks, false)
+	s := ob.snapshot()
+	ob.eventLock.Unlock()
+	ob.settleChanges(bids, asks)
+	return s
+}
+
+func (ob *OrderBook) snapshot() *BookSnapshot {
+	ob.BidBook.lock.Lock()
+	ob.AskBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	defer ob.AskBook.lock.Unlock()
+
+	bids, asks := ob.BidBook.sortedNodes(), ob.AskBook.sortedNodes()
+	return &BookSnapshot{
+		DepthSnapshot: DepthSnapshot{levels(bids, 0), levels(asks, 0), ob.Sequence()},
+		Orders:        append(entries(Buy, bids), entries(Sell, asks)...),
+	}
+}
+
+// LevelChange is a level whose aggregate quantity differs between two
+// snapshots. Before is zero for an added level and After for a removed
+// one.
+type LevelChange struct {
+	Side   Side    `json:"side"`
+	Price  float64 `json:"price"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+type OrderChange struct {
+	Before Entry `json:"before"`
+	After  Entry `json:"after"`
+}
+
+// SnapshotDiff lists what it takes to turn one snapshot into another.
+type SnapshotDiff struct {
+	AddedLevels   []LevelChange `json:"addedLevels,omitempty"`
+	RemovedLevels []LevelChange `json:"removedLevels,omitempty"`
+	ChangedLevels []LevelChange `json:"changedLevels,omitempty"`
+	AddedOrders   []Entry       `json:"addedOrders,omitempty"`
+	RemovedOrders []Entry       `json:"removedOrders,omitempty"`
+	ChangedOrders []OrderChange `json:"changedOrders,omitempty"`
+}
+
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.AddedLevels)+len(d.RemovedLevels)+len(d.ChangedLevels)+
+		len(d.AddedOrders)+len(d.RemovedOrders)+len(d.ChangedOrders) == 0
+}
+
+type orderRef struct {
+	side Side
+	key  string
+}
+
+// Diff compares snapshot a with b, listing changes in the order they
+// appear in b, or in a for removals. Orders are matched by side and key
+// and count as changed when their order or weight differ; entry times
+// are ignored, since independent books stamp their own.
+func Diff(a, b *BookSnapshot) SnapshotDiff {
+	var d SnapshotDiff
+	for _, side := range []Side{Buy, Sell} {
+		before, after := a.Bids, b.Bids
+		if side == Sell {
+			before, after = a.Asks, b.Asks
+		}
+		old := make(map[float64]float64, len(before))
+		for _, l := range before {
+			old[l.Price] = l.Quantity
+		}
+		seen := make(map[float64]bool, len(after))
+		for _, l := range after {
+			seen[l.Price] = true
+			q, ok := old[l.Price]
+			switch {
+			case !ok:
+				d.AddedLevels = append(d.AddedLevels, LevelChange{side, l.Price, 0, l.Quantity})
+			case q != l.Quantity:
+				d.ChangedLevels = append(d.ChangedLevels, LevelChange{side, l.Price, q, l.Quantity})
+			}
+		}
+		for _, l := range before {
+			if !seen[l.Price] {
+				d.RemovedLevels = append(d.RemovedLevels, LevelChange{side, l.Price, l.Quantity, 0})
+			}
+		}
+	}
+
+	old := make(map[orderRef]Entry, len(a.Orders))
+	for _, e := range a.Orders {
+		old[orderRef{e.Side, e.Key}] = e
+	}
+	seen := make(map[orderRef]bool, len(b.Orders))
+	for _, e := range b.Orders {
+		ref := orderRef{e.Side, e.Key}
+		seen[ref] = true
+		prev, ok := old[ref]
+		switch {
+		case !ok:
+			d.AddedOrders = append(d.AddedOrders, e)
+		case prev.Order != e.Order || prev.Weight != e.Weight:
+			d.ChangedOrders = append(d.ChangedOrders, OrderChange{prev, e})
+		}
+	}
+	for _, e := range a.Orders {
+		if !seen[orderRef{e.Side, e.Key}] {
+			d.RemovedOrders = append(d.RemovedOrders, e)
+		}
+	}
+	return d
+}
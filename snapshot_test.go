@@ -0,0 +1,65 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(99, 2, "b1"))
+	ob.PushOrder(Buy, NewOrder(98, 1, "b2"))
+	ob.PushOrder(Sell, NewOrder(101, 3, "a1"))
+	a := ob.Snapshot()
+	if d := Diff(a, ob.Snapshot()); !d.Empty() {
+		t.Fatalf("Expected no differences between identical snapshots, got %+v", d)
+	}
+
+	ob.Cancel("b2")
+	ob.Reduce("a1", 1)
+	ob.PushOrder(Sell, NewOrder(102, 1, "a2"))
+	b := ob.Snapshot()
+	if b.Sequence <= a.Sequence {
+		t.Errorf("Expected the later snapshot to have a higher sequence")
+	}
+
+	d := Diff(a, b)
+	levels := map[string][]LevelChange{
+		"added":   {{Sell, 102, 0, 1}},
+		"removed": {{Buy, 98, 1, 0}},
+		"changed": {{Sell, 101, 3, 2}},
+	}
+	got := map[string][]LevelChange{"added": d.AddedLevels, "removed": d.RemovedLevels, "changed": d.ChangedLevels}
+	for kind, want := range levels {
+		if !reflect.DeepEqual(got[kind], want) {
+			t.Errorf("Expected %s levels %+v, got %+v", kind, want, got[kind])
+		}
+	}
+	if len(d.AddedOrders) != 1 || d.AddedOrders[0].Key != "a2" {
+		t.Errorf("Expected a2 added, got %+v", d.AddedOrders)
+	}
+	if len(d.RemovedOrders) != 1 || d.RemovedOrders[0].Key != "b2" {
+		t.Errorf("Expected b2 removed, got %+v", d.RemovedOrders)
+	}
+	if len(d.ChangedOrders) != 1 || d.ChangedOrders[0].Before.Order.Quantity != 3 || d.ChangedOrders[0].After.Order.Quantity != 2 {
+		t.Errorf("Expected a1 changed from 3 to 2, got %+v", d.ChangedOrders)
+	}
+
+	l2 := &BookSnapshot{DepthSnapshot: b.DepthSnapshot}
+	if d := Diff(l2, b); len(d.AddedOrders) != 3 || len(d.AddedLevels) != 0 {
+		t.Errorf("Expected only orders to differ from a depth-only snapshot, got %+v", d)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "container/heap"
+
+// SortedOrders returns copies of the orders resting on side in priority
+// order, sorting a copy of the side rather than popping the book.
+func (ob *OrderBook) SortedOrders(side Side) []Order {
+	entries := ob.Entries(side)
+	orders := make([]Order, len(entries))
+	for i, e := range entries {
+		orders[i] = e.Order
+	}
+	return orders
+}
+
+// TopOrders returns copies of the best n orders on side in priority
+// order. Only those n are ordered, by popping a copy of the side's heap,
+// so it costs O(n log N) rather than a full sort.
+func (ob *OrderBook) TopOrders(side Side, n int) []Order {
+	if ob.view != nil {
+		orders := ob.SortedOrders(side)
+		if n < len(orders) {
+			orders = orders[:n]
+		}
+		return orders
+	}
+	b := ob.Side(side)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	h := heapCopy{SideOrders{append(BaseHeap(nil), b.Orders.BaseHeap...), b.side, b.Orders.ahead}}
+	var orders []Order
+	for len(orders) < n && h.Len() > 0 {
+		if o := heap.Pop(&h).(*Node).Peek(); o != nil {
+			orders = append(orders, *o)
+		}
+	}
+	return orders
+}
+
+// heapCopy is a side's heap ordering over a copied slice. Its swaps leave
+// the nodes' own heap indexes alone.
+type heapCopy struct {
+	SideOrders
+}
+
+func (h heapCopy) Swap(i, j int) {
+	h.BaseHeap[i], h.BaseHeap[j] = h.BaseHeap[j], h.BaseHeap[i]
+}
+
+func (h *heapCopy) Push(x interface{}) {
+	h.BaseHeap = append(h.BaseHeap, x.(*Node))
+}
+
+func (h *heapCopy) Pop() interface{} {
+	x := h.BaseHeap[len(h.BaseHeap)-1]
+	h.BaseHeap = h.BaseHeap[:len(h.BaseHeap)-1]
+	return x
+}
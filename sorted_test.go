@@ -0,0 +1,51 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSortedOrders(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 0; i < 50; i++ {
+		ob.Submit(NewOrder(float64(100+(i*17)%23), 1, "a"+strconv.Itoa(i)), Sell)
+	}
+	sorted := ob.SortedOrders(Sell)
+	if len(sorted) != 50 || ob.OrderCount(Sell) != 50 {
+		t.Fatalf("Expected 50 sorted orders with the book intact, got %d and %d", len(sorted), ob.OrderCount(Sell))
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Price < sorted[i-1].Price {
+			t.Fatalf("Expected asks in ascending price, got %v after %v", sorted[i].Price, sorted[i-1].Price)
+		}
+	}
+	top := ob.TopOrders(Sell, 10)
+	if len(top) != 10 {
+		t.Fatalf("Expected 10 orders, got %d", len(top))
+	}
+	for i := range top {
+		if top[i] != sorted[i] {
+			t.Errorf("Expected top order %d to be %+v, got %+v", i, sorted[i], top[i])
+		}
+	}
+	if len(ob.TopOrders(Sell, 100)) != 50 || ob.AskBook.Peek() == nil {
+		t.Errorf("Expected TopOrders to stop at the side's length and leave the book alone")
+	}
+	ob.Submit(NewOrder(200, 50, "sweep"), Buy)
+	if ob.OrderCount(Sell) != 0 {
+		t.Errorf("Expected the book's heap to be intact after reading it, got %d asks", ob.OrderCount(Sell))
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "time"
+
+// Stats summarizes the book at one instant. Prices and sizes of a side
+// that is empty are zero, as are Spread and Mid unless both sides are
+// quoted.
+type Stats struct {
+	BestBid     float64 `json:"bestBid"`
+	BestBidSize float64 `json:"bestBidSize"`
+	BestAsk     float64 `json:"bestAsk"`
+	BestAskSize float64 `json:"bestAskSize"`
+	HasBid      bool    `json:"hasBid"`
+	HasAsk      bool    `json:"hasAsk"`
+	Spread      float64 `json:"spread"`
+	Mid         float64 `json:"mid"`
+	BidVolume   float64 `json:"bidVolume"`
+	AskVolume   float64 `json:"askVolume"`
+	BidOrders   int     `json:"bidOrders"`
+	AskOrders   int     `json:"askOrders"`
+	BidLevels   int     `json:"bidLevels"`
+	AskLevels   int     `json:"askLevels"`
+	// Imbalance is (bid - ask) / (bid + ask) over all resting volume.
+	Imbalance float64 `json:"imbalance"`
+	LastTrade float64 `json:"lastTrade"`
+	Traded    bool    `json:"traded"`
+	Sequence  uint64  `json:"sequence"`
+	// LastSecond, LastMinute and Last5Minutes count activity over whole
+	// seconds of the book's clock, the current one included.
+	LastSecond   WindowStats `json:"lastSecond"`
+	LastMinute   WindowStats `json:"lastMinute"`
+	Last5Minutes WindowStats `json:"last5Minutes"`
+}
+
+// Stats returns the book's summary statistics, read together under the
+// event lock and both sides' locks so they describe a single state of the
+// book.
+func (ob *OrderBook) Stats() Stats {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+	ob.BidBook.lock.Lock()
+	defer ob.BidBook.lock.Unlock()
+	ob.AskBook.lock.Lock()
+	defer ob.AskBook.lock.Unlock()
+
+	s := Stats{
+		BidVolume: ob.BidBook.levels.quantity,
+		AskVolume: ob.AskBook.levels.quantity,
+		BidOrders: ob.BidBook.Len(),
+		AskOrders: ob.AskBook.Len(),
+		BidLevels: len(ob.BidBook.levels.byPrice),
+		AskLevels: len(ob.AskBook.levels.byPrice),
+		LastTrade: ob.lastTrade,
+		Traded:    ob.traded,
+		Sequence:  ob.Sequence(),
+	}
+	now := ob.BidBook.clock()
+	s.LastSecond = ob.window(now, time.Second)
+	s.LastMinute = ob.window(now, time.Minute)
+	s.Last5Minutes = ob.window(now, 5*time.Minute)
+	s.BestBid, s.BestBidSize, s.HasBid = ob.BidBook.bestLocked()
+	s.BestAsk, s.BestAskSize, s.HasAsk = ob.AskBook.bestLocked()
+	a, b := ob.AskBook.top(), ob.BidBook.top()
+	if a != nil && b != nil && a.Peek() != nil && b.Peek() != nil {
+		ask, bid := a.Peek().Price, b.Peek().Price
+		s.Spread, s.Mid = ask-bid, (ask+bid)/2
+	}
+	if total := s.BidVolume + s.AskVolume; total > 0 {
+		s.Imbalance = (s.BidVolume - s.AskVolume) / total
+	}
+	return s
+}
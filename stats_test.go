@@ -0,0 +1,104 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	ob := NewOrderBook(WithClock(func() time.Time { return time.Unix(1000, 0) }))
+	if s := ob.Stats(); s != (Stats{}) {
+		t.Errorf("Expected empty stats for an empty book, got %+v", s)
+	}
+
+	ob.Submit(NewOrder(99, 2, "b1"), Buy)
+	ob.Submit(NewOrder(99, 1, "b2"), Buy)
+	ob.Submit(NewOrder(98, 3, "b3"), Buy)
+	ob.Submit(NewOrder(101, 2, "a1"), Sell)
+	ob.Submit(NewOrder(102, 4, "a2"), Sell)
+	ob.Submit(NewOrder(101, 1, "x"), Buy)
+
+	s := ob.Stats()
+	expected := Stats{
+		BestBid: 99, BestBidSize: 3, BestAsk: 101, BestAskSize: 1, HasBid: true, HasAsk: true,
+		Spread: 2, Mid: 100, BidVolume: 6, AskVolume: 5, BidOrders: 3, AskOrders: 2,
+		BidLevels: 2, AskLevels: 2, Imbalance: 1.0 / 11, LastTrade: 101, Traded: true,
+		Sequence: ob.Sequence(),
+	}
+	expected.LastSecond = WindowStats{Adds: 5, Trades: 1, Volume: 1}
+	expected.LastMinute, expected.Last5Minutes = expected.LastSecond, expected.LastSecond
+	if s != expected {
+		t.Errorf("Expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	ob := NewOrderBook()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			id := strconv.Itoa(i)
+			ob.Submit(NewOrder(100, 1, "b"+id), Buy)
+			ob.Submit(NewOrder(101, 1, "a"+id), Sell)
+			ob.Submit(NewOrder(101, 1, "x"+id), Buy)
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		s := ob.Stats()
+		if float64(s.BidOrders) != s.BidVolume || float64(s.AskOrders) != s.AskVolume {
+			t.Fatalf("Expected counts and volumes to agree, got %+v", s)
+		}
+	}
+	wg.Wait()
+}
+
+func TestStatsWindows(t *testing.T) {
+	now := time.Unix(1000, 0)
+	ob := NewOrderBook(WithClock(func() time.Time { return now }))
+	ob.Submit(NewOrder(99, 1, "b1"), Buy)
+	ob.Submit(NewOrder(98, 1, "b2"), Buy)
+	now = now.Add(30 * time.Second)
+	ob.Cancel("b2")
+	ob.Submit(NewOrder(99, 0.5, "x"), Sell)
+	now = now.Add(2 * time.Minute)
+	ob.Submit(NewOrder(101, 2, "a1"), Sell)
+	ob.Submit(NewOrder(101, 1.5, "y"), Buy)
+
+	tests := []struct {
+		after                       time.Duration
+		second, minute, fiveMinutes WindowStats
+	}{
+		{0, WindowStats{Adds: 1, Trades: 1, Volume: 1.5}, WindowStats{Adds: 1, Trades: 1, Volume: 1.5},
+			WindowStats{Adds: 3, Cancels: 1, Trades: 2, Volume: 2}},
+		{time.Second, WindowStats{}, WindowStats{Adds: 1, Trades: 1, Volume: 1.5},
+			WindowStats{Adds: 3, Cancels: 1, Trades: 2, Volume: 2}},
+		{3 * time.Minute, WindowStats{}, WindowStats{}, WindowStats{Adds: 1, Trades: 1, Volume: 1.5}},
+		{10 * time.Minute, WindowStats{}, WindowStats{}, WindowStats{}},
+	}
+	start := now
+	for _, tt := range tests {
+		now = start.Add(tt.after)
+		s := ob.Stats()
+		if s.LastSecond != tt.second || s.LastMinute != tt.minute || s.Last5Minutes != tt.fiveMinutes {
+			t.Errorf("Expected %+v, %+v and %+v after %v, got %+v, %+v and %+v", tt.second, tt.minute, tt.fiveMinutes,
+				tt.after, s.LastSecond, s.LastMinute, s.Last5Minutes)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"container/list"
+	"sync"
+)
+
+// StatusHistory is the number of final order states the book remembers.
+// Past it the oldest are forgotten first.
+const StatusHistory = 1 << 16
+
+// statusBook remembers orders that have left the book filled or canceled,
+// up to StatusHistory of them.
+type statusBook struct {
+	lock  sync.Mutex
+	done  map[string]*list.Element
+	order list.List
+}
+
+type finalStatus struct {
+	key    string
+	status ExecStatus
+}
+
+func (sb *statusBook) set(key string, s ExecStatus) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	sb.add(key, s)
+}
+
+// add records key's final state as the newest. The caller holds the lock.
+func (sb *statusBook) add(key string, s ExecStatus) {
+	if sb.done == nil {
+		sb.done = make(map[string]*list.Element)
+	}
+	if e, ok := sb.done[key]; ok {
+		sb.order.Remove(e)
+	}
+	sb.done[key] = sb.order.PushBack(finalStatus{key, s})
+	for sb.order.Len() > StatusHistory {
+		oldest := sb.order.Front()
+		delete(sb.done, sb.order.Remove(oldest).(finalStatus).key)
+	}
+}
+
+// forget drops key's final state. The caller holds the lock.
+func (sb *statusBook) forget(key string) {
+	if e, ok := sb.done[key]; ok {
+		sb.order.Remove(e)
+		delete(sb.done, key)
+	}
+}
+
+func (sb *statusBook) get(key string) (ExecStatus, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	if e, ok := sb.done[key]; ok {
+		return e.Value.(finalStatus).status, true
+	}
+	return 0, false
+}
+
+// track forgets keys that are pushed again and marks removed ones as
+// canceled. Fills are marked by the matcher.
+func (sb *statusBook) track(bids, asks []change) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	for _, changes := range [][]change{bids, asks} {
+		for _, c := range changes {
+			switch c.op {
+			case opPush, opReplace:
+				sb.forget(c.node.Key)
+			case opRemove:
+				sb.add(c.node.Key, StatusCanceled)
+			}
+		}
+	}
+}
+
+// OrderStatus reports the state of the order with the given id: New or
+// PartiallyFilled while it rests, then Filled or Canceled once it has left
+// the book. Final states are kept until the id is reused or StatusHistory
+// newer ones push them out. Unknown ids, and orders taken off with Pop,
+// report false.
+func (ob *OrderBook) OrderStatus(orderId string) (ExecStatus, bool) {
+	if o, _, ok := ob.Lookup(orderId); ok {
+		if o.Filled > 0 {
+			return StatusPartiallyFilled, true
+		}
+		return StatusNew, true
+	}
+	return ob.statuses.get(orderId)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestOrderStatus(t *testing.T) {
+	ob := NewOrderBook()
+	ob.Submit(NewOrder(100, 5, "a"), Sell)
+	ob.Submit(NewOrder(101, 1, "c"), Sell)
+	ob.Submit(NewOrder(100, 2, "b1"), Buy)
+
+	if o, _, _ := ob.Lookup("a"); o.Filled != 2 || o.Quantity != 3 {
+		t.Errorf("Expected a filled 2 with 3 remaining, got %g and %g", o.Filled, o.Quantity)
+	}
+	ob.Cancel("c")
+
+	cases := []struct {
+		orderId string
+		status  ExecStatus
+		ok      bool
+	}{
+		{"a", StatusPartiallyFilled, true},
+		{"b1", StatusFilled, true},
+		{"c", StatusCanceled, true},
+		{"x", 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.orderId, func(t *testing.T) {
+			if s, ok := ob.OrderStatus(c.orderId); s != c.status || ok != c.ok {
+				t.Errorf("Expected %v %v, got %v %v", c.status, c.ok, s, ok)
+			}
+		})
+	}
+
+	ob.Submit(NewOrder(100, 3, "b2"), Buy)
+	if s, _ := ob.OrderStatus("a"); s != StatusFilled {
+		t.Errorf("Expected a filled, got %v", s)
+	}
+	ob.Submit(NewOrder(99, 1, "c"), Buy)
+	if s, _ := ob.OrderStatus("c"); s != StatusNew {
+		t.Errorf("Expected a reused id to be new, got %v", s)
+	}
+}
+
+func TestOrderStatusHistory(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 0; i <= StatusHistory; i++ {
+		ob.statuses.set(strconv.Itoa(i), StatusFilled)
+	}
+	if _, ok := ob.OrderStatus("0"); ok {
+		t.Errorf("Expected the oldest status to be forgotten")
+	}
+	if s, ok := ob.OrderStatus(strconv.Itoa(StatusHistory)); !ok || s != StatusFilled {
+		t.Errorf("Expected the newest status to be kept, got %v %v", s, ok)
+	}
+	if n := len(ob.statuses.done); n != StatusHistory {
+		t.Errorf("Expected %d statuses kept, got %d", StatusHistory, n)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+)
+
+var (
+	ErrInvalidStop   = errors.New("orderbook: stop needs a positive stop price or a trail")
+	ErrDuplicateStop = errors.New("orderbook: stop order id already pending")
+)
+
+// TriggerPrice is the price a stop watches.
+type TriggerPrice int
+
+const (
+	// LastPrice triggers on the book's own trades. It is the default.
+	LastPrice TriggerPrice = iota
+	// MarkPrice and IndexPrice trigger on prices supplied from outside
+	// the book with UpdateTriggerPrice, as derivatives venues do.
+	MarkPrice
+	IndexPrice
+	numTriggers
+)
+
+func (t TriggerPrice) String() string {
+	return [...]string{"last", "mark", "index"}[t]
+}
+
+// Stop is held off the book until its Trigger price reaches StopPrice
+// (at or above for buys, at or below for sells), then Order is submitted
+// on Side. An Order.Price of zero submits a market order, whose unfilled
+// remainder is reported in Remaining but not rested.
+//
+// A trailing stop sets TrailAmount or TrailPercent instead of StopPrice.
+// Its stop price follows the best trigger price seen since it was placed,
+// the highest for sells and the lowest for buys, by that offset.
+type Stop struct {
+	Order
+	Side         Side         `json:"side"`
+	StopPrice    float64      `json:"stopPrice"`
+	TrailAmount  float64      `json:"trailAmount,omitempty"`
+	TrailPercent float64      `json:"trailPercent,omitempty"`
+	Trigger      TriggerPrice `json:"trigger,omitempty"`
+}
+
+func (s *Stop) trailing() bool {
+	return s.TrailAmount > 0 || s.TrailPercent > 0
+}
+
+func (s *Stop) triggered(p float64) bool {
+	if s.Side == Buy {
+		return p >= s.StopPrice
+	}
+	return p <= s.StopPrice
+}
+
+// pendingStop carries the best trade price a trailing stop has seen.
+type pendingStop struct {
+	Stop
+	ref  float64
+	seen bool
+}
+
+// follow moves a trailing stop's price with the trade price p.
+func (s *pendingStop) follow(p float64) {
+	if !s.trailing() {
+		return
+	}
+	if !s.seen || (s.Side == Sell && p > s.ref) || (s.Side == Buy && p < s.ref) {
+		s.ref, s.seen = p, true
+	}
+	offset := s.TrailAmount
+	if s.TrailPercent > 0 {
+		offset = math.Abs(s.ref) * s.TrailPercent / 100
+	}
+	if s.Side == Sell {
+		s.StopPrice = s.ref - offset
+	} else {
+		s.StopPrice = s.ref + offset
+	}
+}
+
+// stopBook holds pending stops in the order they were placed, which is
+// also the order simultaneous triggers fire in, and the last external
+// price of each kind.
+type stopBook struct {
+	lock  sync.Mutex
+	stops []*pendingStop
+	last  [numTriggers]float64
+	seen  [numTriggers]bool
+}
+
+func (sb *stopBook) add(s Stop) error {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	for _, p := range sb.stops {
+		if p.OrderId == s.OrderId {
+			return ErrDuplicateStop
+		}
+	}
+	sb.stops = append(sb.stops, &pendingStop{Stop: s})
+	return nil
+}
+
+// pending returns copies of the pending stops in the order they were
+// placed.
+func (sb *stopBook) pending() []*pendingStop {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	stops := make([]*pendingStop, len(sb.stops))
+	for i, s := range sb.stops {
+		c := *s
+		stops[i] = &c
+	}
+	return stops
+}
+
+// insert adds p, trailing state and all, unless its order id is taken.
+func (sb *stopBook) insert(p *pendingStop) bool {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	for _, s := range sb.stops {
+		if s.OrderId == p.OrderId {
+			return false
+		}
+	}
+	sb.stops = append(sb.stops, p)
+	return true
+}
+
+func (sb *stopBook) remove(orderId string) bool {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	for i, p := range sb.stops {
+		if p.OrderId == orderId {
+			sb.stops = append(sb.stops[:i], sb.stops[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// observe updates every stop watching trigger with the price p and
+// removes and returns those it triggers.
+func (sb *stopBook) observe(trigger TriggerPrice, p float64) []Stop {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	sb.last[trigger], sb.seen[trigger] = p, true
+	var fired []Stop
+	kept := sb.stops[:0]
+	for _, s := range sb.stops {
+		if s.Trigger != trigger {
+			kept = append(kept, s)
+			continue
+		}
+		s.follow(p)
+		if s.triggered(p) {
+			fired = append(fired, s.Stop)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	sb.stops = kept
+	return fired
+}
+
+func (sb *stopBook) lastPrice(trigger TriggerPrice) (float64, bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	return sb.last[trigger], sb.seen[trigger]
+}
+
+func (sb *stopBook) list() []Stop {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	stops := make([]Stop, len(sb.stops))
+	for i, s := range sb.stops {
+		stops[i] = s.Stop
+	}
+	return stops
+}
+
+// SubmitStop validates s and holds it until triggered. Validators run
+// now, against s.Order as given; a stop that the last trade price, or the
+// last price supplied for its Trigger, has already triggered fires
+// immediately, and its report is returned in Triggered.
+func (ob *OrderBook) SubmitStop(s Stop) ExecutionReport {
+	ob.assignId(&s.Order)
+	report := ExecutionReport{OrderId: s.OrderId, ClientOrderId: s.ClientOrderId,
+		Side: s.Side, Remaining: s.Quantity}
+	err := ob.validateStop(&s)
+	for i := 0; err == nil && i < len(ob.validators); i++ {
+		err = ob.validators[i].Validate(ob, s.Side, &s.Order)
+	}
+	if err == nil {
+		err = ob.stops.add(s)
+	}
+	if err != nil {
+		ob.reject(&report, err)
+		return report
+	}
+	if s.Trigger != LastPrice {
+		if price, ok := ob.stops.lastPrice(s.Trigger); ok {
+			report.Triggered = ob.UpdateTriggerPrice(s.Trigger, price)
+		}
+	} else if price, ok := ob.LastTrade(); ok {
+		report.Triggered = ob.runStops([]float64{price})
+	}
+	return report
+}
+
+func (ob *OrderBook) validateStop(s *Stop) error {
+	o := s.Order
+	if o.Price == 0 {
+		o.Price = 1
+		if ob.instrument.Tick > 0 {
+			o.Price = ob.instrument.Tick
+		}
+	}
+	if err := ob.validate(&o); err != nil {
+		return err
+	}
+	if s.Trigger < LastPrice || s.Trigger >= numTriggers || s.TrailAmount < 0 || s.TrailPercent < 0 || (!s.trailing() && (s.StopPrice == 0 || !ob.validPrice(s.StopPrice))) {
+		return ErrInvalidStop
+	}
+	return nil
+}
+
+// CancelStop removes a pending stop and reports whether it was found.
+func (ob *OrderBook) CancelStop(orderId string) bool {
+	return ob.stops.remove(orderId)
+}
+
+// Stops returns the pending stops in the order they were placed, trailing
+// stops with their current stop price.
+func (ob *OrderBook) Stops() []Stop {
+	return ob.stops.list()
+}
+
+func (ob *OrderBook) LastTrade() (float64, bool) {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	return ob.lastTrade, ob.traded
+}
+
+// runStops feeds trade prices to the pending stops in order, firing the
+// stops they trigger; trades made by fired stops are fed back in turn.
+func (ob *OrderBook) runStops(prices []float64) []ExecutionReport {
+	var reports []ExecutionReport
+	for len(prices) > 0 {
+		p := prices[0]
+		prices = prices[1:]
+		for _, s := range ob.stops.observe(LastPrice, p) {
+			report := ob.fire(s)
+			for _, t := range report.Trades {
+				prices = append(prices, t.Price)
+			}
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// UpdateTriggerPrice feeds an external mark or index price to the pending
+// stops watching it and returns the reports of the stops it fires,
+// followed by those fired in turn by their trades. Updating LastPrice
+// feeds the stops as a trade at price would.
+func (ob *OrderBook) UpdateTriggerPrice(trigger TriggerPrice, price float64) []ExecutionReport {
+	if trigger == LastPrice {
+		return ob.runStops([]float64{price})
+	}
+	var reports []ExecutionReport
+	var prices []float64
+	for _, s := range ob.stops.observe(trigger, price) {
+		report := ob.fire(s)
+		prices = append(prices, tradePrices(report.Trades)...)
+		reports = append(reports, report)
+	}
+	return append(reports, ob.runStops(prices)...)
+}
+
+// FollowTriggerPrices passes each price received on prices to
+// UpdateTriggerPrice until prices is closed or ctx is done, handing the
+// reports of any stops fired to fired when it is set.
+func (ob *OrderBook) FollowTriggerPrices(ctx context.Context, trigger TriggerPrice, prices <-chan float64, fired func([]ExecutionReport)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p, ok := <-prices:
+			if !ok {
+				return
+			}
+			if reports := ob.UpdateTriggerPrice(trigger, p); len(reports) > 0 && fired != nil {
+				fired(reports)
+			}
+		}
+	}
+}
+
+func (ob *OrderBook) fire(s Stop) ExecutionReport {
+	received := ob.monotonic()
+	ob.memberFilled(s.OrderId, true)
+	o := s.Order
+	report := ExecutionReport{OrderId: o.OrderId, ClientOrderId: o.ClientOrderId,
+		Side: s.Side, Remaining: o.Quantity}
+	if o.Price == 0 {
+		o.Price = math.Inf(1)
+		if s.Side == Sell {
+			o.Price = math.Inf(-1)
+		}
+	}
+	ob.execute(&o, s.Side, &report)
+	report.FillLatency = fillLatency(report.Trades, received)
+	return report
+}
+
+func tradePrices(trades []TradeEvent) []float64 {
+	prices := make([]float64, len(trades))
+	for i, t := range trades {
+		prices[i] = t.Price
+	}
+	return prices
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"testing"
+)
+
+// trade prints a single trade at price by resting and then hitting it.
+func trade(ob *OrderBook, price float64) ExecutionReport {
+	ob.PushOrder(Sell, NewOrder(price, 1, "maker"))
+	return ob.Submit(NewOrder(price, 1, "taker"), Buy)
+}
+
+func TestStop(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(90, 5, "bid"))
+	report := ob.SubmitStop(Stop{Order: NewOrder(0, 2, "stop"), Side: Sell, StopPrice: 95})
+	if report.Status != StatusNew || len(ob.Stops()) != 1 {
+		t.Fatalf("Expected stop to be held, got %+v", report)
+	}
+	if r := trade(ob, 96); len(r.Triggered) != 0 {
+		t.Errorf("Expected 96 not to trigger a 95 sell stop")
+	}
+	r := trade(ob, 95)
+	if len(r.Triggered) != 1 {
+		t.Fatalf("Expected 95 to trigger the stop, got %+v", r)
+	}
+	fired := r.Triggered[0]
+	if fired.OrderId != "stop" || fired.Status != StatusFilled || fired.Trades[0].Price != 90 {
+		t.Errorf("Expected the market stop to fill against the 90 bid, got %+v", fired)
+	}
+	if len(ob.Stops()) != 0 || ob.BidBook.Peek().Quantity != 3 {
+		t.Errorf("Expected the stop to be consumed")
+	}
+
+	if report := ob.SubmitStop(Stop{Order: NewOrder(0, 1, "bad"), Side: Sell}); report.Err != ErrInvalidStop {
+		t.Errorf("Expected a stop without a price to be rejected, got %v", report.Err)
+	}
+	ob.SubmitStop(Stop{Order: NewOrder(100, 1, "c"), Side: Buy, StopPrice: 200})
+	if !ob.CancelStop("c") || ob.CancelStop("c") {
+		t.Errorf("Expected the stop to cancel once")
+	}
+}
+
+func TestTrailingStop(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Stop    Stop
+		Prices  []float64
+		Stops   []float64 // stop price after each trade, 0 once fired
+		FiredAt int
+	}{
+		{"sell-amount", Stop{Order: NewOrder(1, 1, "s"), Side: Sell, TrailAmount: 2},
+			[]float64{100, 103, 102, 101}, []float64{98, 101, 101, 0}, 3},
+		{"buy-percent", Stop{Order: NewOrder(1000, 1, "s"), Side: Buy, TrailPercent: 10},
+			[]float64{100, 90, 95, 99}, []float64{110, 99, 99, 0}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ob := NewOrderBook()
+			ob.SubmitStop(test.Stop)
+			for i, p := range test.Prices {
+				r := trade(ob, p)
+				if fired := len(r.Triggered) > 0; fired != (i == test.FiredAt) {
+					t.Fatalf("Expected fired %t after trade %d at %g", i == test.FiredAt, i, p)
+				}
+				stops := ob.Stops()
+				if test.Stops[i] == 0 {
+					if len(stops) != 0 {
+						t.Errorf("Expected no pending stops, got %+v", stops)
+					}
+					continue
+				}
+				if len(stops) != 1 || stops[0].StopPrice != test.Stops[i] {
+					t.Errorf("Expected stop at %g after %g, got %+v", test.Stops[i], p, stops)
+				}
+			}
+		})
+	}
+}
+
+func TestTriggerPriceStops(t *testing.T) {
+	ob := NewOrderBook()
+	ob.PushOrder(Buy, NewOrder(90, 5, "bid"))
+	ob.SubmitStop(Stop{Order: NewOrder(0, 2, "mark"), Side: Sell, StopPrice: 95, Trigger: MarkPrice})
+	ob.SubmitStop(Stop{Order: NewOrder(0, 1, "last"), Side: Sell, StopPrice: 90})
+
+	if r := trade(ob, 94); len(r.Triggered) != 0 {
+		t.Errorf("Expected trades not to trigger a mark price stop, got %+v", r.Triggered)
+	}
+	if reports := ob.UpdateTriggerPrice(MarkPrice, 96); len(reports) != 0 {
+		t.Errorf("Expected a mark of 96 not to trigger, got %+v", reports)
+	}
+	reports := ob.UpdateTriggerPrice(MarkPrice, 95)
+	if len(reports) != 2 || reports[0].OrderId != "mark" || reports[1].OrderId != "last" {
+		t.Fatalf("Expected the mark stop and then the last price stop its trade triggers, got %+v", reports)
+	}
+	if reports[0].Filled != 2 || reports[0].Trades[0].Price != 90 {
+		t.Errorf("Expected the mark stop to sell 2 at 90, got %+v", reports[0])
+	}
+
+	r := ob.SubmitStop(Stop{Order: NewOrder(0, 1, "late"), Side: Sell, StopPrice: 96, Trigger: MarkPrice})
+	if len(r.Triggered) != 1 || r.Triggered[0].OrderId != "late" {
+		t.Errorf("Expected a stop the last mark has passed to fire at once, got %+v", r)
+	}
+	if r := ob.SubmitStop(Stop{Order: NewOrder(0, 1, "bad"), Side: Sell, StopPrice: 90, Trigger: 7}); r.Err != ErrInvalidStop {
+		t.Errorf("Expected an unknown trigger to be rejected, got %v", r.Err)
+	}
+
+	ob.PushOrder(Sell, NewOrder(110, 1, "ask"))
+	ob.SubmitStop(Stop{Order: NewOrder(0, 1, "index"), Side: Buy, StopPrice: 105, Trigger: IndexPrice})
+	prices := make(chan float64, 3)
+	prices <- 100
+	prices <- 106
+	close(prices)
+	var fired []ExecutionReport
+	ob.FollowTriggerPrices(context.Background(), IndexPrice, prices, func(r []ExecutionReport) {
+		fired = append(fired, r...)
+	})
+	if len(fired) != 1 || fired[0].OrderId != "index" || fired[0].Filled != 1 {
+		t.Errorf("Expected the index stop to fire on 106 and buy the ask, got %+v", fired)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// KV is the subset of an ordered key-value store used by KVStore. Scan
+// visits keys with the given prefix in ascending order. Embedded stores
+// such as bbolt or badger are adapted with a bucket or key prefix.
+type KV interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	Scan(prefix []byte, fn func(key, value []byte) error) error
+}
+
+// KVStore keeps a book's orders and trades in a KV, encoded with Codec
+// or, when it is nil, orderbook.VersionedCodec.
+type KVStore struct {
+	KV    KV
+	Codec orderbook.Codec
+}
+
+func (s KVStore) codec() orderbook.Codec {
+	if s.Codec == nil {
+		return orderbook.VersionedCodec
+	}
+	return s.Codec
+}
+
+func orderPrefix(symbol string) []byte {
+	return []byte("orders/" + symbol + "/")
+}
+
+func tradePrefix(symbol string) []byte {
+	return []byte("trades/" + symbol + "/")
+}
+
+func (s KVStore) ReplaceOrders(symbol string, entries []orderbook.Entry) error {
+	var stale [][]byte
+	err := s.KV.Scan(orderPrefix(symbol), func(key, _ []byte) error {
+		stale = append(stale, append([]byte(nil), key...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := s.KV.Delete(key); err != nil {
+			return err
+		}
+	}
+	for i, e := range entries {
+		value, err := s.codec().Encode(e)
+		if err != nil {
+			return err
+		}
+		key := append(orderPrefix(symbol), fmt.Sprintf("%020d", i)...)
+		if err := s.KV.Put(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s KVStore) LoadOrders(symbol string) ([]orderbook.Entry, error) {
+	var entries []orderbook.Entry
+	err := s.KV.Scan(orderPrefix(symbol), func(_, value []byte) error {
+		var e orderbook.Entry
+		if err := s.codec().Decode(value, &e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+func (s KVStore) AppendTrade(symbol string, t orderbook.TradeEvent) error {
+	value, err := s.codec().Encode(t)
+	if err != nil {
+		return err
+	}
+	return s.KV.Put(append(tradePrefix(symbol), fmt.Sprintf("%020d", t.Sequence)...), value)
+}
+
+func (s KVStore) LoadTrades(symbol string) ([]orderbook.TradeEvent, error) {
+	var trades []orderbook.TradeEvent
+	err := s.KV.Scan(tradePrefix(symbol), func(_, value []byte) error {
+		var t orderbook.TradeEvent
+		if err := s.codec().Decode(value, &t); err != nil {
+			return err
+		}
+		trades = append(trades, t)
+		return nil
+	})
+	return trades, err
+}
+
+// MemoryKV is an in-process KV, useful for tests and ephemeral books.
+type MemoryKV struct {
+	lock sync.Mutex
+	data map[string][]byte
+}
+
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{data: make(map[string][]byte)}
+}
+
+func (m *MemoryKV) Put(key, value []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.data[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *MemoryKV) Delete(key []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.data, string(key))
+	return nil
+}
+
+func (m *MemoryKV) Scan(prefix []byte, fn func(key, value []byte) error) error {
+	m.lock.Lock()
+	var keys []string
+	for k := range m.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = m.data[k]
+	}
+	m.lock.Unlock()
+
+	for i, k := range keys {
+		if err := fn([]byte(k), values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+type Dialect int
+
+const (
+	SQLite Dialect = iota
+	Postgres
+)
+
+// SQL stores books in two tables through any database/sql driver; the
+// caller imports the driver for their database.
+type SQL struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// The country column holds Order.Venue, under the field's former name so
+// existing tables keep working.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS orderbook_orders (
+		symbol TEXT NOT NULL,
+		position BIGINT NOT NULL,
+		side TEXT NOT NULL,
+		book_key TEXT NOT NULL,
+		order_id TEXT NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		quantity DOUBLE PRECISION NOT NULL,
+		country TEXT NOT NULL,
+		weight DOUBLE PRECISION NOT NULL,
+		entered_at BIGINT NOT NULL,
+		account TEXT NOT NULL DEFAULT '',
+		filled DOUBLE PRECISION NOT NULL DEFAULT 0,
+		session TEXT NOT NULL DEFAULT '',
+		client_order_id TEXT NOT NULL DEFAULT '',
+		category INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (symbol, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS orderbook_trades (
+		symbol TEXT NOT NULL,
+		sequence BIGINT NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		quantity DOUBLE PRECISION NOT NULL,
+		trade_id BIGINT NOT NULL DEFAULT 0,
+		side TEXT NOT NULL DEFAULT '',
+		maker_id TEXT NOT NULL DEFAULT '',
+		taker_id TEXT NOT NULL DEFAULT '',
+		traded_at BIGINT NOT NULL DEFAULT 0
+	)`,
+}
+
+// migrations are the columns added since the tables were first created,
+// which CreateTables adds to tables that lack them.
+var migrations = []struct {
+	table, column, definition string
+}{
+	{"orderbook_orders", "account", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_orders", "filled", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"orderbook_orders", "session", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_orders", "client_order_id", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_orders", "category", "INTEGER NOT NULL DEFAULT 0"},
+	{"orderbook_trades", "trade_id", "BIGINT NOT NULL DEFAULT 0"},
+	{"orderbook_trades", "side", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_trades", "maker_id", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_trades", "taker_id", "TEXT NOT NULL DEFAULT ''"},
+	{"orderbook_trades", "traded_at", "BIGINT NOT NULL DEFAULT 0"},
+}
+
+// CreateTables creates the tables if they do not exist and adds any
+// columns missing from tables created by earlier versions.
+func (s *SQL) CreateTables() error {
+	for _, stmt := range schema {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	for _, m := range migrations {
+		if s.hasColumn(m.table, m.column) {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table has column, by selecting it from no rows.
+func (s *SQL) hasColumn(table, column string) bool {
+	rows, err := s.DB.Query(fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column, table))
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// rebind rewrites ? placeholders for dialects that number them.
+func (s *SQL) rebind(query string) string {
+	if s.Dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQL) ReplaceOrders(symbol string, entries []orderbook.Entry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.rebind(`DELETE FROM orderbook_orders WHERE symbol = ?`), symbol); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.rebind(`INSERT INTO orderbook_orders
+		(symbol, position, side, book_key, order_id, price, quantity, country, weight, entered_at, account,
+		filled, session, client_order_id, category)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, e := range entries {
+		if _, err := stmt.Exec(symbol, i, e.Side.String(), e.Key, e.Order.OrderId,
+			e.Order.Price, e.Order.Quantity, e.Order.Venue, e.Weight, e.Time.UnixNano(), e.Order.Account,
+			e.Order.Filled, e.Order.Session, e.Order.ClientOrderId, int(e.Order.Category)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) LoadOrders(symbol string) ([]orderbook.Entry, error) {
+	rows, err := s.DB.Query(s.rebind(`SELECT side, book_key, order_id, price, quantity, country, weight, entered_at, account,
+		filled, session, client_order_id, category FROM orderbook_orders WHERE symbol = ? ORDER BY position`), symbol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []orderbook.Entry
+	for rows.Next() {
+		var e orderbook.Entry
+		var side string
+		var entered int64
+		var category int
+		if err := rows.Scan(&side, &e.Key, &e.Order.OrderId, &e.Order.Price,
+			&e.Order.Quantity, &e.Order.Venue, &e.Weight, &entered, &e.Order.Account,
+			&e.Order.Filled, &e.Order.Session, &e.Order.ClientOrderId, &category); err != nil {
+			return nil, err
+		}
+		e.Order.Category = orderbook.Category(category)
+		e.Time = time.Unix(0, entered).UTC()
+		if e.Side, err = orderbook.ParseSide(side); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQL) AppendTrade(symbol string, t orderbook.TradeEvent) error {
+	var traded int64
+	if !t.Time.IsZero() {
+		traded = t.Time.UnixNano()
+	}
+	_, err := s.DB.Exec(s.rebind(`INSERT INTO orderbook_trades
+		(symbol, sequence, price, quantity, trade_id, side, maker_id, taker_id, traded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`), symbol, t.Sequence, t.Price, t.Quantity,
+		t.TradeId, t.Side.String(), t.MakerId, t.TakerId, traded)
+	return err
+}
+
+func (s *SQL) LoadTrades(symbol string) ([]orderbook.TradeEvent, error) {
+	rows, err := s.DB.Query(s.rebind(`SELECT sequence, price, quantity, trade_id, side, maker_id, taker_id, traded_at
+		FROM orderbook_trades WHERE symbol = ? ORDER BY sequence`), symbol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []orderbook.TradeEvent
+	for rows.Next() {
+		var t orderbook.TradeEvent
+		var side string
+		var traded int64
+		if err := rows.Scan(&t.Sequence, &t.Price, &t.Quantity, &t.TradeId, &side,
+			&t.MakerId, &t.TakerId, &traded); err != nil {
+			return nil, err
+		}
+		if side != "" {
+			if t.Side, err = orderbook.ParseSide(side); err != nil {
+				return nil, err
+			}
+		}
+		if traded != 0 {
+			t.Time = time.Unix(0, traded).UTC()
+		}
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
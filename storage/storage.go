@@ -0,0 +1,93 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage persists resting orders and trades so a book can be
+// rebuilt at startup.
+package storage
+
+import (
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+// Store persists the resting orders and trade history of books by symbol.
+// Orders are saved and loaded in priority order.
+type Store interface {
+	ReplaceOrders(symbol string, entries []orderbook.Entry) error
+	LoadOrders(symbol string) ([]orderbook.Entry, error)
+	AppendTrade(symbol string, trade orderbook.TradeEvent) error
+	LoadTrades(symbol string) ([]orderbook.TradeEvent, error)
+}
+
+// Save replaces the stored resting orders for symbol with those in ob.
+func Save(s Store, symbol string, ob *orderbook.OrderBook) error {
+	entries := append(ob.Entries(orderbook.Buy), ob.Entries(orderbook.Sell)...)
+	return s.ReplaceOrders(symbol, entries)
+}
+
+// LoadBook builds a new book from the orders stored for symbol, preserving
+// their priority.
+func LoadBook(s Store, symbol string, opts ...orderbook.Option) (*orderbook.OrderBook, error) {
+	entries, err := s.LoadOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	ob := orderbook.NewOrderBook(opts...)
+	ob.Restore(entries)
+	return ob, nil
+}
+
+// Migrate copies the resting orders and trade history of each symbol from
+// one store to another, orders in priority order and trades in the order
+// they were recorded, so a service can move to a new store without
+// rebuilding its books. The orders stored in to for each symbol are
+// replaced; trades are appended, so to should hold none for it yet.
+// OrderBook.Migrate moves a running book onto another in-memory index.
+func Migrate(from, to Store, symbols ...string) error {
+	for _, symbol := range symbols {
+		entries, err := from.LoadOrders(symbol)
+		if err != nil {
+			return err
+		}
+		trades, err := from.LoadTrades(symbol)
+		if err != nil {
+			return err
+		}
+		if err := to.ReplaceOrders(symbol, entries); err != nil {
+			return err
+		}
+		for _, t := range trades {
+			if err := to.AppendTrade(symbol, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TradeRecorder is an orderbook.EventSink appending every trade to a
+// Store. Failed writes are passed to OnError when set.
+type TradeRecorder struct {
+	Store   Store
+	Symbol  string
+	OnError func(error)
+}
+
+func (r *TradeRecorder) Trade(t *orderbook.TradeEvent) {
+	if err := r.Store.AppendTrade(r.Symbol, *t); err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+func (r *TradeRecorder) Quote(*orderbook.Quote)    {}
+func (r *TradeRecorder) Diff(*orderbook.DepthDiff) {}
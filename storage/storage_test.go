@@ -0,0 +1,285 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	orderbook "github.com/laneshetron/go-orderbook"
+)
+
+func TestKVStoreRoundTrip(t *testing.T) {
+	store := KVStore{KV: NewMemoryKV()}
+	ob := orderbook.NewOrderBook()
+	ob.AddSink(&TradeRecorder{Store: store, Symbol: "BTCUSD"})
+	ob.Submit(orderbook.NewOrder(100, 1, "a"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(100, 2, "b"), orderbook.Buy)
+	ob.Submit(orderbook.Order{Price: 99, Quantity: 1, OrderId: "c", Venue: "GB"}, orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(102, 3, "d"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(103, 1, "e"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(101, 1, "f"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(100, 0.5, "g"), orderbook.Sell)
+
+	if err := Save(store, "BTCUSD", ob); err != nil {
+		t.Fatal(err)
+	}
+	ob.Submit(orderbook.NewOrder(104, 1, "h"), orderbook.Sell)
+	if err := Save(store, "BTCUSD", ob); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(store, "ETHUSD", orderbook.NewOrderBook()); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := LoadBook(store, "BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, side := range []orderbook.Side{orderbook.Buy, orderbook.Sell} {
+		if !reflect.DeepEqual(ob.Entries(side), restored.Entries(side)) {
+			t.Errorf("Expected %v side to round trip:\n%+v\n%+v", side, ob.Entries(side), restored.Entries(side))
+		}
+	}
+	if pos, _ := restored.QueuePosition("b"); pos.Orders != 1 {
+		t.Errorf("Expected b to stay behind a after restore, got %+v", pos)
+	}
+
+	trades, err := store.LoadTrades("BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Price != 100 || trades[0].Quantity != 0.5 {
+		t.Fatalf("Expected g's fill against a to be recorded, got %+v", trades)
+	}
+	if trades[0].TradeId != 1 || trades[0].Side != orderbook.Sell || trades[0].MakerId != "a" || trades[0].TakerId != "g" {
+		t.Errorf("Expected the recorded trade to keep its id, side and order ids, got %+v", trades[0])
+	}
+}
+
+func TestKVStoreLegacyValues(t *testing.T) {
+	kv := NewMemoryKV()
+	kv.Put(append(orderPrefix("BTCUSD"), "0"...), []byte(`{"side":"buy","key":"a","order":{"price":100,"quantity":1,"orderId":"a"},"weight":1}`))
+	entries, err := KVStore{KV: kv}.LoadOrders("BTCUSD")
+	if err != nil || len(entries) != 1 || entries[0].Order.OrderId != "a" {
+		t.Errorf("Expected an entry stored before envelopes to load, got %+v %v", entries, err)
+	}
+}
+
+func TestSQLRebind(t *testing.T) {
+	s := &SQL{Dialect: Postgres}
+	if q := s.rebind("a = ? AND b = ?"); q != "a = $1 AND b = $2" {
+		t.Errorf("Expected numbered placeholders, got %q", q)
+	}
+	s.Dialect = SQLite
+	if q := s.rebind("a = ?"); q != "a = ?" {
+		t.Errorf("Expected placeholders untouched, got %q", q)
+	}
+}
+
+func TestKVStoreCodec(t *testing.T) {
+	store := KVStore{KV: NewMemoryKV(), Codec: orderbook.GobCodec}
+	ob := orderbook.NewOrderBook()
+	ob.Submit(orderbook.NewOrder(100, 1, "a"), orderbook.Buy)
+	if err := Save(store, "BTCUSD", ob); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := LoadBook(store, "BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, _, ok := restored.Lookup("a"); !ok || o.Price != 100 {
+		t.Errorf("Expected a to load through gob, got %+v %v", o, ok)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	from := KVStore{KV: NewMemoryKV()}
+	to := KVStore{KV: NewMemoryKV(), Codec: orderbook.GobCodec}
+	ob := orderbook.NewOrderBook()
+	recorder := &TradeRecorder{Store: from, Symbol: "BTCUSD"}
+	ob.AddSink(recorder)
+	ob.Submit(orderbook.NewOrder(100, 1, "a"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(100, 2, "b"), orderbook.Buy)
+	ob.Submit(orderbook.NewOrder(101, 3, "c"), orderbook.Sell)
+	ob.Submit(orderbook.NewOrder(100, 0.5, "d"), orderbook.Sell)
+	if err := Save(from, "BTCUSD", ob); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(from, to, "BTCUSD", "ETHUSD"); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Store = to
+	ob.Submit(orderbook.NewOrder(101, 1, "e"), orderbook.Buy)
+
+	restored, err := LoadBook(to, "BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := restored.Entries(orderbook.Buy), ob.Entries(orderbook.Buy); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the bids to migrate in priority order:\n%+v\n%+v", want, got)
+	}
+	trades, err := to.LoadTrades("BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].TakerId != "d" || trades[1].TakerId != "e" {
+		t.Errorf("Expected the migrated trade followed by the one recorded after, got %+v", trades)
+	}
+	if entries, err := to.LoadOrders("ETHUSD"); err != nil || len(entries) != 0 {
+		t.Errorf("Expected nothing for a symbol without orders, got %+v %v", entries, err)
+	}
+}
+
+// fakeDB is a database/sql driver holding just enough of the two tables
+// for the SQL store: the columns each has, and the order rows.
+type fakeDB struct {
+	columns map[string]map[string]bool
+	orders  [][]driver.Value
+	alters  []string
+}
+
+var (
+	createTable = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \(`)
+	addColumn   = regexp.MustCompile(`^ALTER TABLE (\w+) ADD COLUMN (\w+) `)
+	probeColumn = regexp.MustCompile(`^SELECT (\w+) FROM (\w+) WHERE 1 = 0$`)
+)
+
+func (db *fakeDB) Open(string) (driver.Conn, error) { return db, nil }
+func (db *fakeDB) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db, strings.Join(strings.Fields(query), " ")}, nil
+}
+func (db *fakeDB) Close() error              { return nil }
+func (db *fakeDB) Begin() (driver.Tx, error) { return db, nil }
+func (db *fakeDB) Commit() error             { return nil }
+func (db *fakeDB) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.db
+	switch {
+	case createTable.MatchString(s.query):
+		table := createTable.FindStringSubmatch(s.query)[1]
+		if db.columns[table] != nil {
+			break
+		}
+		db.columns[table] = map[string]bool{}
+		for _, def := range strings.Split(s.query[strings.Index(s.query, "(")+1:], ",") {
+			if f := strings.Fields(def); len(f) > 0 && f[0] != "PRIMARY" && f[0] != "position)" {
+				db.columns[table][f[0]] = true
+			}
+		}
+	case addColumn.MatchString(s.query):
+		m := addColumn.FindStringSubmatch(s.query)
+		if db.columns[m[1]][m[2]] {
+			return nil, fmt.Errorf("duplicate column %s", m[2])
+		}
+		db.columns[m[1]][m[2]] = true
+		db.alters = append(db.alters, m[2])
+	case strings.HasPrefix(s.query, "DELETE FROM orderbook_orders"):
+		db.orders = nil
+	case strings.HasPrefix(s.query, "INSERT INTO orderbook_orders"):
+		db.orders = append(db.orders, args)
+	default:
+		return nil, fmt.Errorf("unexpected statement %q", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if m := probeColumn.FindStringSubmatch(s.query); m != nil {
+		if !s.db.columns[m[2]][m[1]] {
+			return nil, fmt.Errorf("no such column: %s", m[1])
+		}
+		return &fakeRows{}, nil
+	}
+	if strings.HasPrefix(s.query, "SELECT side,") {
+		rows := &fakeRows{}
+		for _, r := range s.db.orders {
+			rows.rows = append(rows.rows, r[2:])
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", s.query)
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, 13) }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLMigrations(t *testing.T) {
+	db := &fakeDB{columns: map[string]map[string]bool{
+		"orderbook_orders": {"symbol": true, "position": true, "side": true, "book_key": true, "order_id": true,
+			"price": true, "quantity": true, "country": true, "weight": true, "entered_at": true},
+		"orderbook_trades": {"symbol": true, "sequence": true, "price": true, "quantity": true},
+	}}
+	sql.Register("storagefake", db)
+	conn, err := sql.Open("storagefake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s := &SQL{DB: conn}
+
+	if err := s.CreateTables(); err != nil {
+		t.Fatalf("Expected tables with the old schema to migrate, got %v", err)
+	}
+	if len(db.alters) != len(migrations) {
+		t.Errorf("Expected every column to be added, got %v", db.alters)
+	}
+	db.alters = nil
+	if err := s.CreateTables(); err != nil || len(db.alters) != 0 {
+		t.Errorf("Expected migrated tables to be left alone, got %v %v", err, db.alters)
+	}
+
+	o := orderbook.NewOrder(100, 2, "a")
+	o.Filled, o.Session, o.ClientOrderId, o.Category = 1, "s1", "mine", orderbook.Customer
+	ob := orderbook.NewOrderBook()
+	ob.PushOrder(orderbook.Buy, o)
+	if err := Save(s, "BTCUSD", ob); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.LoadOrders("BTCUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Order != o {
+		t.Errorf("Expected %+v to round trip, got %+v", o, entries)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+)
+
+// DefaultStreamBuffer is how many events a stream holds for a slow
+// consumer unless WithStreamBuffer says otherwise.
+const DefaultStreamBuffer = 64
+
+// WithStreamBuffer sets how many events each Quotes, Trades and DepthDiffs
+// stream buffers. Zero makes streams unbuffered, so they only receive
+// events their consumer is already waiting for.
+func WithStreamBuffer(n int) Option {
+	if n < 0 {
+		n = 0
+	}
+	return func(ob *OrderBook) {
+		ob.streams.buffer = n
+	}
+}
+
+// streams are the channels handed out by Quotes and Trades. They are
+// guarded by the book's eventLock, under which events are published.
+type streams struct {
+	buffer  int
+	quotes  []chan Quote
+	trades  []chan TradeEvent
+	depths  []*depthStream
+	watches []*priceWatch
+	dropped uint64
+}
+
+// Quotes returns a channel receiving every quote the book publishes from
+// now until ctx is done, when the channel is closed. Each call returns its
+// own channel. A consumer that falls a full buffer behind misses quotes
+// rather than holding up the book; see StreamDrops.
+func (ob *OrderBook) Quotes(ctx context.Context) <-chan Quote {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	ch := make(chan Quote, ob.streams.buffer)
+	ob.streams.quotes = append(ob.streams.quotes, ch)
+	go func() {
+		<-ctx.Done()
+		ob.eventLock.Lock()
+		defer ob.eventLock.Unlock()
+
+		for i, c := range ob.streams.quotes {
+			if c == ch {
+				ob.streams.quotes = append(ob.streams.quotes[:i], ob.streams.quotes[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Trades is Quotes for trades, of either aggressor side.
+func (ob *OrderBook) Trades(ctx context.Context) <-chan TradeEvent {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	ch := make(chan TradeEvent, ob.streams.buffer)
+	ob.streams.trades = append(ob.streams.trades, ch)
+	go func() {
+		<-ctx.Done()
+		ob.eventLock.Lock()
+		defer ob.eventLock.Unlock()
+
+		for i, c := range ob.streams.trades {
+			if c == ch {
+				ob.streams.trades = append(ob.streams.trades[:i], ob.streams.trades[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// DepthFilter narrows the diffs a DepthDiffs stream receives. The zero
+// value passes every change.
+type DepthFilter struct {
+	// Sides limits the stream to the given sides; empty passes both.
+	Sides []Side
+	// Levels, when positive, passes only changes to the best Levels of a
+	// side as it stands after the change. A level the stream was sent is
+	// sent as emptied once it falls out of the best Levels through a
+	// change of its own; consumers should still keep only the best Levels,
+	// as better levels arriving push others out without touching them.
+	Levels int
+	// MinChange passes a change to a level only when its quantity has
+	// moved by more than MinChange since the stream was last sent it, or
+	// the level appears or empties.
+	MinChange float64
+}
+
+// depthStream is a DepthDiffs channel with its filter and the quantity
+// last sent for each level it holds.
+type depthStream struct {
+	ch         chan DepthDiff
+	filter     DepthFilter
+	bids, asks map[float64]float64
+}
+
+// DepthDiffs returns a channel receiving the book's diffs, filtered by f
+// before they are sent, from now until ctx is done, when the channel is
+// closed. Filtered diffs keep the book's sequence numbers, so gaps are
+// expected. Like Quotes, a consumer that falls a full buffer behind misses
+// diffs.
+func (ob *OrderBook) DepthDiffs(ctx context.Context, f DepthFilter) <-chan DepthDiff {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	ds := &depthStream{ch: make(chan DepthDiff, ob.streams.buffer), filter: f,
+		bids: make(map[float64]float64), asks: make(map[float64]float64)}
+	ob.streams.depths = append(ob.streams.depths, ds)
+	go func() {
+		<-ctx.Done()
+		ob.eventLock.Lock()
+		defer ob.eventLock.Unlock()
+
+		for i, s := range ob.streams.depths {
+			if s == ds {
+				ob.streams.depths = append(ob.streams.depths[:i], ob.streams.depths[i+1:]...)
+				break
+			}
+		}
+		close(ds.ch)
+	}()
+	return ds.ch
+}
+
+// priceWatch is a WatchPrice channel and the level it follows.
+type priceWatch struct {
+	ch    chan Level
+	side  Side
+	price float64
+}
+
+// WatchPrice returns a channel receiving the aggregate size at price on
+// side each time it changes, with a Quantity of zero when the level
+// empties, from now until ctx is done, when the channel is closed. Like
+// Quotes, a consumer that falls a full buffer behind misses changes.
+func (ob *OrderBook) WatchPrice(ctx context.Context, side Side, price float64) <-chan Level {
+	ob.eventLock.Lock()
+	defer ob.eventLock.Unlock()
+
+	pw := &priceWatch{ch: make(chan Level, ob.streams.buffer), side: side, price: price}
+	ob.streams.watches = append(ob.streams.watches, pw)
+	go func() {
+		<-ctx.Done()
+		ob.eventLock.Lock()
+		defer ob.eventLock.Unlock()
+
+		for i, w := range ob.streams.watches {
+			if w == pw {
+				ob.streams.watches = append(ob.streams.watches[:i], ob.streams.watches[i+1:]...)
+				break
+			}
+		}
+		close(pw.ch)
+	}()
+	return pw.ch
+}
+
+// StreamDrops returns how many events streams have missed because their
+// consumers were too far behind.
+func (ob *OrderBook) StreamDrops() uint64 {
+	return atomic.LoadUint64(&ob.streams.dropped)
+}
+
+func (s *streams) quote(q *Quote) {
+	for _, ch := range s.quotes {
+		select {
+		case ch <- *q:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+func (s *streams) trade(e *TradeEvent) {
+	for _, ch := range s.trades {
+		select {
+		case ch <- *e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// diff filters d for each depth stream and sends what passes. Ranks are
+// read from ob's sides as they stand.
+func (s *streams) diff(ob *OrderBook, d *DepthDiff) {
+	for _, ds := range s.depths {
+		out := DepthDiff{Sequence: d.Sequence}
+		if ds.wants(Buy) {
+			out.Bids = ds.pass(ob.Side(Buy), d.Bids, ds.bids)
+		}
+		if ds.wants(Sell) {
+			out.Asks = ds.pass(ob.Side(Sell), d.Asks, ds.asks)
+		}
+		if len(out.Bids)+len(out.Asks) == 0 {
+			continue
+		}
+		select {
+		case ds.ch <- out:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+	for _, pw := range s.watches {
+		lvls := d.Asks
+		if pw.side == Buy {
+			lvls = d.Bids
+		}
+		for _, l := range lvls {
+			if l.Price != pw.price {
+				continue
+			}
+			select {
+			case pw.ch <- l:
+			default:
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		}
+	}
+}
+
+func (ds *depthStream) wants(side Side) bool {
+	if len(ds.filter.Sides) == 0 {
+		return true
+	}
+	return hasSide(ds.filter.Sides, side)
+}
+
+// pass returns the levels of lvls the stream should be sent, updating
+// sent, the quantities it was last sent on sb's side.
+func (ds *depthStream) pass(sb *SideBook, lvls []Level, sent map[float64]float64) []Level {
+	var out []Level
+	for _, l := range lvls {
+		last, held := sent[l.Price]
+		switch {
+		case l.Quantity <= 0 || ds.filter.Levels > 0 && sb.levelRank(l.Price) >= ds.filter.Levels:
+			if held {
+				out = append(out, Level{l.Price, 0})
+				delete(sent, l.Price)
+			}
+		case !held || math.Abs(l.Quantity-last) > ds.filter.MinChange:
+			out = append(out, l)
+			sent[l.Price] = l.Quantity
+		}
+	}
+	return out
+}
+
+// backlog is the number of events buffered across every stream.
+func (s *streams) backlog() int {
+	var n int
+	for _, ch := range s.quotes {
+		n += len(ch)
+	}
+	for _, ch := range s.trades {
+		n += len(ch)
+	}
+	for _, ds := range s.depths {
+		n += len(ds.ch)
+	}
+	for _, pw := range s.watches {
+		n += len(pw.ch)
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStreams(t *testing.T) {
+	ob := NewOrderBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	quotes := []<-chan Quote{ob.Quotes(ctx), ob.Quotes(ctx)}
+	trades := ob.Trades(ctx)
+
+	ob.Submit(NewOrder(101, 1, "a"), Sell)
+	ob.Submit(NewOrder(101, 1, "b"), Buy)
+
+	for i, ch := range quotes {
+		if q := <-ch; q.Ask == nil || q.Ask.OrderId != "a" {
+			t.Errorf("Expected consumer %d to see the a ask quoted, got %+v", i, q)
+		}
+		if q := <-ch; q.Ask != nil || q.Bid != nil {
+			t.Errorf("Expected consumer %d to see the book empty after the trade, got %+v", i, q)
+		}
+	}
+	if trade := <-trades; trade.MakerId != "a" || trade.TakerId != "b" || trade.Side != Buy {
+		t.Errorf("Expected b to buy from a, got %+v", trade)
+	}
+	if n := ob.Backlog(); n != 0 {
+		t.Errorf("Expected drained streams to have no backlog, got %d", n)
+	}
+
+	cancel()
+	for _, ch := range quotes {
+		if _, ok := <-ch; ok {
+			t.Errorf("Expected quote stream to close once its context is done")
+		}
+	}
+	if _, ok := <-trades; ok {
+		t.Errorf("Expected trade stream to close once its context is done")
+	}
+}
+
+func TestStreamDrops(t *testing.T) {
+	ob := NewOrderBook(WithStreamBuffer(1))
+	quotes := ob.Quotes(context.Background())
+	ob.PushOrder(Sell, NewOrder(101, 1, "a"))
+	ob.PushOrder(Sell, NewOrder(100, 1, "b"))
+	ob.PushOrder(Sell, NewOrder(99, 1, "c"))
+
+	if n := ob.Backlog(); n != 1 {
+		t.Errorf("Expected one buffered quote, got %d", n)
+	}
+	if drops := ob.StreamDrops(); drops != 2 {
+		t.Errorf("Expected 2 quotes dropped, got %d", drops)
+	}
+	if q := <-quotes; q.Ask.OrderId != "a" {
+		t.Errorf("Expected the buffered quote to be the first, got %s", q.Ask.OrderId)
+	}
+}
+
+func TestDepthDiffs(t *testing.T) {
+	ob := NewOrderBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	all := ob.DepthDiffs(ctx, DepthFilter{})
+	bids := ob.DepthDiffs(ctx, DepthFilter{Sides: []Side{Buy}})
+	top := ob.DepthDiffs(ctx, DepthFilter{Levels: 2})
+	large := ob.DepthDiffs(ctx, DepthFilter{MinChange: 1})
+
+	ob.Submit(NewOrder(100, 1, "b1"), Buy)   // 1
+	ob.Submit(NewOrder(101, 1, "a1"), Sell)  // 2
+	ob.Submit(NewOrder(99, 1, "b2"), Buy)    // 3
+	ob.Submit(NewOrder(98, 1, "b3"), Buy)    // 4: third bid level
+	ob.Submit(NewOrder(100, 0.5, "b4"), Buy) // 5: +0.5 at 100
+	ob.Submit(NewOrder(100, 1, "b5"), Buy)   // 6: +1 at 100
+	ob.Cancel("b2")                          // 7: 99 empties, 98 moves up
+	ob.Submit(NewOrder(98, 1, "b6"), Buy)    // 8: +1 at 98, not past MinChange
+	cancel()
+
+	collect := func(ch <-chan DepthDiff) []DepthDiff {
+		var out []DepthDiff
+		for d := range ch {
+			out = append(out, d)
+		}
+		return out
+	}
+	if diffs := collect(all); len(diffs) != 8 {
+		t.Errorf("Expected every diff unfiltered, got %+v", diffs)
+	}
+	for _, d := range collect(bids) {
+		if len(d.Asks) != 0 {
+			t.Errorf("Expected only bids, got %+v", d)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		ch       <-chan DepthDiff
+		expected []DepthDiff
+	}{
+		{"top", top, []DepthDiff{
+			{Bids: []Level{{100, 1}}},
+			{Asks: []Level{{101, 1}}},
+			{Bids: []Level{{99, 1}}},
+			{Bids: []Level{{100, 1.5}}},
+			{Bids: []Level{{100, 2.5}}},
+			{Bids: []Level{{99, 0}}},
+			{Bids: []Level{{98, 2}}},
+		}},
+		{"large", large, []DepthDiff{
+			{Bids: []Level{{100, 1}}},
+			{Asks: []Level{{101, 1}}},
+			{Bids: []Level{{99, 1}}},
+			{Bids: []Level{{98, 1}}},
+			{Bids: []Level{{100, 2.5}}},
+			{Bids: []Level{{99, 0}}},
+		}},
+	}
+	for _, tt := range tests {
+		diffs := collect(tt.ch)
+		if len(diffs) != len(tt.expected) {
+			t.Errorf("Expected %d %s diffs, got %+v", len(tt.expected), tt.name, diffs)
+			continue
+		}
+		for i, d := range diffs {
+			want := tt.expected[i]
+			if !sameLevels(d.Bids, want.Bids) || !sameLevels(d.Asks, want.Asks) {
+				t.Errorf("Expected %s diff %d to be %+v, got %+v", tt.name, i, want, d)
+			}
+		}
+	}
+}
+
+func TestWatchPrice(t *testing.T) {
+	ob := NewOrderBook()
+	ctx, cancel := context.WithCancel(context.Background())
+	watch := ob.WatchPrice(ctx, Buy, 100)
+
+	ob.Submit(NewOrder(100, 1, "b1"), Buy)
+	ob.Submit(NewOrder(99, 1, "b2"), Buy)
+	ob.Submit(NewOrder(100, 2, "a1"), Sell) // fills b1 and rests at 100 on the ask
+	ob.Submit(NewOrder(100, 2, "b3"), Buy)
+	ob.Submit(NewOrder(100, 3, "x"), Sell)
+	cancel()
+
+	var got []Level
+	for l := range watch {
+		got = append(got, l)
+	}
+	expected := []Level{{100, 1}, {100, 0}, {100, 1}, {100, 0}}
+	if !sameLevels(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func sameLevels(a, b []Level) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
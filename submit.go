@@ -0,0 +1,262 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrMissingOrderId  = errors.New("orderbook: order id is required")
+	ErrInvalidPrice    = errors.New("orderbook: price must be positive and finite")
+	ErrInvalidQuantity = errors.New("orderbook: quantity must be positive and finite")
+)
+
+type ExecStatus int
+
+const (
+	StatusNew ExecStatus = iota
+	StatusPartiallyFilled
+	StatusFilled
+	StatusRejected
+	StatusCanceled
+)
+
+func (s ExecStatus) String() string {
+	return [...]string{"new", "partially_filled", "filled", "rejected", "canceled"}[s]
+}
+
+func (s ExecStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ExecutionReport describes the outcome of a Submit.
+type ExecutionReport struct {
+	OrderId   string       `json:"orderId"`
+	Side      Side         `json:"side"`
+	Status    ExecStatus   `json:"status"`
+	Trades    []TradeEvent `json:"trades,omitempty"`
+	Filled    float64      `json:"filled"`
+	Remaining float64      `json:"remaining"`
+	Fees      float64      `json:"fees,omitempty"`
+	// Triggered holds the reports of stops fired by this order's trades.
+	Triggered []ExecutionReport `json:"triggered,omitempty"`
+	Resting   bool              `json:"resting"`
+	Err       error             `json:"-"`
+	// ClientOrderId echoes the submitted order's client id.
+	ClientOrderId string `json:"clientOrderId,omitempty"`
+	// Annotations are free for hooks to fill in.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// FillLatency is the time from the order's receipt to its last fill,
+	// by the book's monotonic clock.
+	FillLatency time.Duration `json:"fillLatency,omitempty"`
+}
+
+// WithoutMatching makes Submit rest every order without matching it,
+// for books that aggregate venues and may legitimately cross.
+func WithoutMatching() Option {
+	return func(ob *OrderBook) {
+		ob.noMatching = true
+	}
+}
+
+// WithNegativePrices lets orders rest and trade at zero and negative
+// prices, as energy and some futures markets do. Books are spot-style
+// otherwise and reject any price that is not positive. Zero keeps its
+// meanings elsewhere: a market stop, an amend that leaves the price, an
+// unset peg limit.
+func WithNegativePrices() Option {
+	return func(ob *OrderBook) {
+		ob.negative, ob.AskBook.negative, ob.BidBook.negative = true, true, true
+	}
+}
+
+func (ob *OrderBook) validPrice(price float64) bool {
+	return validPrice(price, ob.negative)
+}
+
+// validPrice reports whether price is finite and, unless negative prices
+// are allowed, positive.
+func validPrice(price float64, negative bool) bool {
+	if negative {
+		return !math.IsNaN(price) && !math.IsInf(price, 0)
+	}
+	return price > 0 && !math.IsInf(price, 0)
+}
+
+// validWeight reports whether weight is finite and positive.
+func validWeight(weight float64) bool {
+	return weight > 0 && !math.IsInf(weight, 0)
+}
+
+func (ob *OrderBook) validate(o *Order) error {
+	if o.OrderId == "" {
+		return ErrMissingOrderId
+	}
+	return ob.validOrder(o)
+}
+
+// validOrder is validate for an order whose id is yet to be generated.
+func (ob *OrderBook) validOrder(o *Order) error {
+	if !ob.validPrice(o.Price) {
+		return ErrInvalidPrice
+	}
+	if !(o.Quantity > 0) || math.IsInf(o.Quantity, 0) {
+		return ErrInvalidQuantity
+	}
+	if err := ob.units.check(o.Quantity); err != nil {
+		return err
+	}
+	return ob.instrument.Validate(o)
+}
+
+// Submit validates order, matches it against the opposite side unless the
+// book was built WithoutMatching, and rests any remainder on side keyed by
+// its OrderId, which is generated if missing and the book has an
+// IDGenerator. Its trades then drive any pending stops.
+func (ob *OrderBook) Submit(order Order, side Side) ExecutionReport {
+	received := ob.monotonic()
+	ob.assignId(&order)
+	report := ExecutionReport{OrderId: order.OrderId, ClientOrderId: order.ClientOrderId,
+		Side: side, Remaining: order.Quantity}
+	if err := ob.admit(side, &order); err != nil {
+		ob.reject(&report, err)
+		return report
+	}
+	ob.execute(&order, side, &report)
+	report.FillLatency = fillLatency(report.Trades, received)
+	report.Triggered = ob.runStops(tradePrices(report.Trades))
+	return report
+}
+
+// admit runs Submit's checks on order: its own validation, the book's
+// validators, the duplicate policy and the rate limit.
+func (ob *OrderBook) admit(side Side, order *Order) error {
+	if err := ob.validate(order); err != nil {
+		return err
+	}
+	for _, v := range ob.validators {
+		if err := v.Validate(ob, side, order); err != nil {
+			return err
+		}
+	}
+	if ob.Side(side).rejects(order.OrderId) {
+		return ErrDuplicateOrder
+	}
+	return ob.rateLimited(side, order)
+}
+
+// execute runs the pre-match hooks, matches o unless matching is disabled
+// and rests the remainder. Market orders, priced at an infinity, never
+// rest.
+func (ob *OrderBook) execute(o *Order, side Side, report *ExecutionReport) {
+	if err := ob.preMatch(side, o); err != nil {
+		ob.reject(report, err)
+		return
+	}
+	err := ob.matchAndRest(o, side, report)
+	switch {
+	case err == nil:
+		ob.postMatch(report)
+	case report.Filled == 0:
+		ob.reject(report, err)
+	default:
+		report.Err = err
+		ob.postMatch(report)
+	}
+}
+
+// matchAndRest matches o unless matching is disabled and rests the
+// remainder, under the match lock.
+func (ob *OrderBook) matchAndRest(o *Order, side Side, report *ExecutionReport) error {
+	ob.lockMatching()
+	defer ob.unlockMatching()
+
+	var err error
+	if !ob.noMatching {
+		report.Trades, err = ob.match(side, o)
+	}
+	ob.fill(o, report)
+	if err == nil && o.Quantity > 0 && !math.IsInf(o.Price, 0) {
+		n := NewNode(o.OrderId, o, ob.weight(o))
+		err = ob.rest(side, &n)
+		report.Resting = err == nil
+	}
+	return err
+}
+
+// fill sets the report's fill totals and status from o after matching.
+func (ob *OrderBook) fill(o *Order, report *ExecutionReport) {
+	for _, t := range report.Trades {
+		report.Fees += t.TakerFee
+	}
+	report.Filled = ob.units.sub(report.Remaining, o.Quantity)
+	report.Remaining = o.Quantity
+	switch {
+	case o.Quantity <= 0:
+		report.Status, report.Remaining = StatusFilled, 0
+		ob.statuses.set(o.OrderId, StatusFilled)
+	case report.Filled > 0:
+		report.Status = StatusPartiallyFilled
+	default:
+		report.Status = StatusNew
+	}
+}
+
+// PushOrder rests a copy of o on side, keyed by its OrderId, without
+// validating or matching it. An existing order with the same id on that
+// side is handled by the book's DuplicatePolicy. Under a Capacity the
+// order makes room as a Submit remainder would, or is refused with
+// ErrCapacity.
+func (ob *OrderBook) PushOrder(side Side, o Order) error {
+	n := NewNode(o.OrderId, &o, ob.weight(&o))
+	if err := ob.rest(side, &n); err == ErrCapacity {
+		// duplicates are handled by the policy, as with Push
+		return err
+	}
+	return nil
+}
+
+// Cancel removes the order with the given id from whichever side it rests
+// on and reports whether it was found.
+func (ob *OrderBook) Cancel(orderId string) bool {
+	return ob.cancelOn(Buy, orderId) || ob.cancelOn(Sell, orderId)
+}
+
+// cancelOn is Cancel for an order resting on side.
+func (ob *OrderBook) cancelOn(side Side, orderId string) bool {
+	b := ob.Side(side)
+	n, ok := b.lookup(orderId)
+	if !ok {
+		return false
+	}
+	if o := n.Peek(); ob.limiter != nil && o != nil && o.Account != "" {
+		ob.limiter.cancelled(o.Account, b.clock())
+	}
+	b.Remove(orderId)
+	return true
+}
+
+// Lookup returns a copy of the resting order with the given id and the
+// side it rests on.
+func (ob *OrderBook) Lookup(orderId string) (Order, Side, bool) {
+	for _, side := range []Side{Buy, Sell} {
+		if n, ok := ob.Side(side).Get(orderId); ok && n.Peek() != nil {
+			return *n.Peek(), side, true
+		}
+	}
+	return Order{}, 0, false
+}
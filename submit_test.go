@@ -0,0 +1,133 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubmit(t *testing.T) {
+	ob := NewOrderBook()
+	steps := []struct {
+		Name      string
+		Order     Order
+		Side      Side
+		Status    ExecStatus
+		Filled    float64
+		Remaining float64
+		Resting   bool
+		Err       error
+	}{
+		{"rest-ask", NewOrder(101, 2, "a"), Sell, StatusNew, 0, 2, true, nil},
+		{"rest-bid", NewOrder(99, 1, "b"), Buy, StatusNew, 0, 1, true, nil},
+		{"partial", NewOrder(101, 3, "c"), Buy, StatusPartiallyFilled, 2, 1, true, nil},
+		{"filled", NewOrder(98, 1, "d"), Sell, StatusFilled, 1, 0, false, nil},
+		{"no-id", NewOrder(100, 1, ""), Buy, StatusRejected, 0, 1, false, ErrMissingOrderId},
+		{"bad-price", NewOrder(math.NaN(), 1, "e"), Buy, StatusRejected, 0, 1, false, ErrInvalidPrice},
+		{"bad-quantity", NewOrder(100, -1, "f"), Sell, StatusRejected, 0, -1, false, ErrInvalidQuantity},
+	}
+	for _, step := range steps {
+		t.Run(step.Name, func(t *testing.T) {
+			r := ob.Submit(step.Order, step.Side)
+			if r.Status != step.Status || r.Filled != step.Filled || r.Remaining != step.Remaining || r.Resting != step.Resting || r.Err != step.Err {
+				t.Errorf("Expected %v filled %f remaining %f resting %t err %v, got %+v",
+					step.Status, step.Filled, step.Remaining, step.Resting, step.Err, r)
+			}
+		})
+	}
+	if price, size, _ := ob.BestBid(); price != 99 || size != 1 {
+		t.Errorf("Expected only b to remain at 99, got %f at %f", size, price)
+	}
+	if _, ok := ob.BidBook.Get("c"); ok {
+		t.Errorf("Expected c's remainder to be filled by d ahead of b")
+	}
+}
+
+func TestSubmitWithoutMatching(t *testing.T) {
+	ob := NewOrderBook(WithoutMatching())
+	ob.Submit(NewOrder(100, 1, "a"), Sell)
+	r := ob.Submit(NewOrder(101, 1, "b"), Buy)
+	if r.Status != StatusNew || len(r.Trades) != 0 || !r.Resting {
+		t.Errorf("Expected crossing order to rest untouched, got %+v", r)
+	}
+	if spread, _ := ob.Spread(); spread != -1 {
+		t.Errorf("Expected a crossed book, got spread %f", spread)
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	ob := NewOrderBook()
+	o := NewOrder(100, 1, "a")
+	ob.PushOrder(Buy, o)
+	o.Price = 1
+	found, side, ok := ob.Lookup("a")
+	if !ok || side != Buy || found.Price != 100 {
+		t.Fatalf("Expected a copy of a resting on the bid, got %+v %v %t", found, side, ok)
+	}
+	found.Quantity = 10
+	if ob.BidBook.Peek().Quantity != 1 {
+		t.Errorf("Expected Lookup to return a copy")
+	}
+	if !ob.Cancel("a") {
+		t.Errorf("Expected a to be canceled")
+	}
+	if ob.Cancel("a") {
+		t.Errorf("Expected a second cancel to report not found")
+	}
+	if _, _, ok := ob.Lookup("a"); ok {
+		t.Errorf("Expected a to be gone")
+	}
+}
+
+func TestNegativePrices(t *testing.T) {
+	spot := NewOrderBook()
+	for _, price := range []float64{0, -1} {
+		if r := spot.Submit(NewOrder(price, 1, "a"), Buy); r.Err != ErrInvalidPrice {
+			t.Errorf("Expected a spot book to reject %v, got %v", price, r.Err)
+		}
+	}
+
+	ob := NewOrderBook(WithNegativePrices(), WithFees(Fees{MakerBps: 10, TakerBps: 20}))
+	ob.Submit(NewOrder(-5, 1, "b1"), Buy)
+	ob.Submit(NewOrder(-4, 1, "b2"), Buy)
+	ob.Submit(NewOrder(0, 1, "a1"), Sell)
+	ob.Submit(NewOrder(-2, 1, "a2"), Sell)
+	if price, _, _ := ob.BestBid(); price != -4 {
+		t.Errorf("Expected -4 to be the best bid, got %v", price)
+	}
+	if price, _, _ := ob.BestAsk(); price != -2 {
+		t.Errorf("Expected -2 to be the best ask, got %v", price)
+	}
+	if mid, _ := ob.Midpoint(); mid != -3 {
+		t.Errorf("Expected a midpoint of -3, got %v", mid)
+	}
+	if bps, _ := ob.SpreadBps(); math.Abs(bps-20000.0/3) > 1e-9 {
+		t.Errorf("Expected the spread in bps of the midpoint's magnitude, got %v", bps)
+	}
+	if c := ob.CostToSell(2); c.Notional != -9 || math.Abs(c.Fees-9*20/1e4) > 1e-12 || c.WorstPrice != -5 {
+		t.Errorf("Expected selling 2 to cost -9 plus fees on 9, got %+v", c)
+	}
+
+	r := ob.Submit(NewOrder(-4.5, 2, "x"), Sell)
+	if r.Filled != 1 || len(r.Trades) != 1 || r.Trades[0].Price != -4 || math.Abs(r.Trades[0].TakerFee-4*20/1e4) > 1e-12 {
+		t.Errorf("Expected x to sell 1 at -4 and pay a positive fee, got %+v", r)
+	}
+	if _, err := ob.Batch([]Command{{Op: OpAmend, Side: Buy, Order: Order{OrderId: "b1", Price: -6}}}); err != nil {
+		t.Errorf("Expected an amend to a negative price, got %v", err)
+	}
+	if r := ob.SubmitStop(Stop{Order: NewOrder(0, 1, "s"), Side: Sell, StopPrice: -10}); r.Err != nil {
+		t.Errorf("Expected a negative stop price to be accepted, got %v", r.Err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import "sync/atomic"
+
+// viewState holds the snapshot published for lock-free reads.
+type viewState struct {
+	current atomic.Pointer[BookSnapshot]
+}
+
+// WithSnapshotReads rebuilds an immutable snapshot of the whole book after
+// every mutation and swaps it in atomically. View, Depth, BestBid,
+// BestAsk and Entries then read it without taking any side lock, at the
+// cost of copying the book on each write. Reads may trail a concurrent
+// write, but the snapshot they see is always a consistent one newer than
+// any seen before.
+func WithSnapshotReads() Option {
+	return func(ob *OrderBook) {
+		ob.view = &viewState{}
+		ob.view.current.Store(ob.snapshot())
+	}
+}
+
+// refreshView publishes a new snapshot. The caller holds eventLock, which
+// serialises rebuilds so a slower one never replaces a newer snapshot.
+func (ob *OrderBook) refreshView() {
+	if ob.view == nil {
+		return
+	}
+	ob.view.current.Store(ob.snapshot())
+}
+
+// View returns the latest published snapshot, which callers must not
+// modify; without WithSnapshotReads it takes a fresh Snapshot.
+func (ob *OrderBook) View() *BookSnapshot {
+	if ob.view == nil {
+		return ob.Snapshot()
+	}
+	return ob.view.current.Load()
+}
+
+// viewDepth is Depth read from the published snapshot.
+func (ob *OrderBook) viewDepth(n int) (bids, asks []Level) {
+	v := ob.view.current.Load()
+	top := func(lvls []Level) []Level {
+		if n > 0 && len(lvls) > n {
+			lvls = lvls[:n]
+		}
+		return append([]Level(nil), lvls...)
+	}
+	return top(v.Bids), top(v.Asks)
+}
+
+func (ob *OrderBook) viewBest(side Side) (price, size float64, ok bool) {
+	v := ob.view.current.Load()
+	lvls := v.Bids
+	if side == Sell {
+		lvls = v.Asks
+	}
+	if len(lvls) == 0 {
+		return 0, 0, false
+	}
+	return lvls[0].Price, lvls[0].Quantity, true
+}
+
+func (ob *OrderBook) viewEntries(side Side) []Entry {
+	var entries []Entry
+	for _, e := range ob.view.current.Load().Orders {
+		if e.Side == side {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
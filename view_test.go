@@ -0,0 +1,79 @@
+// Copyright 2019 Lane A. Shetron
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package orderbook
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotReads(t *testing.T) {
+	ob := NewOrderBook(WithSnapshotReads())
+	if _, _, ok := ob.BestBid(); ok {
+		t.Errorf("Expected an empty initial view")
+	}
+	ob.Submit(NewOrder(99, 2, "b1"), Buy)
+	ob.Submit(NewOrder(98, 1, "b2"), Buy)
+	ob.Submit(NewOrder(101, 3, "a1"), Sell)
+	before := ob.View()
+
+	ob.Submit(NewOrder(101, 1, "t"), Buy)
+	if price, size, ok := ob.BestAsk(); !ok || price != 101 || size != 2 {
+		t.Errorf("Expected best ask 101 x 2 after the fill, got %g x %g", price, size)
+	}
+	if before.Asks[0].Quantity != 3 {
+		t.Errorf("Expected an earlier view to be unaffected, got %g", before.Asks[0].Quantity)
+	}
+	bids, _ := ob.Depth(1)
+	if !reflect.DeepEqual(bids, []Level{{99, 2}}) {
+		t.Errorf("Expected top bid level from the view, got %v", bids)
+	}
+	bids[0].Quantity = 100
+	if ob.View().Bids[0].Quantity != 2 {
+		t.Errorf("Expected Depth to return a copy of the view")
+	}
+	if e := ob.Entries(Buy); len(e) != 2 || e[0].Key != "b1" || e[1].Key != "b2" {
+		t.Errorf("Expected bid entries in priority order, got %+v", e)
+	}
+}
+
+func TestSnapshotReadsConcurrent(t *testing.T) {
+	ob := NewOrderBook(WithSnapshotReads())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			ob.Submit(NewOrder(float64(100+i%10), 1, fmt.Sprint("a", i)), Sell)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var last uint64
+		for i := 0; i < 200; i++ {
+			v := ob.View()
+			if v.Sequence < last {
+				t.Errorf("Expected views to move forward, got %d after %d", v.Sequence, last)
+			}
+			last = v.Sequence
+			ob.Depth(5)
+		}
+	}()
+	wg.Wait()
+	if n := len(ob.Entries(Sell)); n != 200 {
+		t.Errorf("Expected 200 entries in the final view, got %d", n)
+	}
+}